@@ -0,0 +1,34 @@
+// Package openapi содержит описание HTTP API сервиса (openapi.yaml, OpenAPI 3.0):
+// эндпоинты, параметры, ответы и схемы заказа. Описание встроено в бинарник,
+// отдается в /openapi.json и проверяет параметры запросов (см. web.APISpec).
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// YAML возвращает описание в исходном виде.
+func YAML() []byte {
+	return spec
+}
+
+// Load разбирает описание, разрешает ссылки $ref и проверяет его по спецификации OpenAPI 3.
+func Load(ctx context.Context) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	loader.Context = ctx
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("load openapi: %w", err)
+	}
+	if err := doc.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("validate openapi: %w", err)
+	}
+	return doc, nil
+}
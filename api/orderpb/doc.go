@@ -0,0 +1,5 @@
+// Package orderpb содержит gRPC API заказов (order.proto) для внутренних сервисов:
+// чтение, выборки и поток новых заказов. Код *.pb.go генерируется из order.proto.
+package orderpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative order.proto
@@ -2,88 +2,457 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"html/template"
-	"log"
+	"log/slog"
 	"os"
-	"os/signal"
-	"strconv"
 	"time"
 
 	"github.com/mitrich772/go-order-service/internal/cache"
+	"github.com/mitrich772/go-order-service/internal/config"
 	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/feed"
+	"github.com/mitrich772/go-order-service/internal/grpcapi"
+	"github.com/mitrich772/go-order-service/internal/health"
 	"github.com/mitrich772/go-order-service/internal/kafka"
+	"github.com/mitrich772/go-order-service/internal/lifecycle"
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"github.com/mitrich772/go-order-service/internal/metrics"
+	"github.com/mitrich772/go-order-service/internal/pii"
+	"github.com/mitrich772/go-order-service/internal/retry"
+	"github.com/mitrich772/go-order-service/internal/tracing"
 	"github.com/mitrich772/go-order-service/internal/web"
 )
 
 func main() {
-	log.Println("Программа запущена")
-
-	// Параметры для БД читаются из .env (если заданы), иначе используются значения по умолчанию.
-	cfg := database.Config{
-		User:     getenv("DB_USER", "serviceuser"),
-		Password: getenv("DB_PASSWORD", "123"),
-		DBName:   getenv("DB_NAME", "order_management"),
-		SSLMode:  "disable",
-		Host:     getenv("DB_HOST", "localhost"),
-		Port:     getenv("DB_PORT", "5432"),
+	// --- Конфигурация: значения по умолчанию < файл (-config) < окружение < флаги ---
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	printConfig := fs.Bool("print-config", false, "вывести действующую конфигурацию (секреты скрыты) и выйти")
+	cfg, err := config.Load(fs, os.Args[1:])
+	if err != nil {
+		slog.Error("Ошибка конфигурации", logging.Err(err))
+		os.Exit(2)
+	}
+	if *printConfig {
+		if err := cfg.Write(os.Stdout); err != nil {
+			slog.Error("Ошибка вывода конфигурации", logging.Err(err))
+			os.Exit(1)
+		}
+		return
 	}
 
+	setupLogging(cfg.Log)
+	slog.Info("Программа запущена")
+
+	// --- Жизненный цикл ---
+	// Компоненты останавливаются в обратном порядке регистрации:
+	// лента заказов → gRPC → HTTP → Kafka consumer → фоновые задачи и writers → БД → трассировка.
+	lc := lifecycle.New()
+
+	// --- Трассировка ---
+	traces := setupTracing(cfg.Trace)
+	lc.OnStop("tracing", traces.Shutdown)
+
 	// --- Получаем соединение с gorm ---
-	gorm := database.ConnectDB(cfg)
-	defer database.Close(gorm)
+	gorm := database.ConnectDB(cfg.DB.Connection())
+	lc.OnStop("postgres", func(context.Context) error {
+		database.Close(gorm)
+		return nil
+	})
 
 	// --- Создаем обертку для работы с gorm ---
-	database := database.NewGormDatabase(gorm, 3, 500*time.Millisecond)
+	database := database.NewGormDatabase(gorm, cfg.DB.Retries, cfg.DB.RetryDelay)
+	database.UseBreaker(retry.NewBreaker(cfg.DB.BreakerThreshold, cfg.DB.BreakerCooldown))
+	cipher := newCipher(cfg.PII.KeyFile)
+	database.UseEncryption(cipher)
+
+	// --- Метрики ---
+	appMetrics := metrics.NewDefault()
+	database.UseMetrics(appMetrics)
+
+	// --- Health ---
+	checks := health.NewRegistry(2 * time.Second)
+	checks.AddReadiness("lifecycle", health.CheckerFunc(lc.CheckReady))
+	// С кэшем сервис отдает закэшированные заказы и без БД: ее недоступность — degraded, а не 503.
+	addDBCheck := checks.AddReadiness
+	if cfg.Cache.Enabled {
+		addDBCheck = checks.AddDegradable
+	}
+	addDBCheck("postgres", health.CheckerFunc(func(ctx context.Context) (any, error) {
+		return nil, database.Ping(ctx)
+	}))
+	addDBCheck("postgres_circuit", database.Breaker())
+
+	// --- Лента новых заказов для gRPC WatchOrders, SSE и WebSocket ---
+	orderFeed := feed.New(cfg.Feed.Buffer, cfg.Feed.History)
 
 	// --- Создание OrderStore ---
 	var store cache.OrderStore
-	storeCap, err := strconv.Atoi(getenv("CACHE_SIZE", "50"))
-	if err != nil {
-		log.Printf("Ошибка перевода storeCap %v", err)
-		storeCap = 50
-	}
-	if getenv("ENABLE_CACHE", "true") == "true" {
-		store = cache.NewDBWithCacheStore(database, storeCap)
+	var cacheAdmin cache.Admin
+	ctx, cancel := context.WithCancel(context.Background())
+	lc.OnStop("background", func(context.Context) error {
+		cancel()
+		return nil
+	})
+	if cfg.Cache.Enabled {
+		cacheStore := cache.NewDBWithCache(database, newCache(cfg.Cache, cfg.Redis, cipher))
+		accessLog := startAccessLog(lc, cacheStore, cfg.Cache)
+		restored := restoreSnapshot(lc, cacheStore, cfg.Cache, cipher)
+		if !restored || cfg.Cache.WarmupAfterSnapshot {
+			startWarmup(ctx, cacheStore, cfg.Cache, accessLog)
+		}
+		enableInvalidation(ctx, lc, cacheStore, cfg)
+		checks.AddReadiness("cache_warmup", health.CheckerFunc(cacheStore.CheckWarmup))
+		appMetrics.Register(cache.NewCollector(cacheStore))
+		cacheStore.UseFeed(orderFeed)
+		cacheStore.UseLookupCache(cfg.Cache.LookupSize, cfg.Cache.LookupTTL)
+		store = cacheStore
+		cacheAdmin = cacheStore
 	} else {
-		store = cache.NewDBStore(database)
+		dbStore := cache.NewDBStore(database)
+		dbStore.UseFeed(orderFeed)
+		store = dbStore
 	}
 
-	// --- Web ---
-	tpl := template.Must(template.ParseFiles("templates/index.html"))
-	webPort := getenv("PORT", "3000")
-
-	web.Start(store, tpl, webPort)
-
 	// --- Kafka ---
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 	consumer := kafka.NewConsumer(
 		store,
-		[]string{getenv("KAFKA_BROKERS", "localhost:9092")},
-		getenv("KAFKA_TOPIC", "orders"),
-		getenv("KAFKA_GROUP", "order-service"),
-		getenv("KAFKA_DLQ_TOPIC", "orders-dlq"),
+		cfg.Kafka.Brokers,
+		cfg.Kafka.Topic,
+		cfg.Kafka.Group,
+		cfg.Kafka.DLQTopic,
+		kafka.ReaderOptions{
+			MinBytes:       cfg.Kafka.MinBytes,
+			MaxBytes:       cfg.Kafka.MaxBytes,
+			CommitInterval: cfg.Kafka.CommitInterval,
+		},
 	)
+	consumer.MaxLag = cfg.Kafka.MaxLag
+	consumer.RestartDelay = cfg.Kafka.RestartDelay
+	consumer.Metrics = appMetrics
+	checks.AddReadiness("kafka", health.CheckerFunc(consumer.CheckReader))
+	checks.AddReadiness("kafka_dlq", health.CheckerFunc(consumer.CheckDLQ))
+
+	// --- Web ---
+	tpl := template.Must(template.ParseFiles("templates/index.html"))
+	ui := template.Must(web.ParseUI("templates/ui"))
+	api, err := web.NewAPISpec(template.Must(template.ParseFiles("templates/docs.html")))
+	if err != nil {
+		slog.Error("Ошибка загрузки описания API", logging.Err(err))
+		os.Exit(1)
+	}
+	auth := newAuth(cfg.Auth, cfg.HTTP.AdminToken)
+	auditLog := newAuditLog(cfg.HTTP.AuditLogFile)
+	encodings, _ := web.ParseCompression(cfg.HTTP.Compression) // проверено в config.Validate
+	limiter := newRateLimiter(cfg.RateLimit, appMetrics)
+	httpServer := web.Start(&web.Server{
+		Store:      store,
+		Tpl:        tpl,
+		UI:         ui,
+		Cache:      cacheAdmin,
+		AdminToken: cfg.HTTP.AdminToken,
+		Auth:       auth,
+		Audit:      auditLog,
+		Health:     checks,
+		Metrics:    appMetrics,
+		RateLimit:  limiter,
+		Ingest:     newIngester(lc, cfg, store),
+		Feed:       orderFeed,
+		API:        api,
+
+		CacheControl: cfg.HTTP.CacheControl,
+		Compression:  web.NewCompressor(encodings),
+		UnmaskedPII:  cfg.HTTP.UnmaskedPII,
+	}, cfg.HTTP.Port)
+
 	consumer.Start(ctx)
-	log.Printf("Kafka brokers %v\n", consumer.Brokers)
+	checks.AddLiveness("kafka_consumer", health.CheckerFunc(consumer.CheckAlive))
+	lc.OnStop("kafka consumer", consumer.Stop)
+	lc.OnStop("http", httpServer.Shutdown)
+
+	// --- gRPC ---
+	if cfg.GRPC.Port != "" {
+		grpcServer := grpcapi.Start(&grpcapi.Server{
+			Store:       store,
+			Feed:        orderFeed,
+			Auth:        auth,
+			Audit:       auditLog,
+			Metrics:     appMetrics,
+			RateLimit:   limiter,
+			UnmaskedPII: cfg.HTTP.UnmaskedPII,
+		}, cfg.GRPC.Port)
+		lc.OnStop("grpc", func(ctx context.Context) error {
+			return grpcapi.Shutdown(ctx, grpcServer)
+		})
+	}
+	// Подписки WatchOrders, SSE и WebSocket не завершаются сами:
+	// лента закрывается первой, до остановки серверов.
+	lc.OnStop("order feed", func(context.Context) error {
+		orderFeed.Close()
+		return nil
+	})
+	slog.Info("Kafka consumer запущен", slog.Any("brokers", consumer.Brokers), slog.String(logging.KeyTopic, consumer.Topic))
+
 	// --- Graceful shutdown ---
-	waitForShutdown(cancel)
+	if err := lc.Wait(context.Background(), cfg.ShutdownTimeout); err != nil {
+		slog.Error("Сервис остановлен с ошибками", logging.Err(err))
+		os.Exit(1)
+	}
+	slog.Info("Сервис остановлен")
+}
+
+// setupLogging настраивает slog: уровень, формат (text, json) и маскирование
+// payload и персональных данных (отключается ShowSensitive).
+func setupLogging(cfg config.Log) {
+	_, err := logging.Setup(logging.Config{
+		Level:         cfg.Level,
+		Format:        cfg.Format,
+		ShowSensitive: cfg.ShowSensitive,
+	})
+	if err != nil {
+		logging.Setup(logging.Config{})
+		slog.Warn("Ошибка настройки логирования, используется text/info", logging.Err(err))
+	}
+}
+
+// setupTracing настраивает OpenTelemetry по экспортеру: none, stdout или otlp
+// (коллектор по OTLP/HTTP).
+func setupTracing(cfg config.Trace) *tracing.Provider {
+	traces, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Exporter,
+		Endpoint:    cfg.OTLPEndpoint,
+		Insecure:    cfg.OTLPInsecure,
+		ServiceName: cfg.ServiceName,
+		SampleRatio: cfg.SampleRatio,
+	})
+	if err != nil {
+		slog.Error("Ошибка настройки трассировки, трассировка выключена", logging.Err(err))
+		return nil
+	}
+	return traces
+}
+
+// newCipher загружает ключи шифрования персональных данных из path (pii.key_file).
+// Без файла данные хранятся открытыми; нечитаемый файл останавливает сервис,
+// чтобы не записать персональные данные в открытом виде по ошибке конфигурации.
+func newCipher(path string) *pii.Cipher {
+	if path == "" {
+		slog.Warn("PII_KEY_FILE не задан, персональные данные хранятся без шифрования")
+		return nil
+	}
+	keys, err := pii.LoadKeyFile(path)
+	if err != nil {
+		slog.Error("Ошибка загрузки ключей шифрования", slog.String("path", path), logging.Err(err))
+		os.Exit(1)
+	}
+	current, _, _ := keys.CurrentKey()
+	slog.Info("Шифрование персональных данных включено", slog.String("key_id", current))
+	return pii.NewCipher(keys)
+}
+
+// newAuth настраивает аутентификацию API по API-ключам (subject:role:key через запятую),
+// секрету HS256, публичному ключу RS256 (PEM), ожидаемым issuer и audience.
+// Без ключей и JWT возвращает nil: заказы доступны без входа (с маскированием), /admin/ — по ADMIN_TOKEN.
+// Ошибка конфигурации останавливает сервис, чтобы API не оказался открытым.
+func newAuth(auth config.Auth, adminToken string) *web.Authenticator {
+	secret := auth.JWTSecret
+	publicKeyFile := auth.JWTPublicKeyFile
+	if auth.APIKeys == "" && secret == "" && publicKeyFile == "" {
+		return nil
+	}
+
+	keys, err := web.ParseAPIKeys(auth.APIKeys)
+	if err != nil {
+		slog.Error("Ошибка чтения AUTH_API_KEYS", logging.Err(err))
+		os.Exit(1)
+	}
+	if adminToken != "" {
+		keys[adminToken] = web.Principal{Subject: "admin", Role: web.RoleAdmin}
+	}
+	cfg := web.AuthConfig{
+		APIKeys:   keys,
+		JWTSecret: []byte(secret),
+		Issuer:    auth.JWTIssuer,
+		Audience:  auth.JWTAudience,
+	}
+	if publicKeyFile != "" {
+		if cfg.JWTPublicKey, err = web.LoadRSAPublicKey(publicKeyFile); err != nil {
+			slog.Error("Ошибка загрузки ключа RS256", slog.String("path", publicKeyFile), logging.Err(err))
+			os.Exit(1)
+		}
+	}
+	slog.Info("Аутентификация API включена",
+		slog.Int("api_keys", len(keys)),
+		slog.Bool("jwt_hs256", secret != ""),
+		slog.Bool("jwt_rs256", cfg.JWTPublicKey != nil),
+	)
+	return web.NewAuthenticator(cfg)
+}
+
+// newAuditLog открывает журнал аудита обращений к заказам path (дозапись),
+// без файла аудит пишется в stdout.
+func newAuditLog(path string) *web.AuditLog {
+	if path == "" {
+		return web.NewAuditLog(os.Stdout)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		slog.Error("Ошибка открытия журнала аудита", slog.String("path", path), logging.Err(err))
+		os.Exit(1)
+	}
+	return web.NewAuditLog(f)
 }
 
-func waitForShutdown(cancel context.CancelFunc) {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	<-c
+// newRateLimiter создает лимиты запросов к HTTP и gRPC API (корзины клиентов общие);
+// формат уже проверен config.Validate.
+func newRateLimiter(cfg config.RateLimit, m *metrics.Metrics) *web.RateLimiter {
+	routes, _ := web.ParseRouteLimits(cfg.Routes)
+	notFound, _ := web.ParseLimit(cfg.NotFound)
+	return web.NewRateLimiter(web.RateLimitConfig{Routes: routes, NotFound: notFound, Metrics: m})
+}
 
-	log.Println("Останавливаем сервис...")
-	cancel()
+// newIngester настраивает прием заказов по HTTP: в режиме store заказы пишутся в store,
+// в режиме kafka — публикуются в топик заказов (writer закрывается при остановке).
+func newIngester(lc *lifecycle.Manager, cfg *config.Config, store cache.OrderStore) *web.Ingester {
+	switch cfg.Ingest.Mode {
+	case config.IngestStore:
+		return web.NewIngester(store, false)
+	case config.IngestKafka:
+		topic := cfg.Ingest.Topic
+		if topic == "" {
+			topic = cfg.Kafka.Topic
+		}
+		publisher := kafka.NewOrderPublisher(cfg.Kafka.Brokers, topic)
+		lc.OnStop("order publisher", func(context.Context) error { return publisher.Close() })
+		slog.Info("Прием заказов по HTTP через Kafka", slog.String(logging.KeyTopic, topic))
+		return web.NewIngester(publisher, true)
+	default:
+		return nil
+	}
+}
+
+// newCache создает кэш по cache.backend: local (LRU в памяти),
+// redis (общий Redis-совместимый сервер) или tiered (LRU перед Redis).
+// Персональные данные в Redis шифруются cipher, как и в БД.
+func newCache(cfg config.Cache, redis config.Redis, cipher *pii.Cipher) cache.Cache {
+	if cfg.Backend == "local" {
+		return cache.NewOrderCache(cfg.Size)
+	}
+
+	shared := cache.NewRedisCache(cache.NewRespClient(cache.RespConfig{
+		Addr:     redis.Addr,
+		Password: redis.Password,
+		DB:       redis.DB,
+	}), redis.Prefix, cfg.TTL)
+	shared.UseEncryption(cipher)
+
+	if cfg.Backend == "tiered" {
+		return cache.NewTieredCache(cache.NewOrderCache(cfg.Size), shared)
+	}
+	return shared
 }
 
-// getenv возвращает значение переменной окружения key
-// fallback если переменная не установлена или пуста
-func getenv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// restoreSnapshot загружает снимок кэша с диска и запускает периодическое сохранение снимков.
+// Последний снимок сохраняется при остановке, после остановки consumer.
+// Восстановленные заказы перечитываются из БД в фоне: за время простоя они могли измениться.
+// Кэш только в Redis переживает перезапуск сам, снимки для него не ведутся.
+// Персональные данные в снимке шифруются cipher. Возвращает true, если кэш восстановлен из снимка.
+func restoreSnapshot(lc *lifecycle.Manager, store *cache.DBWithCacheStore, cfg config.Cache, cipher *pii.Cipher) bool {
+	path := cfg.SnapshotFile
+	if path == "" {
+		return false
+	}
+	if cfg.Backend == "redis" {
+		slog.Info("Снимки кэша не используются: кэш хранится в Redis", slog.String("path", path))
+		return false
+	}
+
+	restored := false
+	snap, err := cache.LoadSnapshot(path, cfg.SnapshotMaxAge, cipher)
+	switch {
+	case err == nil:
+		store.Restore(snap.Orders)
+		restored = true
+		slog.Info("Кэш восстановлен из снимка", slog.String("path", path), slog.Int("orders", len(snap.Orders)))
+		lc.Go("cache snapshot revalidation", func(ctx context.Context) {
+			evicted, err := store.Revalidate(ctx, snap.Orders)
+			if err != nil {
+				slog.Warn("Ошибка проверки заказов из снимка кэша", logging.Err(err))
+				return
+			}
+			slog.Info("Заказы из снимка кэша проверены по БД", slog.Int("evicted", evicted))
+		})
+	case errors.Is(err, os.ErrNotExist):
+		slog.Info("Снимок кэша не найден", slog.String("path", path))
+	default:
+		slog.Warn("Снимок кэша не загружен", slog.String("path", path), logging.Err(err))
+	}
+
+	lc.Go("cache snapshots", func(ctx context.Context) {
+		cache.RunSnapshots(ctx, path, cfg.SnapshotInterval, store.Entries, cipher)
+	})
+	return restored
+}
+
+// enableInvalidation подключает кэш к межэкземплярной инвалидации через Kafka,
+// если задан cache.invalidation_topic. Writer шины закрывается при остановке.
+// Кэш только в Redis общий для всех экземпляров, ему инвалидация не нужна.
+func enableInvalidation(ctx context.Context, lc *lifecycle.Manager, store *cache.DBWithCacheStore, cfg *config.Config) {
+	topic := cfg.Cache.InvalidationTopic
+	if topic == "" {
+		return
+	}
+	if cfg.Cache.Backend == "redis" {
+		slog.Info("Инвалидация кэша не используется: кэш общий для всех экземпляров", slog.String(logging.KeyTopic, topic))
+		return
+	}
+	// Режим уже проверен config.Validate.
+	mode, _ := cache.ParseInvalidationMode(cfg.Cache.InvalidationMode)
+	instance := cfg.InstanceID
+	if instance == "" {
+		instance, _ = os.Hostname()
+	}
+
+	bus := kafka.NewInvalidationBus(cfg.Kafka.Brokers, topic)
+	if err := bus.EnsureTopic(); err != nil {
+		slog.Warn("Не удалось создать топик инвалидации", slog.String(logging.KeyTopic, topic), logging.Err(err))
+	}
+	store.EnableInvalidation(ctx, bus, instance, mode)
+	lc.OnStop("cache invalidation", func(context.Context) error { return bus.Close() })
+	slog.Info("Инвалидация кэша включена",
+		slog.String(logging.KeyTopic, topic),
+		slog.String("instance", instance),
+		slog.String("mode", string(mode)),
+	)
+}
+
+// startAccessLog включает журнал обращений, если задан cache.access_log. Журнал ведется
+// и при восстановлении из снимка, чтобы следующий прогрев по частоте обращений имел данные.
+func startAccessLog(lc *lifecycle.Manager, store *cache.DBWithCacheStore, cfg config.Cache) *cache.AccessLog {
+	path := cfg.AccessLog
+	if path == "" {
+		return nil
+	}
+	// Журналу нужно заметно больше uid, чем помещается в кэш: прогрев берет Size самых частых.
+	l, err := cache.NewAccessLog(path, max(cfg.Size*4, cache.DefaultAccessLogLimit))
+	if err != nil {
+		slog.Warn("Ошибка чтения журнала обращений", slog.String("path", path), logging.Err(err))
+	}
+	store.TrackAccess(l)
+	lc.Go("cache access log", func(ctx context.Context) {
+		l.Run(ctx, time.Minute)
+	})
+	return l
+}
+
+// startWarmup запускает фоновый прогрев кэша; accessLog нужен стратегии по частоте обращений.
+// Ошибки настройки не останавливают сервис: кэш остается холодным.
+func startWarmup(ctx context.Context, store *cache.DBWithCacheStore, cfg config.Cache, accessLog *cache.AccessLog) {
+	strategy, err := cache.NewWarmupStrategy(cfg.WarmupStrategy, accessLog, cfg.WarmupKeysFile)
+	if err != nil {
+		slog.Warn("Прогрев кэша отключен", logging.Err(err))
+		return
 	}
-	return fallback
+	store.StartWarmup(ctx, strategy, cfg.Size)
 }
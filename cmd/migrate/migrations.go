@@ -1,36 +1,43 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 
+	"github.com/mitrich772/go-order-service/internal/config"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/pii"
+
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
 func main() {
+	// Настройки БД и ключей общие с сервисом: файл -config, окружение, флаги.
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	action := fs.String("action", "up", "up | down | step | rekey")
+	steps := fs.Int("n", 1, "Количество шагов для step")
+	keyFile := fs.String("keys", "", "Файл ключей шифрования для rekey (по умолчанию pii.key_file)")
+	cfg, err := config.Load(fs, os.Args[1:])
+	if err != nil {
+		fatal("Ошибка конфигурации", err)
+	}
 
-	action := flag.String("action", "up", "up | down | step")
-	steps := flag.Int("n", 1, "Количество шагов для step")
-	flag.Parse()
-
-	user := getenv("DB_USER", "serviceuser")
-	password := getenv("DB_PASSWORD", "123")
-	host := getenv("DB_HOST", "localhost")
-	port := getenv("DB_PORT", "5432")
-	dbname := getenv("DB_NAME", "order_management")
-	sslmode := getenv("DB_SSLMODE", "disable")
-
-	dsn := fmt.Sprintf("postgresql://%s:%s@%s:%s/%s?sslmode=%s",
-		user, password, host, port, dbname, sslmode,
-	)
+	if *action == "rekey" {
+		if *keyFile == "" {
+			*keyFile = cfg.PII.KeyFile
+		}
+		rekey(*keyFile, cfg.DB.Connection())
+		return
+	}
 
-	m, err := migrate.New("file://migrations", dsn)
+	m, err := migrate.New("file://migrations", cfg.DB.URL())
 	if err != nil {
-		log.Fatalf("Ошибка создания мигратора: %v", err)
+		fatal("Ошибка создания мигратора", err)
 	}
 
 	switch *action {
@@ -41,19 +48,40 @@ func main() {
 	case "step":
 		err = m.Steps(*steps)
 	default:
-		log.Fatalf("Неизвестное действие: %s", *action)
+		fatal("Неизвестное действие", fmt.Errorf("action %q", *action))
 	}
 
 	if err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("Ошибка миграции: %v", err)
+		fatal("Ошибка миграции", err)
 	}
 
-	log.Println("Миграция завершена")
+	slog.Info("Миграция завершена", slog.String("action", *action))
 }
 
-func getenv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// rekey перешифровывает персональные данные текущим ключом из keyFile
+// (после ротации ключа или при включении шифрования на существующей БД).
+func rekey(keyFile string, cfg database.Config) {
+	if keyFile == "" {
+		fatal("Не задан файл ключей", fmt.Errorf("use -keys, -pii-key-file or PII_KEY_FILE"))
 	}
-	return fallback
+	keys, err := pii.LoadKeyFile(keyFile)
+	if err != nil {
+		fatal("Ошибка загрузки ключей шифрования", err)
+	}
+	conn := database.ConnectDB(cfg)
+	defer database.Close(conn)
+
+	db := database.NewGormDatabase(conn, 1, 0)
+	db.UseEncryption(pii.NewCipher(keys))
+	n, err := db.Rekey(context.Background(), 500)
+	if err != nil {
+		fatal("Ошибка перешифрования", err)
+	}
+	slog.Info("Перешифрование завершено", slog.Int("rows", n))
+}
+
+// fatal логирует ошибку и завершает программу.
+func fatal(msg string, err error) {
+	slog.Error(msg, slog.Any("error", err))
+	os.Exit(1)
 }
@@ -5,42 +5,54 @@ import (
 )
 
 // представляет одну запись в LRU кеше.
-type item struct {
-	Key   string
-	Value interface{}
+type item[K comparable, V any] struct {
+	Key   K
+	Value V
 }
 
-// LRU — структура кеша.
-type LRU struct {
+// LRU — структура кеша. Не потокобезопасна: синхронизацию обеспечивает вызывающий код.
+type LRU[K comparable, V any] struct {
 	capacity int
-	items    map[string]*list.Element
+	items    map[K]*list.Element
 	queue    *list.List
+	onEvict  func(key K, value V)
 }
 
 // NewLru создаёт новый LRU кеш с указанной вместимостью.
-func NewLru(capacity int) *LRU {
-	return &LRU{
+func NewLru[K comparable, V any](capacity int) *LRU[K, V] {
+	return NewLruWithEvict[K, V](capacity, nil)
+}
+
+// NewLruWithEvict создаёт LRU кеш, который вызывает onEvict для каждого элемента,
+// вытесненного из-за нехватки места (при Set или Resize).
+// Delete и Purge onEvict не вызывают.
+func NewLruWithEvict[K comparable, V any](capacity int, onEvict func(key K, value V)) *LRU[K, V] {
+	return &LRU[K, V]{
 		capacity: capacity,
-		items:    make(map[string]*list.Element),
+		items:    make(map[K]*list.Element),
 		queue:    list.New(),
+		onEvict:  onEvict,
 	}
 }
 
 // Set добавляет ключ со значением в кеш или обновляет существующий.
 // Если ключ уже есть, его элемент перемещается в начало очереди
 // Возвращает true, если ключ уже существовал.
-func (c *LRU) Set(key string, value interface{}) (exist bool) {
+func (c *LRU[K, V]) Set(key K, value V) (exist bool) {
 	if element, exists := c.items[key]; exists {
 		c.queue.MoveToFront(element)
-		element.Value.(*item).Value = value
+		element.Value.(*item[K, V]).Value = value
 		return true
 	}
 
-	if c.queue.Len() == c.capacity {
-		c.purge()
+	if c.capacity <= 0 {
+		return false
+	}
+	if c.queue.Len() >= c.capacity {
+		c.removeOldest()
 	}
 
-	item := &item{
+	item := &item[K, V]{
 		Key:   key,
 		Value: value,
 	}
@@ -51,21 +63,85 @@ func (c *LRU) Set(key string, value interface{}) (exist bool) {
 	return false
 }
 
-// purge удаляет самый старый элемент
-func (c *LRU) purge() {
+// removeOldest вытесняет самый старый элемент
+func (c *LRU[K, V]) removeOldest() {
 	if element := c.queue.Back(); element != nil {
-		item := c.queue.Remove(element).(*item)
+		item := c.queue.Remove(element).(*item[K, V])
 		delete(c.items, item.Key)
+		if c.onEvict != nil {
+			c.onEvict(item.Key, item.Value)
+		}
 	}
 }
 
 // Get возвращает значение по ключу из кеша и перемещает элемент в начало очереди.
 // Возвращает false, если ключа нет в кеше.
-func (c *LRU) Get(key string) (interface{}, bool) {
+func (c *LRU[K, V]) Get(key K) (V, bool) {
 	element, exists := c.items[key]
 	if !exists {
-		return nil, false
+		var zero V
+		return zero, false
 	}
 	c.queue.MoveToFront(element)
-	return element.Value.(*item).Value, true
+	return element.Value.(*item[K, V]).Value, true
+}
+
+// Peek возвращает значение по ключу, не меняя его позицию в очереди.
+func (c *LRU[K, V]) Peek(key K) (V, bool) {
+	element, exists := c.items[key]
+	if !exists {
+		var zero V
+		return zero, false
+	}
+	return element.Value.(*item[K, V]).Value, true
+}
+
+// Delete удаляет ключ из кеша. Возвращает true, если ключ был в кеше.
+func (c *LRU[K, V]) Delete(key K) bool {
+	element, exists := c.items[key]
+	if !exists {
+		return false
+	}
+	c.queue.Remove(element)
+	delete(c.items, key)
+	return true
+}
+
+// Len возвращает количество элементов в кеше.
+func (c *LRU[K, V]) Len() int {
+	return c.queue.Len()
+}
+
+// Keys возвращает ключи от самого старого к самому новому.
+func (c *LRU[K, V]) Keys() []K {
+	keys := make([]K, 0, c.queue.Len())
+	for element := c.queue.Back(); element != nil; element = element.Prev() {
+		keys = append(keys, element.Value.(*item[K, V]).Key)
+	}
+	return keys
+}
+
+// Cap возвращает вместимость кеша.
+func (c *LRU[K, V]) Cap() int {
+	return c.capacity
+}
+
+// Resize меняет вместимость кеша, вытесняя лишние старые элементы.
+// Возвращает количество вытесненных элементов.
+func (c *LRU[K, V]) Resize(capacity int) (evicted int) {
+	if capacity < 0 {
+		capacity = 0
+	}
+	for c.queue.Len() > capacity {
+		c.removeOldest()
+		evicted++
+	}
+	c.capacity = capacity
+	return evicted
+}
+
+// Purge удаляет все элементы из кеша.
+func (c *LRU[K, V]) Purge() {
+	c.items = make(map[K]*list.Element)
+	c.queue.Init()
 }
@@ -4,7 +4,7 @@ import "testing"
 
 // Тестирует добавление нового элемента и обновление существующего
 func TestLRU_SetAndGet(t *testing.T) {
-	cache := NewLru(2)
+	cache := NewLru[string, int](2)
 
 	// Добавление нового элемента
 	exist := cache.Set("a", 1)
@@ -29,7 +29,7 @@ func TestLRU_SetAndGet(t *testing.T) {
 
 // Тестирует удаление наименее недавно использованного элемента при переполнении
 func TestLRU_LRURemoval(t *testing.T) {
-	cache := NewLru(2)
+	cache := NewLru[string, int](2)
 
 	cache.Set("a", 1)
 	cache.Set("b", 2)
@@ -52,7 +52,7 @@ func TestLRU_LRURemoval(t *testing.T) {
 
 // Тестирует, что доступ к элементу делает его MRU
 func TestLRU_AccessUpdatesMRU(t *testing.T) {
-	cache := NewLru(2)
+	cache := NewLru[string, int](2)
 
 	cache.Set("a", 1)
 	cache.Set("b", 2)
@@ -75,10 +75,91 @@ func TestLRU_AccessUpdatesMRU(t *testing.T) {
 
 // Тестирует поведение при попытке получения несуществующего ключа
 func TestLRU_GetNonExistent(t *testing.T) {
-	cache := NewLru(2)
+	cache := NewLru[string, int](2)
 
 	_, ok := cache.Get("x")
 	if ok {
 		t.Error("Ожидалось false для отсутствующего ключа")
 	}
 }
+
+// Тестирует Peek, Delete и Purge
+func TestLRU_PeekDeletePurge(t *testing.T) {
+	cache := NewLru[string, int](2)
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+
+	// Peek не делает "a" MRU, поэтому при добавлении "c" удалится именно "a"
+	if val, ok := cache.Peek("a"); !ok || val != 1 {
+		t.Error("Peek должен вернуть значение 'a'")
+	}
+	cache.Set("c", 3)
+	if _, ok := cache.Peek("a"); ok {
+		t.Error("Ожидалось удаление 'a' после Peek")
+	}
+
+	if !cache.Delete("b") {
+		t.Error("Delete должен вернуть true для существующего ключа")
+	}
+	if cache.Delete("b") {
+		t.Error("Delete должен вернуть false для отсутствующего ключа")
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Ожидалась длина 1, получено %d", cache.Len())
+	}
+
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Errorf("Ожидался пустой кеш после Purge, получено %d", cache.Len())
+	}
+	if _, ok := cache.Get("c"); ok {
+		t.Error("Ожидалось отсутствие 'c' после Purge")
+	}
+}
+
+// Тестирует Keys, Resize и вызов колбэка вытеснения
+func TestLRU_ResizeAndEvictCallback(t *testing.T) {
+	var evicted []string
+	cache := NewLruWithEvict(3, func(key string, value int) {
+		evicted = append(evicted, key)
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+	cache.Get("a")
+
+	if keys := cache.Keys(); len(keys) != 3 || keys[0] != "b" || keys[2] != "a" {
+		t.Errorf("Ожидался порядок [b c a], получено %v", keys)
+	}
+
+	if n := cache.Resize(1); n != 2 {
+		t.Errorf("Ожидалось вытеснение 2 элементов, получено %d", n)
+	}
+	if len(evicted) != 2 || evicted[0] != "b" || evicted[1] != "c" {
+		t.Errorf("Ожидалось вытеснение [b c], получено %v", evicted)
+	}
+
+	cache.Set("d", 4) // вытесняет "a"
+	if len(evicted) != 3 || evicted[2] != "a" {
+		t.Errorf("Ожидалось вытеснение 'a' при Set, получено %v", evicted)
+	}
+
+	// Delete и Purge колбэк не вызывают
+	cache.Delete("d")
+	cache.Set("e", 5)
+	cache.Purge()
+	if len(evicted) != 3 {
+		t.Errorf("Delete/Purge не должны вызывать колбэк, получено %v", evicted)
+	}
+}
+
+// Тестирует кеш с нулевой вместимостью
+func TestLRU_ZeroCapacity(t *testing.T) {
+	cache := NewLru[string, int](0)
+	cache.Set("a", 1)
+	if cache.Len() != 0 {
+		t.Errorf("Кеш нулевой вместимости должен оставаться пустым, получено %d", cache.Len())
+	}
+}
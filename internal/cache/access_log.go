@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// DefaultAccessLogLimit — сколько uid по умолчанию хранит журнал обращений.
+const DefaultAccessLogLimit = 100_000
+
+// AccessLog считает обращения к заказам и сохраняет счетчики в файл.
+// Используется стратегией прогрева FrequentStrategy.
+// Журнал хранит не больше limit uid: при переполнении остается более частая половина,
+// редкие uid отбрасываются. Поэтому и файл не растет больше limit записей.
+type AccessLog struct {
+	mu     sync.Mutex
+	path   string
+	limit  int
+	counts map[string]uint64
+}
+
+// NewAccessLog создает журнал обращений и загружает ранее сохраненные счетчики из path.
+// limit — сколько uid хранить, 0 — DefaultAccessLogLimit. Отсутствующий файл не считается ошибкой.
+func NewAccessLog(path string, limit int) (*AccessLog, error) {
+	if limit <= 0 {
+		limit = DefaultAccessLogLimit
+	}
+	l := &AccessLog{
+		path:   path,
+		limit:  limit,
+		counts: make(map[string]uint64),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return l, err
+	}
+	if err := json.Unmarshal(data, &l.counts); err != nil {
+		return l, err
+	}
+	if len(l.counts) > l.limit {
+		l.prune(l.limit)
+	}
+	return l, nil
+}
+
+// Record учитывает одно обращение к заказу uid. Вызывается только для найденных заказов.
+func (l *AccessLog) Record(uid string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.counts[uid]++
+	if len(l.counts) > l.limit {
+		l.prune(max(l.limit/2, 1))
+	}
+}
+
+// Top возвращает до n uid, отсортированных по убыванию числа обращений.
+func (l *AccessLog) Top(n int) []string {
+	l.mu.Lock()
+	keys := l.sorted()
+	l.mu.Unlock()
+
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// prune оставляет keep самых частых uid. Вызывается под l.mu.
+func (l *AccessLog) prune(keep int) {
+	for _, uid := range l.sorted()[keep:] {
+		delete(l.counts, uid)
+	}
+}
+
+// sorted возвращает uid по убыванию числа обращений. Вызывается под l.mu.
+func (l *AccessLog) sorted() []string {
+	keys := make([]string, 0, len(l.counts))
+	for uid := range l.counts {
+		keys = append(keys, uid)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if l.counts[keys[i]] != l.counts[keys[j]] {
+			return l.counts[keys[i]] > l.counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// Save атомарно записывает счетчики в файл.
+func (l *AccessLog) Save() error {
+	l.mu.Lock()
+	data, err := json.Marshal(l.counts)
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), ".access-log-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.path)
+}
+
+// Run периодически сохраняет журнал до отмены ctx, затем сохраняет его последний раз.
+func (l *AccessLog) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := l.Save(); err != nil {
+				slog.ErrorContext(ctx, "Ошибка сохранения журнала обращений", logging.Err(err))
+			}
+			return
+		case <-ticker.C:
+			if err := l.Save(); err != nil {
+				slog.ErrorContext(ctx, "Ошибка сохранения журнала обращений", logging.Err(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package cache
+
+import "github.com/mitrich772/go-order-service/internal/database"
+
+// Admin описывает операции администрирования кэша. Реализуется DBWithCacheStore.
+type Admin interface {
+	Stats() Stats
+	Inspect(uid string) (*database.Order, bool)
+	Evict(uid string) bool
+	Purge()
+	Warm() (int, error)
+	WarmupStatus() WarmupStatus
+}
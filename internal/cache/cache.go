@@ -8,6 +8,14 @@ import (
 type Cache interface {
 	Get(uid string) (*database.Order, bool)
 	Set(order *database.Order) (exist bool)
+	// Peek возвращает заказ без обновления порядка вытеснения и счетчиков.
+	Peek(uid string) (*database.Order, bool)
+	// Delete удаляет заказ из кеша. Возвращает true, если он там был.
+	Delete(uid string) bool
+	// Purge полностью очищает кеш.
+	Purge()
+	// Len возвращает количество заказов в кеше.
+	Len() int
+	// Keys возвращает uid заказов от наименее к наиболее недавно использованному.
+	Keys() []string
 }
-
-
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Описания метрик кэша. Уровень l1 — основной кэш, l2 — общий уровень двухуровневого кэша.
+var (
+	descHits      = prometheus.NewDesc("orders_cache_hits_total", "Попадания в кэш.", []string{"level"}, nil)
+	descMisses    = prometheus.NewDesc("orders_cache_misses_total", "Промахи кэша.", []string{"level"}, nil)
+	descEvictions = prometheus.NewDesc("orders_cache_evictions_total", "Вытеснения из кэша по вместимости.", []string{"level"}, nil)
+	descErrors    = prometheus.NewDesc("orders_cache_errors_total", "Ошибки обращения к внешнему кэшу.", []string{"level"}, nil)
+	descSize      = prometheus.NewDesc("orders_cache_size", "Количество заказов в кэше.", []string{"level"}, nil)
+	descLoads     = prometheus.NewDesc("orders_cache_db_loads_total", "Загрузки заказов из БД при промахах.", nil, nil)
+	descLoadErrs  = prometheus.NewDesc("orders_cache_db_load_errors_total", "Неудачные загрузки заказов из БД.", nil, nil)
+)
+
+// Collector отдает статистику кэша в Prometheus, читая Stats при каждом сборе.
+type Collector struct {
+	admin Admin
+}
+
+// NewCollector создает сборщик метрик для кэша.
+func NewCollector(a Admin) *Collector {
+	return &Collector{admin: a}
+}
+
+// Describe реализует prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{descHits, descMisses, descEvictions, descErrors, descSize, descLoads, descLoadErrs} {
+		ch <- d
+	}
+}
+
+// Collect реализует prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	st := c.admin.Stats()
+	collectLevel(ch, "l1", st)
+	if st.Shared != nil {
+		collectLevel(ch, "l2", *st.Shared)
+	}
+	ch <- prometheus.MustNewConstMetric(descLoads, prometheus.CounterValue, float64(st.Loads))
+	ch <- prometheus.MustNewConstMetric(descLoadErrs, prometheus.CounterValue, float64(st.LoadErrors))
+}
+
+func collectLevel(ch chan<- prometheus.Metric, level string, st Stats) {
+	ch <- prometheus.MustNewConstMetric(descHits, prometheus.CounterValue, float64(st.Hits), level)
+	ch <- prometheus.MustNewConstMetric(descMisses, prometheus.CounterValue, float64(st.Misses), level)
+	ch <- prometheus.MustNewConstMetric(descEvictions, prometheus.CounterValue, float64(st.Evictions), level)
+	ch <- prometheus.MustNewConstMetric(descErrors, prometheus.CounterValue, float64(st.Errors), level)
+	ch <- prometheus.MustNewConstMetric(descSize, prometheus.GaugeValue, float64(st.Size), level)
+}
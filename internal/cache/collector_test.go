@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mitrich772/go-order-service/internal/database"
+	mockdb "github.com/mitrich772/go-order-service/internal/database/mocks"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector_ExportsStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	mockDB.EXPECT().GetOrder(gomock.Any(), "b").Return(&database.Order{OrderUID: "b"}, nil)
+
+	store := NewDBWithCacheStore(mockDB, 1)
+	store.cache.Set(&database.Order{OrderUID: "a"})
+	store.Get(context.Background(), "a") // попадание
+	store.Get(context.Background(), "b") // промах, загрузка из БД и вытеснение "a"
+
+	expected := `
+# HELP orders_cache_evictions_total Вытеснения из кэша по вместимости.
+# TYPE orders_cache_evictions_total counter
+orders_cache_evictions_total{level="l1"} 1
+# HELP orders_cache_hits_total Попадания в кэш.
+# TYPE orders_cache_hits_total counter
+orders_cache_hits_total{level="l1"} 1
+# HELP orders_cache_misses_total Промахи кэша.
+# TYPE orders_cache_misses_total counter
+orders_cache_misses_total{level="l1"} 1
+# HELP orders_cache_db_loads_total Загрузки заказов из БД при промахах.
+# TYPE orders_cache_db_loads_total counter
+orders_cache_db_loads_total 1
+`
+	err := testutil.CollectAndCompare(NewCollector(store), strings.NewReader(expected),
+		"orders_cache_hits_total", "orders_cache_misses_total", "orders_cache_evictions_total", "orders_cache_db_loads_total")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,10 +1,19 @@
 package cache
 
-import "github.com/mitrich772/go-order-service/internal/database"
+import (
+	"context"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/feed"
+	"github.com/mitrich772/go-order-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
 
 // DBStore — хранилище заказов только в базе данных. Реализует OrderStore.
 type DBStore struct {
-	db database.Database
+	db   database.Database
+	feed *feed.Feed
 }
 
 // NewDBStore создает новый DBStore с подключением к базе данных.
@@ -14,12 +23,43 @@ func NewDBStore(db database.Database) *DBStore {
 	}
 }
 
+// UseFeed включает рассылку сохраненных заказов подписчикам f.
+func (s *DBStore) UseFeed(f *feed.Feed) {
+	s.feed = f
+}
+
 // Save сохраняет заказ в базе данных.
-func (s *DBStore) Save(order *database.Order) error {
-	return s.db.SaveOrder(order)
+func (s *DBStore) Save(ctx context.Context, order *database.Order) (err error) {
+	ctx, span := tracing.Start(ctx, "store.save", trace.WithAttributes(attribute.String("order.uid", order.OrderUID)))
+	defer func() { tracing.End(span, err) }()
+	if err := s.db.SaveOrder(ctx, order); err != nil {
+		return err
+	}
+	s.feed.Publish(order)
+	return nil
 }
 
 // Get возвращает заказ из базы данных по uid.
-func (s *DBStore) Get(uid string) (*database.Order, error) {
-	return s.db.GetOrder(uid)
+func (s *DBStore) Get(ctx context.Context, uid string) (*database.Order, error) {
+	return s.db.GetOrder(ctx, uid)
+}
+
+// List выбирает заказы по фильтру из базы данных.
+func (s *DBStore) List(ctx context.Context, filter database.OrderFilter) ([]database.Order, error) {
+	return s.db.ListOrders(ctx, filter)
+}
+
+// Lookup ищет заказы по вторичному ключу в базе данных.
+func (s *DBStore) Lookup(ctx context.Context, field database.LookupField, value string) (_ []*database.Order, err error) {
+	ctx, span := tracing.Start(ctx, "store.lookup", trace.WithAttributes(attribute.String("lookup.field", string(field))))
+	defer func() { tracing.End(span, err) }()
+	uids, err := s.db.FindOrderUIDs(ctx, field, value, LookupLimit)
+	if err != nil {
+		return nil, err
+	}
+	found, err := loadOrders(ctx, s.db, uids)
+	if err != nil {
+		return nil, err
+	}
+	return inOrder(uids, found), nil
 }
@@ -1,51 +1,147 @@
 package cache
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/feed"
+	"github.com/mitrich772/go-order-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrWarmUnsupported возвращается, если кэш не умеет прогреваться из БД.
+var ErrWarmUnsupported = errors.New("cache does not support warm-up")
+
+// warmer реализуется кэшами, которые умеют загружать заказы из БД.
+type warmer interface {
+	Warm(ctx context.Context, db database.Database) (int, error)
+}
+
+// entrieser реализуется кэшами, которые умеют отдавать свое содержимое.
+type entrieser interface {
+	Entries() []*database.Order
+}
+
+// statser реализуется кэшами, которые ведут счетчики обращений.
+type statser interface {
+	Stats() Stats
+}
+
 // DBWithCacheStore — хранилище заказов с кэшем в памяти и базой данных. Реализует OrderStore.
 type DBWithCacheStore struct {
-	db    database.Database
-	cache Cache
+	db     database.Database
+	cache  Cache
+	loads  loadCounters
+	access *AccessLog
+	warmup *Warmup
+	inv    *invalidator
+	feed   *feed.Feed
+	// lookups — соответствия вторичных ключей спискам uid, nil — не кэшируются.
+	lookups *lookupCache
 }
 
 // NewDBWithCacheStore создает новый DBWithCacheStore с указанной емкостью кэша.
+// Кэш создается пустым, прогрев запускается отдельно через StartWarmup.
 func NewDBWithCacheStore(db database.Database, storeCap int) *DBWithCacheStore {
 	return &DBWithCacheStore{
 		db:    db,
-		cache: NewOrderCaheFromDB(db, storeCap),
+		cache: NewOrderCache(storeCap),
+	}
+}
+
+// NewDBWithCache создает DBWithCacheStore поверх готового кэша,
+// например RedisCache или TieredCache.
+func NewDBWithCache(db database.Database, c Cache) *DBWithCacheStore {
+	return &DBWithCacheStore{
+		db:    db,
+		cache: c,
+	}
+}
+
+// TrackAccess включает учет обращений к заказам в журнале l.
+func (s *DBWithCacheStore) TrackAccess(l *AccessLog) {
+	s.access = l
+}
+
+// UseFeed включает рассылку сохраненных заказов подписчикам f.
+func (s *DBWithCacheStore) UseFeed(f *feed.Feed) {
+	s.feed = f
+}
+
+// UseLookupCache включает кэш соответствий вторичных ключей спискам uid
+// на size ключей со сроком жизни ttl. size или ttl 0 выключают его.
+func (s *DBWithCacheStore) UseLookupCache(size int, ttl time.Duration) {
+	s.lookups = newLookupCache(size, ttl)
+}
+
+// StartWarmup запускает фоновый прогрев кэша выбранной стратегией.
+func (s *DBWithCacheStore) StartWarmup(ctx context.Context, strategy WarmupStrategy, limit int) *Warmup {
+	s.warmup = NewWarmup(s.cache, s.db, strategy, limit)
+	s.warmup.Start(ctx)
+	return s.warmup
+}
+
+// WarmupStatus возвращает прогресс фонового прогрева.
+// Если прогрев не запускался, возвращается состояние skipped.
+func (s *DBWithCacheStore) WarmupStatus() WarmupStatus {
+	if s.warmup == nil {
+		return WarmupStatus{State: WarmupSkipped}
+	}
+	return s.warmup.Status()
+}
+
+// CheckWarmup — проверка readiness: не готов, пока идет прогрев кэша.
+func (s *DBWithCacheStore) CheckWarmup(ctx context.Context) (any, error) {
+	st := s.WarmupStatus()
+	if !st.Finished() {
+		return st, fmt.Errorf("cache warm-up in progress: %d/%d", st.Loaded, st.Total)
 	}
+	return st, nil
 }
 
 // Save сохраняет заказ в базе данных и обновляет кэш.
-func (s *DBWithCacheStore) Save(order *database.Order) error {
+func (s *DBWithCacheStore) Save(ctx context.Context, order *database.Order) (err error) {
 	if order == nil {
-		return errors.New("order is nil")
+		return fmt.Errorf("%w: order is nil", database.ErrInvalid)
 	}
-	if err := s.db.SaveOrder(order); err != nil {
+	ctx, span := tracing.Start(ctx, "store.save", trace.WithAttributes(attribute.String("order.uid", order.OrderUID)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.db.SaveOrder(ctx, order); err != nil {
 		return err
 	}
 	if s.cache != nil {
 		s.cache.Set(order)
 	}
+	s.lookups.forget(order)
+	s.publishInvalidation(ctx, order)
+	s.feed.Publish(order)
 	return nil
 }
 
 // Get возвращает заказ из кэша, если он есть, иначе из базы данных, и обновляет кэш.
-func (s *DBWithCacheStore) Get(uid string) (*database.Order, error) {
+func (s *DBWithCacheStore) Get(ctx context.Context, uid string) (_ *database.Order, err error) {
+	ctx, span := tracing.Start(ctx, "store.get", trace.WithAttributes(attribute.String("order.uid", uid)))
+	defer func() { tracing.End(span, err) }()
+
 	if s.cache != nil { // сначала пробуем кэш
-		if order, ok := s.cache.Get(uid); ok {
+		if order, ok := s.cacheGet(ctx, uid); ok {
+			s.recordAccess(uid)
 			return order, nil
 		}
 	}
 
-	order, err := s.db.GetOrder(uid)
+	start := time.Now()
+	order, err := s.db.GetOrder(ctx, uid)
+	s.loads.observe(time.Since(start), err)
 	if err != nil {
 		return nil, err
 	}
+	s.recordAccess(uid)
 
 	if s.cache != nil {
 		s.cache.Set(order)
@@ -53,3 +149,178 @@ func (s *DBWithCacheStore) Get(uid string) (*database.Order, error) {
 	return order, nil
 }
 
+// recordAccess учитывает обращение к найденному заказу в журнале обращений.
+// Несуществующие uid не учитываются, чтобы перебор случайных uid не раздувал журнал.
+func (s *DBWithCacheStore) recordAccess(uid string) {
+	if s.access != nil {
+		s.access.Record(uid)
+	}
+}
+
+// List выбирает заказы по фильтру из базы данных. Результат не кладется в кэш:
+// выборки не должны вытеснять из него часто запрашиваемые заказы.
+func (s *DBWithCacheStore) List(ctx context.Context, filter database.OrderFilter) ([]database.Order, error) {
+	return s.db.ListOrders(ctx, filter)
+}
+
+// Lookup ищет заказы по вторичному ключу. Список uid берется из кэша соответствий
+// или из БД, заказы — из кэша заказов; отсутствующие в нем загружаются одним запросом
+// и кладутся в кэш.
+func (s *DBWithCacheStore) Lookup(ctx context.Context, field database.LookupField, value string) (_ []*database.Order, err error) {
+	ctx, span := tracing.Start(ctx, "store.lookup", trace.WithAttributes(attribute.String("lookup.field", string(field))))
+	defer func() { tracing.End(span, err) }()
+
+	norm, err := database.NormalizeLookup(field, value)
+	if err != nil || norm == "" {
+		return nil, err
+	}
+	key := lookupKey(field, norm)
+	uids, hit := s.lookups.get(key)
+	span.SetAttributes(attribute.Bool("cache.hit", hit))
+	if !hit {
+		if uids, err = s.db.FindOrderUIDs(ctx, field, norm, LookupLimit); err != nil {
+			return nil, err
+		}
+		s.lookups.set(key, uids)
+	}
+
+	found, err := s.getMany(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	return inOrder(uids, found), nil
+}
+
+// getMany возвращает заказы с uid из uids: из кэша, а отсутствующие в нем —
+// одним запросом к БД, после чего они кладутся в кэш.
+func (s *DBWithCacheStore) getMany(ctx context.Context, uids []string) (map[string]*database.Order, error) {
+	found := make(map[string]*database.Order, len(uids))
+	var missing []string
+	for _, uid := range uids {
+		if s.cache != nil {
+			if order, ok := s.cacheGet(ctx, uid); ok {
+				found[uid] = order
+				continue
+			}
+		}
+		missing = append(missing, uid)
+	}
+	loaded, err := loadOrders(ctx, s.db, missing)
+	if err != nil {
+		return nil, err
+	}
+	for uid, order := range loaded {
+		found[uid] = order
+		if s.cache != nil {
+			s.cache.Set(order)
+		}
+	}
+	return found, nil
+}
+
+// cacheGet читает заказ из кэша в отдельном спане с признаком попадания.
+func (s *DBWithCacheStore) cacheGet(ctx context.Context, uid string) (*database.Order, bool) {
+	_, span := tracing.Start(ctx, "cache.get")
+	defer span.End()
+	order, ok := s.cache.Get(uid)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
+	return order, ok
+}
+
+// Stats возвращает счетчики кэша вместе со статистикой загрузок из БД.
+func (s *DBWithCacheStore) Stats() Stats {
+	var st Stats
+	if c, ok := s.cache.(statser); ok {
+		st = c.Stats()
+	}
+	s.loads.fill(&st)
+	return st
+}
+
+// Inspect возвращает заказ из кэша, не обращаясь к БД и не меняя порядок вытеснения.
+func (s *DBWithCacheStore) Inspect(uid string) (*database.Order, bool) {
+	if s.cache == nil {
+		return nil, false
+	}
+	return s.cache.Peek(uid)
+}
+
+// Evict удаляет заказ из кэша.
+func (s *DBWithCacheStore) Evict(uid string) bool {
+	if s.cache == nil {
+		return false
+	}
+	return s.cache.Delete(uid)
+}
+
+// Purge очищает кэш вместе с кэшем соответствий вторичных ключей.
+func (s *DBWithCacheStore) Purge() {
+	if s.cache != nil {
+		s.cache.Purge()
+	}
+	s.lookups.purge()
+}
+
+// Warm заново прогревает кэш последними заказами из БД.
+func (s *DBWithCacheStore) Warm() (int, error) {
+	w, ok := s.cache.(warmer)
+	if !ok {
+		return 0, ErrWarmUnsupported
+	}
+	return w.Warm(context.Background(), s.db)
+}
+
+// Entries возвращает содержимое кэша в порядке вытеснения (от старых к новым).
+// Используется для снимков кэша.
+func (s *DBWithCacheStore) Entries() []*database.Order {
+	if c, ok := s.cache.(entrieser); ok {
+		return c.Entries()
+	}
+	return nil
+}
+
+// Restore кладет заказы в кэш в переданном порядке: последний станет MRU.
+func (s *DBWithCacheStore) Restore(orders []*database.Order) {
+	if s.cache == nil {
+		return
+	}
+	for _, order := range orders {
+		s.cache.Set(order)
+	}
+}
+
+// revalidateBatch — число заказов, перечитываемых Revalidate одним запросом к БД.
+const revalidateBatch = 500
+
+// Revalidate перечитывает из БД заказы, восстановленные из снимка: пока сервис
+// был остановлен, они могли измениться или быть удалены. Актуальные версии заменяют
+// записи, еще оставшиеся в кэше, с сохранением порядка вытеснения; удаленные из БД
+// заказы вытесняются. Возвращает число вытесненных записей.
+func (s *DBWithCacheStore) Revalidate(ctx context.Context, orders []*database.Order) (int, error) {
+	if s.cache == nil {
+		return 0, nil
+	}
+	evicted := 0
+	for start := 0; start < len(orders); start += revalidateBatch {
+		batch := orders[start:min(start+revalidateBatch, len(orders))]
+		uids := make([]string, len(batch))
+		for i, order := range batch {
+			uids[i] = order.OrderUID
+		}
+		fresh, err := loadOrders(ctx, s.db, uids)
+		if err != nil {
+			return evicted, err
+		}
+		for _, uid := range uids {
+			if _, ok := s.cache.Peek(uid); !ok {
+				continue
+			}
+			if order, ok := fresh[uid]; ok {
+				s.cache.Set(order)
+			} else if s.cache.Delete(uid) {
+				evicted++
+			}
+		}
+	}
+	return evicted, nil
+}
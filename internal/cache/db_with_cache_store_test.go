@@ -1,7 +1,9 @@
 package cache
 
 import (
+	"context"
 	"errors"
+	"path/filepath"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -18,17 +20,15 @@ func TestDBWithCacheStore_Save_StoreAndCache(t *testing.T) {
 	mockDB := mockdb.NewMockDatabase(ctrl)
 	mockCache := mockcache.NewMockCache(ctrl)
 
-	mockDB.EXPECT().GetLastNOrders(100).Return(nil, nil)
-
 	store := NewDBWithCacheStore(mockDB, 100)
 	store.cache = mockCache
 
 	order := &database.Order{OrderUID: "test"}
 
-	mockDB.EXPECT().SaveOrder(order).Return(nil).Times(1)
+	mockDB.EXPECT().SaveOrder(gomock.Any(), order).Return(nil).Times(1)
 	mockCache.EXPECT().Set(order).Times(1)
 
-	err := store.Save(order)
+	err := store.Save(context.Background(), order)
 	if err != nil {
 		t.Fatalf("неожиданная ошибка: %v", err)
 	}
@@ -42,17 +42,15 @@ func TestDBWithCacheStore_Save_DBError(t *testing.T) {
 	mockDB := mockdb.NewMockDatabase(ctrl)
 	mockCache := mockcache.NewMockCache(ctrl)
 
-	mockDB.EXPECT().GetLastNOrders(100).Return(nil, nil)
-
 	store := NewDBWithCacheStore(mockDB, 100)
 	store.cache = mockCache
 
 	order := &database.Order{OrderUID: "test"}
 
-	mockDB.EXPECT().SaveOrder(order).Return(errors.New("ошибка БД")).Times(1)
+	mockDB.EXPECT().SaveOrder(gomock.Any(), order).Return(errors.New("ошибка БД")).Times(1)
 	mockCache.EXPECT().Set(order).Times(0)
 
-	err := store.Save(order)
+	err := store.Save(context.Background(), order)
 	if err == nil {
 		t.Fatalf("ожидалась ошибка сохранения в БД, но получили nil")
 	}
@@ -66,8 +64,6 @@ func TestDBWithCacheStore_Get_FromCache(t *testing.T) {
 	mockDB := mockdb.NewMockDatabase(ctrl)
 	mockCache := mockcache.NewMockCache(ctrl)
 
-	mockDB.EXPECT().GetLastNOrders(100).Return(nil, nil)
-
 	store := NewDBWithCacheStore(mockDB, 100)
 	store.cache = mockCache
 
@@ -76,7 +72,7 @@ func TestDBWithCacheStore_Get_FromCache(t *testing.T) {
 
 	mockCache.EXPECT().Get(orderUID).Return(expectedOrder, true).Times(1)
 
-	order, err := store.Get(orderUID)
+	order, err := store.Get(context.Background(), orderUID)
 	if err != nil {
 		t.Fatalf("неожиданная ошибка: %v", err)
 	}
@@ -94,8 +90,6 @@ func TestDBWithCacheStore_Get_FromDB(t *testing.T) {
 	mockDB := mockdb.NewMockDatabase(ctrl)
 	mockCache := mockcache.NewMockCache(ctrl)
 
-	mockDB.EXPECT().GetLastNOrders(100).Return(nil, nil)
-
 	store := NewDBWithCacheStore(mockDB, 100)
 	store.cache = mockCache
 
@@ -103,10 +97,10 @@ func TestDBWithCacheStore_Get_FromDB(t *testing.T) {
 	expectedOrder := &database.Order{OrderUID: orderUID}
 
 	mockCache.EXPECT().Get(orderUID).Return(nil, false).Times(1)
-	mockDB.EXPECT().GetOrder(orderUID).Return(expectedOrder, nil).Times(1)
+	mockDB.EXPECT().GetOrder(gomock.Any(), orderUID).Return(expectedOrder, nil).Times(1)
 	mockCache.EXPECT().Set(expectedOrder).Times(1)
 
-	order, err := store.Get(orderUID)
+	order, err := store.Get(context.Background(), orderUID)
 	if err != nil {
 		t.Fatalf("неожиданная ошибка: %v", err)
 	}
@@ -124,17 +118,15 @@ func TestDBWithCacheStore_Get_NotFound(t *testing.T) {
 	mockDB := mockdb.NewMockDatabase(ctrl)
 	mockCache := mockcache.NewMockCache(ctrl)
 
-	mockDB.EXPECT().GetLastNOrders(100).Return(nil, nil)
-
 	store := NewDBWithCacheStore(mockDB, 100)
 	store.cache = mockCache
 
 	orderUID := "missing"
 
 	mockCache.EXPECT().Get(orderUID).Return(nil, false).Times(1)
-	mockDB.EXPECT().GetOrder(orderUID).Return(nil, errors.New("не найдено")).Times(1)
+	mockDB.EXPECT().GetOrder(gomock.Any(), orderUID).Return(nil, errors.New("не найдено")).Times(1)
 
-	_, err := store.Get(orderUID)
+	_, err := store.Get(context.Background(), orderUID)
 	if err == nil {
 		t.Fatalf("ожидалась ошибка для отсутствующего заказа, но получили nil")
 	}
@@ -148,13 +140,91 @@ func TestDBWithCacheStore_Save_NilOrder(t *testing.T) {
 	mockDB := mockdb.NewMockDatabase(ctrl)
 	mockCache := mockcache.NewMockCache(ctrl)
 
-	mockDB.EXPECT().GetLastNOrders(100).Return(nil, nil)
-
 	store := NewDBWithCacheStore(mockDB, 100)
 	store.cache = mockCache
 
-	err := store.Save(nil)
+	err := store.Save(context.Background(), nil)
 	if err == nil {
 		t.Fatalf("ожидалась ошибка при сохранении nil-заказа, но получили nil")
 	}
 }
+
+// Промах кэша учитывается в счетчиках загрузок из БД
+func TestDBWithCacheStore_Stats_CountsLoads(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	store := NewDBWithCacheStore(mockDB, 10)
+
+	mockDB.EXPECT().GetOrder(gomock.Any(), "a").Return(&database.Order{OrderUID: "a"}, nil).Times(1)
+	mockDB.EXPECT().GetOrder(gomock.Any(), "missing").Return(nil, errors.New("не найдено")).Times(1)
+
+	store.Get(context.Background(), "a")
+	store.Get(context.Background(), "a") // из кэша
+	store.Get(context.Background(), "missing")
+
+	st := store.Stats()
+	if st.Loads != 2 || st.LoadErrors != 1 {
+		t.Errorf("ожидалось loads=2 load_errors=1, получено loads=%d load_errors=%d", st.Loads, st.LoadErrors)
+	}
+	if st.Hits != 1 || st.Misses != 2 {
+		t.Errorf("ожидалось hits=1 misses=2, получено hits=%d misses=%d", st.Hits, st.Misses)
+	}
+}
+
+// Revalidate заменяет восстановленные записи версиями из БД, вытесняет удаленные из БД
+// и не возвращает в кэш уже вытесненные
+func TestDBWithCacheStore_Revalidate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	store := NewDBWithCacheStore(mockDB, 2)
+	restored := []*database.Order{{OrderUID: "a"}, {OrderUID: "b", CustomerID: "old"}, {OrderUID: "c"}}
+	store.Restore(restored) // "a" вытеснен емкостью
+
+	mockDB.EXPECT().ListOrders(gomock.Any(), database.OrderFilter{UIDs: []string{"a", "b", "c"}}).
+		Return([]database.Order{{OrderUID: "a"}, {OrderUID: "b", CustomerID: "new"}}, nil)
+
+	evicted, err := store.Revalidate(context.Background(), restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evicted != 1 {
+		t.Fatalf("ожидалась 1 вытесненная запись, получено %d", evicted)
+	}
+	if _, ok := store.Inspect("a"); ok {
+		t.Fatal("вытесненный заказ не должен возвращаться в кэш")
+	}
+	if order, ok := store.Inspect("b"); !ok || order.CustomerID != "new" {
+		t.Fatalf("ожидалась актуальная версия заказа, получено %+v", order)
+	}
+	if _, ok := store.Inspect("c"); ok {
+		t.Fatal("удаленный из БД заказ должен вытесняться")
+	}
+}
+
+// Журнал обращений учитывает только найденные заказы: из кэша и из БД
+func TestDBWithCacheStore_Get_RecordsOnlyFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	store := NewDBWithCacheStore(mockDB, 10)
+	l, err := NewAccessLog(filepath.Join(t.TempDir(), "access.json"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.TrackAccess(l)
+
+	mockDB.EXPECT().GetOrder(gomock.Any(), "a").Return(&database.Order{OrderUID: "a"}, nil)
+	mockDB.EXPECT().GetOrder(gomock.Any(), "missing").Return(nil, database.ErrNotFound).Times(2)
+	for _, uid := range []string{"a", "a", "missing", "missing"} {
+		_, _ = store.Get(context.Background(), uid)
+	}
+
+	if top := l.Top(10); len(top) != 1 || top[0] != "a" {
+		t.Fatalf("ожидался только [a], получено %v", top)
+	}
+}
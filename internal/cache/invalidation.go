@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// Invalidation — событие об изменении заказа на одном из экземпляров сервиса.
+type Invalidation struct {
+	OrderUID string `json:"order_uid"`
+	// Version — порядок события среди событий того же заказа. Его присваивает шина
+	// при доставке (в Kafka — offset в партиции ключа order_uid), а не часы экземпляра,
+	// поэтому расхождение часов между экземплярами не переставляет события.
+	Version int64  `json:"-"`
+	Source  string `json:"source,omitempty"`
+}
+
+// InvalidationBus доставляет события инвалидации всем экземплярам сервиса.
+type InvalidationBus interface {
+	// Publish отправляет событие всем подписчикам.
+	Publish(ctx context.Context, ev Invalidation) error
+	// Subscribe вызывает handle для каждого события до отмены ctx. Version доставленного
+	// события возрастает с каждым следующим событием того же заказа.
+	Subscribe(ctx context.Context, handle func(Invalidation)) error
+}
+
+// InvalidationMode определяет, что делать с локальной записью при получении события.
+type InvalidationMode string
+
+const (
+	// InvalidateEvict удаляет заказ из локального кэша.
+	InvalidateEvict InvalidationMode = "evict"
+	// InvalidateRefresh перечитывает закэшированный заказ из БД.
+	InvalidateRefresh InvalidationMode = "refresh"
+)
+
+// ParseInvalidationMode разбирает режим инвалидации: evict или refresh.
+func ParseInvalidationMode(s string) (InvalidationMode, error) {
+	switch InvalidationMode(s) {
+	case "", InvalidateEvict:
+		return InvalidateEvict, nil
+	case InvalidateRefresh:
+		return InvalidateRefresh, nil
+	default:
+		return "", fmt.Errorf("unknown invalidation mode %q", s)
+	}
+}
+
+// invalidator публикует изменения заказов и применяет чужие события к локальному кэшу.
+type invalidator struct {
+	bus      InvalidationBus
+	instance string
+	mode     InvalidationMode
+	local    Cache // кэш экземпляра, к которому применяются события
+
+	mu   sync.Mutex
+	seen *LRU[string, int64] // uid -> последняя примененная версия
+}
+
+// EnableInvalidation подключает хранилище к шине инвалидации.
+// После каждого Save событие публикуется в bus; события других экземпляров
+// применяются к локальному кэшу в режиме mode. Подписка работает до отмены ctx.
+// У двухуровневого кэша события меняют только локальный уровень; кэш только в Redis
+// общий для всех экземпляров, и инвалидация для него не включается.
+func (s *DBWithCacheStore) EnableInvalidation(ctx context.Context, bus InvalidationBus, instance string, mode InvalidationMode) {
+	local := localTier(s.cache)
+	if local == nil {
+		slog.WarnContext(ctx, "Инвалидация кэша не нужна: кэш общий для всех экземпляров")
+		return
+	}
+	capacity := 1024
+	if c, ok := local.(statser); ok {
+		capacity = max(capacity, c.Stats().Capacity)
+	}
+	s.inv = &invalidator{
+		bus:      bus,
+		instance: instance,
+		mode:     mode,
+		local:    local,
+		seen:     NewLru[string, int64](capacity),
+	}
+
+	go func() {
+		err := bus.Subscribe(ctx, s.ApplyInvalidation)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Подписка на инвалидацию кэша остановлена", logging.Err(err))
+		}
+	}()
+}
+
+// publishInvalidation сообщает другим экземплярам об изменении заказа.
+// Ошибка публикации не отменяет сохранение: запись на других экземплярах устареет до вытеснения.
+func (s *DBWithCacheStore) publishInvalidation(ctx context.Context, order *database.Order) {
+	if s.inv == nil {
+		return
+	}
+	ev := Invalidation{OrderUID: order.OrderUID, Source: s.inv.instance}
+	if err := s.inv.bus.Publish(ctx, ev); err != nil {
+		slog.WarnContext(ctx, "Ошибка публикации инвалидации заказа", slog.String(logging.KeyOrderUID, order.OrderUID), logging.Err(err))
+	}
+}
+
+// ApplyInvalidation применяет событие инвалидации к локальному кэшу.
+// Собственные события и события со старой версией игнорируются.
+func (s *DBWithCacheStore) ApplyInvalidation(ev Invalidation) {
+	if s.inv == nil || ev.Source == s.inv.instance {
+		return
+	}
+	if !s.inv.remember(ev) {
+		return
+	}
+
+	local := s.inv.local
+	switch s.inv.mode {
+	case InvalidateRefresh:
+		if _, ok := local.Peek(ev.OrderUID); !ok {
+			return
+		}
+		order, err := s.db.GetOrder(context.Background(), ev.OrderUID)
+		if err != nil {
+			slog.Warn("Ошибка обновления заказа после инвалидации", slog.String(logging.KeyOrderUID, ev.OrderUID), logging.Err(err))
+			local.Delete(ev.OrderUID)
+			return
+		}
+		local.Set(order)
+	default:
+		local.Delete(ev.OrderUID)
+	}
+}
+
+// localTier возвращает часть кэша, принадлежащую только этому экземпляру:
+// локальный уровень двухуровневого кэша или сам кэш; nil — кэша нет или он общий (Redis).
+func localTier(c Cache) Cache {
+	switch c := c.(type) {
+	case nil, *RedisCache:
+		return nil
+	case *TieredCache:
+		return c.Local()
+	default:
+		return c
+	}
+}
+
+// remember запоминает версию события. Возвращает false, если уже видели версию не старше.
+func (i *invalidator) remember(ev Invalidation) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if v, ok := i.seen.Peek(ev.OrderUID); ok && v >= ev.Version {
+		return false
+	}
+	i.seen.Set(ev.OrderUID, ev.Version)
+	return true
+}
+
+// MemoryBus — шина инвалидации в памяти процесса. Каждый подписчик получает
+// все события, как экземпляр с собственной consumer group. Версии событий —
+// сквозной номер публикации, как offset единственной партиции. Используется в тестах.
+type MemoryBus struct {
+	mu   sync.Mutex
+	seq  int64
+	subs map[*memorySub]struct{}
+}
+
+type memorySub struct {
+	ch   chan Invalidation
+	done chan struct{}
+}
+
+// NewMemoryBus создает пустую шину в памяти.
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subs: make(map[*memorySub]struct{})}
+}
+
+// Publish нумерует событие и рассылает его всем текущим подписчикам.
+func (b *MemoryBus) Publish(ctx context.Context, ev Invalidation) error {
+	b.mu.Lock()
+	b.seq++
+	ev.Version = b.seq
+	subs := make([]*memorySub, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.ch <- ev:
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Subscribe доставляет события в handle до отмены ctx.
+func (b *MemoryBus) Subscribe(ctx context.Context, handle func(Invalidation)) error {
+	sub := &memorySub{ch: make(chan Invalidation, 64), done: make(chan struct{})}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.subs, sub)
+		b.mu.Unlock()
+		close(sub.done)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev := <-sub.ch:
+			handle(ev)
+		}
+	}
+}
+
+// Subscribers возвращает число активных подписчиков.
+func (b *MemoryBus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/mitrich772/go-order-service/internal/database"
+	mockdb "github.com/mitrich772/go-order-service/internal/database/mocks"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// eventually ждет выполнения условия, проверяя его периодически
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("условие не выполнилось за отведенное время")
+}
+
+// newReplicas создает два экземпляра хранилища, подключенных к общей шине
+func newReplicas(t *testing.T, mode InvalidationMode) (a, b *DBWithCacheStore, dbA, dbB *mockdb.MockDatabase) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	bus := NewMemoryBus()
+	dbA, dbB = mockdb.NewMockDatabase(ctrl), mockdb.NewMockDatabase(ctrl)
+	a, b = NewDBWithCacheStore(dbA, 10), NewDBWithCacheStore(dbB, 10)
+	a.EnableInvalidation(ctx, bus, "a", mode)
+	b.EnableInvalidation(ctx, bus, "b", mode)
+	eventually(t, func() bool { return bus.Subscribers() == 2 })
+	return a, b, dbA, dbB
+}
+
+// Запись на одном экземпляре вытесняет устаревший заказ на другом
+func TestInvalidation_EvictsOnOtherReplica(t *testing.T) {
+	a, b, dbA, _ := newReplicas(t, InvalidateEvict)
+
+	b.Restore([]*database.Order{{OrderUID: "x", TrackNumber: "old"}})
+
+	updated := &database.Order{OrderUID: "x", TrackNumber: "new"}
+	dbA.EXPECT().SaveOrder(gomock.Any(), updated).Return(nil)
+	if err := a.Save(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, func() bool {
+		_, ok := b.Inspect("x")
+		return !ok
+	})
+	// собственное событие не трогает кэш экземпляра a
+	if got, ok := a.Inspect("x"); !ok || got.TrackNumber != "new" {
+		t.Fatalf("экземпляр a должен хранить свою запись, получено %v %v", got, ok)
+	}
+}
+
+// Обновление уже сохраненного заказа идет через upsert в БД и вытесняет его
+// из локального кэша другого экземпляра
+func TestInvalidation_UpsertEvictsOnOtherReplica(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := NewMemoryBus()
+	a := NewDBWithCacheStore(database.NewGormDatabase(gormDB, 1, 0), 10)
+	b := NewDBWithCacheStore(mockdb.NewMockDatabase(gomock.NewController(t)), 10)
+	a.EnableInvalidation(ctx, bus, "a", InvalidateEvict)
+	b.EnableInvalidation(ctx, bus, "b", InvalidateEvict)
+	eventually(t, func() bool { return bus.Subscribers() == 2 })
+
+	b.Restore([]*database.Order{{OrderUID: "x", TrackNumber: "old"}})
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "orders" (.+) ON CONFLICT \("order_uid"\) DO UPDATE`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "deliveries" (.+) ON CONFLICT \("order_uid"\) DO UPDATE`).
+		WillReturnRows(sqlmock.NewRows([]string{"delivery_id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO "payments" (.+) ON CONFLICT \("order_uid"\) DO UPDATE`).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_id"}).AddRow(1))
+	mock.ExpectExec(`DELETE FROM "items"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := a.Save(context.Background(), &database.Order{OrderUID: "x", TrackNumber: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		_, ok := b.Inspect("x")
+		return !ok
+	})
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// В режиме refresh закэшированный заказ перечитывается из БД
+func TestInvalidation_RefreshReloadsFromDB(t *testing.T) {
+	a, b, dbA, dbB := newReplicas(t, InvalidateRefresh)
+
+	b.Restore([]*database.Order{{OrderUID: "x", TrackNumber: "old"}})
+
+	updated := &database.Order{OrderUID: "x", TrackNumber: "new"}
+	dbA.EXPECT().SaveOrder(gomock.Any(), updated).Return(nil)
+	dbB.EXPECT().GetOrder(gomock.Any(), "x").Return(updated, nil)
+	if err := a.Save(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, func() bool {
+		got, ok := b.Inspect("x")
+		return ok && got.TrackNumber == "new"
+	})
+}
+
+// Версию задает шина в порядке публикации, а не экземпляр: значение от издателя
+// с ушедшими вперед часами не блокирует следующие события
+func TestMemoryBus_AssignsVersions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := NewMemoryBus()
+	got := make(chan Invalidation, 2)
+	go bus.Subscribe(ctx, func(ev Invalidation) { got <- ev })
+	eventually(t, func() bool { return bus.Subscribers() == 1 })
+
+	for _, ev := range []Invalidation{{OrderUID: "x", Version: 1 << 62, Source: "a"}, {OrderUID: "x", Source: "b"}} {
+		if err := bus.Publish(ctx, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if first, second := <-got, <-got; first.Version != 1 || second.Version != 2 || second.Source != "b" {
+		t.Fatalf("ожидались версии 1 и 2 в порядке публикации, получено %+v %+v", first, second)
+	}
+}
+
+// Событие с устаревшей версией игнорируется
+func TestInvalidation_IgnoresOlderVersion(t *testing.T) {
+	_, b, _, _ := newReplicas(t, InvalidateEvict)
+
+	b.ApplyInvalidation(Invalidation{OrderUID: "x", Version: 10, Source: "a"})
+	b.Restore([]*database.Order{{OrderUID: "x"}})
+
+	b.ApplyInvalidation(Invalidation{OrderUID: "x", Version: 5, Source: "a"})
+	if _, ok := b.Inspect("x"); !ok {
+		t.Fatal("событие со старой версией не должно вытеснять заказ")
+	}
+
+	b.ApplyInvalidation(Invalidation{OrderUID: "x", Version: 11, Source: "a"})
+	if _, ok := b.Inspect("x"); ok {
+		t.Fatal("событие с новой версией должно вытеснить заказ")
+	}
+}
+
+// С двухуровневым кэшем событие меняет только локальный уровень получателя:
+// запись в общем Redis, сделанная сохранившим заказ экземпляром, остается
+func TestInvalidation_TieredKeepsSharedEntry(t *testing.T) {
+	for _, mode := range []InvalidationMode{InvalidateEvict, InvalidateRefresh} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+
+			shared, srv := newTestRedisCache(t, 0)
+			bus := NewMemoryBus()
+			dbA, dbB := mockdb.NewMockDatabase(ctrl), mockdb.NewMockDatabase(ctrl)
+			a := NewDBWithCache(dbA, NewTieredCache(NewOrderCache(10), shared))
+			tieredB := NewTieredCache(NewOrderCache(10), NewRedisCache(NewRespClient(RespConfig{Addr: srv.Addr()}), "order:", 0))
+			b := NewDBWithCache(dbB, tieredB)
+			a.EnableInvalidation(ctx, bus, "a", mode)
+			b.EnableInvalidation(ctx, bus, "b", mode)
+			eventually(t, func() bool { return bus.Subscribers() == 2 })
+
+			tieredB.Local().Set(&database.Order{OrderUID: "x", TrackNumber: "old"})
+			updated := &database.Order{OrderUID: "x", TrackNumber: "new"}
+			dbA.EXPECT().SaveOrder(gomock.Any(), updated).Return(nil)
+			// refresh перечитывает заказ только в локальный уровень
+			dbB.EXPECT().GetOrder(gomock.Any(), "x").Return(updated, nil).MaxTimes(1)
+			if err := a.Save(context.Background(), updated); err != nil {
+				t.Fatal(err)
+			}
+
+			eventually(t, func() bool {
+				got, ok := tieredB.Local().Peek("x")
+				return !ok || got.TrackNumber == "new"
+			})
+			got, ok := shared.Peek("x")
+			if !ok || got.TrackNumber != "new" {
+				t.Fatalf("общий уровень должен хранить запись экземпляра a, получено %v %v", got, ok)
+			}
+			if st := shared.Stats(); st.Sets != 1 {
+				t.Fatalf("в Redis должен писать только экземпляр a, записей %d", st.Sets)
+			}
+		})
+	}
+}
+
+// Для кэша только в Redis инвалидация не включается: он общий для всех экземпляров
+func TestInvalidation_SkippedForSharedCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	shared, _ := newTestRedisCache(t, 0)
+	store := NewDBWithCache(mockdb.NewMockDatabase(ctrl), shared)
+	bus := NewMemoryBus()
+
+	store.EnableInvalidation(context.Background(), bus, "a", InvalidateEvict)
+	shared.Set(&database.Order{OrderUID: "x"})
+	store.ApplyInvalidation(Invalidation{OrderUID: "x", Version: 1, Source: "b"})
+
+	if bus.Subscribers() != 0 {
+		t.Fatal("подписка на инвалидацию не нужна для общего кэша")
+	}
+	if _, ok := shared.Peek("x"); !ok {
+		t.Fatal("событие не должно удалять заказ из общего кэша")
+	}
+}
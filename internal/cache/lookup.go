@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+// LookupLimit — сколько последних заказов возвращает поиск по вторичному ключу.
+const LookupLimit = 100
+
+// lookupCache хранит соответствие вторичного ключа (трек-номер, покупатель, телефон,
+// email) списку uid заказов. Сами заказы берутся из кэша заказов.
+// Записи живут ttl: заказы, сохраненные другими экземплярами, появляются в поиске не позже.
+// Методы безопасно вызывать на nil: тогда соответствия не кэшируются.
+type lookupCache struct {
+	mu  sync.Mutex
+	lru *LRU[string, lookupEntry]
+	ttl time.Duration
+	now func() time.Time
+}
+
+type lookupEntry struct {
+	uids    []string
+	expires time.Time
+}
+
+func newLookupCache(size int, ttl time.Duration) *lookupCache {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &lookupCache{lru: NewLru[string, lookupEntry](size), ttl: ttl, now: time.Now}
+}
+
+// lookupKey — ключ записи по нормализованному значению.
+func lookupKey(field database.LookupField, normalized string) string {
+	return string(field) + "\x00" + normalized
+}
+
+func (c *lookupCache) get(key string) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lru.Get(key)
+	if !ok {
+		return nil, false
+	}
+	if c.now().After(e.expires) {
+		c.lru.Delete(key)
+		return nil, false
+	}
+	return e.uids, true
+}
+
+func (c *lookupCache) set(key string, uids []string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Set(key, lookupEntry{uids: uids, expires: c.now().Add(c.ttl)})
+}
+
+// forget удаляет записи по всем вторичным ключам заказа: сохраненный заказ
+// должен сразу находиться поиском на этом экземпляре.
+func (c *lookupCache) forget(order *database.Order) {
+	if c == nil {
+		return
+	}
+	values := map[database.LookupField]string{
+		database.LookupTrackNumber: order.TrackNumber,
+		database.LookupCustomerID:  order.CustomerID,
+		database.LookupPhone:       order.Delivery.Phone,
+		database.LookupEmail:       order.Delivery.Email,
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for field, v := range values {
+		if norm, err := database.NormalizeLookup(field, v); err == nil && norm != "" {
+			c.lru.Delete(lookupKey(field, norm))
+		}
+	}
+}
+
+func (c *lookupCache) purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Purge()
+}
+
+// loadOrders загружает из БД заказы с uid из uids одним запросом.
+func loadOrders(ctx context.Context, db database.Database, uids []string) (map[string]*database.Order, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	orders, err := db.ListOrders(ctx, database.OrderFilter{UIDs: uids})
+	if err != nil {
+		return nil, err
+	}
+	byUID := make(map[string]*database.Order, len(orders))
+	for i := range orders {
+		byUID[orders[i].OrderUID] = &orders[i]
+	}
+	return byUID, nil
+}
+
+// inOrder возвращает найденные заказы в порядке uids. Удаленные с момента поиска
+// заказы пропускаются.
+func inOrder(uids []string, found map[string]*database.Order) []*database.Order {
+	out := make([]*database.Order, 0, len(uids))
+	for _, uid := range uids {
+		if o, ok := found[uid]; ok {
+			out = append(out, o)
+		}
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mitrich772/go-order-service/internal/database"
+	mockdb "github.com/mitrich772/go-order-service/internal/database/mocks"
+)
+
+// Проверяет: Lookup берет заказы из кэша, отсутствующие загружает одним запросом
+// в порядке uid, а повторный поиск не обращается к БД.
+func TestDBWithCacheStore_Lookup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	store := NewDBWithCacheStore(mockDB, 100)
+	store.UseLookupCache(10, time.Minute)
+
+	cached := &database.Order{OrderUID: "b", TrackNumber: "WBTRACK"}
+	store.cache.Set(cached)
+
+	mockDB.EXPECT().FindOrderUIDs(gomock.Any(), database.LookupTrackNumber, "WBTRACK", LookupLimit).
+		Return([]string{"c", "b", "a"}, nil).Times(1)
+	mockDB.EXPECT().ListOrders(gomock.Any(), database.OrderFilter{UIDs: []string{"c", "a"}}).
+		Return([]database.Order{{OrderUID: "a"}, {OrderUID: "c"}}, nil).Times(1)
+
+	for range 2 {
+		orders, err := store.Lookup(context.Background(), database.LookupTrackNumber, " WBTRACK ")
+		if err != nil {
+			t.Fatalf("неожиданная ошибка: %v", err)
+		}
+		if len(orders) != 3 || orders[0].OrderUID != "c" || orders[1] != cached || orders[2].OrderUID != "a" {
+			t.Fatalf("неожиданный результат %v", orders)
+		}
+	}
+}
+
+// Проверяет: Save сбрасывает соответствия по ключам заказа, и следующий поиск
+// идет в БД, а соответствия других ключей остаются.
+func TestDBWithCacheStore_Lookup_SaveForgets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	store := NewDBWithCacheStore(mockDB, 100)
+	store.UseLookupCache(10, time.Minute)
+
+	mockDB.EXPECT().FindOrderUIDs(gomock.Any(), database.LookupPhone, "+79161231234", LookupLimit).
+		Return(nil, nil).Times(2)
+	mockDB.EXPECT().FindOrderUIDs(gomock.Any(), database.LookupCustomerID, "other", LookupLimit).
+		Return(nil, nil).Times(1)
+
+	ctx := context.Background()
+	if _, err := store.Lookup(ctx, database.LookupPhone, "+7 916 123-12-34"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Lookup(ctx, database.LookupCustomerID, "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	order := &database.Order{OrderUID: "new", CustomerID: "test", Delivery: database.Delivery{Phone: "+7 (916) 123-12-34"}}
+	mockDB.EXPECT().SaveOrder(gomock.Any(), order).Return(nil)
+	if err := store.Save(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Lookup(ctx, database.LookupPhone, "+79161231234"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Lookup(ctx, database.LookupCustomerID, "other"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Проверяет: записи кэша соответствий истекают через ttl.
+func TestLookupCache_TTL(t *testing.T) {
+	c := newLookupCache(10, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.set("k", []string{"a"})
+	if uids, ok := c.get("k"); !ok || len(uids) != 1 {
+		t.Fatalf("ожидалось попадание, получили %v %v", uids, ok)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.get("k"); ok {
+		t.Fatal("запись должна истечь")
+	}
+
+	disabled := newLookupCache(0, time.Minute)
+	disabled.set("k", []string{"a"})
+	if _, ok := disabled.get("k"); ok {
+		t.Fatal("выключенный кэш не должен хранить записи")
+	}
+}
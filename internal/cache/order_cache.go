@@ -1,61 +1,158 @@
 package cache
 
 import (
+	"context"
+	"log/slog"
 	"sync"
 
 	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
 )
 
 // OrderCache реализует интерфейс Cache и хранит кэш заказов с потокобезопасным доступом.
 type OrderCache struct {
 	mu      sync.RWMutex
-	storage *LRU
+	storage *LRU[string, *database.Order]
+	stats   counters
 }
 
 // NewOrderCache создает новый OrderCache с заданной вместимостью storeCap.
 func NewOrderCache(storeCap int) *OrderCache {
-	return &OrderCache{
-		storage: NewLru(storeCap),
-	}
+	c := &OrderCache{}
+	c.storage = NewLruWithEvict(storeCap, func(string, *database.Order) {
+		c.stats.evictions.Add(1)
+	})
+	return c
 }
 
 // Get возвращает заказ из кэша по uid.
+// Get перемещает элемент в начало очереди LRU, поэтому берет эксклюзивную блокировку.
 func (c *OrderCache) Get(uid string) (*database.Order, bool) {
-	c.mu.RLock()
-	value, ok := c.storage.Get(uid)
-	c.mu.RUnlock()
-
-	if !ok {
-		return nil, ok
-	}
+	c.mu.Lock()
+	order, ok := c.storage.Get(uid)
+	c.mu.Unlock()
 
-	order, ok := value.(*database.Order)
 	if !ok {
+		c.stats.misses.Add(1)
 		return nil, false
 	}
-
-	return order, ok
+	c.stats.hits.Add(1)
+	return order, true
 }
 
 // Set добавляет заказ в кэш или обновляет существующий.
 func (c *OrderCache) Set(order *database.Order) (exist bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	res := c.storage.Set(order.OrderUID, order)
-	return res
+	c.stats.sets.Add(1)
+	return c.storage.Set(order.OrderUID, order)
+}
+
+// Peek возвращает заказ по uid, не влияя на порядок вытеснения и счетчики.
+func (c *OrderCache) Peek(uid string) (*database.Order, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.storage.Peek(uid)
+}
+
+// Delete удаляет заказ из кэша.
+func (c *OrderCache) Delete(uid string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.storage.Delete(uid)
+}
+
+// Purge очищает кэш. Счетчики при этом не сбрасываются.
+func (c *OrderCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.storage.Purge()
+}
+
+// Entries возвращает заказы в порядке от наименее к наиболее недавно использованному.
+func (c *OrderCache) Entries() []*database.Order {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := c.storage.Keys()
+	orders := make([]*database.Order, 0, len(keys))
+	for _, key := range keys {
+		order, _ := c.storage.Peek(key)
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+// Keys возвращает uid закэшированных заказов от наименее к наиболее недавно использованному.
+func (c *OrderCache) Keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.storage.Keys()
+}
+
+// Len возвращает количество заказов в кэше.
+func (c *OrderCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.storage.Len()
+}
+
+// Resize меняет вместимость кэша, вытесняя лишние заказы.
+func (c *OrderCache) Resize(storeCap int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.storage.Resize(storeCap)
+}
+
+// Stats возвращает снимок счетчиков кэша.
+func (c *OrderCache) Stats() Stats {
+	var st Stats
+	c.stats.fill(&st)
+
+	c.mu.RLock()
+	st.Size = c.storage.Len()
+	st.Capacity = c.storage.Cap()
+	c.mu.RUnlock()
+
+	return st
+}
+
+// Warm загружает в кэш последние заказы из базы.
+// Возвращает количество загруженных заказов.
+func (c *OrderCache) Warm(ctx context.Context, db database.Database) (int, error) {
+	c.mu.RLock()
+	n := c.storage.Cap()
+	c.mu.RUnlock()
+
+	orders, err := db.GetLastNOrders(ctx, n)
+	if err != nil {
+		return 0, err
+	}
+
+	// Заказы идут от новых к старым: кладем с конца, чтобы самый новый стал MRU.
+	for i := len(orders) - 1; i >= 0; i-- {
+		c.Set(&orders[i])
+	}
+	c.addWarmed(len(orders))
+	return len(orders), nil
+}
+
+// addWarmed учитывает n заказов, загруженных прогревом.
+func (c *OrderCache) addWarmed(n int) {
+	c.stats.warmed.Add(uint64(n))
 }
 
 // NewOrderCaheFromDB инициализирует OrderCache с данными из базы.
+// Прогрев выполняется синхронно; при ошибке возвращается холодный кэш.
+// Для фонового прогрева используйте Warmup.
 func NewOrderCaheFromDB(db database.Database, storeCap int) *OrderCache {
 	cache := NewOrderCache(storeCap)
 
-	orders, err := db.GetLastNOrders(storeCap)
+	n, err := cache.Warm(context.Background(), db)
 	if err != nil {
-		panic(err)
-	}
-
-	for i := range orders {
-		cache.Set(&orders[i])
+		slog.Error("Ошибка прогрева кэша, кэш пуст", logging.Err(err))
+		return cache
 	}
+	slog.Info("Кэш прогрет", slog.Int("orders", n))
 	return cache
 }
@@ -58,7 +58,7 @@ func TestNewOrderCaheFromDB(t *testing.T) {
 	}
 
 	mockDB.EXPECT().
-		GetLastNOrders(10).
+		GetLastNOrders(gomock.Any(), 10).
 		Return(orders, nil).
 		Times(1)
 
@@ -69,4 +69,51 @@ func TestNewOrderCaheFromDB(t *testing.T) {
 			t.Errorf("заказ %s не найден в кэше", o.OrderUID)
 		}
 	}
+	if warmed := cache.Stats().Warmed; warmed != 2 {
+		t.Errorf("ожидалось warmed=2, получено %d", warmed)
+	}
+}
+
+// Проверяет счетчики попаданий, промахов, записей и вытеснений
+func TestOrderCache_Stats(t *testing.T) {
+	cache := NewOrderCache(2)
+
+	cache.Set(&database.Order{OrderUID: "a"})
+	cache.Set(&database.Order{OrderUID: "b"})
+	cache.Set(&database.Order{OrderUID: "c"}) // вытесняет "a"
+
+	cache.Get("b")
+	cache.Get("a")
+	cache.Peek("c") // не учитывается
+
+	st := cache.Stats()
+	if st.Hits != 1 || st.Misses != 1 {
+		t.Errorf("ожидалось hits=1 misses=1, получено hits=%d misses=%d", st.Hits, st.Misses)
+	}
+	if st.Sets != 3 || st.Evictions != 1 {
+		t.Errorf("ожидалось sets=3 evictions=1, получено sets=%d evictions=%d", st.Sets, st.Evictions)
+	}
+	if st.Size != 2 || st.Capacity != 2 {
+		t.Errorf("ожидалось size=2 capacity=2, получено size=%d capacity=%d", st.Size, st.Capacity)
+	}
+	if st.HitRatio != 0.5 {
+		t.Errorf("ожидался hit ratio 0.5, получено %v", st.HitRatio)
+	}
+}
+
+// Проверяет, что уменьшение вместимости вытесняет старые заказы и учитывается в статистике
+func TestOrderCache_Resize(t *testing.T) {
+	cache := NewOrderCache(3)
+	for _, uid := range []string{"a", "b", "c"} {
+		cache.Set(&database.Order{OrderUID: uid})
+	}
+
+	cache.Resize(1)
+
+	if keys := cache.Keys(); len(keys) != 1 || keys[0] != "c" {
+		t.Fatalf("ожидался только 'c', получено %v", keys)
+	}
+	if st := cache.Stats(); st.Evictions != 2 || st.Capacity != 1 {
+		t.Errorf("ожидалось evictions=2 capacity=1, получено %+v", st)
+	}
 }
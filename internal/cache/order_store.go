@@ -1,12 +1,20 @@
 package cache
 
 import (
+	"context"
+
 	"github.com/mitrich772/go-order-service/internal/database"
 )
 
 // OrderStore описывает интерфейс хранилища заказов (БД или БД+кэш).
 type OrderStore interface {
-	Save(order *database.Order) error
-	Get(uid string) (*database.Order, error)
+	Save(ctx context.Context, order *database.Order) error
+	Get(ctx context.Context, uid string) (*database.Order, error)
+	// List выбирает заказы по фильтру напрямую из БД, минуя кэш.
+	List(ctx context.Context, filter database.OrderFilter) ([]database.Order, error)
+	// Lookup возвращает до LookupLimit последних заказов по вторичному ключу
+	// (трек-номер, покупатель, телефон, email).
+	Lookup(ctx context.Context, field database.LookupField, value string) ([]*database.Order, error)
+	// Search ищет заказы полнотекстовым поиском по товарам и доставке.
+	Search(ctx context.Context, search database.OrderSearch) ([]database.SearchResult, error)
 }
-
@@ -0,0 +1,283 @@
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"github.com/mitrich772/go-order-service/internal/pii"
+)
+
+// orderCodecV1 — первый байт закодированного заказа: JSON, сжатый deflate.
+const orderCodecV1 = 1
+
+// RedisCache реализует Cache поверх общего Redis-совместимого сервера.
+// Ошибки сервера не пробрасываются: Get считает их промахом, Set — пропуском записи.
+// Set пишет ключ командой SET ... GET (Redis 6.2+); на более старых серверах
+// она отвергается синтаксической ошибкой, и кэш переходит на EXISTS + SET.
+type RedisCache struct {
+	client *RespClient
+	prefix string
+	ttl    time.Duration
+	cipher *pii.Cipher
+	stats  counters
+	fails  atomic.Uint64
+	// legacySet — сервер не поддерживает SET ... GET (Redis до 6.2).
+	legacySet atomic.Bool
+}
+
+// NewRedisCache создает кэш в Redis. Ключи хранятся как prefix+order_uid
+// и живут ttl (0 — без срока жизни).
+func NewRedisCache(client *RespClient, prefix string, ttl time.Duration) *RedisCache {
+	return &RedisCache{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// UseEncryption включает шифрование персональных данных доставки и платежа в значениях
+// Redis тем же шифратором, что и в БД: общий сервер не хранит их открытыми.
+// Открытые значения, записанные до включения, читаются как есть.
+func (c *RedisCache) UseEncryption(cipher *pii.Cipher) {
+	c.cipher = cipher
+}
+
+// Get возвращает заказ из Redis.
+func (c *RedisCache) Get(uid string) (*database.Order, bool) {
+	order, ok := c.Peek(uid)
+	if !ok {
+		c.stats.misses.Add(1)
+		return nil, false
+	}
+	c.stats.hits.Add(1)
+	return order, true
+}
+
+// Set записывает заказ в Redis с TTL. Возвращает true, если ключ уже существовал.
+func (c *RedisCache) Set(order *database.Order) (exist bool) {
+	data, err := encodeOrder(c.cipher, order)
+	if err != nil {
+		slog.Error("Ошибка кодирования заказа для Redis", slog.String(logging.KeyOrderUID, order.OrderUID), logging.Err(err))
+		return false
+	}
+
+	key := c.prefix + order.OrderUID
+	c.stats.sets.Add(1)
+	if !c.legacySet.Load() {
+		_, err = c.client.Do(c.setArgs(key, data, "GET")...)
+		var respErr RespError
+		switch {
+		case err == nil:
+			return true
+		case errors.Is(err, errNil):
+			return false
+		case errors.As(err, &respErr) && strings.Contains(string(respErr), "syntax error"):
+			slog.Warn("Redis не поддерживает SET ... GET (нужна версия 6.2+), используется EXISTS + SET")
+			c.legacySet.Store(true)
+		default:
+			c.fail("SET", err)
+			return false
+		}
+	}
+	return c.setLegacy(key, data)
+}
+
+// setLegacy записывает ключ для Redis до 6.2: EXISTS и SET — две команды,
+// поэтому признак существования ключа неатомарен и служит только для статистики.
+func (c *RedisCache) setLegacy(key string, data []byte) (exist bool) {
+	reply, err := c.client.Do("EXISTS", key)
+	if err != nil {
+		c.fail("EXISTS", err)
+	}
+	n, _ := reply.(int64)
+	if _, err := c.client.Do(c.setArgs(key, data)...); err != nil {
+		c.fail("SET", err)
+		return false
+	}
+	return n > 0
+}
+
+// setArgs собирает команду SET с TTL кэша и дополнительными опциями.
+func (c *RedisCache) setArgs(key string, data []byte, opts ...string) []string {
+	args := append([]string{"SET", key, string(data)}, opts...)
+	if c.ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(c.ttl.Milliseconds(), 10))
+	}
+	return args
+}
+
+// Peek возвращает заказ из Redis без учета в счетчиках.
+func (c *RedisCache) Peek(uid string) (*database.Order, bool) {
+	reply, err := c.client.Do("GET", c.prefix+uid)
+	if err != nil {
+		if !errors.Is(err, errNil) {
+			c.fail("GET", err)
+		}
+		return nil, false
+	}
+	s, ok := reply.(string)
+	if !ok {
+		return nil, false
+	}
+	order, err := decodeOrder(c.cipher, []byte(s))
+	if err != nil {
+		slog.Error("Ошибка декодирования заказа из Redis", slog.String(logging.KeyOrderUID, uid), logging.Err(err))
+		return nil, false
+	}
+	return order, true
+}
+
+// Delete удаляет заказ из Redis.
+func (c *RedisCache) Delete(uid string) bool {
+	reply, err := c.client.Do("DEL", c.prefix+uid)
+	if err != nil {
+		c.fail("DEL", err)
+		return false
+	}
+	n, _ := reply.(int64)
+	return n > 0
+}
+
+// Purge удаляет все ключи с префиксом кэша.
+func (c *RedisCache) Purge() {
+	for _, key := range c.scan() {
+		if _, err := c.client.Do("DEL", key); err != nil {
+			c.fail("DEL", err)
+			return
+		}
+	}
+}
+
+// Len возвращает количество ключей с префиксом кэша. Проходит все ключи базы (SCAN),
+// поэтому не подходит для частых вызовов; Stats берет размер из DBSIZE.
+func (c *RedisCache) Len() int {
+	return len(c.scan())
+}
+
+// Keys возвращает uid заказов в кэше. Порядок не определен: Redis не хранит порядок обращений.
+func (c *RedisCache) Keys() []string {
+	keys := c.scan()
+	for i, key := range keys {
+		keys[i] = key[len(c.prefix):]
+	}
+	return keys
+}
+
+// Stats возвращает счетчики обращений и размер кэша. Размер приблизительный
+// (SizeApprox): это число ключей базы Redis (DBSIZE, O(1)), потому что Stats читается
+// при каждом сборе метрик, а подсчет ключей с префиксом требует полного SCAN.
+// Если база общая с другими приложениями, размер включает их ключи.
+func (c *RedisCache) Stats() Stats {
+	var st Stats
+	c.stats.fill(&st)
+	st.Size = c.dbSize()
+	st.SizeApprox = true
+	st.Errors = c.fails.Load()
+	return st
+}
+
+// dbSize возвращает число ключей в базе Redis, 0 при ошибке.
+func (c *RedisCache) dbSize() int {
+	reply, err := c.client.Do("DBSIZE")
+	if err != nil {
+		c.fail("DBSIZE", err)
+		return 0
+	}
+	n, _ := reply.(int64)
+	return int(n)
+}
+
+// Ping проверяет доступность сервера.
+func (c *RedisCache) Ping() error {
+	_, err := c.client.Do("PING")
+	return err
+}
+
+// scan возвращает все ключи с префиксом кэша.
+func (c *RedisCache) scan() []string {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := c.client.Do("SCAN", cursor, "MATCH", c.prefix+"*", "COUNT", "100")
+		if err != nil {
+			c.fail("SCAN", err)
+			return keys
+		}
+		arr, ok := reply.([]any)
+		if !ok || len(arr) != 2 {
+			c.fail("SCAN", fmt.Errorf("unexpected reply %v", reply))
+			return keys
+		}
+		cursor, _ = arr[0].(string)
+		batch, _ := arr[1].([]any)
+		for _, k := range batch {
+			if key, ok := k.(string); ok {
+				keys = append(keys, key)
+			}
+		}
+		if cursor == "0" || cursor == "" {
+			return keys
+		}
+	}
+}
+
+// fail учитывает и логирует ошибку обращения к серверу.
+func (c *RedisCache) fail(cmd string, err error) {
+	c.fails.Add(1)
+	slog.Warn("Ошибка Redis", slog.String("command", cmd), logging.Err(err))
+}
+
+// encodeOrder кодирует заказ компактно: байт версии + JSON, сжатый deflate.
+// Персональные данные шифруются cipher (nil — без шифрования).
+func encodeOrder(cipher *pii.Cipher, order *database.Order) ([]byte, error) {
+	order, err := database.EncryptOrder(cipher, order)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(orderCodecV1)
+	zw, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(zw).Encode(order); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeOrder декодирует заказ, записанный encodeOrder, и расшифровывает персональные данные.
+func decodeOrder(cipher *pii.Cipher, data []byte) (*database.Order, error) {
+	if len(data) == 0 || data[0] != orderCodecV1 {
+		return nil, errors.New("unknown order encoding")
+	}
+	zr := flate.NewReader(bytes.NewReader(data[1:]))
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	var order database.Order
+	if err := json.Unmarshal(raw, &order); err != nil {
+		return nil, err
+	}
+	if err := database.DecryptOrder(cipher, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
@@ -0,0 +1,257 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/cache/resptest"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/pii"
+	"github.com/mitrich772/go-order-service/internal/retry"
+	"github.com/mitrich772/go-order-service/producer/generate"
+)
+
+func testCipher(t *testing.T) *pii.Cipher {
+	t.Helper()
+	keys, err := pii.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, pii.KeySize)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pii.NewCipher(keys)
+}
+
+func newTestRedisCache(t *testing.T, ttl time.Duration) (*RedisCache, *resptest.Server) {
+	t.Helper()
+	srv := resptest.NewServer()
+	t.Cleanup(srv.Close)
+	client := NewRespClient(RespConfig{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisCache(client, "order:", ttl), srv
+}
+
+// Заказ сохраняется в Redis с TTL и читается обратно целиком
+func TestRedisCache_SetGetWithTTL(t *testing.T) {
+	c, srv := newTestRedisCache(t, time.Minute)
+	order := generate.MakeOrder()
+
+	if c.Set(&order) {
+		t.Fatal("ожидалось exist=false для нового ключа")
+	}
+	if !c.Set(&order) {
+		t.Fatal("ожидалось exist=true для существующего ключа")
+	}
+
+	got, ok := c.Get(order.OrderUID)
+	if !ok {
+		t.Fatal("заказ не найден в Redis")
+	}
+	if got.OrderUID != order.OrderUID || len(got.Items) != len(order.Items) || got.Delivery.Phone != order.Delivery.Phone {
+		t.Fatalf("заказ восстановлен неверно: %+v", got)
+	}
+	if ttl := srv.TTL("order:" + order.OrderUID); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("ожидался TTL до минуты, получено %v", ttl)
+	}
+
+	srv.Advance(2 * time.Minute)
+	if _, ok := c.Get(order.OrderUID); ok {
+		t.Fatal("заказ должен истечь по TTL")
+	}
+
+	st := c.Stats()
+	if st.Hits != 1 || st.Misses != 1 || st.Sets != 2 {
+		t.Fatalf("неожиданная статистика: %+v", st)
+	}
+}
+
+// Delete, Len, Keys и Purge работают только с ключами своего префикса
+func TestRedisCache_DeletePurgeKeepsForeignKeys(t *testing.T) {
+	c, srv := newTestRedisCache(t, 0)
+	other := NewRedisCache(NewRespClient(RespConfig{Addr: srv.Addr()}), "other:", 0)
+
+	for _, uid := range []string{"a", "b", "c"} {
+		c.Set(&database.Order{OrderUID: uid})
+	}
+	other.Set(&database.Order{OrderUID: "z"})
+
+	if !c.Delete("a") || c.Delete("a") {
+		t.Fatal("Delete должен вернуть true только для существующего ключа")
+	}
+	if n := c.Len(); n != 2 {
+		t.Fatalf("ожидалось 2 ключа, получено %d", n)
+	}
+	if size := c.Stats().Size; size != 3 {
+		t.Fatalf("Stats.Size — DBSIZE всей базы, ожидалось 3, получено %d", size)
+	}
+	if keys := c.Keys(); len(keys) != 2 || keys[0] != "b" || keys[1] != "c" {
+		t.Fatalf("ожидались ключи [b c], получено %v", keys)
+	}
+
+	c.Purge()
+	if n := c.Len(); n != 0 {
+		t.Fatalf("ожидался пустой кэш после Purge, получено %d", n)
+	}
+	if _, ok := other.Peek("z"); !ok {
+		t.Fatal("Purge не должен трогать чужие ключи")
+	}
+}
+
+// Недоступный сервер дает промах, а не панику, и учитывается в ошибках
+func TestRedisCache_ServerDown(t *testing.T) {
+	c, srv := newTestRedisCache(t, 0)
+	srv.Close()
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("ожидался промах при недоступном сервере")
+	}
+	c.Set(&database.Order{OrderUID: "a"})
+	if st := c.Stats(); st.Errors < 2 || st.Misses != 1 {
+		t.Fatalf("ожидались учтенные ошибки, получено %+v", st)
+	}
+}
+
+// Пока сервер недоступен, клиент не пытается подключаться на каждой команде
+func TestRespClient_DialCooldown(t *testing.T) {
+	srv := resptest.NewServer()
+	addr := srv.Addr()
+	srv.Close()
+	client := NewRespClient(RespConfig{Addr: addr, DialCooldown: time.Hour})
+
+	if _, err := client.Do("PING"); err == nil || errors.Is(err, retry.ErrCircuitOpen) {
+		t.Fatalf("первая команда должна получить ошибку подключения, получено %v", err)
+	}
+	if _, err := client.Do("PING"); !errors.Is(err, retry.ErrCircuitOpen) {
+		t.Fatalf("ожидалась ErrCircuitOpen без повторного подключения, получено %v", err)
+	}
+}
+
+// Длина строки или массива в ответе сервера ограничена
+func TestReadRespValue_LengthLimits(t *testing.T) {
+	for _, reply := range []string{"$1073741824\r\n", "*1073741824\r\n"} {
+		if _, err := readRespValue(bufio.NewReader(strings.NewReader(reply))); err == nil || !strings.Contains(err.Error(), "exceeds") {
+			t.Errorf("%q: ожидалась ошибка превышения длины, получено %v", reply, err)
+		}
+	}
+}
+
+// На Redis до 6.2 без SET ... GET запись идет через EXISTS + SET
+func TestRedisCache_LegacySet(t *testing.T) {
+	c, srv := newTestRedisCache(t, time.Minute)
+	srv.EmulateLegacySet()
+	order := &database.Order{OrderUID: "a"}
+
+	if c.Set(order) {
+		t.Fatal("ожидалось exist=false для нового ключа")
+	}
+	if !c.Set(order) {
+		t.Fatal("ожидалось exist=true для существующего ключа")
+	}
+	if got, ok := c.Get("a"); !ok || got.OrderUID != "a" {
+		t.Fatalf("заказ не записан: %v %v", got, ok)
+	}
+	if ttl := srv.TTL("order:a"); ttl <= 0 {
+		t.Fatal("TTL не установлен")
+	}
+	if st := c.Stats(); st.Errors != 0 || !st.SizeApprox {
+		t.Fatalf("неожиданная статистика %+v", st)
+	}
+}
+
+// Клиент проходит AUTH, если сервер требует пароль
+func TestRespClient_Auth(t *testing.T) {
+	srv := resptest.NewServerWithAuth("secret")
+	defer srv.Close()
+
+	bad := NewRedisCache(NewRespClient(RespConfig{Addr: srv.Addr()}), "o:", 0)
+	if err := bad.Ping(); err == nil {
+		t.Fatal("ожидалась ошибка без пароля")
+	}
+	good := NewRedisCache(NewRespClient(RespConfig{Addr: srv.Addr(), Password: "secret"}), "o:", 0)
+	if err := good.Ping(); err != nil {
+		t.Fatalf("неожиданная ошибка с паролем: %v", err)
+	}
+}
+
+// Закодированный заказ компактнее обычного JSON
+func TestOrderCodec_Compact(t *testing.T) {
+	order := generate.MakeOrder()
+	data, err := encodeOrder(nil, &order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := json.Marshal(order)
+	if len(data) >= len(plain) {
+		t.Fatalf("ожидалось сжатие: %d >= %d", len(data), len(plain))
+	}
+	got, err := decodeOrder(nil, data)
+	if err != nil || got.OrderUID != order.OrderUID {
+		t.Fatalf("ошибка декодирования: %v %+v", err, got)
+	}
+}
+
+// С шифрованием персональные данные хранятся в Redis зашифрованными, а читаются открытыми
+func TestRedisCache_EncryptsPII(t *testing.T) {
+	c, srv := newTestRedisCache(t, 0)
+	c.UseEncryption(testCipher(t))
+	order := generate.MakeOrder()
+	c.Set(&order)
+
+	raw, err := NewRespClient(RespConfig{Addr: srv.Addr()}).Do("GET", "order:"+order.OrderUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored database.Order
+	if err := json.NewDecoder(flate.NewReader(strings.NewReader(raw.(string)[1:]))).Decode(&stored); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{stored.Delivery.Name, stored.Delivery.Phone, stored.Delivery.Email, stored.Delivery.Address, stored.Payment.Transaction, stored.Payment.Bank} {
+		if !pii.IsEncrypted(v) {
+			t.Fatalf("в Redis открытое значение %q", v)
+		}
+	}
+	if strings.Contains(raw.(string), order.Delivery.Phone) {
+		t.Fatal("телефон попал в Redis открытым")
+	}
+
+	got, ok := c.Get(order.OrderUID)
+	if !ok || got.Delivery != order.Delivery || got.Payment != order.Payment {
+		t.Fatalf("заказ расшифрован неверно: %+v", got)
+	}
+	if order.Delivery.Phone == stored.Delivery.Phone {
+		t.Fatal("исходный заказ не должен меняться")
+	}
+}
+
+// Две реплики с двухуровневым кэшем видят записи друг друга через общий уровень
+func TestTieredCache_SharedBetweenReplicas(t *testing.T) {
+	shared, srv := newTestRedisCache(t, time.Minute)
+	replicaA := NewTieredCache(NewOrderCache(10), shared)
+	replicaB := NewTieredCache(NewOrderCache(10), NewRedisCache(NewRespClient(RespConfig{Addr: srv.Addr()}), "order:", time.Minute))
+
+	replicaA.Set(&database.Order{OrderUID: "x"})
+
+	if _, ok := replicaB.local.Peek("x"); ok {
+		t.Fatal("локальный уровень реплики B не должен содержать чужую запись")
+	}
+	if _, ok := replicaB.Get("x"); !ok {
+		t.Fatal("реплика B должна найти заказ в общем уровне")
+	}
+	if _, ok := replicaB.local.Peek("x"); !ok {
+		t.Fatal("попадание в общий уровень должно поднять заказ в локальный")
+	}
+
+	st := replicaB.Stats()
+	if st.Shared == nil || st.Shared.Hits != 1 || st.Misses != 1 {
+		t.Fatalf("неожиданная статистика двухуровневого кэша: %+v", st)
+	}
+
+	replicaB.Delete("x")
+	if _, ok := replicaA.shared.Peek("x"); ok {
+		t.Fatal("Delete должен удалять заказ и из общего уровня")
+	}
+}
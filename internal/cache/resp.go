@@ -0,0 +1,232 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/retry"
+)
+
+// RespError — ошибка, которую вернул сервер (ответ "-ERR ...").
+type RespError string
+
+func (e RespError) Error() string { return string(e) }
+
+// errNil — ответ nil (bulk string или массив длины -1).
+var errNil = errors.New("resp: nil reply")
+
+// Ограничения на длины из ответа сервера: поврежденный или чужой ответ не должен
+// заставить клиент выделить произвольный объем памяти. 512 МиБ — предел строки в Redis.
+const (
+	maxRespBulk  = 512 << 20
+	maxRespArray = 1 << 20
+)
+
+// RespConfig содержит настройки подключения к Redis-совместимому серверу.
+type RespConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	Timeout  time.Duration
+	PoolSize int
+	// DialCooldown — сколько не открывать новые соединения после неудачного подключения,
+	// 0 — 1 секунда. Пока сервер недоступен, команды сразу получают retry.ErrCircuitOpen,
+	// а не ждут Timeout на каждом промахе кэша.
+	DialCooldown time.Duration
+}
+
+// RespClient — минимальный клиент протокола RESP с пулом соединений.
+// Поддерживает только то, что нужно RedisCache.
+type RespClient struct {
+	cfg  RespConfig
+	pool chan *respConn
+	dial *retry.Breaker
+}
+
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// NewRespClient создает клиент. Соединения открываются лениво.
+func NewRespClient(cfg RespConfig) *RespClient {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 500 * time.Millisecond
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 8
+	}
+	if cfg.DialCooldown <= 0 {
+		cfg.DialCooldown = time.Second
+	}
+	return &RespClient{
+		cfg:  cfg,
+		pool: make(chan *respConn, cfg.PoolSize),
+		dial: retry.NewBreaker(1, cfg.DialCooldown),
+	}
+}
+
+// Do выполняет одну команду и возвращает ответ:
+// string для simple/bulk string, int64 для integer, []any для массива.
+// Ответ nil возвращается как (nil, errNil).
+func (c *RespClient) Do(args ...string) (any, error) {
+	conn, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(c.cfg.Timeout, args...)
+	var respErr RespError
+	if err != nil && !errors.Is(err, errNil) && !errors.As(err, &respErr) {
+		// Сетевая ошибка или рассинхронизация протокола: соединение больше не используем.
+		conn.conn.Close()
+		return nil, err
+	}
+	c.put(conn)
+	return reply, err
+}
+
+// Close закрывает все свободные соединения пула.
+func (c *RespClient) Close() error {
+	for {
+		select {
+		case conn := <-c.pool:
+			conn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *RespClient) get() (*respConn, error) {
+	select {
+	case conn := <-c.pool:
+		return conn, nil
+	default:
+	}
+
+	if err := c.dial.Allow(); err != nil {
+		return nil, err
+	}
+	nc, err := net.DialTimeout("tcp", c.cfg.Addr, c.cfg.Timeout)
+	if err != nil {
+		c.dial.Failure()
+		return nil, err
+	}
+	c.dial.Success()
+	conn := &respConn{conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	if c.cfg.Password != "" {
+		if _, err := conn.do(c.cfg.Timeout, "AUTH", c.cfg.Password); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("resp auth: %w", err)
+		}
+	}
+	if c.cfg.DB != 0 {
+		if _, err := conn.do(c.cfg.Timeout, "SELECT", strconv.Itoa(c.cfg.DB)); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("resp select: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (c *RespClient) put(conn *respConn) {
+	select {
+	case c.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+func (c *respConn) do(timeout time.Duration, args ...string) (any, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	if err := writeRespCommand(c.w, args...); err != nil {
+		return nil, err
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return readRespValue(c.r)
+}
+
+// writeRespCommand записывает команду как массив bulk-строк.
+func writeRespCommand(w io.Writer, args ...string) error {
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if _, err := fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRespValue читает одно значение RESP.
+// Ошибка сервера возвращается как RespError, nil — как errNil.
+func readRespValue(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("resp: malformed line %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, RespError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, errNil
+		}
+		if n > maxRespBulk {
+			return nil, fmt.Errorf("resp: bulk string of %d bytes exceeds %d", n, maxRespBulk)
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, errNil
+		}
+		if n > maxRespArray {
+			return nil, fmt.Errorf("resp: array of %d elements exceeds %d", n, maxRespArray)
+		}
+		arr := make([]any, n)
+		for i := range arr {
+			v, err := readRespValue(r)
+			if err != nil && !errors.Is(err, errNil) {
+				return nil, err
+			}
+			arr[i] = v
+		}
+		return arr, nil
+	default:
+		return nil, fmt.Errorf("resp: unknown type %q", kind)
+	}
+}
@@ -0,0 +1,382 @@
+// Package resptest предоставляет встроенный RESP-сервер для тестов кэша.
+// Поддерживается подмножество команд Redis: PING, AUTH, SELECT, GET, SET
+// (EX, PX, NX, GET), DEL, EXISTS, SCAN (MATCH, COUNT), DBSIZE и FLUSHDB.
+package resptest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server — RESP-сервер в памяти процесса.
+type Server struct {
+	password  string
+	legacySet bool // SET без опции GET, как в Redis до 6.2
+
+	ln    net.Listener
+	mu    sync.Mutex
+	data  map[string]entry
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+	now   func() time.Time
+}
+
+type entry struct {
+	value    string
+	expireAt time.Time
+}
+
+// NewServer запускает сервер на случайном локальном порту.
+func NewServer() *Server {
+	return NewServerWithAuth("")
+}
+
+// NewServerWithAuth запускает сервер, который требует AUTH password до остальных команд.
+func NewServerWithAuth(password string) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("resptest: listen: %v", err))
+	}
+	s := &Server{
+		password: password,
+		ln:       ln,
+		data:     make(map[string]entry),
+		conns:    make(map[net.Conn]struct{}),
+		now:      time.Now,
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// EmulateLegacySet отключает опцию GET команды SET, как в Redis до 6.2.
+func (s *Server) EmulateLegacySet() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.legacySet = true
+}
+
+// Addr возвращает адрес сервера.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close останавливает сервер и закрывает все соединения.
+func (s *Server) Close() {
+	s.ln.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Advance сдвигает часы сервера на d, чтобы проверить истечение TTL.
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.now
+	s.now = func() time.Time { return prev().Add(d) }
+}
+
+// TTL возвращает оставшееся время жизни ключа (0 — без срока жизни или нет ключа).
+func (s *Server) TTL(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.data[key]
+	if !ok || e.expireAt.IsZero() {
+		return 0
+	}
+	return e.expireAt.Sub(s.now())
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(c)
+	}
+}
+
+func (s *Server) handle(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+
+	r := bufio.NewReader(c)
+	w := bufio.NewWriter(c)
+	authed := s.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		switch {
+		case cmd == "AUTH":
+			if len(args) == 2 && args[1] == s.password {
+				authed = true
+				writeSimple(w, "OK")
+			} else {
+				writeError(w, "WRONGPASS invalid password")
+			}
+		case !authed:
+			writeError(w, "NOAUTH Authentication required.")
+		default:
+			s.exec(w, cmd, args[1:])
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) exec(w *bufio.Writer, cmd string, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	switch cmd {
+	case "PING":
+		writeSimple(w, "PONG")
+	case "SELECT":
+		writeSimple(w, "OK")
+	case "GET":
+		if len(args) != 1 {
+			writeError(w, "ERR wrong number of arguments for 'get' command")
+			return
+		}
+		e, ok := s.lookup(args[0], now)
+		if !ok {
+			writeNil(w)
+			return
+		}
+		writeBulk(w, e.value)
+	case "SET":
+		s.set(w, args, now)
+	case "DEL":
+		n := 0
+		for _, key := range args {
+			if _, ok := s.lookup(key, now); ok {
+				delete(s.data, key)
+				n++
+			}
+		}
+		writeInt(w, n)
+	case "EXISTS":
+		n := 0
+		for _, key := range args {
+			if _, ok := s.lookup(key, now); ok {
+				n++
+			}
+		}
+		writeInt(w, n)
+	case "DBSIZE":
+		n := 0
+		for key := range s.data {
+			if _, ok := s.lookup(key, now); ok {
+				n++
+			}
+		}
+		writeInt(w, n)
+	case "FLUSHDB":
+		s.data = make(map[string]entry)
+		writeSimple(w, "OK")
+	case "SCAN":
+		s.scan(w, args, now)
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", cmd))
+	}
+}
+
+// lookup возвращает неистекшую запись, удаляя истекшую.
+func (s *Server) lookup(key string, now time.Time) (entry, bool) {
+	e, ok := s.data[key]
+	if !ok {
+		return entry{}, false
+	}
+	if !e.expireAt.IsZero() && !now.Before(e.expireAt) {
+		delete(s.data, key)
+		return entry{}, false
+	}
+	return e, true
+}
+
+func (s *Server) set(w *bufio.Writer, args []string, now time.Time) {
+	if len(args) < 2 {
+		writeError(w, "ERR wrong number of arguments for 'set' command")
+		return
+	}
+	key, value := args[0], args[1]
+	var ttl time.Duration
+	var nx, get bool
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				writeError(w, "ERR syntax error")
+				return
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				writeError(w, "ERR invalid expire time in 'set' command")
+				return
+			}
+			if opt == "EX" {
+				ttl = time.Duration(n) * time.Second
+			} else {
+				ttl = time.Duration(n) * time.Millisecond
+			}
+			i++
+		case "NX":
+			nx = true
+		case "GET":
+			if s.legacySet {
+				writeError(w, "ERR syntax error")
+				return
+			}
+			get = true
+		default:
+			writeError(w, "ERR syntax error")
+			return
+		}
+	}
+
+	old, exists := s.lookup(key, now)
+	if !(nx && exists) {
+		e := entry{value: value}
+		if ttl > 0 {
+			e.expireAt = now.Add(ttl)
+		}
+		s.data[key] = e
+	}
+
+	switch {
+	case get && exists:
+		writeBulk(w, old.value)
+	case get, nx && exists:
+		writeNil(w)
+	default:
+		writeSimple(w, "OK")
+	}
+}
+
+func (s *Server) scan(w *bufio.Writer, args []string, now time.Time) {
+	if len(args) < 1 {
+		writeError(w, "ERR wrong number of arguments for 'scan' command")
+		return
+	}
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil {
+		writeError(w, "ERR invalid cursor")
+		return
+	}
+	pattern, count := "*", 10
+	for i := 1; i+1 < len(args); i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, _ = strconv.Atoi(args[i+1])
+		}
+	}
+
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		if _, ok := s.lookup(key, now); ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	end := cursor + count
+	next := end
+	if end >= len(keys) {
+		end, next = len(keys), 0
+	}
+	var batch []string
+	if cursor < end {
+		for _, key := range keys[cursor:end] {
+			if ok, _ := path.Match(pattern, key); ok {
+				batch = append(batch, key)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "*2\r\n")
+	writeBulk(w, strconv.Itoa(next))
+	fmt.Fprintf(w, "*%d\r\n", len(batch))
+	for _, key := range batch {
+		writeBulk(w, key)
+	}
+}
+
+// readCommand читает команду — массив bulk-строк.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return nil, fmt.Errorf("resptest: expected array, got %q", line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("resptest: bad array length %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("resptest: expected bulk string, got %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("resptest: bad bulk length %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+func writeSimple(w io.Writer, s string) { fmt.Fprintf(w, "+%s\r\n", s) }
+func writeError(w io.Writer, s string)  { fmt.Fprintf(w, "-%s\r\n", s) }
+func writeInt(w io.Writer, n int)       { fmt.Fprintf(w, ":%d\r\n", n) }
+func writeNil(w io.Writer)              { fmt.Fprint(w, "$-1\r\n") }
+func writeBulk(w io.Writer, s string)   { fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s) }
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/tracing"
+)
+
+// searchResults собирает результаты в порядке hits; заказы, удаленные с момента поиска, пропускаются.
+func searchResults(hits []database.SearchHit, found map[string]*database.Order) []database.SearchResult {
+	out := make([]database.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if order, ok := found[hit.OrderUID]; ok {
+			out = append(out, database.SearchResult{Order: order, Rank: hit.Rank, Highlights: hit.Highlights})
+		}
+	}
+	return out
+}
+
+func hitUIDs(hits []database.SearchHit) []string {
+	uids := make([]string, len(hits))
+	for i, hit := range hits {
+		uids[i] = hit.OrderUID
+	}
+	return uids
+}
+
+// Search ищет заказы полнотекстовым поиском в базе данных.
+func (s *DBStore) Search(ctx context.Context, search database.OrderSearch) (_ []database.SearchResult, err error) {
+	ctx, span := tracing.Start(ctx, "store.search")
+	defer func() { tracing.End(span, err) }()
+	hits, err := s.db.SearchOrders(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	found, err := loadOrders(ctx, s.db, hitUIDs(hits))
+	if err != nil {
+		return nil, err
+	}
+	return searchResults(hits, found), nil
+}
+
+// Search ищет заказы полнотекстовым поиском в БД; результаты поиска не кэшируются,
+// сами заказы берутся из кэша, отсутствующие загружаются одним запросом.
+func (s *DBWithCacheStore) Search(ctx context.Context, search database.OrderSearch) (_ []database.SearchResult, err error) {
+	ctx, span := tracing.Start(ctx, "store.search")
+	defer func() { tracing.End(span, err) }()
+	hits, err := s.db.SearchOrders(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	found, err := s.getMany(ctx, hitUIDs(hits))
+	if err != nil {
+		return nil, err
+	}
+	return searchResults(hits, found), nil
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mitrich772/go-order-service/internal/database"
+	mockdb "github.com/mitrich772/go-order-service/internal/database/mocks"
+)
+
+// Проверяет: Search сохраняет порядок выдачи БД, берет заказы из кэша
+// и пропускает удаленные с момента поиска
+func TestDBWithCacheStore_Search(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	store := NewDBWithCacheStore(mockDB, 100)
+	cached := &database.Order{OrderUID: "a"}
+	store.cache.Set(cached)
+
+	search := database.OrderSearch{Items: "nike", Limit: 10}
+	mockDB.EXPECT().SearchOrders(gomock.Any(), search).Return([]database.SearchHit{
+		{OrderUID: "b", Rank: 0.9},
+		{OrderUID: "gone", Rank: 0.5},
+		{OrderUID: "a", Rank: 0.1, Highlights: database.SearchHighlights{City: "<mark>Казань</mark>"}},
+	}, nil)
+	mockDB.EXPECT().ListOrders(gomock.Any(), database.OrderFilter{UIDs: []string{"b", "gone"}}).
+		Return([]database.Order{{OrderUID: "b"}}, nil)
+
+	results, err := store.Search(context.Background(), search)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if len(results) != 2 || results[0].Order.OrderUID != "b" || results[1].Order != cached || results[1].Highlights.City == "" {
+		t.Fatalf("неожиданный результат %+v", results)
+	}
+	if _, ok := store.cache.Get("b"); !ok {
+		t.Fatal("загруженный заказ должен попасть в кэш")
+	}
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"github.com/mitrich772/go-order-service/internal/pii"
+)
+
+// Формат снимка кэша (big-endian):
+//
+//	magic   [4]byte  "OCSN"
+//	version uint16
+//	created int64    unix nano
+//	count   uint32
+//	payload          gob-поток из count заказов, от LRU к MRU; персональные данные
+//	                 доставки и платежа зашифрованы, если задан шифратор
+//	crc32   uint32   IEEE по всем предыдущим байтам
+const (
+	snapshotMagic      = "OCSN"
+	snapshotVersion    = 1
+	snapshotHeaderSize = 4 + 2 + 8 + 4
+	snapshotTrailer    = 4
+)
+
+var (
+	// ErrSnapshotCorrupt возвращается, если снимок поврежден или имеет неизвестный формат.
+	ErrSnapshotCorrupt = errors.New("cache snapshot is corrupt")
+	// ErrSnapshotStale возвращается, если снимок старше допустимого возраста.
+	ErrSnapshotStale = errors.New("cache snapshot is stale")
+	// ErrSnapshotEmpty возвращается, если в снимке нет заказов: восстанавливать из него нечего.
+	ErrSnapshotEmpty = errors.New("cache snapshot is empty")
+)
+
+// Snapshot — содержимое снимка кэша.
+type Snapshot struct {
+	Created time.Time
+	Orders  []*database.Order
+}
+
+// WriteSnapshot записывает заказы в w в формате снимка, шифруя персональные данные
+// cipher (nil — без шифрования).
+func WriteSnapshot(w io.Writer, orders []*database.Order, created time.Time, cipher *pii.Cipher) error {
+	var buf bytes.Buffer
+	buf.WriteString(snapshotMagic)
+	_ = binary.Write(&buf, binary.BigEndian, uint16(snapshotVersion))
+	_ = binary.Write(&buf, binary.BigEndian, created.UnixNano())
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(orders)))
+
+	enc := gob.NewEncoder(&buf)
+	for _, order := range orders {
+		stored, err := database.EncryptOrder(cipher, order)
+		if err != nil {
+			return fmt.Errorf("encrypt order %s: %w", order.OrderUID, err)
+		}
+		if err := enc.Encode(stored); err != nil {
+			return fmt.Errorf("encode order %s: %w", order.OrderUID, err)
+		}
+	}
+	_ = binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(buf.Bytes()))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ReadSnapshot читает снимок из r, проверяет контрольную сумму и расшифровывает
+// персональные данные cipher.
+func ReadSnapshot(r io.Reader, cipher *pii.Cipher) (*Snapshot, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < snapshotHeaderSize+snapshotTrailer || string(data[:4]) != snapshotMagic {
+		return nil, ErrSnapshotCorrupt
+	}
+
+	body, sum := data[:len(data)-snapshotTrailer], data[len(data)-snapshotTrailer:]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(sum) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+	if v := binary.BigEndian.Uint16(data[4:6]); v != snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrSnapshotCorrupt, v)
+	}
+	created := time.Unix(0, int64(binary.BigEndian.Uint64(data[6:14])))
+	count := binary.BigEndian.Uint32(data[14:18])
+
+	dec := gob.NewDecoder(bytes.NewReader(body[snapshotHeaderSize:]))
+	orders := make([]*database.Order, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var order database.Order
+		if err := dec.Decode(&order); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+		}
+		if err := database.DecryptOrder(cipher, &order); err != nil {
+			return nil, fmt.Errorf("decrypt order %s: %w", order.OrderUID, err)
+		}
+		orders = append(orders, &order)
+	}
+
+	return &Snapshot{Created: created, Orders: orders}, nil
+}
+
+// SaveSnapshot атомарно записывает снимок заказов в файл path.
+func SaveSnapshot(path string, orders []*database.Order, cipher *pii.Cipher) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cache-snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := WriteSnapshot(tmp, orders, time.Now(), cipher); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot читает снимок из файла path.
+// Если maxAge > 0 и снимок старше maxAge, возвращается ErrSnapshotStale,
+// если в снимке нет заказов — ErrSnapshotEmpty.
+func LoadSnapshot(path string, maxAge time.Duration, cipher *pii.Cipher) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snap, err := ReadSnapshot(f, cipher)
+	if err != nil {
+		return nil, err
+	}
+	if age := time.Since(snap.Created); maxAge > 0 && age > maxAge {
+		return nil, fmt.Errorf("%w: age %v exceeds %v", ErrSnapshotStale, age.Round(time.Second), maxAge)
+	}
+	if len(snap.Orders) == 0 {
+		return nil, ErrSnapshotEmpty
+	}
+	return snap, nil
+}
+
+// RunSnapshots периодически сохраняет содержимое entries() в файл path
+// и делает последний снимок после отмены ctx. Пустой кэш не сохраняется,
+// чтобы не затереть предыдущий снимок.
+func RunSnapshots(ctx context.Context, path string, interval time.Duration, entries func() []*database.Order, cipher *pii.Cipher) {
+	save := func() {
+		orders := entries()
+		if len(orders) == 0 {
+			slog.DebugContext(ctx, "Снимок кэша пропущен: кэш пуст", slog.String("path", path))
+			return
+		}
+		if err := SaveSnapshot(path, orders, cipher); err != nil {
+			slog.ErrorContext(ctx, "Ошибка сохранения снимка кэша", slog.String("path", path), logging.Err(err))
+			return
+		}
+		slog.DebugContext(ctx, "Снимок кэша сохранен", slog.String("path", path), slog.Int("orders", len(orders)))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			save()
+			return
+		case <-ticker.C:
+			save()
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+// Снимок сохраняет заказы и порядок вытеснения
+func TestSnapshot_RoundTripKeepsRecency(t *testing.T) {
+	src := NewOrderCache(3)
+	for _, uid := range []string{"a", "b", "c"} {
+		src.Set(&database.Order{
+			OrderUID: uid,
+			Items:    []database.Item{{Name: "item-" + uid, Price: 10}},
+		})
+	}
+	src.Get("a") // "a" становится MRU, "b" — LRU
+
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	if err := SaveSnapshot(path, src.Entries(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := LoadSnapshot(path, time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Orders) != 3 || snap.Orders[0].OrderUID != "b" || snap.Orders[2].OrderUID != "a" {
+		t.Fatalf("неверный порядок заказов в снимке: %v", snap.Orders)
+	}
+	if snap.Orders[2].Items[0].Name != "item-a" {
+		t.Fatalf("вложенные данные не восстановлены: %+v", snap.Orders[2])
+	}
+
+	dst := NewOrderCache(3)
+	for _, order := range snap.Orders {
+		dst.Set(order)
+	}
+	dst.Set(&database.Order{OrderUID: "d"})
+	if _, ok := dst.Peek("b"); ok {
+		t.Error("после восстановления первым должен вытесняться 'b'")
+	}
+}
+
+// Поврежденный снимок отклоняется по контрольной сумме
+func TestSnapshot_Corrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, []*database.Order{{OrderUID: "a"}}, time.Now(), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	data[len(data)/2] ^= 0xff
+
+	if _, err := ReadSnapshot(bytes.NewReader(data), nil); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Fatalf("ожидалась ErrSnapshotCorrupt, получено %v", err)
+	}
+	if _, err := ReadSnapshot(bytes.NewReader([]byte("garbage")), nil); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Fatalf("ожидалась ErrSnapshotCorrupt для мусора, получено %v", err)
+	}
+}
+
+// Устаревший снимок не загружается
+func TestSnapshot_Stale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSnapshot(f, []*database.Order{{OrderUID: "a"}}, time.Now().Add(-2*time.Hour), nil); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if _, err := LoadSnapshot(path, time.Hour, nil); !errors.Is(err, ErrSnapshotStale) {
+		t.Fatalf("ожидалась ErrSnapshotStale, получено %v", err)
+	}
+	if _, err := LoadSnapshot(path, 0, nil); err != nil {
+		t.Fatalf("без ограничения возраста снимок должен загружаться: %v", err)
+	}
+}
+
+// Пустой кэш не затирает снимок, а пустой снимок не считается восстановлением
+func TestSnapshot_Empty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	if err := SaveSnapshot(path, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnapshot(path, time.Hour, nil); !errors.Is(err, ErrSnapshotEmpty) {
+		t.Fatalf("ожидалась ErrSnapshotEmpty, получено %v", err)
+	}
+
+	if err := SaveSnapshot(path, []*database.Order{{OrderUID: "a"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	RunSnapshots(ctx, path, time.Hour, func() []*database.Order { return nil }, nil)
+	snap, err := LoadSnapshot(path, time.Hour, nil)
+	if err != nil || len(snap.Orders) != 1 {
+		t.Fatalf("пустой кэш затер снимок: %v, %v", snap, err)
+	}
+}
+
+// С шифрованием персональные данные в файле снимка зашифрованы и читаются тем же ключом
+func TestSnapshot_EncryptsPII(t *testing.T) {
+	c := testCipher(t)
+	order := &database.Order{
+		OrderUID: "a",
+		Delivery: database.Delivery{Name: "Ivan", Phone: "+79161231234", Email: "ivan@mail.ru", Address: "Lenina 1"},
+		Payment:  database.Payment{Transaction: "a", Bank: "alpha"},
+	}
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	if err := SaveSnapshot(path, []*database.Order{order}, c); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"Ivan", "+79161231234", "ivan@mail.ru", "Lenina 1", "alpha"} {
+		if bytes.Contains(data, []byte(v)) {
+			t.Fatalf("%q записано в снимок открытым", v)
+		}
+	}
+
+	if _, err := LoadSnapshot(path, time.Hour, nil); err == nil {
+		t.Fatal("без ключа зашифрованный снимок не должен читаться")
+	}
+	snap, err := LoadSnapshot(path, time.Hour, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := snap.Orders[0]; got.Delivery != order.Delivery || got.Payment != order.Payment {
+		t.Fatalf("снимок расшифрован неверно: %+v", got)
+	}
+	if order.Delivery.Phone != "+79161231234" {
+		t.Fatal("исходный заказ не должен меняться")
+	}
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats содержит счетчики работы кеша.
+type Stats struct {
+	Hits      uint64  `json:"hits"`
+	Misses    uint64  `json:"misses"`
+	Evictions uint64  `json:"evictions"`
+	Sets      uint64  `json:"sets"`
+	Size      int     `json:"size"`
+	Capacity  int     `json:"capacity"`
+	HitRatio  float64 `json:"hit_ratio"`
+	Warmed    uint64  `json:"warmed"`
+	// Errors — ошибки обращения к внешнему кэшу (Redis).
+	Errors uint64 `json:"errors,omitempty"`
+	// SizeApprox — Size приблизительный (Redis: все ключи базы, а не только ключи кэша).
+	SizeApprox bool `json:"size_approx,omitempty"`
+	// Shared — статистика общего уровня двухуровневого кэша.
+	Shared *Stats `json:"shared,omitempty"`
+
+	// Загрузки из БД при промахах.
+	Loads         uint64 `json:"loads"`
+	LoadErrors    uint64 `json:"load_errors"`
+	LoadTimeTotal int64  `json:"load_time_total_ms"`
+	LoadTimeMax   int64  `json:"load_time_max_ms"`
+}
+
+// counters — потокобезопасные счетчики обращений к кешу.
+type counters struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+	sets      atomic.Uint64
+	warmed    atomic.Uint64
+}
+
+// fill переносит значения счетчиков в st и считает hit ratio.
+func (c *counters) fill(st *Stats) {
+	st.Hits = c.hits.Load()
+	st.Misses = c.misses.Load()
+	st.Evictions = c.evictions.Load()
+	st.Sets = c.sets.Load()
+	st.Warmed = c.warmed.Load()
+	if total := st.Hits + st.Misses; total > 0 {
+		st.HitRatio = float64(st.Hits) / float64(total)
+	}
+}
+
+// loadCounters — счетчики загрузок заказов из БД.
+type loadCounters struct {
+	loads  atomic.Uint64
+	errors atomic.Uint64
+	total  atomic.Int64
+	max    atomic.Int64
+}
+
+// observe учитывает одну загрузку длительностью d.
+func (c *loadCounters) observe(d time.Duration, err error) {
+	c.loads.Add(1)
+	if err != nil {
+		c.errors.Add(1)
+	}
+	c.total.Add(d.Milliseconds())
+	for {
+		cur := c.max.Load()
+		if d.Milliseconds() <= cur || c.max.CompareAndSwap(cur, d.Milliseconds()) {
+			return
+		}
+	}
+}
+
+// fill переносит значения счетчиков загрузок в st.
+func (c *loadCounters) fill(st *Stats) {
+	st.Loads = c.loads.Load()
+	st.LoadErrors = c.errors.Load()
+	st.LoadTimeTotal = c.total.Load()
+	st.LoadTimeMax = c.max.Load()
+}
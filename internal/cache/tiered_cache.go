@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+// TieredCache — двухуровневый кэш: локальный LRU перед общим кэшем (Redis).
+// Запись идет в оба уровня, чтение сначала из локального.
+type TieredCache struct {
+	local  *OrderCache
+	shared Cache
+}
+
+// NewTieredCache создает двухуровневый кэш.
+func NewTieredCache(local *OrderCache, shared Cache) *TieredCache {
+	return &TieredCache{local: local, shared: shared}
+}
+
+// Get ищет заказ в локальном кэше, затем в общем. Попадание в общий кэш
+// поднимает заказ в локальный.
+func (c *TieredCache) Get(uid string) (*database.Order, bool) {
+	if order, ok := c.local.Get(uid); ok {
+		return order, true
+	}
+	order, ok := c.shared.Get(uid)
+	if !ok {
+		return nil, false
+	}
+	c.local.Set(order)
+	return order, true
+}
+
+// Set записывает заказ в оба уровня.
+func (c *TieredCache) Set(order *database.Order) (exist bool) {
+	localExist := c.local.Set(order)
+	sharedExist := c.shared.Set(order)
+	return localExist || sharedExist
+}
+
+// Peek ищет заказ в обоих уровнях без учета в счетчиках.
+func (c *TieredCache) Peek(uid string) (*database.Order, bool) {
+	if order, ok := c.local.Peek(uid); ok {
+		return order, true
+	}
+	return c.shared.Peek(uid)
+}
+
+// Delete удаляет заказ из обоих уровней.
+func (c *TieredCache) Delete(uid string) bool {
+	localOK := c.local.Delete(uid)
+	sharedOK := c.shared.Delete(uid)
+	return localOK || sharedOK
+}
+
+// Local возвращает локальный уровень. Инвалидация между экземплярами меняет только его:
+// общий уровень уже содержит запись экземпляра, который сохранил заказ.
+func (c *TieredCache) Local() *OrderCache {
+	return c.local
+}
+
+// Purge очищает оба уровня.
+func (c *TieredCache) Purge() {
+	c.local.Purge()
+	c.shared.Purge()
+}
+
+// Len возвращает размер общего уровня, он содержит все заказы локального.
+func (c *TieredCache) Len() int {
+	return c.shared.Len()
+}
+
+// Keys возвращает uid из общего уровня.
+func (c *TieredCache) Keys() []string {
+	return c.shared.Keys()
+}
+
+// Entries возвращает содержимое локального уровня для снимков.
+func (c *TieredCache) Entries() []*database.Order {
+	return c.local.Entries()
+}
+
+// Stats возвращает статистику локального уровня и, в поле Shared, общего.
+func (c *TieredCache) Stats() Stats {
+	st := c.local.Stats()
+	if s, ok := c.shared.(statser); ok {
+		shared := s.Stats()
+		st.Shared = &shared
+	}
+	return st
+}
+
+// Warm прогревает оба уровня последними заказами из БД.
+func (c *TieredCache) Warm(ctx context.Context, db database.Database) (int, error) {
+	orders, err := db.GetLastNOrders(ctx, c.local.storage.Cap())
+	if err != nil {
+		return 0, err
+	}
+	for i := len(orders) - 1; i >= 0; i-- {
+		c.Set(&orders[i])
+	}
+	c.addWarmed(len(orders))
+	return len(orders), nil
+}
+
+func (c *TieredCache) addWarmed(n int) {
+	c.local.addWarmed(n)
+}
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// WarmupState — состояние фонового прогрева кэша.
+type WarmupState string
+
+const (
+	WarmupSkipped WarmupState = "skipped"
+	WarmupPending WarmupState = "pending"
+	WarmupRunning WarmupState = "running"
+	WarmupDone    WarmupState = "done"
+	WarmupFailed  WarmupState = "failed"
+)
+
+// WarmupStatus описывает прогресс прогрева.
+type WarmupStatus struct {
+	State      WarmupState `json:"state"`
+	Strategy   string      `json:"strategy"`
+	Loaded     int         `json:"loaded"`
+	Total      int         `json:"total"`
+	Error      string      `json:"error,omitempty"`
+	StartedAt  time.Time   `json:"started_at,omitzero"`
+	FinishedAt time.Time   `json:"finished_at,omitzero"`
+}
+
+// Finished сообщает, завершился ли прогрев (успешно или нет).
+// Пропущенный прогрев считается завершенным.
+func (s WarmupStatus) Finished() bool {
+	return s.State == WarmupDone || s.State == WarmupFailed || s.State == WarmupSkipped
+}
+
+// WarmupSink принимает заказы, загруженные стратегией прогрева.
+type WarmupSink interface {
+	// Expect сообщает, сколько заказов стратегия собирается загрузить.
+	Expect(total int)
+	// Add кладет загруженный заказ в кэш.
+	Add(order *database.Order)
+}
+
+// WarmupStrategy выбирает, какими заказами прогревать кэш.
+type WarmupStrategy interface {
+	Name() string
+	// Load загружает не более n заказов и передает их в sink.
+	Load(ctx context.Context, db database.Database, n int, sink WarmupSink) error
+}
+
+// Warmup прогревает кэш в фоне, не блокируя запуск сервиса.
+// Ошибка прогрева не останавливает сервис: кэш остается холодным.
+type Warmup struct {
+	cache    Cache
+	db       database.Database
+	strategy WarmupStrategy
+	limit    int
+
+	mu     sync.RWMutex
+	status WarmupStatus
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewWarmup создает прогрев кэша c выбранной стратегией и лимитом заказов.
+func NewWarmup(c Cache, db database.Database, strategy WarmupStrategy, limit int) *Warmup {
+	return &Warmup{
+		cache:    c,
+		db:       db,
+		strategy: strategy,
+		limit:    limit,
+		status:   WarmupStatus{State: WarmupPending, Strategy: strategy.Name()},
+		done:     make(chan struct{}),
+	}
+}
+
+// Start запускает прогрев в отдельной горутине. Повторные вызовы игнорируются.
+func (w *Warmup) Start(ctx context.Context) {
+	w.once.Do(func() {
+		go w.run(ctx)
+	})
+}
+
+// Status возвращает текущий прогресс прогрева.
+func (w *Warmup) Status() WarmupStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.status
+}
+
+// Done закрывается после завершения прогрева.
+func (w *Warmup) Done() <-chan struct{} {
+	return w.done
+}
+
+// Ready сообщает, можно ли считать сервис готовым с точки зрения кэша.
+func (w *Warmup) Ready() bool {
+	return w.Status().Finished()
+}
+
+// Wait ждет завершения прогрева или отмены ctx.
+func (w *Warmup) Wait(ctx context.Context) error {
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Warmup) run(ctx context.Context) {
+	defer close(w.done)
+
+	w.mu.Lock()
+	w.status.State = WarmupRunning
+	w.status.StartedAt = time.Now()
+	w.mu.Unlock()
+	slog.InfoContext(ctx, "Прогрев кэша запущен", slog.String("strategy", w.strategy.Name()), slog.Int("limit", w.limit))
+
+	err := w.load(ctx)
+
+	w.mu.Lock()
+	w.status.FinishedAt = time.Now()
+	if err != nil {
+		w.status.State = WarmupFailed
+		w.status.Error = err.Error()
+	} else {
+		w.status.State = WarmupDone
+	}
+	st := w.status
+	w.mu.Unlock()
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Прогрев кэша не удался, работаем с холодным кэшем", logging.Err(err))
+		return
+	}
+	slog.InfoContext(ctx, "Кэш прогрет",
+		slog.Int("loaded", st.Loaded),
+		slog.Int("total", st.Total),
+		slog.Duration("duration", st.FinishedAt.Sub(st.StartedAt)),
+	)
+}
+
+// load вызывает стратегию, превращая панику в ошибку.
+func (w *Warmup) load(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("warm-up panic: %v", r)
+		}
+	}()
+	return w.strategy.Load(ctx, w.db, w.limit, warmupSink{w})
+}
+
+// warmupSink передает заказы стратегии в кэш и обновляет прогресс.
+type warmupSink struct {
+	w *Warmup
+}
+
+func (s warmupSink) Expect(total int) {
+	s.w.mu.Lock()
+	s.w.status.Total = total
+	s.w.mu.Unlock()
+}
+
+func (s warmupSink) Add(order *database.Order) {
+	s.w.cache.Set(order)
+	if c, ok := s.w.cache.(interface{ addWarmed(n int) }); ok {
+		c.addWarmed(1)
+	}
+	s.w.mu.Lock()
+	s.w.status.Loaded++
+	s.w.mu.Unlock()
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"gorm.io/gorm"
+)
+
+// NewestStrategy прогревает кэш самыми свежими заказами по date_created.
+type NewestStrategy struct{}
+
+// Name возвращает имя стратегии.
+func (NewestStrategy) Name() string { return "newest" }
+
+// Load загружает n последних заказов одним запросом.
+func (NewestStrategy) Load(ctx context.Context, db database.Database, n int, sink WarmupSink) error {
+	orders, err := db.GetLastNOrders(ctx, n)
+	if err != nil {
+		return err
+	}
+	sink.Expect(len(orders))
+	// Заказы приходят от новых к старым: кладем с конца, чтобы самый новый стал MRU.
+	for i := len(orders) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		sink.Add(&orders[i])
+	}
+	return nil
+}
+
+// FrequentStrategy прогревает кэш самыми часто запрашиваемыми заказами
+// по сохраненному журналу обращений.
+type FrequentStrategy struct {
+	Log *AccessLog
+}
+
+// Name возвращает имя стратегии.
+func (FrequentStrategy) Name() string { return "frequent" }
+
+// Load загружает n самых популярных заказов из журнала обращений.
+func (s FrequentStrategy) Load(ctx context.Context, db database.Database, n int, sink WarmupSink) error {
+	if s.Log == nil {
+		return errors.New("access log not configured")
+	}
+	return loadKeys(ctx, db, s.Log.Top(n), sink)
+}
+
+// KeyListStrategy прогревает кэш заказами из файла со списком uid (по одному в строке).
+type KeyListStrategy struct {
+	Path string
+}
+
+// Name возвращает имя стратегии.
+func (KeyListStrategy) Name() string { return "keys" }
+
+// Load читает uid из файла и загружает не более n заказов.
+func (s KeyListStrategy) Load(ctx context.Context, db database.Database, n int, sink WarmupSink) error {
+	keys, err := readKeyList(s.Path, n)
+	if err != nil {
+		return err
+	}
+	return loadKeys(ctx, db, keys, sink)
+}
+
+// NewWarmupStrategy возвращает стратегию по имени: newest, frequent или keys.
+func NewWarmupStrategy(name string, accessLog *AccessLog, keysFile string) (WarmupStrategy, error) {
+	switch name {
+	case "", "newest":
+		return NewestStrategy{}, nil
+	case "frequent":
+		return FrequentStrategy{Log: accessLog}, nil
+	case "keys":
+		if keysFile == "" {
+			return nil, errors.New("keys strategy requires a key list file")
+		}
+		return KeyListStrategy{Path: keysFile}, nil
+	default:
+		return nil, fmt.Errorf("unknown warm-up strategy %q", name)
+	}
+}
+
+// loadKeys загружает заказы по списку uid, пропуская отсутствующие в БД.
+// Ключи идут от самых важных к менее важным, поэтому загружаются с конца,
+// чтобы самые важные стали MRU.
+func loadKeys(ctx context.Context, db database.Database, keys []string, sink WarmupSink) error {
+	sink.Expect(len(keys))
+	for i := len(keys) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		order, err := db.GetOrder(ctx, keys[i])
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.WarnContext(ctx, "Прогрев: заказ не найден, пропускаем", slog.String(logging.KeyOrderUID, keys[i]))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		sink.Add(order)
+	}
+	return nil
+}
+
+// readKeyList читает не более n uid из файла, пропуская пустые строки и комментарии.
+func readKeyList(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() && len(keys) < n {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	return keys, sc.Err()
+}
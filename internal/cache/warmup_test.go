@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mitrich772/go-order-service/internal/database"
+	mockdb "github.com/mitrich772/go-order-service/internal/database/mocks"
+	"gorm.io/gorm"
+)
+
+func waitWarmup(t *testing.T, w *Warmup) WarmupStatus {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := w.Wait(ctx); err != nil {
+		t.Fatalf("прогрев не завершился: %v", err)
+	}
+	return w.Status()
+}
+
+// Фоновый прогрев стратегией newest кладет заказы в кэш, самый новый становится MRU
+func TestWarmup_Newest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	mockDB.EXPECT().GetLastNOrders(gomock.Any(), 2).Return([]database.Order{
+		{OrderUID: "new"},
+		{OrderUID: "old"},
+	}, nil)
+
+	store := NewDBWithCacheStore(mockDB, 2)
+	w := store.StartWarmup(context.Background(), NewestStrategy{}, 2)
+
+	st := waitWarmup(t, w)
+	if st.State != WarmupDone || st.Loaded != 2 || st.Total != 2 {
+		t.Fatalf("неожиданный статус прогрева: %+v", st)
+	}
+	if !w.Ready() {
+		t.Fatal("ожидалась готовность после прогрева")
+	}
+
+	// "old" — наименее недавно использованный и вытесняется первым
+	store.cache.Set(&database.Order{OrderUID: "third"})
+	if _, ok := store.Inspect("old"); ok {
+		t.Error("ожидалось вытеснение самого старого заказа")
+	}
+	if _, ok := store.Inspect("new"); !ok {
+		t.Error("самый новый заказ должен остаться в кэше")
+	}
+}
+
+// Ошибка прогрева не паникует: кэш остается холодным, статус failed
+func TestWarmup_FailureLeavesColdCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	mockDB.EXPECT().GetLastNOrders(gomock.Any(), 10).Return(nil, errors.New("db down"))
+
+	store := NewDBWithCacheStore(mockDB, 10)
+	w := store.StartWarmup(context.Background(), NewestStrategy{}, 10)
+
+	st := waitWarmup(t, w)
+	if st.State != WarmupFailed || st.Error == "" {
+		t.Fatalf("ожидался статус failed с ошибкой, получено %+v", st)
+	}
+	if !w.Ready() {
+		t.Fatal("после неудачного прогрева сервис должен быть готов с холодным кэшем")
+	}
+	if store.Stats().Size != 0 {
+		t.Fatal("ожидался пустой кэш")
+	}
+}
+
+// Стратегия keys читает файл, пропускает комментарии и отсутствующие заказы
+func TestWarmup_KeyList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	path := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(path, []byte("# hot keys\na\n\nmissing\nb\nc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	mockDB.EXPECT().GetOrder(gomock.Any(), "a").Return(&database.Order{OrderUID: "a"}, nil)
+	mockDB.EXPECT().GetOrder(gomock.Any(), "missing").Return(nil, gorm.ErrRecordNotFound)
+	mockDB.EXPECT().GetOrder(gomock.Any(), "b").Return(&database.Order{OrderUID: "b"}, nil)
+
+	store := NewDBWithCacheStore(mockDB, 10)
+	w := store.StartWarmup(context.Background(), KeyListStrategy{Path: path}, 3)
+
+	st := waitWarmup(t, w)
+	if st.State != WarmupDone || st.Loaded != 2 || st.Total != 3 {
+		t.Fatalf("неожиданный статус прогрева: %+v", st)
+	}
+	if store.Stats().Warmed != 2 {
+		t.Errorf("ожидалось warmed=2, получено %d", store.Stats().Warmed)
+	}
+}
+
+// Журнал обращений ограничен: при переполнении отбрасываются редкие uid,
+// а в файл попадает не больше limit записей
+func TestAccessLog_Bounded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.json")
+	l, err := NewAccessLog(path, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		l.Record("hot")
+	}
+	for i := range 100 {
+		l.Record(fmt.Sprintf("cold-%d", i))
+	}
+	if n := len(l.Top(100)); n > 4 {
+		t.Fatalf("журнал хранит %d uid при лимите 4", n)
+	}
+	if top := l.Top(1); top[0] != "hot" {
+		t.Fatalf("частый uid вытеснен: %v", top)
+	}
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewAccessLog(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top := restored.Top(100); len(top) != 2 || top[0] != "hot" {
+		t.Fatalf("ожидалось 2 uid с hot первым, получено %v", top)
+	}
+}
+
+// Журнал обращений сохраняется в файл и используется стратегией frequent
+func TestWarmup_FrequentFromAccessLog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	path := filepath.Join(t.TempDir(), "access.json")
+	l, err := NewAccessLog(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, uid := range []string{"b", "a", "b", "c", "b", "a"} {
+		l.Record(uid)
+	}
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewAccessLog(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top := restored.Top(2); len(top) != 2 || top[0] != "b" || top[1] != "a" {
+		t.Fatalf("ожидалось [b a], получено %v", top)
+	}
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	mockDB.EXPECT().GetOrder(gomock.Any(), "a").Return(&database.Order{OrderUID: "a"}, nil)
+	mockDB.EXPECT().GetOrder(gomock.Any(), "b").Return(&database.Order{OrderUID: "b"}, nil)
+
+	store := NewDBWithCacheStore(mockDB, 10)
+	st := waitWarmup(t, store.StartWarmup(context.Background(), FrequentStrategy{Log: restored}, 2))
+	if st.State != WarmupDone || st.Loaded != 2 {
+		t.Fatalf("неожиданный статус прогрева: %+v", st)
+	}
+}
+
+func TestNewWarmupStrategy(t *testing.T) {
+	if _, err := NewWarmupStrategy("keys", nil, ""); err == nil {
+		t.Error("ожидалась ошибка для keys без файла")
+	}
+	if _, err := NewWarmupStrategy("bogus", nil, ""); err == nil {
+		t.Error("ожидалась ошибка для неизвестной стратегии")
+	}
+	if s, err := NewWarmupStrategy("", nil, ""); err != nil || s.Name() != "newest" {
+		t.Errorf("по умолчанию ожидалась стратегия newest, получено %v %v", s, err)
+	}
+}
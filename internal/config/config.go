@@ -0,0 +1,266 @@
+// Package config собирает настройки сервиса и CLI миграций в одну типизированную структуру.
+// Источники в порядке возрастания приоритета: значения по умолчанию, файл YAML/TOML,
+// переменные окружения, флаги командной строки.
+package config
+
+import (
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+// Config — полная конфигурация сервиса.
+// Тег env задает переменную окружения (из нее же выводится имя флага: DB_HOST → -db-host),
+// secret:"true" — значение скрывается при выводе конфигурации.
+type Config struct {
+	DB              DB            `yaml:"db" toml:"db"`
+	Kafka           Kafka         `yaml:"kafka" toml:"kafka"`
+	Cache           Cache         `yaml:"cache" toml:"cache"`
+	Redis           Redis         `yaml:"redis" toml:"redis"`
+	HTTP            HTTP          `yaml:"http" toml:"http"`
+	GRPC            GRPC          `yaml:"grpc" toml:"grpc"`
+	Feed            Feed          `yaml:"feed" toml:"feed"`
+	RateLimit       RateLimit     `yaml:"rate_limit" toml:"rate_limit"`
+	Ingest          Ingest        `yaml:"ingest" toml:"ingest"`
+	Auth            Auth          `yaml:"auth" toml:"auth"`
+	PII             PII           `yaml:"pii" toml:"pii"`
+	Log             Log           `yaml:"log" toml:"log"`
+	Trace           Trace         `yaml:"trace" toml:"trace"`
+	InstanceID      string        `yaml:"instance_id" toml:"instance_id" env:"INSTANCE_ID"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+}
+
+// DB — подключение к PostgreSQL и повторы запросов.
+type DB struct {
+	User             string        `yaml:"user" toml:"user" env:"DB_USER"`
+	Password         string        `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name             string        `yaml:"name" toml:"name" env:"DB_NAME"`
+	Host             string        `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port             string        `yaml:"port" toml:"port" env:"DB_PORT"`
+	SSLMode          string        `yaml:"sslmode" toml:"sslmode" env:"DB_SSLMODE"`
+	Retries          int           `yaml:"retries" toml:"retries" env:"DB_RETRIES"`
+	RetryDelay       time.Duration `yaml:"retry_delay" toml:"retry_delay" env:"DB_RETRY_DELAY"`
+	BreakerThreshold int           `yaml:"breaker_threshold" toml:"breaker_threshold" env:"DB_BREAKER_THRESHOLD"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown" toml:"breaker_cooldown" env:"DB_BREAKER_COOLDOWN"`
+}
+
+// Kafka — consumer заказов и DLQ.
+type Kafka struct {
+	Brokers        []string      `yaml:"brokers" toml:"brokers" env:"KAFKA_BROKERS"`
+	Topic          string        `yaml:"topic" toml:"topic" env:"KAFKA_TOPIC"`
+	Group          string        `yaml:"group" toml:"group" env:"KAFKA_GROUP"`
+	DLQTopic       string        `yaml:"dlq_topic" toml:"dlq_topic" env:"KAFKA_DLQ_TOPIC"`
+	MaxLag         int64         `yaml:"max_lag" toml:"max_lag" env:"KAFKA_MAX_LAG"`
+	MinBytes       int           `yaml:"min_bytes" toml:"min_bytes" env:"KAFKA_MIN_BYTES"`
+	MaxBytes       int           `yaml:"max_bytes" toml:"max_bytes" env:"KAFKA_MAX_BYTES"`
+	CommitInterval time.Duration `yaml:"commit_interval" toml:"commit_interval" env:"KAFKA_COMMIT_INTERVAL"`
+	RestartDelay   time.Duration `yaml:"restart_delay" toml:"restart_delay" env:"KAFKA_RESTART_DELAY"`
+}
+
+// Cache — кэш заказов, прогрев, снимки и межэкземплярная инвалидация.
+type Cache struct {
+	Enabled             bool          `yaml:"enabled" toml:"enabled" env:"ENABLE_CACHE"`
+	Backend             string        `yaml:"backend" toml:"backend" env:"CACHE_BACKEND"`
+	Size                int           `yaml:"size" toml:"size" env:"CACHE_SIZE"`
+	TTL                 time.Duration `yaml:"ttl" toml:"ttl" env:"CACHE_TTL"`
+	WarmupStrategy      string        `yaml:"warmup_strategy" toml:"warmup_strategy" env:"CACHE_WARMUP_STRATEGY"`
+	WarmupKeysFile      string        `yaml:"warmup_keys_file" toml:"warmup_keys_file" env:"CACHE_WARMUP_KEYS_FILE"`
+	WarmupAfterSnapshot bool          `yaml:"warmup_after_snapshot" toml:"warmup_after_snapshot" env:"CACHE_WARMUP_AFTER_SNAPSHOT"`
+	AccessLog           string        `yaml:"access_log" toml:"access_log" env:"CACHE_ACCESS_LOG"`
+	SnapshotFile        string        `yaml:"snapshot_file" toml:"snapshot_file" env:"CACHE_SNAPSHOT_FILE"`
+	SnapshotInterval    time.Duration `yaml:"snapshot_interval" toml:"snapshot_interval" env:"CACHE_SNAPSHOT_INTERVAL"`
+	SnapshotMaxAge      time.Duration `yaml:"snapshot_max_age" toml:"snapshot_max_age" env:"CACHE_SNAPSHOT_MAX_AGE"`
+	InvalidationTopic   string        `yaml:"invalidation_topic" toml:"invalidation_topic" env:"CACHE_INVALIDATION_TOPIC"`
+	InvalidationMode    string        `yaml:"invalidation_mode" toml:"invalidation_mode" env:"CACHE_INVALIDATION_MODE"`
+	LookupSize          int           `yaml:"lookup_size" toml:"lookup_size" env:"CACHE_LOOKUP_SIZE"`
+	LookupTTL           time.Duration `yaml:"lookup_ttl" toml:"lookup_ttl" env:"CACHE_LOOKUP_TTL"`
+}
+
+// Redis — сервер для CACHE_BACKEND=redis и tiered.
+type Redis struct {
+	Addr     string `yaml:"addr" toml:"addr" env:"REDIS_ADDR"`
+	Password string `yaml:"password" toml:"password" env:"REDIS_PASSWORD" secret:"true"`
+	DB       int    `yaml:"db" toml:"db" env:"REDIS_DB"`
+	Prefix   string `yaml:"prefix" toml:"prefix" env:"REDIS_PREFIX"`
+}
+
+// HTTP — веб-сервер, admin API и аудит.
+type HTTP struct {
+	Port         string `yaml:"port" toml:"port" env:"PORT"`
+	AdminToken   string `yaml:"admin_token" toml:"admin_token" env:"ADMIN_TOKEN" secret:"true"`
+	UnmaskedPII  bool   `yaml:"unmasked_pii" toml:"unmasked_pii" env:"PII_UNMASKED"`
+	AuditLogFile string `yaml:"audit_log_file" toml:"audit_log_file" env:"AUDIT_LOG_FILE"`
+	// CacheControl — Cache-Control ответов GET /order/, пустой не отправляется.
+	CacheControl string `yaml:"cache_control" toml:"cache_control" env:"HTTP_CACHE_CONTROL"`
+	// Compression — кодировки сжатия ответов в порядке предпочтения ("zstd,br,gzip"), "off" выключает.
+	Compression string `yaml:"compression" toml:"compression" env:"HTTP_COMPRESSION"`
+}
+
+// GRPC — gRPC API заказов.
+type GRPC struct {
+	// Port — порт gRPC-сервера, пустой выключает его.
+	Port string `yaml:"port" toml:"port" env:"GRPC_PORT"`
+}
+
+// Feed — лента новых заказов для gRPC WatchOrders, SSE и WebSocket.
+type Feed struct {
+	// Buffer — сколько заказов ждет отправки одному подписчику,
+	// при переполнении подписка завершается.
+	Buffer int `yaml:"buffer" toml:"buffer" env:"FEED_BUFFER"`
+	// History — сколько последних заказов хранится для продолжения с Last-Event-ID,
+	// 0 выключает продолжение.
+	History int `yaml:"history" toml:"history" env:"FEED_HISTORY"`
+}
+
+// RateLimit — лимиты запросов на клиента (IP или subject API-ключа/JWT).
+// Формат лимита: "<n>/<s|m|h>[:burst]", пустое значение выключает лимит.
+type RateLimit struct {
+	// Routes — лимиты групп маршрутов: "order=20/s:40,admin=5/s".
+	Routes string `yaml:"routes" toml:"routes" env:"RATE_LIMIT_ROUTES"`
+	// NotFound — отдельный лимит ответов 404 (перебор несуществующих uid).
+	NotFound string `yaml:"not_found" toml:"not_found" env:"RATE_LIMIT_NOT_FOUND"`
+}
+
+// Режимы приема заказов по HTTP.
+const (
+	IngestOff   = "off"   // POST /orders выключен
+	IngestStore = "store" // заказы пишутся в хранилище сразу
+	IngestKafka = "kafka" // заказы публикуются в топик и сохраняются consumer'ом
+)
+
+// Ingest — прием заказов по HTTP (POST /orders, POST /orders:batch).
+type Ingest struct {
+	Mode string `yaml:"mode" toml:"mode" env:"INGEST_MODE"`
+	// Topic — топик для режима kafka, по умолчанию kafka.topic.
+	Topic string `yaml:"topic" toml:"topic" env:"INGEST_TOPIC"`
+}
+
+// Auth — API-ключи и проверка JWT.
+type Auth struct {
+	APIKeys          string `yaml:"api_keys" toml:"api_keys" env:"AUTH_API_KEYS" secret:"true"`
+	JWTSecret        string `yaml:"jwt_secret" toml:"jwt_secret" env:"AUTH_JWT_SECRET" secret:"true"`
+	JWTPublicKeyFile string `yaml:"jwt_public_key_file" toml:"jwt_public_key_file" env:"AUTH_JWT_PUBLIC_KEY_FILE"`
+	JWTIssuer        string `yaml:"jwt_issuer" toml:"jwt_issuer" env:"AUTH_JWT_ISSUER"`
+	JWTAudience      string `yaml:"jwt_audience" toml:"jwt_audience" env:"AUTH_JWT_AUDIENCE"`
+}
+
+// PII — шифрование персональных данных.
+type PII struct {
+	KeyFile string `yaml:"key_file" toml:"key_file" env:"PII_KEY_FILE"`
+}
+
+// Log — настройки slog.
+type Log struct {
+	Level         string `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+	Format        string `yaml:"format" toml:"format" env:"LOG_FORMAT"`
+	ShowSensitive bool   `yaml:"show_sensitive" toml:"show_sensitive" env:"LOG_SHOW_SENSITIVE"`
+}
+
+// Trace — экспорт трасс OpenTelemetry.
+type Trace struct {
+	Exporter     string  `yaml:"exporter" toml:"exporter" env:"TRACE_EXPORTER"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"TRACE_OTLP_ENDPOINT"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" toml:"otlp_insecure" env:"TRACE_OTLP_INSECURE"`
+	ServiceName  string  `yaml:"service_name" toml:"service_name" env:"TRACE_SERVICE_NAME"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACE_SAMPLE_RATIO"`
+}
+
+// Default возвращает конфигурацию по умолчанию (локальный запуск без Docker).
+func Default() Config {
+	return Config{
+		DB: DB{
+			User:             "serviceuser",
+			Password:         "123",
+			Name:             "order_management",
+			Host:             "localhost",
+			Port:             "5432",
+			SSLMode:          "disable",
+			Retries:          3,
+			RetryDelay:       500 * time.Millisecond,
+			BreakerThreshold: 5,
+			BreakerCooldown:  10 * time.Second,
+		},
+		Kafka: Kafka{
+			Brokers:        []string{"localhost:9092"},
+			Topic:          "orders",
+			Group:          "order-service",
+			DLQTopic:       "orders-dlq",
+			MinBytes:       10e3,
+			MaxBytes:       10e6,
+			CommitInterval: time.Second,
+			RestartDelay:   10 * time.Second,
+		},
+		Cache: Cache{
+			Enabled:          true,
+			Backend:          "local",
+			Size:             50,
+			TTL:              24 * time.Hour,
+			WarmupStrategy:   "newest",
+			SnapshotInterval: time.Minute,
+			SnapshotMaxAge:   time.Hour,
+			InvalidationMode: "evict",
+			LookupSize:       1000,
+			LookupTTL:        time.Minute,
+		},
+		Redis: Redis{
+			Addr:   "localhost:6379",
+			Prefix: "order:",
+		},
+		HTTP: HTTP{
+			Port:         "3000",
+			CacheControl: "private, no-cache",
+			Compression:  "zstd,br,gzip",
+		},
+		GRPC: GRPC{
+			Port: "9090",
+		},
+		Feed: Feed{
+			Buffer:  64,
+			History: 1000,
+		},
+		RateLimit: RateLimit{
+			Routes:   "order=20/s:40,ingest=10/s:20,admin=5/s:10",
+			NotFound: "1/s:10",
+		},
+		Ingest: Ingest{
+			Mode: IngestStore,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "text",
+		},
+		Trace: Trace{
+			Exporter:     "none",
+			OTLPInsecure: true,
+			ServiceName:  "order-service",
+			SampleRatio:  1,
+		},
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+// Connection возвращает параметры подключения для database.ConnectDB.
+func (d DB) Connection() database.Config {
+	return database.Config{
+		User:     d.User,
+		Password: d.Password,
+		DBName:   d.Name,
+		SSLMode:  d.SSLMode,
+		Host:     d.Host,
+		Port:     d.Port,
+	}
+}
+
+// URL возвращает адрес БД в формате postgresql:// (для golang-migrate).
+func (d DB) URL() string {
+	u := url.URL{
+		Scheme:   "postgresql",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     net.JoinHostPort(d.Host, d.Port),
+		Path:     "/" + d.Name,
+		RawQuery: url.Values{"sslmode": {d.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
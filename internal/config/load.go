@@ -0,0 +1,213 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvFile — переменная окружения с путем к файлу конфигурации (флаг -config важнее).
+const EnvFile = "CONFIG_FILE"
+
+// Load регистрирует в fs флаг -config и флаги всех настроек, разбирает args и собирает
+// конфигурацию: значения по умолчанию < файл < переменные окружения < флаги.
+// Собственные флаги команды регистрируются в fs до вызова Load.
+// Возвращенная конфигурация проверена Validate.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	file := fs.String("config", "", "файл конфигурации YAML или TOML (или $"+EnvFile+")")
+	cfg := Default()
+	fields := collectFields(&cfg)
+	flagValues := make(map[string]string)
+	for _, f := range fields {
+		fs.Var(&flagValue{values: flagValues, name: f.flag, isBool: f.value.Kind() == reflect.Bool}, f.flag,
+			fmt.Sprintf("%s ($%s)", f.path, f.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	path := *file
+	if path == "" {
+		path = os.Getenv(EnvFile)
+	}
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if v := os.Getenv(f.env); v != "" {
+			if err := setValue(f.value, v); err != nil {
+				errs = append(errs, fmt.Errorf("$%s: %w", f.env, err))
+			}
+		}
+	}
+	for _, f := range fields {
+		if v, ok := flagValues[f.flag]; ok {
+			if err := setValue(f.value, v); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", f.flag, err))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadFile читает файл конфигурации; формат определяется по расширению.
+// Неизвестные ключи считаются ошибкой, чтобы опечатка не оставляла значение по умолчанию.
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported config format %q (use .yaml, .yml or .toml)", path, ext)
+	}
+	return nil
+}
+
+// field — настройка с тегом env.
+type field struct {
+	path   string // путь в файле: db.host
+	env    string // DB_HOST
+	flag   string // db-host
+	secret bool
+	value  reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// collectFields обходит структуру конфигурации и возвращает все поля с тегом env.
+func collectFields(cfg *Config) []field {
+	var fields []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name := prefix + sf.Tag.Get("yaml")
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), name+".")
+				continue
+			}
+			env := sf.Tag.Get("env")
+			if env == "" {
+				continue
+			}
+			fields = append(fields, field{
+				path:   name,
+				env:    env,
+				flag:   strings.ToLower(strings.ReplaceAll(env, "_", "-")),
+				secret: sf.Tag.Get("secret") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return fields
+}
+
+// setValue разбирает строковое значение из окружения или флага в поле нужного типа.
+// Списки задаются через запятую, пробелы по краям значения отбрасываются.
+func setValue(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// flagValue запоминает значения флагов, переданных явно:
+// они применяются после окружения, а не вместо значений по умолчанию.
+type flagValue struct {
+	values map[string]string
+	name   string
+	isBool bool
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.values == nil {
+		return ""
+	}
+	return f.values[f.name]
+}
+
+func (f *flagValue) Set(s string) error {
+	f.values[f.name] = s
+	return nil
+}
+
+// IsBoolFlag позволяет писать -enable-cache вместо -enable-cache=true.
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted возвращает копию конфигурации, в которой заданные секреты
+// (пароли, токены, API-ключи) заменены на [REDACTED].
+func (c Config) Redacted() Config {
+	out := c
+	out.Kafka.Brokers = append([]string(nil), c.Kafka.Brokers...)
+	for _, f := range collectFields(&out) {
+		if f.secret && f.value.Kind() == reflect.String && f.value.String() != "" {
+			f.value.SetString(logging.Redacted)
+		}
+	}
+	return out
+}
+
+// Write выводит действующую конфигурацию в YAML со скрытыми секретами;
+// вывод можно использовать как файл конфигурации.
+func (c Config) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mitrich772/go-order-service/internal/cache"
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"github.com/mitrich772/go-order-service/internal/tracing"
+	"github.com/mitrich772/go-order-service/internal/web"
+)
+
+var (
+	sslModes       = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	cacheBackends  = []string{"local", "redis", "tiered"}
+	logFormats     = []string{logging.FormatText, logging.FormatJSON}
+	traceExporters = []string{tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP}
+	ingestModes    = []string{IngestOff, IngestStore, IngestKafka}
+)
+
+// Validate проверяет конфигурацию целиком и возвращает все найденные ошибки сразу.
+// Каждая ошибка называет ключ файла и переменную окружения, например
+// "db.port ($DB_PORT): must be a port number 1-65535, got "abc"".
+func (c *Config) Validate() error {
+	v := validator{env: envNames(c)}
+
+	v.required("db.user", c.DB.User)
+	v.required("db.name", c.DB.Name)
+	v.required("db.host", c.DB.Host)
+	v.port("db.port", c.DB.Port)
+	v.oneOf("db.sslmode", c.DB.SSLMode, sslModes)
+	v.check("db.retries", c.DB.Retries >= 1, "must be at least 1, got %d", c.DB.Retries)
+	v.check("db.retry_delay", c.DB.RetryDelay >= 0, "must not be negative, got %s", c.DB.RetryDelay)
+	v.check("db.breaker_threshold", c.DB.BreakerThreshold >= 1, "must be at least 1, got %d", c.DB.BreakerThreshold)
+	v.check("db.breaker_cooldown", c.DB.BreakerCooldown > 0, "must be positive, got %s", c.DB.BreakerCooldown)
+
+	v.check("kafka.brokers", len(c.Kafka.Brokers) > 0, "at least one broker is required")
+	v.required("kafka.topic", c.Kafka.Topic)
+	v.required("kafka.group", c.Kafka.Group)
+	v.check("kafka.max_lag", c.Kafka.MaxLag >= 0, "must not be negative, got %d", c.Kafka.MaxLag)
+	v.check("kafka.min_bytes", c.Kafka.MinBytes >= 1, "must be at least 1, got %d", c.Kafka.MinBytes)
+	v.check("kafka.max_bytes", c.Kafka.MaxBytes >= c.Kafka.MinBytes,
+		"must not be less than kafka.min_bytes (%d), got %d", c.Kafka.MinBytes, c.Kafka.MaxBytes)
+	v.check("kafka.commit_interval", c.Kafka.CommitInterval >= 0, "must not be negative, got %s", c.Kafka.CommitInterval)
+	v.check("kafka.restart_delay", c.Kafka.RestartDelay > 0, "must be positive, got %s", c.Kafka.RestartDelay)
+
+	v.oneOf("cache.backend", c.Cache.Backend, cacheBackends)
+	v.check("cache.size", c.Cache.Size >= 1, "must be at least 1, got %d", c.Cache.Size)
+	v.check("cache.ttl", c.Cache.TTL >= 0, "must not be negative, got %s", c.Cache.TTL)
+	v.check("cache.snapshot_interval", c.Cache.SnapshotInterval > 0, "must be positive, got %s", c.Cache.SnapshotInterval)
+	v.check("cache.lookup_size", c.Cache.LookupSize >= 0, "must not be negative, got %d", c.Cache.LookupSize)
+	v.check("cache.lookup_ttl", c.Cache.LookupTTL >= 0, "must not be negative, got %s", c.Cache.LookupTTL)
+	v.check("cache.snapshot_max_age", c.Cache.SnapshotMaxAge >= 0, "must not be negative, got %s", c.Cache.SnapshotMaxAge)
+	if _, err := cache.ParseInvalidationMode(c.Cache.InvalidationMode); err != nil {
+		v.add("cache.invalidation_mode", err)
+	}
+
+	v.port("http.port", c.HTTP.Port)
+	v.check("http.cache_control", !strings.ContainsAny(c.HTTP.CacheControl, "\r\n"), "must be a single header line")
+	if _, err := web.ParseCompression(c.HTTP.Compression); err != nil {
+		v.add("http.compression", err)
+	}
+	if c.GRPC.Port != "" {
+		v.port("grpc.port", c.GRPC.Port)
+		v.check("grpc.port", c.GRPC.Port != c.HTTP.Port, "must differ from http.port")
+	}
+	v.check("feed.buffer", c.Feed.Buffer >= 1, "must be at least 1, got %d", c.Feed.Buffer)
+	v.check("feed.history", c.Feed.History >= 0, "must not be negative, got %d", c.Feed.History)
+	if _, err := web.ParseRouteLimits(c.RateLimit.Routes); err != nil {
+		v.add("rate_limit.routes", err)
+	}
+	if _, err := web.ParseLimit(c.RateLimit.NotFound); err != nil {
+		v.add("rate_limit.not_found", err)
+	}
+
+	v.oneOf("ingest.mode", c.Ingest.Mode, ingestModes)
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		v.add("log.level", err)
+	}
+	v.oneOf("log.format", c.Log.Format, logFormats)
+
+	v.oneOf("trace.exporter", c.Trace.Exporter, traceExporters)
+	v.check("trace.otlp_endpoint", c.Trace.Exporter != tracing.ExporterOTLP || c.Trace.OTLPEndpoint != "",
+		"is required when trace.exporter is %q", tracing.ExporterOTLP)
+	v.check("trace.sample_ratio", c.Trace.SampleRatio >= 0 && c.Trace.SampleRatio <= 1,
+		"must be between 0 and 1, got %g", c.Trace.SampleRatio)
+
+	v.check("shutdown_timeout", c.ShutdownTimeout > 0, "must be positive, got %s", c.ShutdownTimeout)
+	return errors.Join(v.errs...)
+}
+
+// validator накапливает ошибки проверки с указанием ключа и переменной окружения.
+type validator struct {
+	env  map[string]string
+	errs []error
+}
+
+func (v *validator) add(path string, err error) {
+	if env, ok := v.env[path]; ok {
+		path = fmt.Sprintf("%s ($%s)", path, env)
+	}
+	v.errs = append(v.errs, fmt.Errorf("%s: %w", path, err))
+}
+
+func (v *validator) check(path string, ok bool, format string, args ...any) {
+	if !ok {
+		v.add(path, fmt.Errorf(format, args...))
+	}
+}
+
+func (v *validator) required(path, value string) {
+	v.check(path, value != "", "is required")
+}
+
+func (v *validator) oneOf(path, value string, allowed []string) {
+	v.check(path, slices.Contains(allowed, value), "must be one of %v, got %q", allowed, value)
+}
+
+func (v *validator) port(path, value string) {
+	n, err := strconv.Atoi(value)
+	v.check(path, err == nil && n >= 1 && n <= 65535, "must be a port number 1-65535, got %q", value)
+}
+
+// envNames сопоставляет ключи файла с переменными окружения для сообщений об ошибках.
+func envNames(c *Config) map[string]string {
+	names := make(map[string]string)
+	for _, f := range collectFields(c) {
+		names[f.path] = f.env
+	}
+	return names
+}
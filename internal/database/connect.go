@@ -1,13 +1,21 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/logging"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
+// slowQueryThreshold — длительность запроса, после которой он пишется в лог как медленный.
+const slowQueryThreshold = 200 * time.Millisecond
+
 // ConnectDB подключается к базе данных PostgreSQL через GORM.
 func ConnectDB(cfg Config) *gorm.DB {
 	dsn := fmt.Sprintf(
@@ -15,12 +23,12 @@ func ConnectDB(cfg Config) *gorm.DB {
 		cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode, cfg.Host, cfg.Port,
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: newLogger(slog.Default())})
 	if err != nil {
 		panic(err)
 	}
 
-	log.Println("База данных подключена")
+	slog.Info("База данных подключена", slog.String("host", cfg.Host), slog.String("db", cfg.DBName))
 	return db
 }
 
@@ -28,8 +36,36 @@ func ConnectDB(cfg Config) *gorm.DB {
 func Close(gormDB *gorm.DB) {
 	sqlDB, err := gormDB.DB()
 	if err != nil {
-		log.Println("Ошибка получения *sql.DB:", err)
+		slog.Error("Ошибка получения *sql.DB", logging.Err(err))
 		return
 	}
 	sqlDB.Close()
 }
+
+// gormLogger пишет запросы GORM в slog без значений параметров: в них персональные
+// данные заказов. Встроенный slog-логгер GORM не фильтрует параметры сам, поэтому
+// фильтр (gorm.ParamsFilter) реализован здесь.
+type gormLogger struct {
+	logger.Interface
+}
+
+// newLogger создает логгер GORM поверх l: ошибки (кроме «не найдено») и медленные
+// запросы, SQL с плейсхолдерами вместо значений.
+func newLogger(l *slog.Logger) logger.Interface {
+	return gormLogger{logger.NewSlogLogger(l, logger.Config{
+		SlowThreshold:             slowQueryThreshold,
+		LogLevel:                  logger.Warn,
+		IgnoreRecordNotFoundError: true,
+		ParameterizedQueries:      true,
+	})}
+}
+
+// LogMode сохраняет фильтр параметров при смене уровня (gorm.Session, Debug).
+func (l gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return gormLogger{l.Interface.LogMode(level)}
+}
+
+// ParamsFilter отбрасывает значения параметров, оставляя SQL с плейсхолдерами.
+func (gormLogger) ParamsFilter(_ context.Context, sql string, _ ...any) (string, []any) {
+	return sql, nil
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Проверяет: логгер GORM пишет SQL с плейсхолдерами без значений параметров,
+// в том числе после смены уровня, и не пишет «не найдено»
+func TestGormLogger_HidesParameters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var buf bytes.Buffer
+	l := newLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{Logger: l.LogMode(logger.Info)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery(`SELECT \* FROM "payments"`).WillReturnError(errors.New("boom"))
+	var p Payment
+	gormDB.WithContext(context.Background()).Where("transaction = ?", "secret-phone-79990000000").Take(&p)
+	mock.ExpectQuery(`SELECT \* FROM "payments"`).WillReturnError(gorm.ErrRecordNotFound)
+	gormDB.Session(&gorm.Session{Logger: l}).Where("transaction = ?", "missing").Take(&p)
+
+	out := buf.String()
+	if strings.Contains(out, "secret-phone") || !strings.Contains(out, "$1") || !strings.Contains(out, "boom") {
+		t.Fatalf("unexpected log %q", out)
+	}
+	if strings.Count(out, "SQL executed") != 1 {
+		t.Fatalf("expected only the failed query to be logged: %q", out)
+	}
+}
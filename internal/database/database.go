@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 )
@@ -9,10 +10,36 @@ import (
 
 // Database описывает набор операций для работы с заказами.
 type Database interface {
-	GetLastNOrders(n int) ([]Order, error)
-	GetAllOrders() ([]Order, error)
-	GetOrder(uid string) (*Order, error)
-	SaveOrder(order *Order) error
+	GetLastNOrders(ctx context.Context, n int) ([]Order, error)
+	GetAllOrders(ctx context.Context) ([]Order, error)
+	GetOrder(ctx context.Context, uid string) (*Order, error)
+	SaveOrder(ctx context.Context, order *Order) error
+	ListOrders(ctx context.Context, filter OrderFilter) ([]Order, error)
+	FindOrderUIDs(ctx context.Context, field LookupField, value string, limit int) ([]string, error)
+	SearchOrders(ctx context.Context, search OrderSearch) ([]SearchHit, error)
+}
+
+// OrderFilter задает условия выборки ListOrders. Пустые поля выборку не ограничивают.
+// Заказы выдаются от новых к старым: по date_created, при равенстве — по order_uid.
+type OrderFilter struct {
+	// UIDs ограничивает выборку заказами с этими order_uid.
+	UIDs            []string
+	CustomerID      string
+	TrackNumber     string
+	DeliveryService string
+	// CreatedFrom и CreatedTo — полуинтервал [CreatedFrom, CreatedTo) по date_created.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// After — курсор страницы: выдаются заказы, идущие в порядке выдачи после него.
+	After *OrderCursor
+	// Limit — максимум заказов, 0 — без ограничения.
+	Limit int
+}
+
+// OrderCursor — позиция заказа в порядке выдачи ListOrders.
+type OrderCursor struct {
+	DateCreated time.Time
+	OrderUID    string
 }
 
 // Config содержит настройки подключения к базе данных.
@@ -26,68 +53,75 @@ type Config struct {
 }
 
 // Order представляет заказ с вложенными Delivery, Payment и Items.
+// Теги xml задают вид заказа при выгрузке в XML: имена полей как в JSON.
 type Order struct {
-	OrderUID          string    `gorm:"primaryKey;type:varchar(36)" json:"order_uid" validate:"required"`
-	CustomerID        string    `gorm:"type:varchar(50);not null" json:"customer_id" validate:"required"`
-	Locale            string    `gorm:"type:char(2)" json:"locale" validate:"required"`
-	DeliveryService   string    `gorm:"type:varchar(50)" json:"delivery_service" validate:"required"`
-	ShardKey          string    `gorm:"type:varchar(10)" json:"shardkey" validate:"required"`
-	SmID              int16     `gorm:"type:smallint" json:"sm_id" validate:"gt=0"`
-	DateCreated       time.Time `gorm:"type:timestamptz" json:"date_created" validate:"required,lte"`
-	OofShard          string    `gorm:"type:varchar(10)" json:"oof_shard" validate:"required"`
-	TrackNumber       string    `gorm:"type:varchar(50)" json:"track_number" validate:"required"`
-	Entry             string    `gorm:"type:varchar(20)" json:"entry" validate:"required"`
-	InternalSignature string    `gorm:"type:varchar(255)" json:"internal_signature" validate:"omitempty,max=255"`
+	OrderUID          string    `gorm:"primaryKey;type:varchar(36)" json:"order_uid" xml:"order_uid" validate:"required"`
+	CustomerID        string    `gorm:"type:varchar(50);not null" json:"customer_id" xml:"customer_id" validate:"required"`
+	Locale            string    `gorm:"type:char(2)" json:"locale" xml:"locale" validate:"required"`
+	DeliveryService   string    `gorm:"type:varchar(50)" json:"delivery_service" xml:"delivery_service" validate:"required"`
+	ShardKey          string    `gorm:"type:varchar(10)" json:"shardkey" xml:"shardkey" validate:"required"`
+	SmID              int16     `gorm:"type:smallint" json:"sm_id" xml:"sm_id" validate:"gt=0"`
+	DateCreated       time.Time `gorm:"type:timestamptz" json:"date_created" xml:"date_created" validate:"required,lte"`
+	OofShard          string    `gorm:"type:varchar(10)" json:"oof_shard" xml:"oof_shard" validate:"required"`
+	TrackNumber       string    `gorm:"type:varchar(50)" json:"track_number" xml:"track_number" validate:"required"`
+	Entry             string    `gorm:"type:varchar(20)" json:"entry" xml:"entry" validate:"required"`
+	InternalSignature string    `gorm:"type:varchar(255)" json:"internal_signature" xml:"internal_signature" validate:"omitempty,max=255"`
 
-	Delivery Delivery `gorm:"foreignKey:OrderUID;references:OrderUID" json:"delivery" validate:"required"`
-	Payment  Payment  `gorm:"foreignKey:OrderUID;references:OrderUID" json:"payment" validate:"required"`
-	Items    []Item   `gorm:"foreignKey:OrderUID;references:OrderUID" json:"items" validate:"required,min=1,dive"`
+	Delivery Delivery `gorm:"foreignKey:OrderUID;references:OrderUID" json:"delivery" xml:"delivery" validate:"required"`
+	Payment  Payment  `gorm:"foreignKey:OrderUID;references:OrderUID" json:"payment" xml:"payment" validate:"required"`
+	Items    []Item   `gorm:"foreignKey:OrderUID;references:OrderUID" json:"items" xml:"items>item" validate:"required,min=1,dive"`
 }
 
 // Delivery содержит информацию о доставке заказа.
+// Name, Phone, Email и Address хранятся зашифрованными, если включено шифрование (см. GormDatabase.UseEncryption).
 type Delivery struct {
-	DeliveryID uint   `gorm:"primaryKey;autoIncrement;type:bigserial" json:"delivery_id"`
-	OrderUID   string `gorm:"type:varchar(36);uniqueIndex" json:"order_uid"`
-	Name       string `gorm:"type:varchar(100)" json:"name" validate:"required"`
-	Phone      string `gorm:"type:varchar(20)" json:"phone" validate:"required,e164"`
-	Zip        string `gorm:"type:varchar(20)" json:"zip" validate:"required"`
-	City       string `gorm:"type:varchar(50)" json:"city" validate:"required"`
-	Address    string `gorm:"type:varchar(200)" json:"address" validate:"required"`
-	Region     string `gorm:"type:varchar(50)" json:"region" validate:"required"`
-	Email      string `gorm:"type:varchar(100)" json:"email" validate:"required,email"`
+	DeliveryID uint   `gorm:"primaryKey;autoIncrement;type:bigserial" json:"delivery_id" xml:"delivery_id"`
+	OrderUID   string `gorm:"type:varchar(36);uniqueIndex" json:"order_uid" xml:"order_uid"`
+	Name       string `gorm:"type:text" json:"name" xml:"name" validate:"required"`
+	Phone      string `gorm:"type:text" json:"phone" xml:"phone" validate:"required,e164"`
+	Zip        string `gorm:"type:varchar(20)" json:"zip" xml:"zip" validate:"required"`
+	City       string `gorm:"type:varchar(50)" json:"city" xml:"city" validate:"required"`
+	Address    string `gorm:"type:text" json:"address" xml:"address" validate:"required"`
+	Region     string `gorm:"type:varchar(50)" json:"region" xml:"region" validate:"required"`
+	Email      string `gorm:"type:text" json:"email" xml:"email" validate:"required,email"`
+	// PhoneLookup и EmailLookup — слепые индексы нормализованных телефона и email
+	// для поиска (см. FindOrderUIDs), в API не отдаются.
+	PhoneLookup string `gorm:"type:varchar(64)" json:"-" xml:"-"`
+	EmailLookup string `gorm:"type:varchar(64)" json:"-" xml:"-"`
 }
 
 // Payment содержит информацию о платеже заказа.
+// Transaction и Bank хранятся зашифрованными, если включено шифрование.
 type Payment struct {
-	PaymentID    uint    `gorm:"primaryKey;autoIncrement;type:bigserial" json:"payment_id"`
-	OrderUID     string  `gorm:"type:varchar(36);uniqueIndex" json:"order_uid"`
-	Transaction  string  `gorm:"type:varchar(36)" json:"transaction" validate:"required"`
-	RequestID    string  `gorm:"type:varchar(50)" json:"request_id"`
-	Currency     string  `gorm:"type:char(3)" json:"currency" validate:"required,len=3"`
-	Provider     string  `gorm:"type:varchar(50)" json:"provider" validate:"required"`
-	Amount       float64 `gorm:"type:numeric(12,2)" json:"amount" validate:"gte=0"`
-	PaymentDT    int64   `gorm:"type:bigint" json:"payment_dt"`
-	Bank         string  `gorm:"type:varchar(50)" json:"bank"`
-	DeliveryCost float64 `gorm:"type:numeric(12,2)" json:"delivery_cost" validate:"gte=0"`
-	GoodsTotal   float64 `gorm:"type:numeric(12,2)" json:"goods_total" validate:"gte=0"`
-	CustomFee    float64 `gorm:"type:numeric(12,2)" json:"custom_fee" validate:"gte=0"`
+	PaymentID    uint    `gorm:"primaryKey;autoIncrement;type:bigserial" json:"payment_id" xml:"payment_id"`
+	OrderUID     string  `gorm:"type:varchar(36);uniqueIndex" json:"order_uid" xml:"order_uid"`
+	Transaction  string  `gorm:"type:text" json:"transaction" xml:"transaction" validate:"required"`
+	RequestID    string  `gorm:"type:varchar(50)" json:"request_id" xml:"request_id"`
+	Currency     string  `gorm:"type:char(3)" json:"currency" xml:"currency" validate:"required,len=3"`
+	Provider     string  `gorm:"type:varchar(50)" json:"provider" xml:"provider" validate:"required"`
+	Amount       float64 `gorm:"type:numeric(12,2)" json:"amount" xml:"amount" validate:"gte=0"`
+	PaymentDT    int64   `gorm:"type:bigint" json:"payment_dt" xml:"payment_dt"`
+	Bank         string  `gorm:"type:text" json:"bank" xml:"bank"`
+	DeliveryCost float64 `gorm:"type:numeric(12,2)" json:"delivery_cost" xml:"delivery_cost" validate:"gte=0"`
+	GoodsTotal   float64 `gorm:"type:numeric(12,2)" json:"goods_total" xml:"goods_total" validate:"gte=0"`
+	CustomFee    float64 `gorm:"type:numeric(12,2)" json:"custom_fee" xml:"custom_fee" validate:"gte=0"`
 }
 
 // Item представляет товар в заказе.
 type Item struct {
-	ItemID      uint    `gorm:"primaryKey;autoIncrement;type:bigserial" json:"item_id"`
-	OrderUID    string  `gorm:"type:varchar(36);index" json:"order_uid"`
-	ChrtID      int64   `gorm:"type:bigint" json:"chrt_id" validate:"gt=0"`
-	TrackNumber string  `gorm:"type:varchar(50)" json:"track_number" validate:"required"`
-	Price       float64 `gorm:"type:numeric(12,2)" json:"price" validate:"gte=0"`
-	RID         string  `gorm:"column:rid;type:varchar(36)" json:"rid"`
-	Name        string  `gorm:"type:varchar(200)" json:"name" validate:"required"`
-	Sale        float64 `gorm:"type:numeric(5,2)" json:"sale" validate:"gte=0"`
-	Size        string  `gorm:"type:varchar(10)" json:"size"`
-	TotalPrice  float64 `gorm:"type:numeric(12,2)" json:"total_price" validate:"gte=0"`
-	NmID        int64   `gorm:"type:bigint" json:"nm_id" validate:"gt=0"`
-	Brand       string  `gorm:"type:varchar(100)" json:"brand"`
-	Status      int16   `gorm:"type:smallint" json:"status"`
+	ItemID      uint    `gorm:"primaryKey;autoIncrement;type:bigserial" json:"item_id" xml:"item_id"`
+	OrderUID    string  `gorm:"type:varchar(36);index" json:"order_uid" xml:"order_uid"`
+	ChrtID      int64   `gorm:"type:bigint" json:"chrt_id" xml:"chrt_id" validate:"gt=0"`
+	TrackNumber string  `gorm:"type:varchar(50)" json:"track_number" xml:"track_number" validate:"required"`
+	Price       float64 `gorm:"type:numeric(12,2)" json:"price" xml:"price" validate:"gte=0"`
+	RID         string  `gorm:"column:rid;type:varchar(36)" json:"rid" xml:"rid"`
+	Name        string  `gorm:"type:varchar(200)" json:"name" xml:"name" validate:"required"`
+	Sale        float64 `gorm:"type:numeric(5,2)" json:"sale" xml:"sale" validate:"gte=0"`
+	Size        string  `gorm:"type:varchar(10)" json:"size" xml:"size"`
+	TotalPrice  float64 `gorm:"type:numeric(12,2)" json:"total_price" xml:"total_price" validate:"gte=0"`
+	NmID        int64   `gorm:"type:bigint" json:"nm_id" xml:"nm_id" validate:"gt=0"`
+	Brand       string  `gorm:"type:varchar(100)" json:"brand" xml:"brand"`
+	Status      int16   `gorm:"type:smallint" json:"status" xml:"status"`
 }
 
 // OrderFromJSON преобразует JSON-данные в структуру Order
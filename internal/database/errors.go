@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mitrich772/go-order-service/internal/retry"
+	"gorm.io/gorm"
+)
+
+// Классы ошибок хранилища. Слои выше (cache, web, grpcapi) определяют по ним ответ:
+// не найдено — 404, неверный запрос — 400, конфликт с сохраненными данными — 409,
+// хранилище недоступно — 503, остальное — 500.
+var (
+	// ErrNotFound — заказа нет. Ошибки gorm.ErrRecordNotFound относятся к тому же классу.
+	ErrNotFound = errors.New("order not found")
+	// ErrInvalid — запрос к хранилищу неверен (пустой uid, неизвестное поле, пустой запрос).
+	ErrInvalid = errors.New("invalid request")
+	// ErrConflict — запись нарушает ограничение целостности БД (уникальность, внешний ключ,
+	// check, not null): повтор того же запроса не поможет. Ошибки PostgreSQL класса 23
+	// относятся к тому же классу.
+	ErrConflict = errors.New("order conflicts with stored data")
+	// ErrUnavailable — хранилище временно недоступно, запрос стоит повторить позже.
+	ErrUnavailable = errors.New("order store unavailable")
+)
+
+// IsNotFound сообщает, что ошибка означает отсутствие заказа.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// IsInvalid сообщает, что ошибка вызвана неверным запросом, включая непрошедшую
+// проверку заказа (*ValidationError): повтор того же запроса не поможет.
+func IsInvalid(err error) bool {
+	var ve *ValidationError
+	return errors.Is(err, ErrInvalid) || errors.As(err, &ve)
+}
+
+// IsConflict сообщает, что запись нарушила ограничение целостности БД (SQLSTATE 23xxx).
+func IsConflict(err error) bool {
+	if errors.Is(err, ErrConflict) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, "23")
+}
+
+// IsUnavailable сообщает, что хранилище временно недоступно: автомат разомкнут,
+// истек таймаут, соединение с БД не установлено или разорвано, сервер БД
+// перегружен или перезапускается.
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrUnavailable) || errors.Is(err, retry.ErrCircuitOpen) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var connErr *pgconn.ConnectError
+	if errors.As(err, &connErr) || pgconn.Timeout(err) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 08 — ошибки соединения, 53 — нехватка ресурсов, 57P01–57P03 — остановка сервера
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "53") ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
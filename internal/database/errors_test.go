@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mitrich772/go-order-service/internal/retry"
+	"gorm.io/gorm"
+)
+
+// Проверяет: классы ошибок хранилища определяются через обертки fmt.Errorf
+func TestErrorClasses(t *testing.T) {
+	wrap := func(err error) error { return fmt.Errorf("get order: %w", err) }
+	for _, tc := range []struct {
+		name                           string
+		err                            error
+		notFound, invalid, unavailable bool
+	}{
+		{"gorm not found", wrap(gorm.ErrRecordNotFound), true, false, false},
+		{"not found", wrap(ErrNotFound), true, false, false},
+		{"invalid", wrap(ErrInvalid), false, true, false},
+		{"validation", wrap(&ValidationError{}), false, true, false},
+		{"circuit open", wrap(&retry.OpenError{}), false, false, true},
+		{"timeout", wrap(context.DeadlineExceeded), false, false, true},
+		{"connect", wrap(&pgconn.ConnectError{}), false, false, true},
+		{"admin shutdown", wrap(&pgconn.PgError{Code: "57P01"}), false, false, true},
+		{"network", wrap(&net.OpError{Op: "dial", Err: errors.New("refused")}), false, false, true},
+		{"syntax", wrap(&pgconn.PgError{Code: "42601"}), false, false, false},
+		{"other", errors.New("decrypt failed"), false, false, false},
+	} {
+		if IsNotFound(tc.err) != tc.notFound || IsInvalid(tc.err) != tc.invalid || IsUnavailable(tc.err) != tc.unavailable {
+			t.Errorf("%s: got not found %v, invalid %v, unavailable %v", tc.name,
+				IsNotFound(tc.err), IsInvalid(tc.err), IsUnavailable(tc.err))
+		}
+	}
+}
+
+// Проверяет: ошибки класса 23 — конфликт, они не повторяются и не считаются отказами автомата
+func TestIsConflict(t *testing.T) {
+	for _, code := range []string{"23505", "23503", "23514", "23502"} {
+		err := fmt.Errorf("save order: %w", &pgconn.PgError{Code: code})
+		if !IsConflict(err) || IsUnavailable(err) || isTemporaryGormError(err) {
+			t.Errorf("%s: expected permanent conflict", code)
+		}
+	}
+	if !IsConflict(fmt.Errorf("save: %w", ErrConflict)) {
+		t.Error("expected ErrConflict to be a conflict")
+	}
+	if IsConflict(&pgconn.PgError{Code: "42601"}) || IsConflict(errors.New("db error")) {
+		t.Error("unexpected conflict")
+	}
+}
@@ -1,16 +1,29 @@
 package database
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/mitrich772/go-order-service/internal/metrics"
+	"github.com/mitrich772/go-order-service/internal/pii"
 	"github.com/mitrich772/go-order-service/internal/retry"
+	"github.com/mitrich772/go-order-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// Функция проверки временных ошибок
+// Функция проверки временных ошибок. Постоянные ошибки не повторяются
+// и не считаются отказами автомата: БД ответила, дело в запросе.
 func isTemporaryGormError(err error) bool {
-	if errors.Is(err, gorm.ErrRecordNotFound) {
+	if IsNotFound(err) || IsInvalid(err) || IsConflict(err) {
+		return false
+	}
+	// Запрос отменен вызывающим: повтор не поможет.
+	if errors.Is(err, context.Canceled) {
 		return false
 	}
 	return true
@@ -21,6 +34,9 @@ type GormDatabase struct {
 	db       *gorm.DB
 	attempts int
 	delay    time.Duration
+	breaker  *retry.Breaker
+	metrics  *metrics.Metrics
+	cipher   *pii.Cipher
 }
 
 // NewGormDatabase создает новый GormDatabase с указанным подключением gorm
@@ -34,39 +50,152 @@ func NewGormDatabase(db *gorm.DB, attempts int, delay time.Duration) *GormDataba
 	}
 }
 
-// GetLastNOrders возвращает последние N заказов с подгруженными зависимостями с Retry
+// UseBreaker включает автомат: при серии временных ошибок запросы
+// перестают выполняться и сразу возвращают retry.ErrCircuitOpen.
+func (r *GormDatabase) UseBreaker(b *retry.Breaker) {
+	r.breaker = b
+}
+
+// UseMetrics включает метрики длительности запросов и числа повторов.
+func (r *GormDatabase) UseMetrics(m *metrics.Metrics) {
+	r.metrics = m
+}
+
+// UseEncryption включает шифрование персональных данных доставки и платежа при записи.
+// Открытые значения, записанные до включения, читаются как есть до запуска Rekey.
+func (r *GormDatabase) UseEncryption(c *pii.Cipher) {
+	r.cipher = c
+}
+
+// Breaker возвращает автомат базы данных или nil, если он не включен.
+func (r *GormDatabase) Breaker() *retry.Breaker {
+	return r.breaker
+}
+
+// Ping проверяет соединение с базой данных.
+func (r *GormDatabase) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// withRetry выполняет запрос op через автомат (если включен) и Retry,
+// записывая длительность каждой попытки и число повторов в метрики.
+// Весь запрос вместе с повторами — один спан db.<op>, повторы — события спана.
+func withRetry[T any](ctx context.Context, r *GormDatabase, op string, fn func(tx *gorm.DB) (T, error)) (result T, err error) {
+	ctx, span := tracing.Start(ctx, "db."+op, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", op),
+		))
+	defer func() { tracing.End(span, err) }()
+
+	notify := func(attempt int, err error) {
+		r.metrics.DBRetry(op)
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("error", err.Error()),
+		))
+	}
+	tx := r.db.WithContext(ctx)
+	return retry.Do(r.breaker, isTemporaryGormError, func() (T, error) {
+		return retry.RetryNotify(r.attempts, r.delay, isTemporaryGormError, notify, func() (T, error) {
+			start := time.Now()
+			result, err := fn(tx)
+			r.metrics.DBQuery(op, time.Since(start), err)
+			return result, err
+		})
+	})
+}
+
+// GetLastNOrders возвращает последние N заказов по date_created (от новых к старым)
+// с подгруженными зависимостями.
 // Выполняется с Retry для повторных попыток при временных ошибках БД.
-func (r *GormDatabase) GetLastNOrders(n int) ([]Order, error) {
-	return retry.Retry(r.attempts, r.delay, isTemporaryGormError, func() ([]Order, error) {
+func (r *GormDatabase) GetLastNOrders(ctx context.Context, n int) ([]Order, error) {
+	return withRetry(ctx, r, "get_last_n_orders", func(tx *gorm.DB) ([]Order, error) {
 		var orders []Order
-		err := r.db.Preload("Delivery").
+		err := tx.Preload("Delivery").
 			Preload("Payment").
 			Preload("Items").
+			Order("date_created DESC").
 			Limit(n).
 			Find(&orders).Error
-		return orders, err
+		if err != nil {
+			return nil, err
+		}
+		return orders, r.decryptAll(orders)
 	})
 }
 
 // GetAllOrders возвращает все заказы с подгруженными зависимостями с Retry
 // Выполняется с Retry для повторных попыток при временных ошибках БД.
-func (r *GormDatabase) GetAllOrders() ([]Order, error) {
-	return retry.Retry(r.attempts, r.delay, isTemporaryGormError, func() ([]Order, error) {
+func (r *GormDatabase) GetAllOrders(ctx context.Context) ([]Order, error) {
+	return withRetry(ctx, r, "get_all_orders", func(tx *gorm.DB) ([]Order, error) {
 		var orders []Order
-		err := r.db.Preload("Delivery").
+		err := tx.Preload("Delivery").
 			Preload("Payment").
 			Preload("Items").
 			Find(&orders).Error
-		return orders, err
+		if err != nil {
+			return nil, err
+		}
+		return orders, r.decryptAll(orders)
+	})
+}
+
+// ListOrders возвращает заказы, подходящие под filter, от новых к старым
+// с подгруженными зависимостями. Страницы выбираются по курсору (keyset),
+// поэтому новые заказы не сдвигают уже выданные.
+// Выполняется с Retry для повторных попыток при временных ошибках БД.
+func (r *GormDatabase) ListOrders(ctx context.Context, filter OrderFilter) ([]Order, error) {
+	return withRetry(ctx, r, "list_orders", func(tx *gorm.DB) ([]Order, error) {
+		q := tx.Preload("Delivery").
+			Preload("Payment").
+			Preload("Items")
+		if len(filter.UIDs) > 0 {
+			q = q.Where("order_uid IN ?", filter.UIDs)
+		}
+		if filter.CustomerID != "" {
+			q = q.Where("customer_id = ?", filter.CustomerID)
+		}
+		if filter.TrackNumber != "" {
+			q = q.Where("track_number = ?", filter.TrackNumber)
+		}
+		if filter.DeliveryService != "" {
+			q = q.Where("delivery_service = ?", filter.DeliveryService)
+		}
+		if !filter.CreatedFrom.IsZero() {
+			q = q.Where("date_created >= ?", filter.CreatedFrom)
+		}
+		if !filter.CreatedTo.IsZero() {
+			q = q.Where("date_created < ?", filter.CreatedTo)
+		}
+		if c := filter.After; c != nil {
+			q = q.Where("(date_created, order_uid) < (?, ?)", c.DateCreated, c.OrderUID)
+		}
+		q = q.Order("date_created DESC, order_uid DESC")
+		if filter.Limit > 0 {
+			q = q.Limit(filter.Limit)
+		}
+		var orders []Order
+		if err := q.Find(&orders).Error; err != nil {
+			return nil, err
+		}
+		return orders, r.decryptAll(orders)
 	})
 }
 
 // GetOrder возвращает заказ по UID с подгруженными зависимостями с Retry
 // Выполняется с Retry для повторных попыток при временных ошибках БД.
-func (r *GormDatabase) GetOrder(uid string) (*Order, error) {
-	return retry.Retry(r.attempts, r.delay, isTemporaryGormError, func() (*Order, error) {
+func (r *GormDatabase) GetOrder(ctx context.Context, uid string) (*Order, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("%w: order_uid required", ErrInvalid)
+	}
+	return withRetry(ctx, r, "get_order", func(tx *gorm.DB) (*Order, error) {
 		var order Order
-		err := r.db.Preload("Delivery").
+		err := tx.Preload("Delivery").
 			Preload("Payment").
 			Preload("Items").
 			Where("order_uid = ?", uid).
@@ -74,17 +203,70 @@ func (r *GormDatabase) GetOrder(uid string) (*Order, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err := DecryptOrder(r.cipher, &order); err != nil {
+			return nil, err
+		}
 		return &order, nil
 	})
 }
 
-// SaveOrder сохраняет заказ и связанные данные в транзакции
+// SaveOrder сохраняет заказ и связанные данные в транзакции. Повторная запись заказа
+// с тем же order_uid заменяет сохраненный: заказ, доставка и платеж обновляются на месте,
+// товары заменяются целиком. Поэтому повтор пакета, HTTP-запроса или сообщения Kafka
+// не приводит к ошибке уникальности. Идентификаторы доставки, платежа и товаров
+// выдает БД, присланные в заказе игнорируются.
 // Выполняется с Retry для повторных попыток при временных ошибках БД.
-func (r *GormDatabase) SaveOrder(order *Order) error {
-	_, err := retry.Retry(r.attempts, r.delay, isTemporaryGormError, func() (any, error) {
-		return nil, r.db.Transaction(func(tx *gorm.DB) error {
-			return tx.Create(order).Error
+// При включенном шифровании в БД пишется зашифрованная копия, order остается открытым.
+func (r *GormDatabase) SaveOrder(ctx context.Context, order *Order) error {
+	stored, err := EncryptOrder(r.cipher, order)
+	if err != nil {
+		return err
+	}
+	if err := setLookups(r.cipher, &stored.Delivery); err != nil {
+		return err
+	}
+	_, err = withRetry(ctx, r, "save_order", func(tx *gorm.DB) (any, error) {
+		return nil, tx.Transaction(func(tx *gorm.DB) error {
+			return upsertOrder(tx, stored)
 		})
 	})
+	// Идентификаторы, выданные БД, переносим в исходный заказ (товары у копии общие с ним).
+	order.Delivery.DeliveryID, order.Payment.PaymentID = stored.Delivery.DeliveryID, stored.Payment.PaymentID
 	return err
 }
+
+// upsertOrder записывает заказ поверх сохраненного с тем же order_uid.
+func upsertOrder(tx *gorm.DB, order *Order) error {
+	byOrderUID := clause.OnConflict{Columns: []clause.Column{{Name: "order_uid"}}, UpdateAll: true}
+	if err := tx.Omit(clause.Associations).Clauses(byOrderUID).Create(order).Error; err != nil {
+		return err
+	}
+	order.Delivery.DeliveryID, order.Delivery.OrderUID = 0, order.OrderUID
+	if err := tx.Clauses(byOrderUID).Create(&order.Delivery).Error; err != nil {
+		return err
+	}
+	order.Payment.PaymentID, order.Payment.OrderUID = 0, order.OrderUID
+	if err := tx.Clauses(byOrderUID).Create(&order.Payment).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("order_uid = ?", order.OrderUID).Delete(&Item{}).Error; err != nil {
+		return err
+	}
+	if len(order.Items) == 0 {
+		return nil
+	}
+	for i := range order.Items {
+		order.Items[i].ItemID, order.Items[i].OrderUID = 0, order.OrderUID
+	}
+	return tx.Create(&order.Items).Error
+}
+
+// decryptAll расшифровывает персональные данные во всех заказах.
+func (r *GormDatabase) decryptAll(orders []Order) error {
+	for i := range orders {
+		if err := DecryptOrder(r.cipher, &orders[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
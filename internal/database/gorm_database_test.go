@@ -1,10 +1,17 @@
 package database
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mitrich772/go-order-service/internal/metrics"
+	"github.com/mitrich772/go-order-service/internal/retry"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -28,29 +35,75 @@ func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock, func()) {
 
 	return gormDB, mock, cleanup
 }
+
+// expectSaveOrder ожидает транзакцию SaveOrder: заказ, доставка и платеж записываются
+// с ON CONFLICT DO UPDATE, товары заменяются.
+func expectSaveOrder(mock sqlmock.Sqlmock, items int) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "orders" (.+) ON CONFLICT \("order_uid"\) DO UPDATE SET`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`INSERT INTO "deliveries" (.+) ON CONFLICT \("order_uid"\) DO UPDATE SET (.+) RETURNING "delivery_id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"delivery_id"}).AddRow(7))
+	mock.ExpectQuery(`INSERT INTO "payments" (.+) ON CONFLICT \("order_uid"\) DO UPDATE SET (.+) RETURNING "payment_id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_id"}).AddRow(8))
+	mock.ExpectExec(`DELETE FROM "items" WHERE order_uid = \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if items > 0 {
+		rows := sqlmock.NewRows([]string{"item_id"})
+		for i := range items {
+			rows.AddRow(100 + i)
+		}
+		mock.ExpectQuery(`INSERT INTO "items"`).WillReturnRows(rows)
+	}
+	mock.ExpectCommit()
+}
+
 func TestSaveOrder_Success(t *testing.T) {
 	gormDB, mock, cleanup := setupMockDB(t) // Gorm с мок бд
 	defer cleanup()
 
 	repo := NewGormDatabase(gormDB, 1, 0)
-	// ожидаем цепочку: начало транзакции -> оперцию к бд -> успешный результат -> коммит
-	mock.ExpectBegin()
-	mock.ExpectExec(`INSERT INTO "orders"`).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectCommit()
+	expectSaveOrder(mock, 1)
 
-	order := &Order{OrderUID: "123"}
+	order := &Order{OrderUID: "123", Items: []Item{{ItemID: 55, Name: "a"}}}
 
-	err := repo.SaveOrder(order)
+	err := repo.SaveOrder(context.Background(), order)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if order.Delivery.DeliveryID != 7 || order.Payment.PaymentID != 8 || order.Items[0].ItemID != 100 {
+		t.Fatalf("ids from the database not applied: %+v", order)
+	}
 
 	// проверяем что все по ожидаемое произошло
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatal(err)
 	}
 }
+
+// Проверяет: повторная запись того же заказа заменяет сохраненный и не размыкает автомат
+func TestSaveOrder_ResendIsUpsert(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := NewGormDatabase(gormDB, 1, 0)
+	breaker := retry.NewBreaker(1, time.Minute)
+	repo.UseBreaker(breaker)
+	expectSaveOrder(mock, 0)
+	expectSaveOrder(mock, 0)
+
+	for range 2 {
+		if err := repo.SaveOrder(context.Background(), &Order{OrderUID: "123"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if state := breaker.State(); state != retry.BreakerClosed {
+		t.Fatalf("expected closed breaker got %s", state)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
 func TestSaveOrder_RollbackOnError(t *testing.T) {
 	gormDB, mock, cleanup := setupMockDB(t)
 	defer cleanup()
@@ -63,7 +116,7 @@ func TestSaveOrder_RollbackOnError(t *testing.T) {
 	mock.ExpectRollback()
 
 	order := &Order{}
-	err := repo.SaveOrder(order)
+	err := repo.SaveOrder(context.Background(), order)
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -82,7 +135,7 @@ func TestGetOrder_NotFound_NoRetry(t *testing.T) {
 	mock.ExpectQuery(`SELECT (.+)FROM "orders"`).
 		WillReturnError(gorm.ErrRecordNotFound)
 
-	_, err := repo.GetOrder("uid-x")
+	_, err := repo.GetOrder(context.Background(), "uid-x")
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		t.Fatalf("expected ErrRecordNotFound got %v", err)
 	}
@@ -91,3 +144,114 @@ func TestGetOrder_NotFound_NoRetry(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+func TestGetLastNOrders_OrderedByDateCreated(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := NewGormDatabase(gormDB, 1, 0)
+	mock.ExpectQuery(`SELECT \* FROM "orders" ORDER BY date_created DESC LIMIT \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+
+	if _, err := repo.GetLastNOrders(context.Background(), 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Проверяет: фильтры и курсор попадают в запрос, порядок — от новых к старым
+func TestListOrders_FilterAndCursor(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := NewGormDatabase(gormDB, 1, 0)
+	after := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT \* FROM "orders" WHERE customer_id = \$1 AND \(date_created, order_uid\) < \(\$2, \$3\) ORDER BY date_created DESC, order_uid DESC LIMIT \$4`).
+		WithArgs("c1", after, "uid-9", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+
+	_, err := repo.ListOrders(context.Background(), OrderFilter{
+		CustomerID: "c1",
+		After:      &OrderCursor{DateCreated: after, OrderUID: "uid-9"},
+		Limit:      3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetOrder_BreakerOpen_NoQuery(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := NewGormDatabase(gormDB, 1, 0)
+	repo.UseBreaker(retry.NewBreaker(1, time.Minute))
+	mock.ExpectQuery(`SELECT (.+)FROM "orders"`).
+		WillReturnError(errors.New("connection refused"))
+
+	if _, err := repo.GetOrder(context.Background(), "uid-x"); err == nil {
+		t.Fatal("expected error")
+	}
+	// автомат разомкнут: второй запрос в БД не уходит
+	if _, err := repo.GetOrder(context.Background(), "uid-x"); !errors.Is(err, retry.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetOrder_RetryMetrics(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	m := metrics.New(prometheus.NewRegistry())
+	repo := NewGormDatabase(gormDB, 3, 0)
+	repo.UseMetrics(m)
+	mock.ExpectQuery(`SELECT (.+)FROM "orders"`).
+		WillReturnError(errors.New("connection refused"))
+	mock.ExpectQuery(`SELECT (.+)FROM "orders"`).
+		WillReturnError(errors.New("connection refused"))
+	mock.ExpectQuery(`SELECT (.+)FROM "orders"`).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	if _, err := repo.GetOrder(context.Background(), "uid-x"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected ErrRecordNotFound got %v", err)
+	}
+	if got := testutil.ToFloat64(m.DBRetries.WithLabelValues("get_order")); got != 2 {
+		t.Fatalf("expected 2 retries, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.DBQueryDuration); got != 1 {
+		t.Fatalf("expected query durations recorded, got %d series", got)
+	}
+}
+
+// Проверяет: нарушение ограничения БД не повторяется и не размыкает автомат
+func TestSaveOrder_ConflictNotRetried(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := NewGormDatabase(gormDB, 3, 0)
+	breaker := retry.NewBreaker(1, time.Minute)
+	repo.UseBreaker(breaker)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "orders"`).
+		WillReturnError(&pgconn.PgError{Code: "23503"})
+	mock.ExpectRollback()
+
+	if err := repo.SaveOrder(context.Background(), &Order{OrderUID: "x"}); !IsConflict(err) {
+		t.Fatalf("expected conflict got %v", err)
+	}
+	if state := breaker.State(); state != retry.BreakerClosed {
+		t.Fatalf("expected closed breaker got %s", state)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
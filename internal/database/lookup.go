@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mitrich772/go-order-service/internal/pii"
+	"gorm.io/gorm"
+)
+
+// LookupField — вторичный ключ поиска заказов.
+type LookupField string
+
+// Вторичные ключи поиска.
+const (
+	LookupTrackNumber LookupField = "track_number"
+	LookupCustomerID  LookupField = "customer_id"
+	LookupPhone       LookupField = "phone"
+	LookupEmail       LookupField = "email"
+)
+
+// LookupFields — все вторичные ключи в порядке разбора запроса.
+var LookupFields = []LookupField{LookupTrackNumber, LookupCustomerID, LookupPhone, LookupEmail}
+
+// Personal сообщает, что ключ — персональные данные (телефон или email).
+func (f LookupField) Personal() bool {
+	return f == LookupPhone || f == LookupEmail
+}
+
+// NormalizeLookup приводит значение ключа к виду, в котором оно ищется:
+// телефон — "+" и цифры, email — в нижнем регистре, остальное — без пробелов по краям.
+func NormalizeLookup(field LookupField, value string) (string, error) {
+	switch field {
+	case LookupTrackNumber, LookupCustomerID:
+		return strings.TrimSpace(value), nil
+	case LookupPhone:
+		return pii.NormalizePhone(value), nil
+	case LookupEmail:
+		return pii.NormalizeEmail(value), nil
+	default:
+		return "", fmt.Errorf("%w: unknown lookup field %q", ErrInvalid, field)
+	}
+}
+
+// setLookups заполняет слепые индексы телефона и email по открытым значениям d.
+func setLookups(c *pii.Cipher, d *Delivery) error {
+	var err error
+	if d.PhoneLookup, err = c.LookupHash(pii.NormalizePhone(d.Phone)); err != nil {
+		return err
+	}
+	d.EmailLookup, err = c.LookupHash(pii.NormalizeEmail(d.Email))
+	return err
+}
+
+// FindOrderUIDs возвращает uid не более limit заказов со значением вторичного ключа,
+// от новых к старым. Телефон и email ищутся по слепому индексу под всеми ключами
+// шифрования, поэтому поиск работает и до перешифрования после ротации.
+// Выполняется с Retry для повторных попыток при временных ошибках БД.
+func (r *GormDatabase) FindOrderUIDs(ctx context.Context, field LookupField, value string, limit int) ([]string, error) {
+	value, err := NormalizeLookup(field, value)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	var column string
+	var args any = value
+	switch field {
+	case LookupTrackNumber:
+		column = "orders.track_number = ?"
+	case LookupCustomerID:
+		column = "orders.customer_id = ?"
+	case LookupPhone, LookupEmail:
+		column = "deliveries.phone_lookup IN ?"
+		if field == LookupEmail {
+			column = "deliveries.email_lookup IN ?"
+		}
+		if args, err = r.cipher.LookupHashes(value); err != nil {
+			return nil, err
+		}
+	}
+	return withRetry(ctx, r, "find_order_uids", func(tx *gorm.DB) ([]string, error) {
+		q := tx.Model(&Order{}).Where(column, args)
+		if field.Personal() {
+			q = q.Joins("JOIN deliveries ON deliveries.order_uid = orders.order_uid")
+		}
+		q = q.Order("orders.date_created DESC, orders.order_uid DESC")
+		if limit > 0 {
+			q = q.Limit(limit)
+		}
+		var uids []string
+		if err := q.Pluck("orders.order_uid", &uids).Error; err != nil {
+			return nil, err
+		}
+		return uids, nil
+	})
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestFindOrderUIDs_Track(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := NewGormDatabase(gormDB, 1, 0)
+	mock.ExpectQuery(`SELECT "orders"."order_uid" FROM "orders" WHERE orders.track_number = \$1 ORDER BY orders.date_created DESC, orders.order_uid DESC LIMIT \$2`).
+		WithArgs("WBTRACK", 10).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}).AddRow("b").AddRow("a"))
+
+	uids, err := repo.FindOrderUIDs(context.Background(), LookupTrackNumber, " WBTRACK ", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uids) != 2 || uids[0] != "b" {
+		t.Fatalf("unexpected uids %v", uids)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Телефон ищется по слепому индексу: хэш без ключа (строки до шифрования) и хэш каждого ключа.
+func TestFindOrderUIDs_PhoneEncrypted(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	c := testCipher(t)
+	repo := NewGormDatabase(gormDB, 1, 0)
+	repo.UseEncryption(c)
+
+	hashes, _ := c.LookupHashes("+79161231234")
+	args := make([]driver.Value, 0, len(hashes)+1)
+	for _, h := range hashes {
+		args = append(args, h)
+	}
+	args = append(args, 100)
+	mock.ExpectQuery(`SELECT "orders"."order_uid" FROM "orders" JOIN deliveries ON deliveries.order_uid = orders.order_uid WHERE deliveries.phone_lookup IN \(\$1,\$2\) ORDER BY (.+) LIMIT \$3`).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}).AddRow("a"))
+
+	uids, err := repo.FindOrderUIDs(context.Background(), LookupPhone, "+7 (916) 123-12-34", 100)
+	if err != nil || len(uids) != 1 {
+		t.Fatalf("unexpected result %v, %v", uids, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.FindOrderUIDs(context.Background(), "name", "x", 1); err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+}
+
+func TestSetLookups(t *testing.T) {
+	c := testCipher(t)
+	d := Delivery{Phone: "+7 916 123 12 34", Email: "Ivan@Mail.ru"}
+	if err := setLookups(c, &d); err != nil {
+		t.Fatal(err)
+	}
+	phone, _ := c.LookupHash("+79161231234")
+	email, _ := c.LookupHash("ivan@mail.ru")
+	if d.PhoneLookup != phone || d.EmailLookup != email {
+		t.Fatalf("unexpected lookups %+v", d)
+	}
+}
@@ -5,6 +5,7 @@
 package mock_database
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,61 +35,106 @@ func (m *MockDatabase) EXPECT() *MockDatabaseMockRecorder {
 	return m.recorder
 }
 
+// FindOrderUIDs mocks base method.
+func (m *MockDatabase) FindOrderUIDs(arg0 context.Context, arg1 database.LookupField, arg2 string, arg3 int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrderUIDs", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrderUIDs indicates an expected call of FindOrderUIDs.
+func (mr *MockDatabaseMockRecorder) FindOrderUIDs(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrderUIDs", reflect.TypeOf((*MockDatabase)(nil).FindOrderUIDs), arg0, arg1, arg2, arg3)
+}
+
 // GetAllOrders mocks base method.
-func (m *MockDatabase) GetAllOrders() ([]database.Order, error) {
+func (m *MockDatabase) GetAllOrders(arg0 context.Context) ([]database.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllOrders")
+	ret := m.ctrl.Call(m, "GetAllOrders", arg0)
 	ret0, _ := ret[0].([]database.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllOrders indicates an expected call of GetAllOrders.
-func (mr *MockDatabaseMockRecorder) GetAllOrders() *gomock.Call {
+func (mr *MockDatabaseMockRecorder) GetAllOrders(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllOrders", reflect.TypeOf((*MockDatabase)(nil).GetAllOrders))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllOrders", reflect.TypeOf((*MockDatabase)(nil).GetAllOrders), arg0)
 }
 
 // GetLastNOrders mocks base method.
-func (m *MockDatabase) GetLastNOrders(arg0 int) ([]database.Order, error) {
+func (m *MockDatabase) GetLastNOrders(arg0 context.Context, arg1 int) ([]database.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetLastNOrders", arg0)
+	ret := m.ctrl.Call(m, "GetLastNOrders", arg0, arg1)
 	ret0, _ := ret[0].([]database.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetLastNOrders indicates an expected call of GetLastNOrders.
-func (mr *MockDatabaseMockRecorder) GetLastNOrders(arg0 interface{}) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) GetLastNOrders(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastNOrders", reflect.TypeOf((*MockDatabase)(nil).GetLastNOrders), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastNOrders", reflect.TypeOf((*MockDatabase)(nil).GetLastNOrders), arg0, arg1)
 }
 
 // GetOrder mocks base method.
-func (m *MockDatabase) GetOrder(arg0 string) (*database.Order, error) {
+func (m *MockDatabase) GetOrder(arg0 context.Context, arg1 string) (*database.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrder", arg0)
+	ret := m.ctrl.Call(m, "GetOrder", arg0, arg1)
 	ret0, _ := ret[0].(*database.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOrder indicates an expected call of GetOrder.
-func (mr *MockDatabaseMockRecorder) GetOrder(arg0 interface{}) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) GetOrder(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockDatabase)(nil).GetOrder), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockDatabase)(nil).GetOrder), arg0, arg1)
+}
+
+// ListOrders mocks base method.
+func (m *MockDatabase) ListOrders(arg0 context.Context, arg1 database.OrderFilter) ([]database.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOrders", arg0, arg1)
+	ret0, _ := ret[0].([]database.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOrders indicates an expected call of ListOrders.
+func (mr *MockDatabaseMockRecorder) ListOrders(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOrders", reflect.TypeOf((*MockDatabase)(nil).ListOrders), arg0, arg1)
 }
 
 // SaveOrder mocks base method.
-func (m *MockDatabase) SaveOrder(arg0 *database.Order) error {
+func (m *MockDatabase) SaveOrder(arg0 context.Context, arg1 *database.Order) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveOrder", arg0)
+	ret := m.ctrl.Call(m, "SaveOrder", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SaveOrder indicates an expected call of SaveOrder.
-func (mr *MockDatabaseMockRecorder) SaveOrder(arg0 interface{}) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) SaveOrder(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrder", reflect.TypeOf((*MockDatabase)(nil).SaveOrder), arg0, arg1)
+}
+
+// SearchOrders mocks base method.
+func (m *MockDatabase) SearchOrders(arg0 context.Context, arg1 database.OrderSearch) ([]database.SearchHit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchOrders", arg0, arg1)
+	ret0, _ := ret[0].([]database.SearchHit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchOrders indicates an expected call of SearchOrders.
+func (mr *MockDatabaseMockRecorder) SearchOrders(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrder", reflect.TypeOf((*MockDatabase)(nil).SaveOrder), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchOrders", reflect.TypeOf((*MockDatabase)(nil).SearchOrders), arg0, arg1)
 }
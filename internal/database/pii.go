@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mitrich772/go-order-service/internal/pii"
+	"gorm.io/gorm"
+)
+
+// deliverySecrets возвращает поля доставки, которые хранятся зашифрованными.
+func deliverySecrets(d *Delivery) []*string {
+	return []*string{&d.Name, &d.Phone, &d.Email, &d.Address}
+}
+
+// paymentSecrets возвращает поля платежа, которые хранятся зашифрованными.
+func paymentSecrets(p *Payment) []*string {
+	return []*string{&p.Transaction, &p.Bank}
+}
+
+// EncryptOrder возвращает копию заказа с зашифрованными полями доставки и платежа
+// для записи в БД, общий кэш и снимок кэша. Исходный заказ не меняется; c == nil
+// возвращает его же.
+func EncryptOrder(c *pii.Cipher, order *Order) (*Order, error) {
+	if c == nil {
+		return order, nil
+	}
+	enc := *order
+	for _, f := range append(deliverySecrets(&enc.Delivery), paymentSecrets(&enc.Payment)...) {
+		v, err := c.Encrypt(*f)
+		if err != nil {
+			return nil, err
+		}
+		*f = v
+	}
+	return &enc, nil
+}
+
+// DecryptOrder расшифровывает поля доставки и платежа на месте.
+// Открытые значения остаются как есть.
+func DecryptOrder(c *pii.Cipher, order *Order) error {
+	for _, f := range append(deliverySecrets(&order.Delivery), paymentSecrets(&order.Payment)...) {
+		v, err := c.Decrypt(*f)
+		if err != nil {
+			return err
+		}
+		*f = v
+	}
+	return nil
+}
+
+// MaskOrder возвращает копию заказа с замаскированными персональными данными
+// для ответов вызывающим без права их видеть.
+func MaskOrder(order *Order) *Order {
+	m := *order
+	m.CustomerID = pii.Middle(m.CustomerID, 1, 0)
+	m.Delivery.Name = pii.Name(m.Delivery.Name)
+	m.Delivery.Phone = pii.Phone(m.Delivery.Phone)
+	m.Delivery.Email = pii.Email(m.Delivery.Email)
+	m.Delivery.Address = pii.Redact(m.Delivery.Address)
+	m.Payment.Transaction = pii.Tail(m.Payment.Transaction, 4)
+	m.Payment.Bank = pii.Middle(m.Payment.Bank, 1, 0)
+	return &m
+}
+
+// Rekey перешифровывает текущим ключом значения, зашифрованные старыми ключами
+// или записанные открытыми до включения шифрования, и пересчитывает слепые индексы
+// телефона и email. Возвращает число обновленных строк.
+// Запускается после ротации ключа; строки обрабатываются пачками по batch.
+func (r *GormDatabase) Rekey(ctx context.Context, batch int) (int, error) {
+	if r.cipher == nil {
+		return 0, errors.New("encryption is not enabled")
+	}
+	nd, err := rekeyTable(ctx, r, batch, deliverySecrets, r.refreshLookups)
+	if err != nil {
+		return nd, err
+	}
+	np, err := rekeyTable(ctx, r, batch, paymentSecrets, nil)
+	return nd + np, err
+}
+
+// refreshLookups пересчитывает слепые индексы доставки текущим ключом
+// и сообщает, изменились ли они.
+func (r *GormDatabase) refreshLookups(d *Delivery) (bool, error) {
+	phone, err := r.cipher.Decrypt(d.Phone)
+	if err != nil {
+		return false, err
+	}
+	email, err := r.cipher.Decrypt(d.Email)
+	if err != nil {
+		return false, err
+	}
+	plain := Delivery{Phone: phone, Email: email}
+	if err := setLookups(r.cipher, &plain); err != nil {
+		return false, err
+	}
+	if plain.PhoneLookup == d.PhoneLookup && plain.EmailLookup == d.EmailLookup {
+		return false, nil
+	}
+	d.PhoneLookup, d.EmailLookup = plain.PhoneLookup, plain.EmailLookup
+	return true, nil
+}
+
+// rekeyTable перешифровывает поля secrets во всех строках таблицы модели T;
+// refresh, если задан, обновляет производные поля строки.
+func rekeyTable[T any](ctx context.Context, r *GormDatabase, batch int, secrets func(*T) []*string, refresh func(*T) (bool, error)) (int, error) {
+	updated := 0
+	var rows []T
+	res := r.db.WithContext(ctx).FindInBatches(&rows, batch, func(tx *gorm.DB, _ int) error {
+		for i := range rows {
+			changed := false
+			for _, f := range secrets(&rows[i]) {
+				if !r.cipher.NeedsRekey(*f) {
+					continue
+				}
+				plain, err := r.cipher.Decrypt(*f)
+				if err != nil {
+					return err
+				}
+				if *f, err = r.cipher.Encrypt(plain); err != nil {
+					return err
+				}
+				changed = true
+			}
+			if refresh != nil {
+				refreshed, err := refresh(&rows[i])
+				if err != nil {
+					return err
+				}
+				changed = changed || refreshed
+			}
+			if !changed {
+				continue
+			}
+			if err := r.db.WithContext(ctx).Save(&rows[i]).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	return updated, res.Error
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mitrich772/go-order-service/internal/pii"
+)
+
+func testCipher(t *testing.T) *pii.Cipher {
+	t.Helper()
+	keys, err := pii.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, pii.KeySize)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pii.NewCipher(keys)
+}
+
+func TestEncryptOrder_KeepsOriginalPlain(t *testing.T) {
+	c := testCipher(t)
+	order := &Order{
+		OrderUID: "uid-1",
+		Delivery: Delivery{Name: "Ivan", Phone: "+79161231234", Email: "ivan@mail.ru", Address: "Lenina 1", City: "Moscow"},
+		Payment:  Payment{Transaction: "uid-1", Bank: "alpha"},
+	}
+
+	enc, err := EncryptOrder(c, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Delivery.Phone != "+79161231234" {
+		t.Fatal("original order must not be modified")
+	}
+	for _, v := range []string{enc.Delivery.Name, enc.Delivery.Phone, enc.Delivery.Email, enc.Delivery.Address, enc.Payment.Transaction, enc.Payment.Bank} {
+		if !pii.IsEncrypted(v) {
+			t.Fatalf("expected encrypted value, got %q", v)
+		}
+	}
+	if enc.Delivery.City != "Moscow" {
+		t.Fatal("non-sensitive fields must stay plain")
+	}
+
+	if err := DecryptOrder(c, enc); err != nil {
+		t.Fatal(err)
+	}
+	if enc.Delivery != order.Delivery || enc.Payment != order.Payment {
+		t.Fatalf("round trip mismatch: %+v %+v", enc.Delivery, enc.Payment)
+	}
+}
+
+func TestGetOrder_DecryptsStoredValues(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	c := testCipher(t)
+	repo := NewGormDatabase(gormDB, 1, 0)
+	repo.UseEncryption(c)
+
+	phone, _ := c.Encrypt("+79161231234")
+	bank, _ := c.Encrypt("alpha")
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(`SELECT (.+)FROM "orders"`).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}).AddRow("uid-1"))
+	mock.ExpectQuery(`SELECT (.+)FROM "deliveries"`).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "phone", "zip"}).AddRow("uid-1", phone, "101000"))
+	mock.ExpectQuery(`SELECT (.+)FROM "payments"`).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "bank"}).AddRow("uid-1", bank))
+	mock.ExpectQuery(`SELECT (.+)FROM "items"`).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+
+	order, err := repo.GetOrder(context.Background(), "uid-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Delivery.Phone != "+79161231234" || order.Payment.Bank != "alpha" || order.Delivery.Zip != "101000" {
+		t.Fatalf("unexpected decrypted order %+v %+v", order.Delivery, order.Payment)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaskOrder(t *testing.T) {
+	order := &Order{
+		OrderUID:   "uid-1",
+		CustomerID: "test",
+		Delivery:   Delivery{Name: "Ivan Petrov", Phone: "+79161231234", Email: "ivan@mail.ru", Address: "Lenina 1", City: "Moscow"},
+		Payment:    Payment{Transaction: "b563feb7b2b84b6test", Bank: "alpha"},
+	}
+	m := MaskOrder(order)
+	if m.Delivery.Phone != "+7******1234" || m.Delivery.Email != "i***@mail.ru" || m.Delivery.Name != "I*** P***" {
+		t.Fatalf("unexpected masked delivery %+v", m.Delivery)
+	}
+	if m.Delivery.Address != "***" || m.Payment.Transaction != "***************test" || m.Payment.Bank != "a****" {
+		t.Fatalf("unexpected masked values %+v %+v", m.Delivery, m.Payment)
+	}
+	if order.Delivery.Phone != "+79161231234" {
+		t.Fatal("original order must not be modified")
+	}
+}
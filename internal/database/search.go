@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// OrderSearch задает полнотекстовый поиск SearchOrders. Запросы — в синтаксисе
+// websearch_to_tsquery: слова через пробел (все обязательны), "фраза", or, -исключение.
+// Заданные запросы должны совпасть оба.
+type OrderSearch struct {
+	// Items ищется по названиям и брендам товаров заказа.
+	Items string
+	// Delivery ищется по городу, региону и адресу доставки.
+	Delivery string
+	// IncludeAddress разрешает совпадения по адресу: адрес — персональные данные.
+	IncludeAddress bool
+	// Offset и Limit задают страницу выдачи, Limit 0 — без ограничения.
+	Offset int
+	Limit  int
+}
+
+// SearchHit — заказ, найденный SearchOrders.
+type SearchHit struct {
+	OrderUID string
+	// Rank — релевантность: сумма рангов совпадений в товарах и в доставке, каждый в [0, 1).
+	Rank       float64
+	Highlights SearchHighlights
+}
+
+// SearchResult — найденный заказ с релевантностью и подсветкой, как его отдает хранилище
+// (cache.OrderStore.Search).
+type SearchResult struct {
+	Order      *Order
+	Rank       float64
+	Highlights SearchHighlights
+}
+
+// SearchHighlights — совпавшие поля с найденными словами в <mark>…</mark>.
+// Текст экранирован для HTML, теги <mark> — единственная разметка. Адрес не подсвечивается.
+type SearchHighlights struct {
+	Items  []ItemHighlight `json:"items,omitempty"`
+	City   string          `json:"city,omitempty"`
+	Region string          `json:"region,omitempty"`
+}
+
+// ItemHighlight — подсветка совпавшего товара; поле без совпадения пустое.
+type ItemHighlight struct {
+	NmID  int64  `json:"nm_id"`
+	Name  string `json:"name,omitempty"`
+	Brand string `json:"brand,omitempty"`
+}
+
+// Выражения запросов и параметры подсветки; конфигурация order_search и колонки
+// search создаются миграцией 000005. ts_headline отмечает совпадения символами из
+// области частного использования Unicode: текст партнеров экранируется в hit, и только
+// затем метки заменяются на <mark>. Сами метки из исходного текста удаляются в SQL.
+const (
+	itemsQuery    = "websearch_to_tsquery('order_search', @items)"
+	deliveryQuery = "websearch_to_tsquery('order_search', @delivery)"
+	markStart     = "\ue000"
+	markStop      = "\ue001"
+	headlineOpts  = "'StartSel=" + markStart + ", StopSel=" + markStop + ", HighlightAll=true'"
+)
+
+// markReplacer заменяет метки ts_headline на теги подсветки.
+var markReplacer = strings.NewReplacer(markStart, "<mark>", markStop, "</mark>")
+
+// searchRow — строка результата SearchOrders до разбора подсветки.
+type searchRow struct {
+	OrderUID string
+	Rank     float64
+	Items    string
+	City     string
+	Region   string
+}
+
+// SearchOrders ищет заказы по товарам и доставке в колонках tsvector с GIN-индексами
+// и возвращает их от более релевантных к менее, при равенстве — от новых к старым.
+// Без IncludeAddress совпадения только в адресе (вес D) не учитываются.
+// Выполняется с Retry для повторных попыток при временных ошибках БД.
+func (r *GormDatabase) SearchOrders(ctx context.Context, s OrderSearch) ([]SearchHit, error) {
+	s.Items, s.Delivery = strings.TrimSpace(s.Items), strings.TrimSpace(s.Delivery)
+	if s.Items == "" && s.Delivery == "" {
+		return nil, fmt.Errorf("%w: search query required", ErrInvalid)
+	}
+	query := searchSQL(s)
+	args := map[string]any{"items": s.Items, "delivery": s.Delivery, "offset": s.Offset}
+	if s.Limit > 0 {
+		args["limit"] = s.Limit
+	}
+	return withRetry(ctx, r, "search_orders", func(tx *gorm.DB) ([]SearchHit, error) {
+		var rows []searchRow
+		if err := tx.Raw(query, args).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		hits := make([]SearchHit, len(rows))
+		for i, row := range rows {
+			hit, err := row.hit()
+			if err != nil {
+				return nil, err
+			}
+			hits[i] = hit
+		}
+		return hits, nil
+	})
+}
+
+// searchSQL собирает запрос: сначала страница uid с рангом (условия используют
+// GIN-индексы), затем подсветка только для нее.
+func searchSQL(s OrderSearch) string {
+	var where, rank []string
+	itemsHL, cityHL, regionHL := "''", "''", "''"
+	if s.Items != "" {
+		where = append(where, "o.order_uid IN (SELECT i.order_uid FROM items i WHERE i.search @@ "+itemsQuery+")")
+		rank = append(rank, "coalesce((SELECT max(ts_rank_cd(i.search, "+itemsQuery+", 32)) FROM items i"+
+			" WHERE i.order_uid = o.order_uid AND i.search @@ "+itemsQuery+"), 0)")
+		itemsHL = fmt.Sprintf("coalesce((SELECT json_agg(json_build_object('nm_id', i.nm_id,"+
+			" 'name', %s, 'brand', %s) ORDER BY i.item_id)"+
+			" FROM items i WHERE i.order_uid = h.order_uid AND i.search @@ %s)::text, '')",
+			headline("i.name", itemsQuery), headline("i.brand", itemsQuery), itemsQuery)
+	}
+	if s.Delivery != "" {
+		vector := "d.search"
+		where = append(where, "d.search @@ "+deliveryQuery)
+		if !s.IncludeAddress {
+			vector = "ts_filter(d.search, '{a,b}')"
+			where = append(where, vector+" @@ "+deliveryQuery)
+		}
+		rank = append(rank, "ts_rank_cd("+vector+", "+deliveryQuery+", 32)")
+		cityHL = headline("d.city", deliveryQuery)
+		regionHL = headline("d.region", deliveryQuery)
+	}
+	limit := ""
+	if s.Limit > 0 {
+		limit = " LIMIT @limit"
+	}
+	return "WITH hits AS (SELECT o.order_uid, o.date_created, " + strings.Join(rank, " + ") + " AS rank" +
+		" FROM orders o JOIN deliveries d ON d.order_uid = o.order_uid" +
+		" WHERE " + strings.Join(where, " AND ") +
+		" ORDER BY rank DESC, o.date_created DESC, o.order_uid DESC" + limit + " OFFSET @offset)" +
+		" SELECT h.order_uid, h.rank, " + itemsHL + " AS items, " + cityHL + " AS city, " + regionHL + " AS region" +
+		" FROM hits h JOIN deliveries d ON d.order_uid = h.order_uid" +
+		" ORDER BY h.rank DESC, h.date_created DESC, h.order_uid DESC"
+}
+
+// headline подсвечивает совпадения query в column метками markStart/markStop,
+// предварительно удалив их из текста.
+func headline(column, query string) string {
+	return "ts_headline('order_search', translate(" + column + ", '" + markStart + markStop + "', ''), " +
+		query + ", " + headlineOpts + ")"
+}
+
+// hit разбирает подсветку; поля, в которых ничего не найдено, очищаются.
+func (row searchRow) hit() (SearchHit, error) {
+	hit := SearchHit{OrderUID: row.OrderUID, Rank: row.Rank}
+	if row.Items != "" {
+		if err := json.Unmarshal([]byte(row.Items), &hit.Highlights.Items); err != nil {
+			return hit, fmt.Errorf("decode item highlights: %w", err)
+		}
+		for i := range hit.Highlights.Items {
+			it := &hit.Highlights.Items[i]
+			it.Name, it.Brand = marked(it.Name), marked(it.Brand)
+		}
+	}
+	hit.Highlights.City, hit.Highlights.Region = marked(row.City), marked(row.Region)
+	return hit, nil
+}
+
+// marked экранирует текст для HTML и заменяет метки совпадений на <mark>.
+func marked(s string) string {
+	if !strings.Contains(s, markStart) {
+		return ""
+	}
+	return markReplacer.Replace(html.EscapeString(s))
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// Проверяет: запрос по товарам и доставке использует обе колонки search, без доступа
+// к персональным данным адрес отфильтрован, подсветка без совпадений очищается
+func TestSearchOrders(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	repo := NewGormDatabase(gormDB, 1, 0)
+	items := `[{"nm_id": 1, "name": "Кроссовки \ue000Nike\ue001 Air", "brand": "\ue000Nike\ue001"},` +
+		`{"nm_id": 2, "name": "Носки", "brand": "\ue000Nike\ue001"}]`
+	mock.ExpectQuery(`WITH hits AS \(SELECT (.+) FROM orders o JOIN deliveries d ON d.order_uid = o.order_uid ` +
+		`WHERE o.order_uid IN \(SELECT i.order_uid FROM items i WHERE i.search @@ websearch_to_tsquery\('order_search', \$\d+\)\) ` +
+		`AND d.search @@ websearch_to_tsquery\('order_search', \$\d+\) AND ts_filter\(d.search, '\{a,b\}'\) @@ (.+) LIMIT \$\d+ OFFSET \$\d+\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "rank", "items", "city", "region"}).
+			AddRow("a", 0.5, items, markStart+"Казань"+markStop, "Татарстан"))
+
+	hits, err := repo.SearchOrders(context.Background(), OrderSearch{Items: " nike ", Delivery: "казань", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].OrderUID != "a" || hits[0].Rank != 0.5 {
+		t.Fatalf("unexpected hits %+v", hits)
+	}
+	hl := hits[0].Highlights
+	if len(hl.Items) != 2 || hl.Items[0].Name != "Кроссовки <mark>Nike</mark> Air" || hl.Items[1].Name != "" ||
+		hl.Items[1].Brand != "<mark>Nike</mark>" || hl.City != "<mark>Казань</mark>" || hl.Region != "" {
+		t.Fatalf("unexpected highlights %+v", hl)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.SearchOrders(context.Background(), OrderSearch{Items: "  "}); err == nil {
+		t.Fatal("expected error for empty query")
+	}
+}
+
+// Проверяет: текст партнера экранируется, разметкой остаются только метки совпадений,
+// а метки из исходного текста удаляются до ts_headline
+func TestSearchHighlights_Escaped(t *testing.T) {
+	got := marked(`<script>alert("x")</script> ` + markStart + `Nike&Co` + markStop)
+	want := `&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <mark>Nike&amp;Co</mark>`
+	if got != want {
+		t.Fatalf("marked() = %q, want %q", got, want)
+	}
+	if marked("<mark>Nike</mark>") != "" {
+		t.Fatal("expected literal <mark> in source text not to count as a match")
+	}
+	if q := searchSQL(OrderSearch{Items: "nike"}); !strings.Contains(q, "translate(i.name, '"+markStart+markStop+"', '')") {
+		t.Fatalf("expected markers to be stripped from source text: %s", q)
+	}
+}
+
+// Проверяет: с IncludeAddress совпадения по адресу не отфильтровываются,
+// без запроса по товарам таблица items не участвует
+func TestSearchSQL_Address(t *testing.T) {
+	q := searchSQL(OrderSearch{Delivery: "ленина", IncludeAddress: true})
+	if strings.Contains(q, "ts_filter") || strings.Contains(q, "items i") || strings.Contains(q, "LIMIT") {
+		t.Fatalf("unexpected query %s", q)
+	}
+}
@@ -2,7 +2,7 @@ package database
 
 import (
 	"fmt"
-	"log"
+	"reflect"
 	"strings"
 	"time"
 
@@ -12,6 +12,14 @@ import (
 // NewValidator создает новый валидатор с кастомными проверками
 func NewValidator() *validator.Validate {
 	v := validator.New()
+	// Пути полей в ошибках — как в JSON заказа (delivery.phone), их видят клиенты API
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 
 	// Проверка дата не из будущего
 	err := v.RegisterValidation("notfuture", func(fl validator.FieldLevel) bool {
@@ -19,13 +27,15 @@ func NewValidator() *validator.Validate {
 		return ok && !t.IsZero() && !t.After(time.Now())
 	})
 	if err != nil {
-		log.Fatalf("failed to register validation: %v", err)
+		panic(fmt.Errorf("failed to register validation: %w", err))
 	}
 
-	// Проверка согласованности track_number в items и заказе
-	v.RegisterStructValidation(orderStructLevelValidation, Order{})
-	// Проверка сумм: total(items) + delivery + custom_fee = payment.amount
-	v.RegisterStructValidation(validateOrderTotal, Order{})
+	// Для типа действует одна struct-level проверка (повторная регистрация заменяет
+	// прежнюю), поэтому согласованность track_number и сумм проверяются вместе
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		orderStructLevelValidation(sl)
+		validateOrderTotal(sl)
+	}, Order{})
 
 	return v
 }
@@ -35,7 +45,7 @@ func orderStructLevelValidation(sl validator.StructLevel) {
 	order := sl.Current().Interface().(Order)
 	for i, item := range order.Items {
 		if item.TrackNumber != order.TrackNumber {
-			sl.ReportError(item.TrackNumber, fmt.Sprintf("Items[%d].TrackNumber", i), "track_number", "trackmatch", order.TrackNumber)
+			sl.ReportError(item.TrackNumber, fmt.Sprintf("items[%d].track_number", i), fmt.Sprintf("Items[%d].TrackNumber", i), "trackmatch", order.TrackNumber)
 		}
 	}
 }
@@ -51,11 +61,35 @@ func validateOrderTotal(sl validator.StructLevel) {
 	total := sum + order.Payment.DeliveryCost + order.Payment.CustomFee
 	diff := total - order.Payment.Amount
 	if diff < -0.01 || diff > 0.01 {
-		sl.ReportError(order.Payment.Amount, "Payment.Amount", "payment.amount", "totalmatch", fmt.Sprintf("%.2f", total))
+		sl.ReportError(order.Payment.Amount, "payment.amount", "Payment.Amount", "totalmatch", fmt.Sprintf("%.2f", total))
 	}
 }
 
-// ValidateOrder проверяет заказ на несоответствия
+// FieldError — ошибка проверки одного поля заказа.
+type FieldError struct {
+	// Field — путь поля в JSON заказа: delivery.phone, items[0].track_number.
+	Field string `json:"field"`
+	// Rule — нарушенное правило: required, email, trackmatch и т.п.
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError — заказ не прошел проверку. Содержит ошибки по каждому полю,
+// чтобы HTTP API мог вернуть их клиенту списком.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	out := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		out[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(out, "; ")
+}
+
+// ValidateOrder проверяет заказ на несоответствия.
+// Ошибки проверки полей возвращаются как *ValidationError.
 func ValidateOrder(order *Order) error {
 	v := NewValidator()
 	if err := v.Struct(order); err != nil {
@@ -67,31 +101,39 @@ func ValidateOrder(order *Order) error {
 	return nil
 }
 
-// Проходит ошибки валидатора и выводит их
+// Проходит ошибки валидатора и переводит их в ValidationError
 func formatValidationErrors(errs validator.ValidationErrors) error {
-	var out []string
+	out := &ValidationError{Fields: make([]FieldError, 0, len(errs))}
 	for _, e := range errs {
-		field := e.StructNamespace()
-		switch e.Tag() {
-		case "required":
-			out = append(out, fmt.Sprintf("%s: обязательное поле", field))
-		case "email":
-			out = append(out, fmt.Sprintf("%s: некорректный email", field))
-		case "e164":
-			out = append(out, fmt.Sprintf("%s: телефон должен быть в формате +71234567890", field))
-		case "gt", "gte":
-			out = append(out, fmt.Sprintf("%s: значение должно быть >= 0", field))
-		case "len":
-			out = append(out, fmt.Sprintf("%s: длина должна быть %s", field, e.Param()))
-		case "min":
-			out = append(out, fmt.Sprintf("%s: минимум %s элементов", field, e.Param()))
-		case "notfuture":
-			out = append(out, fmt.Sprintf("%s: дата не может быть в будущем", field))
-		case "trackmatch":
-			out = append(out, fmt.Sprintf("%s: track_number не совпадает с заказом (%s)", field, e.Param()))
-		default:
-			out = append(out, fmt.Sprintf("%s: ошибка валидации (%s)", field, e.Tag()))
-		}
+		// Namespace начинается с имени структуры: Order.delivery.phone
+		_, field, _ := strings.Cut(e.Namespace(), ".")
+		out.Fields = append(out.Fields, FieldError{Field: field, Rule: e.Tag(), Message: fieldMessage(e)})
+	}
+	return out
+}
+
+// fieldMessage описывает нарушенное правило для человека.
+func fieldMessage(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required":
+		return "обязательное поле"
+	case "email":
+		return "некорректный email"
+	case "e164":
+		return "телефон должен быть в формате +71234567890"
+	case "gt", "gte":
+		return "значение должно быть >= 0"
+	case "len":
+		return fmt.Sprintf("длина должна быть %s", e.Param())
+	case "min":
+		return fmt.Sprintf("минимум %s элементов", e.Param())
+	case "notfuture":
+		return "дата не может быть в будущем"
+	case "trackmatch":
+		return fmt.Sprintf("track_number не совпадает с заказом (%s)", e.Param())
+	case "totalmatch":
+		return fmt.Sprintf("сумма не совпадает с позициями и доставкой (%s)", e.Param())
+	default:
+		return fmt.Sprintf("ошибка валидации (%s)", e.Tag())
 	}
-	return fmt.Errorf("%s", strings.Join(out, "; "))
 }
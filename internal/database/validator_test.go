@@ -0,0 +1,48 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/producer/generate"
+)
+
+// Проверяет: сгенерированный заказ проходит проверку
+func TestValidateOrder_Valid(t *testing.T) {
+	o := generate.MakeOrder()
+	if err := database.ValidateOrder(&o); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+}
+
+// Проверяет: ошибки возвращаются как ValidationError с путями полей в JSON заказа
+func TestValidateOrder_FieldErrors(t *testing.T) {
+	o := generate.MakeOrder()
+	o.Delivery.Phone = ""
+	o.Items[0].TrackNumber = "OTHER"
+	o.Payment.Amount += 100
+
+	err := database.ValidateOrder(&o)
+	var ve *database.ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("ожидали *ValidationError, получили %v", err)
+	}
+	got := map[string]string{}
+	for _, f := range ve.Fields {
+		got[f.Field] = f.Rule
+		if f.Message == "" {
+			t.Errorf("%s: пустое сообщение", f.Field)
+		}
+	}
+	want := map[string]string{
+		"delivery.phone":        "required",
+		"items[0].track_number": "trackmatch",
+		"payment.amount":        "totalmatch",
+	}
+	for field, rule := range want {
+		if got[field] != rule {
+			t.Errorf("%s: правило %q, ожидали %q (все ошибки: %v)", field, got[field], rule, got)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Package feed рассылает сохраненные заказы подписчикам в пределах экземпляра сервиса
+// (gRPC WatchOrders, SSE и WebSocket).
+package feed
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+// ErrLagged — подписчик не успевал забирать заказы и был отключен.
+// Пропущенные заказы можно дочитать, подписавшись заново с номером последнего
+// полученного события, или через ListOrders.
+var ErrLagged = errors.New("subscriber lagged behind")
+
+// ErrClosed — лента закрыта (сервис останавливается).
+var ErrClosed = errors.New("feed closed")
+
+// Event — опубликованный заказ и его номер в ленте.
+type Event struct {
+	ID    uint64
+	Order *database.Order
+}
+
+// Feed раздает каждый опубликованный заказ всем подписчикам и хранит последние
+// события для продолжения с места обрыва (Last-Event-ID).
+// Publish не блокируется: подписчик с заполненным буфером отключается с ErrLagged,
+// чтобы медленный клиент не задерживал сохранение заказов.
+// Методы безопасно вызывать на nil: тогда лента выключена.
+type Feed struct {
+	buffer int
+
+	mu      sync.Mutex
+	subs    map[*Subscription]struct{}
+	closed  bool
+	lastID  uint64
+	history []Event // кольцевой буфер, next — место следующей записи
+	next    int
+	full    bool
+}
+
+// New создает ленту с буфером buffer событий на подписчика и историей
+// из history последних событий (0 — без продолжения с места обрыва).
+// Номера событий начинаются с текущего времени в микросекундах, поэтому номер,
+// полученный до перезапуска сервиса, всегда меньше новых и распознается как устаревший.
+func New(buffer, history int) *Feed {
+	return &Feed{
+		buffer:  max(buffer, 1),
+		subs:    make(map[*Subscription]struct{}),
+		lastID:  uint64(time.Now().UnixMicro()),
+		history: make([]Event, max(history, 0)),
+	}
+}
+
+// Publish рассылает заказ подписчикам. Заказ передается подписчикам как есть
+// и после публикации не должен изменяться.
+func (f *Feed) Publish(order *database.Order) {
+	if f == nil || order == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastID++
+	ev := Event{ID: f.lastID, Order: order}
+	if len(f.history) > 0 {
+		f.history[f.next] = ev
+		f.next = (f.next + 1) % len(f.history)
+		f.full = f.full || f.next == 0
+	}
+	for sub := range f.subs {
+		select {
+		case sub.ch <- ev:
+		default:
+			f.drop(sub, ErrLagged)
+		}
+	}
+}
+
+// Subscribe подписывает на новые заказы. Если after не 0, сначала отправляются
+// события из истории с номером больше after; Missed сообщает, что часть
+// событий после after в истории уже не сохранилась. Подписку нужно закрыть через Close.
+func (f *Feed) Subscribe(after uint64) (*Subscription, error) {
+	if f == nil {
+		return nil, ErrClosed
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, ErrClosed
+	}
+	var replay []Event
+	missed := false
+	if after != 0 {
+		replay, missed = f.since(after)
+	}
+	sub := &Subscription{feed: f, ch: make(chan Event, f.buffer+len(replay)), missed: missed}
+	for _, ev := range replay {
+		sub.ch <- ev
+	}
+	f.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// since возвращает события истории с номером больше after и признак пропуска;
+// вызывается под f.mu.
+func (f *Feed) since(after uint64) ([]Event, bool) {
+	if after >= f.lastID {
+		// Все уже получено; номер из будущего — от другого экземпляра или до перезапуска.
+		return nil, after > f.lastID
+	}
+	var events []Event
+	if f.full {
+		events = append(events, f.history[f.next:]...)
+	}
+	events = append(events, f.history[:f.next]...)
+	if len(events) == 0 {
+		return nil, true
+	}
+	oldest := events[0].ID
+	if after < oldest-1 {
+		return events, true
+	}
+	return events[after-oldest+1:], false
+}
+
+// Subscribers возвращает число активных подписок.
+func (f *Feed) Subscribers() int {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs)
+}
+
+// Close закрывает все подписки с ErrClosed; новые подписки не принимаются.
+func (f *Feed) Close() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for sub := range f.subs {
+		f.drop(sub, ErrClosed)
+	}
+}
+
+// drop отключает подписчика; вызывается под f.mu.
+func (f *Feed) drop(sub *Subscription, err error) {
+	delete(f.subs, sub)
+	sub.err = err
+	close(sub.ch)
+}
+
+// Subscription — подписка на ленту заказов.
+type Subscription struct {
+	feed   *Feed
+	ch     chan Event
+	err    error
+	missed bool
+}
+
+// C возвращает канал событий. Канал закрывается при отключении подписки,
+// причина — в Err.
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+// Missed сообщает, что при продолжении с номера after часть событий была потеряна:
+// подписчику стоит заново загрузить состояние (например, через ListOrders).
+func (s *Subscription) Missed() bool {
+	return s.missed
+}
+
+// Err возвращает причину закрытия канала: ErrLagged, ErrClosed или nil после Close.
+func (s *Subscription) Err() error {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	return s.err
+}
+
+// Close отписывает от ленты. Повторный вызов безопасен.
+func (s *Subscription) Close() {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	if _, ok := s.feed.subs[s]; ok {
+		s.feed.drop(s, nil)
+	}
+}
@@ -0,0 +1,133 @@
+package feed
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+// Проверяет: заказ получают все подписчики, после Close подписка больше не получает заказы
+func TestFeed_PublishToAll(t *testing.T) {
+	f := New(4, 0)
+	a, _ := f.Subscribe(0)
+	b, _ := f.Subscribe(0)
+
+	f.Publish(&database.Order{OrderUID: "1"})
+	for _, sub := range []*Subscription{a, b} {
+		if ev := <-sub.C(); ev.Order.OrderUID != "1" {
+			t.Fatalf("получили %q, ожидали 1", ev.Order.OrderUID)
+		}
+	}
+
+	a.Close()
+	a.Close()
+	f.Publish(&database.Order{OrderUID: "2"})
+	if _, ok := <-a.C(); ok || a.Err() != nil {
+		t.Fatalf("закрытая подписка: ожидали закрытый канал без ошибки, err=%v", a.Err())
+	}
+	if ev := <-b.C(); ev.Order.OrderUID != "2" {
+		t.Fatalf("получили %q, ожидали 2", ev.Order.OrderUID)
+	}
+	if n := f.Subscribers(); n != 1 {
+		t.Fatalf("подписчиков %d, ожидали 1", n)
+	}
+}
+
+// Проверяет: подписчик с полным буфером отключается с ErrLagged, не блокируя Publish
+func TestFeed_SlowSubscriberDropped(t *testing.T) {
+	f := New(1, 0)
+	slow, _ := f.Subscribe(0)
+
+	f.Publish(&database.Order{OrderUID: "1"})
+	f.Publish(&database.Order{OrderUID: "2"})
+
+	if ev := <-slow.C(); ev.Order.OrderUID != "1" {
+		t.Fatalf("получили %q, ожидали 1", ev.Order.OrderUID)
+	}
+	if _, ok := <-slow.C(); ok {
+		t.Fatal("ожидали закрытый канал")
+	}
+	if !errors.Is(slow.Err(), ErrLagged) {
+		t.Fatalf("ожидали ErrLagged, получили %v", slow.Err())
+	}
+}
+
+// Проверяет: Close закрывает подписки с ErrClosed и запрещает новые
+func TestFeed_Close(t *testing.T) {
+	f := New(1, 0)
+	sub, _ := f.Subscribe(0)
+	f.Close()
+
+	if _, ok := <-sub.C(); ok || !errors.Is(sub.Err(), ErrClosed) {
+		t.Fatalf("ожидали закрытый канал с ErrClosed, err=%v", sub.Err())
+	}
+	if _, err := f.Subscribe(0); !errors.Is(err, ErrClosed) {
+		t.Fatalf("ожидали ErrClosed, получили %v", err)
+	}
+
+	var nilFeed *Feed
+	nilFeed.Publish(&database.Order{})
+	nilFeed.Close()
+}
+
+// publishN публикует заказы с uid "1".."n" и возвращает их события из подписки.
+func publishN(t *testing.T, f *Feed, n int) []Event {
+	t.Helper()
+	sub, _ := f.Subscribe(0)
+	defer sub.Close()
+	events := make([]Event, n)
+	for i := range n {
+		f.Publish(&database.Order{OrderUID: strconv.Itoa(i + 1)})
+		events[i] = <-sub.C()
+	}
+	return events
+}
+
+// Проверяет: подписка с номером события получает из истории только более поздние события
+func TestFeed_Resume(t *testing.T) {
+	f := New(1, 10)
+	events := publishN(t, f, 5)
+	if events[1].ID != events[0].ID+1 {
+		t.Fatalf("номера событий должны идти подряд: %d, %d", events[0].ID, events[1].ID)
+	}
+
+	sub, _ := f.Subscribe(events[2].ID)
+	defer sub.Close()
+	if sub.Missed() {
+		t.Fatal("события после номера сохранены, пропуска быть не должно")
+	}
+	for _, want := range []string{"4", "5"} {
+		if ev := <-sub.C(); ev.Order.OrderUID != want {
+			t.Fatalf("получили %q, ожидали %s", ev.Order.OrderUID, want)
+		}
+	}
+
+	f.Publish(&database.Order{OrderUID: "6"})
+	if ev := <-sub.C(); ev.Order.OrderUID != "6" {
+		t.Fatalf("после истории ожидали новое событие 6, получили %q", ev.Order.OrderUID)
+	}
+}
+
+// Проверяет: номер старше истории или из другого запуска помечает подписку как Missed
+func TestFeed_ResumeMissed(t *testing.T) {
+	f := New(1, 2)
+	events := publishN(t, f, 5)
+
+	sub, _ := f.Subscribe(events[0].ID)
+	if !sub.Missed() {
+		t.Fatal("события 2 нет в истории: ожидали Missed")
+	}
+	if ev := <-sub.C(); ev.Order.OrderUID != "4" {
+		t.Fatalf("ожидали историю с 4, получили %q", ev.Order.OrderUID)
+	}
+	sub.Close()
+
+	if sub, _ := f.Subscribe(events[4].ID + 100); !sub.Missed() {
+		t.Fatal("номер из будущего: ожидали Missed")
+	}
+	if sub, _ := f.Subscribe(events[4].ID); sub.Missed() || len(sub.C()) != 0 {
+		t.Fatal("последний номер: ожидали подписку без истории и пропуска")
+	}
+}
@@ -0,0 +1,93 @@
+package grpcapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/mitrich772/go-order-service/api/orderpb"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// orderToProto переводит заказ в сообщение API.
+func orderToProto(o *database.Order) *orderpb.Order {
+	items := make([]*orderpb.Item, len(o.Items))
+	for i, it := range o.Items {
+		items[i] = &orderpb.Item{
+			ChrtId:      it.ChrtID,
+			TrackNumber: it.TrackNumber,
+			Price:       it.Price,
+			Rid:         it.RID,
+			Name:        it.Name,
+			Sale:        it.Sale,
+			Size:        it.Size,
+			TotalPrice:  it.TotalPrice,
+			NmId:        it.NmID,
+			Brand:       it.Brand,
+			Status:      int32(it.Status),
+		}
+	}
+	return &orderpb.Order{
+		OrderUid:          o.OrderUID,
+		TrackNumber:       o.TrackNumber,
+		Entry:             o.Entry,
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerId:        o.CustomerID,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.ShardKey,
+		SmId:              int32(o.SmID),
+		DateCreated:       timestamppb.New(o.DateCreated),
+		OofShard:          o.OofShard,
+		Delivery: &orderpb.Delivery{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: &orderpb.Payment{
+			Transaction:  o.Payment.Transaction,
+			RequestId:    o.Payment.RequestID,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       o.Payment.Amount,
+			PaymentDt:    o.Payment.PaymentDT,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: o.Payment.DeliveryCost,
+			GoodsTotal:   o.Payment.GoodsTotal,
+			CustomFee:    o.Payment.CustomFee,
+		},
+		Items: items,
+	}
+}
+
+// errBadPageToken — page_token поврежден или выдан не этим сервисом.
+var errBadPageToken = errors.New("invalid page_token")
+
+// pageToken — курсор ListOrders, передаваемый клиенту непрозрачной строкой.
+type pageToken struct {
+	DateCreated time.Time `json:"t"`
+	OrderUID    string    `json:"u"`
+}
+
+func encodePageToken(c database.OrderCursor) string {
+	data, _ := json.Marshal(pageToken{DateCreated: c.DateCreated, OrderUID: c.OrderUID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(s string) (*database.OrderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errBadPageToken
+	}
+	var t pageToken
+	if err := json.Unmarshal(data, &t); err != nil || t.OrderUID == "" {
+		return nil, errBadPageToken
+	}
+	return &database.OrderCursor{DateCreated: t.DateCreated, OrderUID: t.OrderUID}, nil
+}
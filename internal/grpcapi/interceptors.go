@@ -0,0 +1,192 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/textproto"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/mitrich772/go-order-service/api/orderpb"
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"github.com/mitrich772/go-order-service/internal/tracing"
+	"github.com/mitrich772/go-order-service/internal/web"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	ctx, header, done := s.begin(ctx, info.FullMethod)
+	defer func() { done(err) }()
+	if ctx, err = s.authorize(ctx, header); err != nil {
+		return nil, err
+	}
+	client := s.clientKey(ctx)
+	if err = s.allow(ctx, client, requestCost(req)); err != nil {
+		return nil, err
+	}
+	resp, err = handler(ctx, req)
+	s.RateLimit.NotFound(client, notFoundCount(resp, err))
+	return resp, err
+}
+
+func (s *Server) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	ctx, header, done := s.begin(ss.Context(), info.FullMethod)
+	defer func() { done(err) }()
+	if ctx, err = s.authorize(ctx, header); err != nil {
+		return err
+	}
+	if err = s.allow(ctx, s.clientKey(ctx), 1); err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// begin готовит контекст вызова так же, как HTTP middleware: request_id из метаданных
+// x-request-id (или новый, возвращается клиенту в заголовке ответа) и серверный спан
+// с родителем из traceparent. done пишет лог, метрики и завершает спан.
+func (s *Server) begin(ctx context.Context, method string) (context.Context, http.Header, func(error)) {
+	start := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := make(http.Header, len(md))
+	for k, v := range md {
+		header[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+
+	id := header.Get(logging.HeaderRequestID)
+	if !logging.ValidRequestID(id) {
+		id = logging.NewRequestID()
+	}
+	ctx = logging.WithRequestID(ctx, id)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(logging.HeaderRequestID, id))
+
+	service, rpc := path.Split(method)
+	ctx = tracing.Propagator.Extract(ctx, propagation.HeaderCarrier(header))
+	ctx, span := tracing.Start(ctx, method[1:], trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service[1:len(service)-1]),
+			attribute.String("rpc.method", rpc),
+		))
+
+	return ctx, header, func(err error) {
+		code := status.Code(err)
+		span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+		level := slog.LevelInfo
+		if serverFault(code) {
+			level = slog.LevelError
+			span.SetStatus(otelcodes.Error, code.String())
+		}
+		span.End()
+		s.Metrics.GRPCCall(method, code.String(), time.Since(start))
+		slog.Log(ctx, level, "grpc request",
+			slog.String("method", method),
+			slog.String("code", code.String()),
+			slog.Duration("duration", time.Since(start)),
+		)
+	}
+}
+
+// serverFault сообщает, что код означает ошибку сервиса, а не запроса клиента.
+func serverFault(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// authorize определяет вызывающего по метаданным теми же правилами, что HTTP:
+// неверные учетные данные — UNAUTHENTICATED; при включенном Auth без них — тоже,
+// роль без доступа к заказам — PERMISSION_DENIED.
+func (s *Server) authorize(ctx context.Context, header http.Header) (context.Context, error) {
+	p, err := s.Auth.AuthenticateHeader(header)
+	switch {
+	case err == nil:
+		if !p.Role.CanReadOrders() {
+			return ctx, status.Error(codes.PermissionDenied, "forbidden")
+		}
+		ctx = web.WithPrincipal(ctx, p)
+		return logging.With(ctx, slog.String("subject", p.Subject), slog.String("role", string(p.Role))), nil
+	case errors.Is(err, web.ErrNoCredentials):
+		if s.Auth != nil {
+			return ctx, status.Error(codes.Unauthenticated, "credentials required")
+		}
+		return ctx, nil
+	default:
+		slog.WarnContext(ctx, "Отказ в аутентификации", logging.Err(err))
+		return ctx, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+}
+
+// allow применяет лимиты группы order, общие с HTTP API: корзину запросов клиента
+// (стоимость — cost запросов) и бюджет ответов «не найдено». При превышении —
+// RESOURCE_EXHAUSTED с RetryInfo и метаданными retry-after (секунды).
+func (s *Server) allow(ctx context.Context, client string, cost int) error {
+	wait, ok := s.RateLimit.Allow(ctx, web.RouteOrder, client, cost)
+	if ok {
+		wait, ok = s.RateLimit.AllowNotFound(ctx, web.RouteOrder, client)
+	}
+	if ok {
+		return nil
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(web.RetryAfterSeconds(wait))))
+	st, err := status.New(codes.ResourceExhausted, "too many requests").
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, "too many requests")
+	}
+	return st.Err()
+}
+
+// clientKey определяет клиента для лимитов, как HTTP: subject вызывающего или IP соединения.
+func (s *Server) clientKey(ctx context.Context) string {
+	var remote string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	return web.ClientKey(ctx, remote)
+}
+
+// requestCost — стоимость вызова в запросах: BatchGetOrders читает каждый uid отдельно
+// и стоит столько же, сколько GetOrder на каждый (не больше maxBatchGet).
+func requestCost(req any) int {
+	if r, ok := req.(*orderpb.BatchGetOrdersRequest); ok {
+		return min(max(len(r.GetOrderUids()), 1), maxBatchGet)
+	}
+	return 1
+}
+
+// notFoundCount — число ненайденных заказов в ответе для бюджета «не найдено».
+func notFoundCount(resp any, err error) int {
+	if status.Code(err) == codes.NotFound {
+		return 1
+	}
+	if r, ok := resp.(*orderpb.BatchGetOrdersResponse); ok {
+		return len(r.GetNotFound())
+	}
+	return 0
+}
+
+// serverStream подменяет контекст потока контекстом с вызывающим и request_id.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
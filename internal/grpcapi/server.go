@@ -0,0 +1,317 @@
+// Package grpcapi — gRPC API заказов (orderpb.OrderService) поверх того же
+// хранилища, аутентификации и журнала аудита, что и HTTP API.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/mitrich772/go-order-service/api/orderpb"
+	"github.com/mitrich772/go-order-service/internal/cache"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/feed"
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"github.com/mitrich772/go-order-service/internal/metrics"
+	"github.com/mitrich772/go-order-service/internal/web"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Ограничения запросов.
+const (
+	maxBatchGet     = 100
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// Server реализует orderpb.OrderService.
+type Server struct {
+	orderpb.UnimplementedOrderServiceServer
+
+	Store cache.OrderStore
+	// Feed — лента сохраненных заказов для WatchOrders, nil отключает метод.
+	Feed *feed.Feed
+	// Auth — проверка API-ключей и JWT (метаданные x-api-key, authorization).
+	// Если задан, вызовы доступны только ролям support, analyst и admin;
+	// nil оставляет API открытым с маскированием персональных данных.
+	Auth *web.Authenticator
+	// Audit — журнал обращений к заказам, nil отключает его.
+	Audit *web.AuditLog
+	// Metrics — метрики вызовов, nil отключает их.
+	Metrics *metrics.Metrics
+	// RateLimit — лимиты группы order, общие с HTTP API (корзина клиента и бюджет
+	// «не найдено»); BatchGetOrders стоит запрос и промах за каждый uid. nil отключает лимиты.
+	RateLimit *web.RateLimiter
+	// UnmaskedPII отдает персональные данные без маски всем вызывающим (только для разработки).
+	UnmaskedPII bool
+}
+
+// GetOrder возвращает заказ по order_uid.
+func (s *Server) GetOrder(ctx context.Context, req *orderpb.GetOrderRequest) (*orderpb.Order, error) {
+	uid := req.GetOrderUid()
+	if uid == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_uid required")
+	}
+	order, err := s.Store.Get(ctx, uid)
+	if err != nil {
+		if database.IsNotFound(err) {
+			s.audit(ctx, uid, web.AuditNotFound, false)
+		}
+		return nil, storeError(ctx, err)
+	}
+	return s.present(ctx, order), nil
+}
+
+// BatchGetOrders возвращает найденные заказы в порядке запроса и uid отсутствующих.
+// Заказы читаются через кэш, как GetOrder.
+func (s *Server) BatchGetOrders(ctx context.Context, req *orderpb.BatchGetOrdersRequest) (*orderpb.BatchGetOrdersResponse, error) {
+	uids := req.GetOrderUids()
+	if len(uids) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order_uids required")
+	}
+	if len(uids) > maxBatchGet {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d order_uids per request", maxBatchGet)
+	}
+	resp := &orderpb.BatchGetOrdersResponse{Orders: make([]*orderpb.Order, 0, len(uids))}
+	for _, uid := range uids {
+		if uid == "" {
+			return nil, status.Error(codes.InvalidArgument, "order_uids must not be empty")
+		}
+		order, err := s.Store.Get(ctx, uid)
+		switch {
+		case err == nil:
+			resp.Orders = append(resp.Orders, s.present(ctx, order))
+		case database.IsNotFound(err):
+			s.audit(ctx, uid, web.AuditNotFound, false)
+			resp.NotFound = append(resp.NotFound, uid)
+		default:
+			return nil, storeError(ctx, err)
+		}
+	}
+	return resp, nil
+}
+
+// ListOrders выбирает заказы по фильтрам от новых к старым. Страницы задаются курсором:
+// next_page_token указывает на последний выданный заказ.
+func (s *Server) ListOrders(ctx context.Context, req *orderpb.ListOrdersRequest) (*orderpb.ListOrdersResponse, error) {
+	size := int(req.GetPageSize())
+	switch {
+	case size < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case size == 0:
+		size = defaultPageSize
+	case size > maxPageSize:
+		size = maxPageSize
+	}
+	filter := database.OrderFilter{
+		CustomerID:      req.GetCustomerId(),
+		TrackNumber:     req.GetTrackNumber(),
+		DeliveryService: req.GetDeliveryService(),
+		Limit:           size + 1, // лишний заказ показывает, есть ли следующая страница
+	}
+	if ts := req.GetCreatedAfter(); ts != nil {
+		filter.CreatedFrom = ts.AsTime()
+	}
+	if ts := req.GetCreatedBefore(); ts != nil {
+		filter.CreatedTo = ts.AsTime()
+	}
+	if token := req.GetPageToken(); token != "" {
+		cursor, err := decodePageToken(token)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		filter.After = cursor
+	}
+
+	orders, err := s.Store.List(ctx, filter)
+	if err != nil {
+		return nil, storeError(ctx, err)
+	}
+	resp := &orderpb.ListOrdersResponse{}
+	if len(orders) > size {
+		orders = orders[:size]
+		last := orders[size-1]
+		resp.NextPageToken = encodePageToken(database.OrderCursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID})
+	}
+	resp.Orders = make([]*orderpb.Order, len(orders))
+	for i := range orders {
+		resp.Orders[i] = s.present(ctx, &orders[i])
+	}
+	return resp, nil
+}
+
+// LookupOrders ищет заказы по одному вторичному ключу через Store.Lookup.
+// Телефон и email ищут только вызывающие, которым видны персональные данные.
+func (s *Server) LookupOrders(ctx context.Context, req *orderpb.LookupOrdersRequest) (*orderpb.LookupOrdersResponse, error) {
+	var field database.LookupField
+	var value string
+	switch key := req.GetKey().(type) {
+	case *orderpb.LookupOrdersRequest_TrackNumber:
+		field, value = database.LookupTrackNumber, key.TrackNumber
+	case *orderpb.LookupOrdersRequest_CustomerId:
+		field, value = database.LookupCustomerID, key.CustomerId
+	case *orderpb.LookupOrdersRequest_Phone:
+		field, value = database.LookupPhone, key.Phone
+	case *orderpb.LookupOrdersRequest_Email:
+		field, value = database.LookupEmail, key.Email
+	}
+	if field == "" || strings.TrimSpace(value) == "" {
+		return nil, status.Error(codes.InvalidArgument, "one of track_number, customer_id, phone, email required")
+	}
+	if field.Personal() && !s.canViewPII(ctx) {
+		return nil, status.Errorf(codes.PermissionDenied, "lookup by %s requires access to personal data", field)
+	}
+	orders, err := s.Store.Lookup(ctx, field, value)
+	if err != nil {
+		return nil, storeError(ctx, err)
+	}
+	resp := &orderpb.LookupOrdersResponse{Orders: make([]*orderpb.Order, len(orders))}
+	for i, order := range orders {
+		resp.Orders[i] = s.present(ctx, order)
+	}
+	return resp, nil
+}
+
+// WatchOrders отправляет заказы, сохраненные этим экземпляром после подписки.
+func (s *Server) WatchOrders(req *orderpb.WatchOrdersRequest, stream orderpb.OrderService_WatchOrdersServer) error {
+	sub, err := s.Feed.Subscribe(0)
+	if err != nil {
+		return status.Error(codes.Unavailable, "order feed unavailable")
+	}
+	defer sub.Close()
+
+	ctx := stream.Context()
+	slog.InfoContext(ctx, "Подписка на новые заказы", slog.Int("subscribers", s.Feed.Subscribers()))
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case ev, ok := <-sub.C():
+			if !ok {
+				if errors.Is(sub.Err(), feed.ErrLagged) {
+					return status.Error(codes.ResourceExhausted, "client is too slow, resume with ListOrders")
+				}
+				return status.Error(codes.Unavailable, "server is shutting down")
+			}
+			if !watchMatches(req, ev.Order) {
+				continue
+			}
+			if err := stream.Send(s.present(ctx, ev.Order)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func watchMatches(req *orderpb.WatchOrdersRequest, o *database.Order) bool {
+	return (req.GetCustomerId() == "" || req.GetCustomerId() == o.CustomerID) &&
+		(req.GetDeliveryService() == "" || req.GetDeliveryService() == o.DeliveryService)
+}
+
+// present маскирует персональные данные, если вызывающему их видеть нельзя,
+// записывает обращение в журнал аудита и переводит заказ в сообщение API.
+func (s *Server) present(ctx context.Context, order *database.Order) *orderpb.Order {
+	masked := !s.canViewPII(ctx)
+	s.audit(ctx, order.OrderUID, web.AuditOK, masked)
+	if masked {
+		order = database.MaskOrder(order)
+	}
+	return orderToProto(order)
+}
+
+// canViewPII — как у HTTP: полные значения видят роли support и admin, если не включен UnmaskedPII.
+func (s *Server) canViewPII(ctx context.Context) bool {
+	if s.UnmaskedPII {
+		return true
+	}
+	p, ok := web.PrincipalFrom(ctx)
+	return ok && p.Role.CanViewPII()
+}
+
+func (s *Server) audit(ctx context.Context, uid, result string, masked bool) {
+	if s.Audit == nil {
+		return
+	}
+	var remote string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	s.Audit.RecordContext(ctx, remote, web.AuditEvent{Action: web.AuditOrderRead, OrderUID: uid, Result: result, Masked: masked})
+}
+
+// storeError переводит ошибку хранилища в статус gRPC по ее классу, как web
+// для HTTP: не найдено, неверный запрос, конфликт, недоступно; остальное — Internal без подробностей.
+func storeError(ctx context.Context, err error) error {
+	switch {
+	case database.IsNotFound(err):
+		return status.Error(codes.NotFound, "order not found")
+	case database.IsInvalid(err):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case database.IsConflict(err):
+		return status.Error(codes.FailedPrecondition, database.ErrConflict.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case database.IsUnavailable(err):
+		return status.Error(codes.Unavailable, "order store unavailable")
+	default:
+		slog.ErrorContext(ctx, "Ошибка хранилища заказов", logging.Err(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// NewGRPCServer создает gRPC-сервер с OrderService, перехватчиками
+// (request_id, трассировка, аутентификация, лимиты, лог, метрики) и reflection для grpcurl.
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	gs := grpc.NewServer(opts...)
+	orderpb.RegisterOrderServiceServer(gs, s)
+	reflection.Register(gs)
+	return gs
+}
+
+// Start запускает gRPC-сервер на port в отдельной горутине.
+// Остановка — через Shutdown.
+func Start(srv *Server, port string) *grpc.Server {
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		slog.Error("gRPC сервер не запущен", slog.String("port", port), logging.Err(err))
+		os.Exit(1)
+	}
+	gs := srv.NewGRPCServer()
+	go func() {
+		slog.Info("gRPC сервер запущен", slog.String("port", port))
+		if err := gs.Serve(lis); err != nil {
+			slog.Error("gRPC сервер остановлен", logging.Err(err))
+			os.Exit(1)
+		}
+	}()
+	return gs
+}
+
+// Shutdown перестает принимать вызовы и дожидается текущих;
+// по истечении ctx оставшиеся вызовы прерываются.
+// Потоки WatchOrders завершаются закрытием Feed до вызова Shutdown.
+func Shutdown(ctx context.Context, gs *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		gs.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,304 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mitrich772/go-order-service/api/orderpb"
+	mockcache "github.com/mitrich772/go-order-service/internal/cache/mocks"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/feed"
+	"github.com/mitrich772/go-order-service/internal/web"
+	"github.com/mitrich772/go-order-service/producer/generate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/gorm"
+)
+
+var testAuth = web.NewAuthenticator(web.AuthConfig{APIKeys: map[string]web.Principal{
+	"support": {Subject: "support", Role: web.RoleSupport},
+	"analyst": {Subject: "analyst", Role: web.RoleAnalyst},
+	"partner": {Subject: "partner", Role: web.RoleIngest},
+}})
+
+// startServer запускает srv на bufconn и возвращает клиента.
+func startServer(t *testing.T, srv *Server) orderpb.OrderServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := srv.NewGRPCServer()
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return orderpb.NewOrderServiceClient(conn)
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func makeOrder(uid string) *database.Order {
+	o := generate.MakeOrder()
+	o.OrderUID = uid
+	return &o
+}
+
+// Проверяет: support видит заказ полностью, analyst — с маской; обращения пишутся в аудит,
+// request_id возвращается в заголовке ответа
+func TestGetOrder_MaskingAndAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	order := makeOrder("uid-1")
+	store.EXPECT().Get(gomock.Any(), "uid-1").Return(order, nil).Times(2)
+
+	var audit bytes.Buffer
+	client := startServer(t, &Server{Store: store, Auth: testAuth, Audit: web.NewAuditLog(&audit)})
+
+	var header metadata.MD
+	full, err := client.GetOrder(withKey("support"), &orderpb.GetOrderRequest{OrderUid: "uid-1"}, grpc.Header(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.GetDelivery().GetPhone() != order.Delivery.Phone || full.GetItems()[0].GetNmId() != order.Items[0].NmID {
+		t.Fatalf("support должен видеть заказ полностью: %v", full)
+	}
+	if len(header.Get("x-request-id")) != 1 {
+		t.Fatalf("ожидали x-request-id в заголовке ответа, получили %v", header)
+	}
+
+	masked, err := client.GetOrder(withKey("analyst"), &orderpb.GetOrderRequest{OrderUid: "uid-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if masked.GetDelivery().GetPhone() == order.Delivery.Phone {
+		t.Fatal("analyst должен получать телефон с маской")
+	}
+	if n := strings.Count(audit.String(), `"action":"order.read"`); n != 2 {
+		t.Fatalf("ожидали 2 записи аудита, получили %d: %s", n, audit.String())
+	}
+}
+
+// Проверяет коды ошибок: нет ключа, чужая роль, пустой uid, заказ не найден, хранилище недоступно
+func TestGetOrder_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().Get(gomock.Any(), "missing").Return(nil, gorm.ErrRecordNotFound)
+	store.EXPECT().Get(gomock.Any(), "down").Return(nil, context.DeadlineExceeded)
+	client := startServer(t, &Server{Store: store, Auth: testAuth})
+
+	cases := []struct {
+		ctx  context.Context
+		uid  string
+		want codes.Code
+	}{
+		{context.Background(), "uid-1", codes.Unauthenticated},
+		{withKey("wrong"), "uid-1", codes.Unauthenticated},
+		{withKey("partner"), "uid-1", codes.PermissionDenied},
+		{withKey("support"), "", codes.InvalidArgument},
+		{withKey("support"), "missing", codes.NotFound},
+		{withKey("support"), "down", codes.DeadlineExceeded},
+	}
+	for _, c := range cases {
+		_, err := client.GetOrder(c.ctx, &orderpb.GetOrderRequest{OrderUid: c.uid})
+		if got := status.Code(err); got != c.want {
+			t.Errorf("uid %q: код %s, ожидали %s", c.uid, got, c.want)
+		}
+	}
+}
+
+// Проверяет: найденные заказы возвращаются по порядку, отсутствующие — в not_found
+func TestBatchGetOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().Get(gomock.Any(), "a").Return(makeOrder("a"), nil)
+	store.EXPECT().Get(gomock.Any(), "b").Return(nil, gorm.ErrRecordNotFound)
+	store.EXPECT().Get(gomock.Any(), "c").Return(makeOrder("c"), nil)
+	client := startServer(t, &Server{Store: store})
+
+	resp, err := client.BatchGetOrders(context.Background(), &orderpb.BatchGetOrdersRequest{OrderUids: []string{"a", "b", "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Orders) != 2 || resp.Orders[0].OrderUid != "a" || resp.Orders[1].OrderUid != "c" {
+		t.Fatalf("неверные заказы: %v", resp.Orders)
+	}
+	if len(resp.NotFound) != 1 || resp.NotFound[0] != "b" {
+		t.Fatalf("неверный not_found: %v", resp.NotFound)
+	}
+
+	_, err = client.BatchGetOrders(context.Background(), &orderpb.BatchGetOrdersRequest{OrderUids: make([]string, maxBatchGet+1)})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("ожидали InvalidArgument для слишком большого пакета, получили %v", err)
+	}
+}
+
+// Проверяет: вызовы ограничиваются теми же лимитами, что HTTP — пакет стоит запрос за каждый uid,
+// его промахи списываются из бюджета «не найдено»; отказ — RESOURCE_EXHAUSTED с RetryInfo
+func TestRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, database.ErrNotFound).Times(4)
+	limiter := web.NewRateLimiter(web.RateLimitConfig{
+		Routes:   map[string]web.Limit{web.RouteOrder: {Rate: 1.0 / 3600, Burst: 10}},
+		NotFound: web.Limit{Rate: 1.0 / 60, Burst: 3},
+	})
+	client := startServer(t, &Server{Store: store, Auth: testAuth, RateLimit: limiter})
+
+	resp, err := client.BatchGetOrders(withKey("support"), &orderpb.BatchGetOrdersRequest{OrderUids: []string{"a", "b", "c", "d"}})
+	if err != nil || len(resp.NotFound) != 4 {
+		t.Fatalf("первый пакет должен пройти: %v %v", resp, err)
+	}
+	_, err = client.GetOrder(withKey("support"), &orderpb.GetOrderRequest{OrderUid: "e"})
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("после 4 промахов при бюджете 3 ожидали ResourceExhausted, получили %v", err)
+	}
+	var delay time.Duration
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			delay = info.GetRetryDelay().AsDuration()
+		}
+	}
+	if delay.Round(time.Second) != 2*time.Minute {
+		t.Fatalf("RetryInfo: ожидали 2m до погашения долга бюджета, получили %v", delay)
+	}
+
+	// корзина запросов общая для HTTP и gRPC: пакет на 4 uid и GetOrder, отклоненный
+	// бюджетом «не найдено», списали 5 из 10
+	ctx := web.WithPrincipal(context.Background(), web.Principal{Subject: "support", Role: web.RoleSupport})
+	if _, ok := limiter.Allow(ctx, web.RouteOrder, web.ClientKey(ctx, ""), 5); !ok {
+		t.Fatal("в корзине должно остаться 5 запросов")
+	}
+	if _, ok := limiter.Allow(ctx, web.RouteOrder, web.ClientKey(ctx, ""), 1); ok {
+		t.Fatal("корзина должна быть пуста")
+	}
+}
+
+// Проверяет: ключ из oneof передается в Store.Lookup; телефон ищет только support,
+// пустой ключ отклоняется
+func TestLookupOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().Lookup(gomock.Any(), database.LookupTrackNumber, "WBTRACK").
+		Return([]*database.Order{makeOrder("b"), makeOrder("a")}, nil)
+	store.EXPECT().Lookup(gomock.Any(), database.LookupPhone, "+79161231234").
+		Return([]*database.Order{makeOrder("a")}, nil)
+	client := startServer(t, &Server{Store: store, Auth: testAuth})
+
+	resp, err := client.LookupOrders(withKey("analyst"), &orderpb.LookupOrdersRequest{
+		Key: &orderpb.LookupOrdersRequest_TrackNumber{TrackNumber: "WBTRACK"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Orders) != 2 || resp.Orders[0].OrderUid != "b" {
+		t.Fatalf("неверные заказы: %v", resp.Orders)
+	}
+
+	phone := &orderpb.LookupOrdersRequest{Key: &orderpb.LookupOrdersRequest_Phone{Phone: "+79161231234"}}
+	if _, err := client.LookupOrders(withKey("analyst"), phone); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("ожидали PermissionDenied для analyst, получили %v", err)
+	}
+	if resp, err := client.LookupOrders(withKey("support"), phone); err != nil || len(resp.Orders) != 1 {
+		t.Fatalf("support должен искать по телефону: %v, %v", resp, err)
+	}
+	if _, err := client.LookupOrders(withKey("support"), &orderpb.LookupOrdersRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("ожидали InvalidArgument без ключа, получили %v", err)
+	}
+}
+
+// Проверяет: фильтры передаются в хранилище, next_page_token ведет на следующую страницу
+func TestListOrders_Paging(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	page := func(uids ...string) []database.Order {
+		out := make([]database.Order, len(uids))
+		for i, uid := range uids {
+			out[i] = *makeOrder(uid)
+			out[i].DateCreated = created
+		}
+		return out
+	}
+	store.EXPECT().List(gomock.Any(), database.OrderFilter{CustomerID: "c1", Limit: 3}).
+		Return(page("z", "y", "x"), nil)
+	store.EXPECT().List(gomock.Any(), database.OrderFilter{
+		CustomerID: "c1",
+		Limit:      3,
+		After:      &database.OrderCursor{DateCreated: created, OrderUID: "y"},
+	}).Return(page("x"), nil)
+	client := startServer(t, &Server{Store: store})
+
+	first, err := client.ListOrders(context.Background(), &orderpb.ListOrdersRequest{CustomerId: "c1", PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Orders) != 2 || first.NextPageToken == "" {
+		t.Fatalf("первая страница: %d заказов, token %q", len(first.Orders), first.NextPageToken)
+	}
+	second, err := client.ListOrders(context.Background(), &orderpb.ListOrdersRequest{CustomerId: "c1", PageSize: 2, PageToken: first.NextPageToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Orders) != 1 || second.Orders[0].OrderUid != "x" || second.NextPageToken != "" {
+		t.Fatalf("вторая страница: %v, token %q", second.Orders, second.NextPageToken)
+	}
+
+	_, err = client.ListOrders(context.Background(), &orderpb.ListOrdersRequest{PageToken: "garbage"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("ожидали InvalidArgument для неверного page_token, получили %v", err)
+	}
+}
+
+// Проверяет: поток получает новые заказы по фильтру и завершается с UNAVAILABLE при закрытии ленты
+func TestWatchOrders(t *testing.T) {
+	f := feed.New(8, 0)
+	client := startServer(t, &Server{Feed: f})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchOrders(ctx, &orderpb.WatchOrdersRequest{DeliveryService: "meest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for f.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	other := makeOrder("skip")
+	other.DeliveryService = "dhl"
+	match := makeOrder("uid-1")
+	match.DeliveryService = "meest"
+	f.Publish(other)
+	f.Publish(match)
+
+	got, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.OrderUid != "uid-1" {
+		t.Fatalf("получили %q, ожидали uid-1", got.OrderUid)
+	}
+
+	f.Close()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("ожидали Unavailable после закрытия ленты, получили %v", err)
+	}
+}
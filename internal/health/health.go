@@ -0,0 +1,196 @@
+// Package health собирает проверки состояния компонентов сервиса
+// для эндпоинтов /healthz (liveness) и /readyz (readiness).
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Статусы компонентов и сервиса в целом.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+	// StatusDegraded — компонент недоступен, но сервис продолжает обслуживать запросы.
+	StatusDegraded = "degraded"
+)
+
+// Checker проверяет состояние одного компонента.
+// details попадает в ответ как есть и может быть nil.
+type Checker interface {
+	Check(ctx context.Context) (details any, err error)
+}
+
+// CheckerFunc позволяет использовать функцию как Checker.
+type CheckerFunc func(ctx context.Context) (any, error)
+
+// Check вызывает f(ctx).
+func (f CheckerFunc) Check(ctx context.Context) (any, error) {
+	return f(ctx)
+}
+
+// ComponentStatus — результат проверки одного компонента.
+type ComponentStatus struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	Details    any    `json:"details,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report — результат всех проверок.
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Registry хранит проверки liveness и readiness.
+type Registry struct {
+	timeout time.Duration
+
+	mu         sync.RWMutex
+	liveness   map[string]Checker
+	readiness  map[string]Checker
+	degradable map[string]bool // проверки readiness, провал которых дает degraded
+}
+
+// NewRegistry создает реестр проверок; каждая проверка ограничена timeout.
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		timeout:    timeout,
+		liveness:   make(map[string]Checker),
+		readiness:  make(map[string]Checker),
+		degradable: make(map[string]bool),
+	}
+}
+
+// AddLiveness регистрирует проверку, провал которой означает, что процесс нужно перезапустить.
+func (r *Registry) AddLiveness(name string, c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.liveness[name] = c
+}
+
+// AddReadiness регистрирует проверку, провал которой снимает сервис с балансировки.
+func (r *Registry) AddReadiness(name string, c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness[name] = c
+	delete(r.degradable, name)
+}
+
+// AddDegradable регистрирует проверку readiness, провал которой не снимает сервис
+// с балансировки: компонент и сервис получают статус degraded, ответ остается 200.
+// Подходит для зависимостей, без которых сервис частично работает (БД при включенном кэше).
+func (r *Registry) AddDegradable(name string, c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness[name] = c
+	r.degradable[name] = true
+}
+
+// Liveness выполняет проверки liveness.
+func (r *Registry) Liveness(ctx context.Context) Report {
+	checkers, _ := r.snapshot(r.liveness)
+	return r.run(ctx, checkers, nil)
+}
+
+// Readiness выполняет проверки readiness.
+func (r *Registry) Readiness(ctx context.Context) Report {
+	checkers, degradable := r.snapshot(r.readiness)
+	return r.run(ctx, checkers, degradable)
+}
+
+// LivenessHandler отдает результат Liveness: 200 если все up, иначе 503.
+func (r *Registry) LivenessHandler(w http.ResponseWriter, req *http.Request) {
+	writeReport(w, r.Liveness(req.Context()))
+}
+
+// ReadinessHandler отдает результат Readiness: 200 если нет компонентов down, иначе 503.
+func (r *Registry) ReadinessHandler(w http.ResponseWriter, req *http.Request) {
+	writeReport(w, r.Readiness(req.Context()))
+}
+
+func (r *Registry) snapshot(m map[string]Checker) (map[string]Checker, map[string]bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string]Checker, len(m))
+	degradable := make(map[string]bool)
+	for name, c := range m {
+		out[name] = c
+		if r.degradable[name] {
+			degradable[name] = true
+		}
+	}
+	return out, degradable
+}
+
+// run выполняет проверки параллельно, каждую со своим таймаутом.
+// Провал проверки из degradable дает статус degraded, остальных — down.
+func (r *Registry) run(ctx context.Context, checkers map[string]Checker, degradable map[string]bool) Report {
+	report := Report{Status: StatusUp, Components: make(map[string]ComponentStatus, len(checkers))}
+
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]ComponentStatus, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, c Checker) {
+			defer wg.Done()
+			results[i] = r.check(ctx, c)
+		}(i, checkers[name])
+	}
+	wg.Wait()
+
+	for i, name := range names {
+		if results[i].Status == StatusDown && degradable[name] {
+			results[i].Status = StatusDegraded
+		}
+		report.Components[name] = results[i]
+		switch {
+		case results[i].Status == StatusDown:
+			report.Status = StatusDown
+		case results[i].Status == StatusDegraded && report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (r *Registry) check(ctx context.Context, c Checker) ComponentStatus {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	details, err := c.Check(ctx)
+	st := ComponentStatus{
+		Status:     StatusUp,
+		Details:    details,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		st.Status = StatusDown
+		st.Error = err.Error()
+	}
+	return st
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status == StatusDown {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func up(details any) Checker {
+	return CheckerFunc(func(ctx context.Context) (any, error) { return details, nil })
+}
+
+func TestReadiness_AllUp(t *testing.T) {
+	r := NewRegistry(time.Second)
+	r.AddReadiness("postgres", up(nil))
+	r.AddReadiness("kafka", up(map[string]any{"lag": 0}))
+
+	w := httptest.NewRecorder()
+	r.ReadinessHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var report Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != StatusUp || len(report.Components) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestReadiness_ComponentDown(t *testing.T) {
+	r := NewRegistry(time.Second)
+	r.AddReadiness("postgres", up(nil))
+	r.AddReadiness("cache_warmup", CheckerFunc(func(ctx context.Context) (any, error) {
+		return nil, errors.New("warm-up in progress")
+	}))
+
+	w := httptest.NewRecorder()
+	r.ReadinessHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	var report Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != StatusDown {
+		t.Fatalf("expected down, got %s", report.Status)
+	}
+	if c := report.Components["cache_warmup"]; c.Status != StatusDown || c.Error != "warm-up in progress" {
+		t.Fatalf("unexpected component %+v", c)
+	}
+	if c := report.Components["postgres"]; c.Status != StatusUp {
+		t.Fatalf("unexpected component %+v", c)
+	}
+
+	// liveness не зависит от readiness
+	w = httptest.NewRecorder()
+	r.LivenessHandler(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected liveness 200, got %d", w.Code)
+	}
+}
+
+// Проверяет: провал degradable-проверки дает degraded и 200, провал обычной — down и 503
+func TestReadiness_Degraded(t *testing.T) {
+	down := CheckerFunc(func(ctx context.Context) (any, error) { return nil, errors.New("connection refused") })
+	r := NewRegistry(time.Second)
+	r.AddReadiness("kafka", up(nil))
+	r.AddDegradable("postgres", down)
+
+	w := httptest.NewRecorder()
+	r.ReadinessHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var report Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if c := report.Components["postgres"]; report.Status != StatusDegraded || c.Status != StatusDegraded || c.Error != "connection refused" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	r.AddReadiness("kafka", down)
+	if report := r.Readiness(context.Background()); report.Status != StatusDown {
+		t.Fatalf("expected down, got %s", report.Status)
+	}
+}
+
+func TestReadiness_Timeout(t *testing.T) {
+	r := NewRegistry(20 * time.Millisecond)
+	r.AddReadiness("slow", CheckerFunc(func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+
+	report := r.Readiness(context.Background())
+	if c := report.Components["slow"]; c.Status != StatusDown || c.Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("expected timeout, got %+v", c)
+	}
+}
@@ -2,36 +2,93 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mitrich772/go-order-service/internal/cache"
 	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"github.com/mitrich772/go-order-service/internal/metrics"
+	"github.com/mitrich772/go-order-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/segmentio/kafka-go"
 )
 
+// ErrNoDLQ возвращается вместо отправки в DLQ, если у Consumer нет writer DLQ
+// (Consumer собран без NewConsumer, например в тестах). Ошибка обработки при этом
+// не теряется: она обернута вместе с ErrNoDLQ.
+var ErrNoDLQ = errors.New("dead letter queue is not configured")
+
+// messageReader — часть *kafka.Reader, которой пользуется Consumer (подменяется в тестах).
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Stats() kafka.ReaderStats
+	Close() error
+}
+
 // Consumer представляет Kafka consumer, который читает сообщения и сохраняет их в OrderStore.
 type Consumer struct {
-	Store     cache.OrderStore
-	reader    *kafka.Reader
+	Store cache.OrderStore
+	// newReader создает reader заново после ошибки, чтобы незафиксированное сообщение
+	// было прочитано повторно; nil — reader не пересоздается.
+	newReader func() messageReader
+	readerMu  sync.Mutex
+	reader    messageReader
 	dlqWriter *kafka.Writer
 	Brokers   []string
 	Topic     string
 	GroupID   string
+	// MaxLag — допустимое отставание consumer group для readiness, 0 — не проверять.
+	MaxLag int64
+	// Metrics — метрики обработки сообщений, nil отключает их.
+	Metrics *metrics.Metrics
+	// RestartDelay — пауза перед повторным запуском чтения после ошибки, 0 — 10 секунд.
+	RestartDelay time.Duration
+	// MaxHandleTime — сколько может обрабатываться одно сообщение, прежде чем CheckAlive
+	// сочтет consumer зависшим, 0 — 5 минут.
+	MaxHandleTime time.Duration
+
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopping atomic.Bool
+	handling atomic.Int64 // начало обработки текущего сообщения (UnixNano), 0 — не обрабатывается
+}
+
+// ReaderOptions — параметры чтения из Kafka. Нулевые поля заменяются значениями по умолчанию.
+type ReaderOptions struct {
+	MinBytes       int           // по умолчанию 10 КБ
+	MaxBytes       int           // по умолчанию 10 МБ
+	CommitInterval time.Duration // по умолчанию 1 секунда
 }
 
 // NewConsumer создает нового Kafka consumer с заданными параметрами.
-func NewConsumer(cacheStore cache.OrderStore, brokers []string, topic, groupID, dlqTopic string) *Consumer {
-	r := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:        brokers,
-		GroupID:        groupID,
-		Topic:          topic,
-		MinBytes:       10e3,
-		MaxBytes:       10e6,
-		CommitInterval: time.Second,
-	})
+func NewConsumer(cacheStore cache.OrderStore, brokers []string, topic, groupID, dlqTopic string, opts ReaderOptions) *Consumer {
+	if opts.MinBytes == 0 {
+		opts.MinBytes = 10e3
+	}
+	if opts.MaxBytes == 0 {
+		opts.MaxBytes = 10e6
+	}
+	if opts.CommitInterval == 0 {
+		opts.CommitInterval = time.Second
+	}
+	newReader := func() messageReader {
+		return kafka.NewReader(kafka.ReaderConfig{
+			Brokers:        brokers,
+			GroupID:        groupID,
+			Topic:          topic,
+			MinBytes:       opts.MinBytes,
+			MaxBytes:       opts.MaxBytes,
+			CommitInterval: opts.CommitInterval,
+		})
+	}
 
 	w := &kafka.Writer{
 		Addr:     kafka.TCP(brokers...),
@@ -41,7 +98,8 @@ func NewConsumer(cacheStore cache.OrderStore, brokers []string, topic, groupID,
 
 	return &Consumer{
 		Store:     cacheStore,
-		reader:    r,
+		newReader: newReader,
+		reader:    newReader(),
 		dlqWriter: w,
 		Brokers:   brokers,
 		Topic:     topic,
@@ -49,87 +107,299 @@ func NewConsumer(cacheStore cache.OrderStore, brokers []string, topic, groupID,
 	}
 }
 
-// Consume читает сообщения из Kafka и вызывает handler для каждого сообщения.
-func (c *Consumer) Consume(ctx context.Context, handler func(key, value []byte)) error {
+// Consume читает сообщения из Kafka, вызывает handler для каждого сообщения
+// и фиксирует offset после успешной обработки.
+// Если handler вернул ошибку (например, не удалось записать в DLQ), offset не фиксируется
+// и Consume возвращает ошибку: после перезапуска сообщение будет прочитано снова.
+// Отмена ctx прекращает чтение, но не прерывает обработку уже полученного сообщения:
+// handler получает контекст без отмены, чтобы сообщение не ушло в DLQ из-за остановки.
+func (c *Consumer) Consume(ctx context.Context, handler func(ctx context.Context, msg kafka.Message) error) error {
+	reader := c.currentReader()
 	for {
-		m, err := c.reader.ReadMessage(ctx)
+		m, err := reader.FetchMessage(ctx)
 		if err != nil {
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
 			return err
 		}
-		log.Printf("consumed message: key=%s value=%s\n\n", string(m.Key), string(m.Value))
-		handler(m.Key, m.Value)
+		// Значение сообщения содержит персональные данные и в лог не пишется.
+		slog.DebugContext(ctx, "Сообщение прочитано из Kafka",
+			slog.String(logging.KeyTopic, m.Topic),
+			slog.Int(logging.KeyPartition, m.Partition),
+			slog.Int64(logging.KeyOffset, m.Offset),
+			slog.Int("size", len(m.Value)),
+		)
+		c.Metrics.MessageConsumed(m.Topic, m.Partition, m.HighWaterMark-m.Offset-1)
+		c.handling.Store(time.Now().UnixNano())
+		err = handler(context.WithoutCancel(ctx), m)
+		c.handling.Store(0)
+		if err != nil {
+			return fmt.Errorf("handle offset %d: %w", m.Offset, err)
+		}
+		if err := reader.CommitMessages(context.WithoutCancel(ctx), m); err != nil {
+			return fmt.Errorf("commit offset %d: %w", m.Offset, err)
+		}
+	}
+}
+
+// currentReader возвращает текущий reader.
+func (c *Consumer) currentReader() messageReader {
+	c.readerMu.Lock()
+	defer c.readerMu.Unlock()
+	return c.reader
+}
+
+// resetReader закрывает reader (фиксируя offset уже обработанных сообщений) и создает новый,
+// который продолжит чтение с последнего зафиксированного offset.
+func (c *Consumer) resetReader(ctx context.Context) {
+	if c.newReader == nil {
+		return
+	}
+	c.readerMu.Lock()
+	defer c.readerMu.Unlock()
+	if err := c.reader.Close(); err != nil {
+		slog.WarnContext(ctx, "Ошибка закрытия reader перед перезапуском", logging.Err(err))
+	}
+	c.reader = c.newReader()
+}
+
+// CheckReader проверяет соединение с брокерами и отставание consumer group.
+func (c *Consumer) CheckReader(ctx context.Context) (any, error) {
+	stats := c.currentReader().Stats()
+	details := map[string]any{
+		"topic":  stats.Topic,
+		"lag":    stats.Lag,
+		"errors": stats.Errors,
+	}
+	if err := dialAny(ctx, c.Brokers); err != nil {
+		return details, err
+	}
+	if c.MaxLag > 0 && stats.Lag > c.MaxLag {
+		return details, fmt.Errorf("consumer lag %d exceeds %d", stats.Lag, c.MaxLag)
+	}
+	return details, nil
+}
+
+// CheckAlive — проверка liveness: цикл чтения, запущенный Start, работает и не обрабатывает
+// одно сообщение дольше MaxHandleTime. До Start и после Stop проверка проходит.
+func (c *Consumer) CheckAlive(context.Context) (any, error) {
+	if c.done == nil || c.stopping.Load() {
+		return nil, nil
+	}
+	select {
+	case <-c.done:
+		return nil, errors.New("consumer loop exited")
+	default:
+	}
+	limit := c.MaxHandleTime
+	if limit <= 0 {
+		limit = 5 * time.Minute
+	}
+	if since := c.handling.Load(); since != 0 {
+		if d := time.Since(time.Unix(0, since)); d > limit {
+			return map[string]any{"handling_ms": d.Milliseconds()}, fmt.Errorf("message handling stuck for %s", d.Round(time.Second))
+		}
 	}
+	return nil, nil
 }
 
-// Close закрывает Kafka reader.
+// CheckDLQ проверяет, что writer DLQ может достучаться до брокеров.
+func (c *Consumer) CheckDLQ(ctx context.Context) (any, error) {
+	if c.dlqWriter == nil {
+		return nil, fmt.Errorf("DLQ writer not configured")
+	}
+	stats := c.dlqWriter.Stats()
+	details := map[string]any{
+		"topic":  stats.Topic,
+		"writes": stats.Writes,
+		"errors": stats.Errors,
+	}
+	return details, dialAny(ctx, c.Brokers)
+}
+
+// dialAny проверяет, что хотя бы один брокер принимает соединения.
+func dialAny(ctx context.Context, brokers []string) error {
+	var lastErr error
+	for _, broker := range brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("no kafka broker reachable: %w", lastErr)
+}
+
+// Close закрывает Kafka reader (отправляя незафиксированные offset) и writer DLQ.
 func (c *Consumer) Close() error {
-	return c.reader.Close()
+	var errs []error
+	if err := c.currentReader().Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close reader: %w", err))
+	}
+	if c.dlqWriter != nil {
+		if err := c.dlqWriter.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close DLQ writer: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stop прекращает чтение новых сообщений, ждет завершения обработки текущего
+// и фиксации его offset, затем закрывает reader и writer DLQ.
+// Если ctx истекает раньше, reader и writer все равно закрываются.
+func (c *Consumer) Stop(ctx context.Context) error {
+	c.stopping.Store(true)
+	if c.cancel != nil {
+		c.cancel()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return errors.Join(fmt.Errorf("drain in-flight message: %w", ctx.Err()), c.Close())
+		}
+	}
+	return c.Close()
 }
 
 // Функция для отправки сообщения в DLQ
+// class — класс ошибки для метрик (decode, validation, store).
 // err — ошибка из-за которой сообщение не удалось обработать.
 // retryable — флаг показывающий, можно ли повторно обработать сообщение.
-func (c *Consumer) sendToDLQ(value []byte, err error, retryable bool) error {
-	headers := []kafka.Header{
-		{Key: "error.class", Value: []byte(fmt.Sprintf("%T", err))},
-		{Key: "error.message", Value: []byte(err.Error())},
-		{Key: "retryable", Value: []byte(fmt.Sprintf("%v", retryable))},
-		{Key: "ts.failed", Value: []byte(fmt.Sprintf("%d", time.Now().UnixMilli()))},
+// Контекст трассировки из ctx копируется в заголовки сообщения DLQ.
+func (c *Consumer) sendToDLQ(ctx context.Context, value []byte, class string, err error, retryable bool) error {
+	if c.dlqWriter == nil {
+		return fmt.Errorf("%w: %w", ErrNoDLQ, err)
 	}
 
-	errWrite := c.dlqWriter.WriteMessages(context.Background(), kafka.Message{
-		Value:   value,
-		Headers: headers,
-	})
+	msg := kafka.Message{
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: "error.class", Value: []byte(fmt.Sprintf("%T", err))},
+			{Key: "error.message", Value: []byte(err.Error())},
+			{Key: "retryable", Value: []byte(fmt.Sprintf("%v", retryable))},
+			{Key: "ts.failed", Value: []byte(fmt.Sprintf("%d", time.Now().UnixMilli()))},
+		},
+	}
+	InjectTrace(ctx, &msg)
+	if id := logging.RequestID(ctx); id != "" {
+		HeaderCarrier{Headers: &msg.Headers}.Set(logging.HeaderRequestID, id)
+	}
+
+	errWrite := c.dlqWriter.WriteMessages(context.WithoutCancel(ctx), msg)
 
 	if errWrite != nil {
 		return fmt.Errorf("ошибка отправки в DLQ: %w", errWrite)
 	}
 
-	log.Printf("Сообщение успешно отправлено в DLQ")
+	c.Metrics.MessageDLQ(class)
+	slog.WarnContext(ctx, "Сообщение отправлено в DLQ",
+		slog.String("class", class),
+		slog.Bool("retryable", retryable),
+		logging.Err(err),
+	)
 	return nil
 }
 
 // HandleMessage обрабатывает одно сообщение из Kafka
 func (c *Consumer) HandleMessage(value []byte) error {
-	order, err := database.OrderFromJSON(value)
+	return c.ProcessMessage(context.Background(), kafka.Message{Topic: c.Topic, Value: value})
+}
+
+// ProcessMessage обрабатывает сообщение в спане kafka.consume,
+// продолжая трассировку из заголовков сообщения.
+// Логи обработки получают поля topic, partition, offset, order_uid и request_id
+// (из заголовка X-Request-ID, если producer его передал).
+func (c *Consumer) ProcessMessage(ctx context.Context, msg kafka.Message) (err error) {
+	ctx = logging.With(ctx,
+		slog.String(logging.KeyTopic, msg.Topic),
+		slog.Int(logging.KeyPartition, msg.Partition),
+		slog.Int64(logging.KeyOffset, msg.Offset),
+	)
+	if id := (HeaderCarrier{Headers: &msg.Headers}).Get(logging.HeaderRequestID); id != "" {
+		ctx = logging.WithRequestID(ctx, id)
+	}
+	ctx, span := tracing.Start(ExtractTrace(ctx, msg), "kafka.consume",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int("messaging.kafka.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.offset", msg.Offset),
+		))
+	defer func() { tracing.End(span, err) }()
+
+	start := time.Now()
+	class, err := c.handle(ctx, msg.Value)
+	span.SetAttributes(attribute.String("order.result", class))
+	c.Metrics.MessageHandled(class, time.Since(start))
+	return err
+}
+
+// handle обрабатывает сообщение и возвращает класс результата для метрик.
+func (c *Consumer) handle(ctx context.Context, value []byte) (string, error) {
+	order, err := decode(ctx, value)
 	if err != nil { // Не парсится
-		return c.sendToDLQ(value, err, false)
+		return metrics.ClassDecode, c.sendToDLQ(ctx, value, metrics.ClassDecode, err, false)
 	}
+	ctx = logging.With(ctx, slog.String(logging.KeyOrderUID, order.OrderUID))
 
-	if err := database.ValidateOrder(order); err != nil { // Не валидируется
-		return c.sendToDLQ(value, err, false)
+	if err := validate(ctx, order); err != nil { // Не валидируется
+		return metrics.ClassValidation, c.sendToDLQ(ctx, value, metrics.ClassValidation, err, false)
 	}
 
-	if err := c.Store.Save(order); err != nil { // Если retry в бд не пробьется
-		return c.sendToDLQ(value, err, true)
+	if err := c.Store.Save(ctx, order); err != nil {
+		if database.IsConflict(err) { // Нарушено ограничение БД: повтор не поможет
+			return metrics.ClassConflict, c.sendToDLQ(ctx, value, metrics.ClassConflict, err, false)
+		}
+		// Если retry в бд не пробьется
+		return metrics.ClassStore, c.sendToDLQ(ctx, value, metrics.ClassStore, err, true)
 	}
 
-	return nil
+	slog.InfoContext(ctx, "Заказ сохранен")
+	return metrics.ClassOK, nil
+}
+
+// decode разбирает JSON заказа в спане order.decode.
+func decode(ctx context.Context, value []byte) (order *database.Order, err error) {
+	_, span := tracing.Start(ctx, "order.decode")
+	defer func() { tracing.End(span, err) }()
+	order, err = database.OrderFromJSON(value)
+	if err == nil {
+		span.SetAttributes(attribute.String("order.uid", order.OrderUID))
+	}
+	return order, err
 }
 
-// Start пытается запустить Kafka consumer в отдельной горутине
+// validate проверяет заказ в спане order.validate.
+func validate(ctx context.Context, order *database.Order) (err error) {
+	_, span := tracing.Start(ctx, "order.validate")
+	defer func() { tracing.End(span, err) }()
+	return database.ValidateOrder(order)
+}
+
+// Start пытается запустить Kafka consumer в отдельной горутине.
+// Остановить его можно отменой ctx или вызовом Stop, который дожидается обработки текущего сообщения.
 func (c *Consumer) Start(ctx context.Context) {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+	delay := c.RestartDelay
+	if delay <= 0 {
+		delay = 10 * time.Second
+	}
 	go func() {
+		defer close(c.done)
 		for {
-			err := c.Consume(ctx, func(_, value []byte) {
-				if err := c.HandleMessage(value); err != nil {
-					log.Printf("Ошибка обработки сообщения: %v", err)
-				}
-			})
-
-			if err != nil {
-				log.Printf("Consumer error: %v. Повтор через 10 секунд", err)
+			err := c.Consume(ctx, c.ProcessMessage)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Ошибка consumer, повтор", slog.Duration("delay", delay), logging.Err(err))
+				c.resetReader(ctx)
 			}
 
 			select {
 			case <-ctx.Done():
-				log.Println("Consumer ctx cancel")
+				slog.InfoContext(ctx, "Consumer остановлен")
 				return
-			case <-time.After(10 * time.Second):
+			case <-time.After(delay):
 			}
 		}
 	}()
@@ -1,14 +1,19 @@
 package kafka
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	mock_cache "github.com/mitrich772/go-order-service/internal/cache/mocks"
 	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/metrics"
 	"github.com/mitrich772/go-order-service/producer/generate"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 // Проверяет: валидное сообщение → парсится → проходит валидацию → Save вызывается 1 раз
@@ -30,8 +35,8 @@ func TestConsumer_HandleMessage_SavesOrder(t *testing.T) {
 	}
 
 	mockStore.EXPECT().
-		Save(gomock.AssignableToTypeOf(&database.Order{})).
-		DoAndReturn(func(o *database.Order) error {
+		Save(gomock.Any(), gomock.AssignableToTypeOf(&database.Order{})).
+		DoAndReturn(func(_ context.Context, o *database.Order) error {
 			if o.OrderUID != correctUID {
 				t.Fatalf("неверный UID: %s", o.OrderUID)
 			}
@@ -59,7 +64,7 @@ func TestConsumer_HandleInvalidJSON_NoSave(t *testing.T) {
 	payload := []byte(`{"order_uid":"123"}`)
 
 	mockStore.EXPECT().
-		Save(gomock.Any()).
+		Save(gomock.Any(), gomock.Any()).
 		Times(0)
 
 	err := consumer.HandleMessage(payload)
@@ -82,13 +87,17 @@ func TestConsumer_HandleMalformedJSON_NoSave(t *testing.T) {
 	payload := []byte(`{"order_uid":123`) // пропущена скобка
 
 	mockStore.EXPECT().
-		Save(gomock.Any()).
+		Save(gomock.Any(), gomock.Any()).
 		Times(0)
 
 	err := consumer.HandleMessage(payload)
 	if err == nil {
 		t.Fatalf("ожидалась ошибка парсинга, но получили nil")
 	}
+	// без writer DLQ сообщение не отправляется, а ошибка возвращается вместе с ErrNoDLQ
+	if !errors.Is(err, ErrNoDLQ) {
+		t.Fatalf("ожидалась ErrNoDLQ, получено %v", err)
+	}
 }
 
 // Проверяет: Save возвращает ошибку → метод не паникует → ошибка возвращается наружу
@@ -110,7 +119,7 @@ func TestConsumer_HandleMessage_SaveError(t *testing.T) {
 	}
 
 	mockStore.EXPECT().
-		Save(gomock.Any()).
+		Save(gomock.Any(), gomock.Any()).
 		Return(errors.New("ошибка БД"))
 
 	errH := consumer.HandleMessage(payload)
@@ -118,3 +127,43 @@ func TestConsumer_HandleMessage_SaveError(t *testing.T) {
 		t.Fatalf("ожидалась ошибка сохранения, но получили nil")
 	}
 }
+
+// Проверяет: ошибки обработки учитываются в метриках по классу
+func TestConsumer_HandleMessage_Metrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mock_cache.NewMockOrderStore(ctrl)
+	m := metrics.New(prometheus.NewRegistry())
+	consumer := Consumer{
+		Store:   mockStore,
+		Metrics: m,
+	}
+
+	payload, err := json.Marshal(generate.MakeOrder())
+	if err != nil {
+		t.Fatalf("ошибка маршалинга: %v", err)
+	}
+	mockStore.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil)
+	mockStore.EXPECT().Save(gomock.Any(), gomock.Any()).Return(errors.New("ошибка БД"))
+	mockStore.EXPECT().Save(gomock.Any(), gomock.Any()).Return(fmt.Errorf("save: %w", database.ErrConflict))
+
+	_ = consumer.HandleMessage(payload)
+	_ = consumer.HandleMessage(payload)
+	_ = consumer.HandleMessage(payload)
+	_ = consumer.HandleMessage([]byte(`{"order_uid":123`))
+
+	if got := testutil.ToFloat64(m.MessagesFailed.WithLabelValues(metrics.ClassStore)); got != 1 {
+		t.Fatalf("ожидалась 1 ошибка store, получили %v", got)
+	}
+	if got := testutil.ToFloat64(m.MessagesFailed.WithLabelValues(metrics.ClassConflict)); got != 1 {
+		t.Fatalf("ожидалась 1 ошибка conflict, получили %v", got)
+	}
+	if got := testutil.ToFloat64(m.MessagesFailed.WithLabelValues(metrics.ClassDecode)); got != 1 {
+		t.Fatalf("ожидалась 1 ошибка decode, получили %v", got)
+	}
+	// DLQ не настроен — в DLQ ничего не ушло
+	if got := testutil.CollectAndCount(m.MessagesDLQ); got != 0 {
+		t.Fatalf("не ожидались сообщения в DLQ, получили %d", got)
+	}
+}
@@ -0,0 +1,164 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+
+	"github.com/mitrich772/go-order-service/internal/cache"
+	"github.com/mitrich772/go-order-service/internal/logging"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// InvalidationBus реализует cache.InvalidationBus поверх compacted-топика Kafka.
+// Ключ сообщения — order_uid, поэтому после компакции в топике остается
+// только последняя версия каждого заказа. События одного заказа попадают в одну
+// партицию, и версией события служит его offset в ней; число партиций топика
+// после создания менять нельзя, иначе ключ переедет в другую партицию.
+// Каждый экземпляр читает топик без consumer group, поэтому перезапуск пода
+// не оставляет на брокере брошенных групп.
+type InvalidationBus struct {
+	writer  *kafka.Writer
+	brokers []string
+	topic   string
+}
+
+// NewInvalidationBus создает шину инвалидации.
+func NewInvalidationBus(brokers []string, topic string) *InvalidationBus {
+	return &InvalidationBus{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Topic:    topic,
+			Balancer: &kafka.Hash{},
+		},
+		brokers: brokers,
+		topic:   topic,
+	}
+}
+
+// EnsureTopic создает compacted-топик, если его еще нет.
+func (b *InvalidationBus) EnsureTopic() error {
+	conn, err := kafka.Dial("tcp", b.brokers[0])
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	controller, err := conn.Controller()
+	if err != nil {
+		return err
+	}
+	cconn, err := kafka.Dial("tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		return err
+	}
+	defer cconn.Close()
+
+	return cconn.CreateTopics(kafka.TopicConfig{
+		Topic:             b.topic,
+		NumPartitions:     1,
+		ReplicationFactor: 1,
+		ConfigEntries: []kafka.ConfigEntry{
+			{ConfigName: "cleanup.policy", ConfigValue: "compact"},
+		},
+	})
+}
+
+// Publish отправляет событие инвалидации в топик.
+func (b *InvalidationBus) Publish(ctx context.Context, ev cache.Invalidation) error {
+	value, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return b.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(ev.OrderUID),
+		Value: value,
+	})
+}
+
+// Subscribe читает все партиции топика начиная с конца и вызывает handle для каждого
+// события до отмены ctx. Пропущенные до подписки события не нужны: локальный кэш
+// экземпляра после старта пуст или прогрет из БД.
+func (b *InvalidationBus) Subscribe(ctx context.Context, handle func(cache.Invalidation)) error {
+	partitions, err := b.partitions(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(partitions))
+	for _, p := range partitions {
+		go func() { errs <- b.readPartition(ctx, p.ID, handle) }()
+	}
+	err = <-errs
+	cancel()
+	for range len(partitions) - 1 {
+		<-errs
+	}
+	return err
+}
+
+// partitions возвращает партиции топика инвалидации.
+func (b *InvalidationBus) partitions(ctx context.Context) ([]kafka.Partition, error) {
+	conn, err := kafka.DialContext(ctx, "tcp", b.brokers[0])
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(b.topic)
+	if err != nil {
+		return nil, fmt.Errorf("read partitions of %s: %w", b.topic, err)
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("topic %s has no partitions", b.topic)
+	}
+	return partitions, nil
+}
+
+// readPartition читает одну партицию с последнего offset до отмены ctx или ошибки.
+func (b *InvalidationBus) readPartition(ctx context.Context, partition int, handle func(cache.Invalidation)) error {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   b.brokers,
+		Topic:     b.topic,
+		Partition: partition,
+	})
+	defer r.Close()
+	if err := r.SetOffset(kafka.LastOffset); err != nil {
+		return err
+	}
+
+	for {
+		m, err := r.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		var ev cache.Invalidation
+		if err := json.Unmarshal(m.Value, &ev); err != nil {
+			slog.WarnContext(ctx, "Некорректное событие инвалидации",
+				slog.Int(logging.KeyPartition, m.Partition),
+				slog.Int64(logging.KeyOffset, m.Offset),
+				logging.Err(err),
+			)
+			continue
+		}
+		ev.Version = m.Offset
+		handle(ev)
+	}
+}
+
+// Close закрывает writer шины.
+func (b *InvalidationBus) Close() error {
+	if err := b.writer.Close(); err != nil {
+		return fmt.Errorf("close invalidation writer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// OrderPublisher отправляет заказы в топик заказов, откуда их читает Consumer.
+// Реализует метод Save, поэтому подставляется вместо OrderStore там,
+// где заказ нужно не записать сразу, а поставить в общую очередь (HTTP-прием заказов).
+type OrderPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewOrderPublisher создает publisher для топика topic.
+func NewOrderPublisher(brokers []string, topic string) *OrderPublisher {
+	return &OrderPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+}
+
+// Save публикует заказ с ключом order_uid (заказы одного uid попадают в одну партицию).
+// Контекст трассировки и request_id передаются в заголовках, как у producer.
+func (p *OrderPublisher) Save(ctx context.Context, order *database.Order) error {
+	value, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	msg := kafka.Message{
+		Key:   []byte(order.OrderUID),
+		Value: value,
+		Time:  time.Now(),
+	}
+	InjectTrace(ctx, &msg)
+	if id := logging.RequestID(ctx); id != "" {
+		HeaderCarrier{Headers: &msg.Headers}.Set(logging.HeaderRequestID, id)
+	}
+	return p.writer.WriteMessages(ctx, msg)
+}
+
+// Close отправляет буферизованные сообщения и закрывает writer.
+func (p *OrderPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,219 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeReader отдает одно сообщение, затем блокируется до отмены ctx
+// и записывает порядок вызовов.
+type fakeReader struct {
+	mu      sync.Mutex
+	calls   []string
+	fetched bool
+}
+
+func (r *fakeReader) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	first := !r.fetched
+	r.fetched = true
+	r.mu.Unlock()
+	if first {
+		return kafka.Message{Topic: "orders", Offset: 7}, nil
+	}
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) wasFetched() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fetched
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.record("commit")
+	return nil
+}
+
+func (r *fakeReader) Stats() kafka.ReaderStats { return kafka.ReaderStats{} }
+
+func (r *fakeReader) Close() error {
+	r.record("close")
+	return nil
+}
+
+// Проверяет: Stop дожидается обработки текущего сообщения, фиксирует его offset
+// и только потом закрывает reader; контекст обработчика при этом не отменяется
+func TestConsumer_Stop_DrainsInFlight(t *testing.T) {
+	reader := &fakeReader{}
+	c := &Consumer{reader: reader}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var handlerErr error
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		_ = c.Consume(ctx, func(ctx context.Context, msg kafka.Message) error {
+			close(started)
+			<-release
+			handlerErr = ctx.Err()
+			reader.record("handled")
+			return nil
+		})
+	}()
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- c.Stop(context.Background()) }()
+	select {
+	case <-stopped:
+		t.Fatal("Stop вернулся до завершения обработки сообщения")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+
+	if err := <-stopped; err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if handlerErr != nil {
+		t.Fatalf("контекст обработчика отменен: %v", handlerErr)
+	}
+	want := []string{"handled", "commit", "close"}
+	if !reflect.DeepEqual(reader.calls, want) {
+		t.Fatalf("порядок вызовов %v, ожидали %v", reader.calls, want)
+	}
+}
+
+// Проверяет: если обработка не укладывается в срок, Stop возвращает ошибку, но reader закрывается
+func TestConsumer_Stop_Deadline(t *testing.T) {
+	reader := &fakeReader{}
+	c := &Consumer{reader: reader}
+	c.cancel = func() {}
+	c.done = make(chan struct{}) // обработка никогда не завершится
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := c.Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ожидали DeadlineExceeded, получили %v", err)
+	}
+	if !reflect.DeepEqual(reader.calls, []string{"close"}) {
+		t.Fatalf("reader не закрыт: %v", reader.calls)
+	}
+}
+
+// Проверяет: если сообщение не удалось ни обработать, ни записать в DLQ, offset не фиксируется,
+// а reader пересоздается, чтобы после RestartDelay прочитать сообщение снова
+func TestConsumer_DLQFailure_NoCommit(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		readers []*fakeReader
+	)
+	newReader := func() messageReader {
+		mu.Lock()
+		defer mu.Unlock()
+		r := &fakeReader{}
+		readers = append(readers, r)
+		return r
+	}
+	c := &Consumer{
+		newReader: newReader,
+		reader:    newReader(),
+		// брокер DLQ недоступен: сообщение с неразбираемым телом некуда отложить
+		dlqWriter: &kafka.Writer{
+			Addr:         kafka.TCP("127.0.0.1:1"),
+			Topic:        "orders-dlq",
+			MaxAttempts:  1,
+			BatchTimeout: time.Millisecond,
+		},
+		RestartDelay: time.Millisecond,
+	}
+	c.Start(context.Background())
+	eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(readers) >= 2 && readers[1].wasFetched()
+	})
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, r := range readers {
+		r.mu.Lock()
+		calls := r.calls
+		r.mu.Unlock()
+		for _, call := range calls {
+			if call == "commit" {
+				t.Fatalf("reader %d зафиксировал offset необработанного сообщения: %v", i, calls)
+			}
+		}
+	}
+	if first := readers[0].calls; !reflect.DeepEqual(first, []string{"close"}) {
+		t.Fatalf("первый reader должен быть закрыт перед перезапуском: %v", first)
+	}
+}
+
+// eventually ждет выполнения условия, проверяя его периодически
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("условие не выполнилось за отведенное время")
+}
+
+// Проверяет: CheckAlive сообщает о сообщении, обрабатываемом дольше MaxHandleTime,
+// и проходит после остановки consumer
+func TestConsumer_CheckAlive_StuckHandler(t *testing.T) {
+	reader := &fakeReader{}
+	c := &Consumer{reader: reader, MaxHandleTime: 10 * time.Millisecond}
+	if _, err := c.CheckAlive(context.Background()); err != nil {
+		t.Fatalf("до Start проверка должна проходить: %v", err)
+	}
+
+	release := make(chan struct{})
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		_ = c.Consume(ctx, func(context.Context, kafka.Message) error {
+			<-release
+			return nil
+		})
+	}()
+
+	eventually(t, func() bool {
+		_, err := c.CheckAlive(context.Background())
+		return err != nil
+	})
+	close(release)
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if _, err := c.CheckAlive(context.Background()); err != nil {
+		t.Fatalf("после Stop проверка должна проходить: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/mitrich772/go-order-service/internal/tracing"
+	"github.com/segmentio/kafka-go"
+)
+
+// HeaderCarrier позволяет читать и писать контекст трассировки в заголовки сообщения Kafka.
+type HeaderCarrier struct {
+	Headers *[]kafka.Header
+}
+
+// Get возвращает значение первого заголовка key.
+func (c HeaderCarrier) Get(key string) string {
+	for _, h := range *c.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set заменяет заголовок key (или добавляет, если его нет).
+func (c HeaderCarrier) Set(key, value string) {
+	for i, h := range *c.Headers {
+		if h.Key == key {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+// Keys возвращает имена всех заголовков.
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.Headers))
+	for _, h := range *c.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// InjectTrace записывает контекст трассировки из ctx в заголовки сообщения (traceparent, baggage).
+func InjectTrace(ctx context.Context, msg *kafka.Message) {
+	tracing.Propagator.Inject(ctx, HeaderCarrier{Headers: &msg.Headers})
+}
+
+// ExtractTrace возвращает ctx с контекстом трассировки из заголовков сообщения.
+func ExtractTrace(ctx context.Context, msg kafka.Message) context.Context {
+	return tracing.Propagator.Extract(ctx, HeaderCarrier{Headers: &msg.Headers})
+}
@@ -0,0 +1,63 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mock_cache "github.com/mitrich772/go-order-service/internal/cache/mocks"
+	"github.com/mitrich772/go-order-service/internal/tracing"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Проверяет: traceparent из заголовков продолжается в спанах обработки, Save получает контекст трассы
+func TestConsumer_ProcessMessage_ContinuesTrace(t *testing.T) {
+	p, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterMemory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Shutdown(context.Background())
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockStore := mock_cache.NewMockOrderStore(ctrl)
+	consumer := Consumer{Store: mockStore}
+
+	// сообщение от producer со своей трассой
+	producerCtx, producerSpan := tracing.Start(context.Background(), "kafka.produce")
+	msg := kafka.Message{Topic: "orders", Value: []byte(`{"order_uid":"123"}`)}
+	InjectTrace(producerCtx, &msg)
+	producerSpan.End()
+	traceID := producerSpan.SpanContext().TraceID()
+
+	mockStore.EXPECT().Save(gomock.Any(), gomock.Any()).Times(0)
+	_ = consumer.ProcessMessage(context.Background(), msg)
+
+	names := map[string]bool{}
+	for _, s := range p.Memory.GetSpans() {
+		if s.SpanContext.TraceID() != traceID {
+			t.Fatalf("span %s has foreign trace %s", s.Name, s.SpanContext.TraceID())
+		}
+		names[s.Name] = true
+		if s.Name == "kafka.consume" && s.SpanKind != trace.SpanKindConsumer {
+			t.Fatalf("unexpected kind %v", s.SpanKind)
+		}
+	}
+	for _, name := range []string{"kafka.consume", "order.decode", "order.validate"} {
+		if !names[name] {
+			t.Fatalf("span %s not recorded, got %v", name, names)
+		}
+	}
+}
+
+func TestHeaderCarrier_SetReplaces(t *testing.T) {
+	headers := []kafka.Header{{Key: "traceparent", Value: []byte("old")}, {Key: "retryable", Value: []byte("true")}}
+	c := HeaderCarrier{Headers: &headers}
+	c.Set("traceparent", "new")
+	c.Set("baggage", "k=v")
+
+	if len(headers) != 3 || c.Get("traceparent") != "new" || c.Get("baggage") != "k=v" {
+		t.Fatalf("unexpected headers %v", headers)
+	}
+}
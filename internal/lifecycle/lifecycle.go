@@ -0,0 +1,128 @@
+// Package lifecycle координирует остановку сервиса: ждет SIGINT/SIGTERM и останавливает
+// компоненты в порядке, обратном регистрации (как defer), в пределах общего срока.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// ErrStopping возвращается проверкой готовности после начала остановки.
+var ErrStopping = errors.New("service is shutting down")
+
+// StopFunc останавливает компонент. ctx ограничен общим сроком остановки;
+// после его истечения компонент должен освободить ресурсы как можно быстрее.
+type StopFunc func(ctx context.Context) error
+
+type hook struct {
+	name string
+	stop StopFunc
+}
+
+// Manager хранит шаги остановки компонентов.
+// Компоненты регистрируются по мере запуска, поэтому останавливаются в обратном порядке:
+// сначала те, что принимают работу (HTTP, consumer), последними — те, от которых они зависят (БД).
+type Manager struct {
+	mu       sync.Mutex
+	hooks    []hook
+	stopping atomic.Bool
+	once     sync.Once
+	err      error
+}
+
+// New создает пустой Manager.
+func New() *Manager {
+	return &Manager{}
+}
+
+// OnStop регистрирует шаг остановки компонента name.
+func (m *Manager) OnStop(name string, stop StopFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{name: name, stop: stop})
+}
+
+// Go запускает фоновую задачу run и регистрирует ее остановку:
+// на своем шаге контекст задачи отменяется, и Manager ждет ее завершения.
+func (m *Manager) Go(name string, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+	m.OnStop(name, func(stopCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return stopCtx.Err()
+		}
+	})
+}
+
+// Stopping сообщает, что остановка началась.
+func (m *Manager) Stopping() bool {
+	return m.stopping.Load()
+}
+
+// CheckReady — проверка готовности: после начала остановки сервис не готов принимать трафик.
+func (m *Manager) CheckReady(context.Context) (any, error) {
+	if m.Stopping() {
+		return nil, ErrStopping
+	}
+	return nil, nil
+}
+
+// Shutdown выполняет шаги остановки в обратном порядке регистрации.
+// Все шаги выполняются даже после ошибки или истечения ctx, чтобы освободить ресурсы;
+// ошибки объединяются. Повторные вызовы возвращают результат первого.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.once.Do(func() {
+		m.stopping.Store(true)
+		m.mu.Lock()
+		hooks := append([]hook(nil), m.hooks...)
+		m.mu.Unlock()
+
+		var errs []error
+		for i := len(hooks) - 1; i >= 0; i-- {
+			h := hooks[i]
+			start := time.Now()
+			if err := h.stop(ctx); err != nil {
+				slog.ErrorContext(ctx, "Ошибка остановки компонента", slog.String("component", h.name), logging.Err(err))
+				errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+				continue
+			}
+			slog.InfoContext(ctx, "Компонент остановлен",
+				slog.String("component", h.name),
+				slog.Duration("duration", time.Since(start)),
+			)
+		}
+		m.err = errors.Join(errs...)
+	})
+	return m.err
+}
+
+// Wait блокируется до SIGINT/SIGTERM (или отмены ctx), затем останавливает компоненты,
+// отводя на всю остановку timeout.
+func (m *Manager) Wait(ctx context.Context, timeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
+	slog.Info("Останавливаем сервис", slog.Duration("timeout", timeout))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return m.Shutdown(shutdownCtx)
+}
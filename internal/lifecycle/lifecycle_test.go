@@ -0,0 +1,118 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Проверяет: шаги выполняются в обратном порядке регистрации, даже если какой-то вернул ошибку
+func TestManager_Shutdown_ReverseOrder(t *testing.T) {
+	m := New()
+	var order []string
+	step := func(name string, err error) StopFunc {
+		return func(context.Context) error {
+			order = append(order, name)
+			return err
+		}
+	}
+	errConsumer := errors.New("commit failed")
+	m.OnStop("postgres", step("postgres", nil))
+	m.OnStop("kafka consumer", step("kafka consumer", errConsumer))
+	m.OnStop("http", step("http", nil))
+
+	err := m.Shutdown(context.Background())
+	if !errors.Is(err, errConsumer) {
+		t.Fatalf("ожидали ошибку consumer, получили %v", err)
+	}
+	want := []string{"http", "kafka consumer", "postgres"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("порядок остановки %v, ожидали %v", order, want)
+	}
+}
+
+// Проверяет: повторный Shutdown не выполняет шаги заново и возвращает прежний результат
+func TestManager_Shutdown_Once(t *testing.T) {
+	m := New()
+	calls := 0
+	m.OnStop("db", func(context.Context) error {
+		calls++
+		return errors.New("boom")
+	})
+
+	first := m.Shutdown(context.Background())
+	second := m.Shutdown(context.Background())
+	if calls != 1 {
+		t.Fatalf("шаг выполнен %d раз, ожидали 1", calls)
+	}
+	if first == nil || first != second {
+		t.Fatalf("ожидали одинаковую ошибку, получили %v и %v", first, second)
+	}
+}
+
+// Проверяет: после начала остановки сервис перестает быть готовым
+func TestManager_CheckReady(t *testing.T) {
+	m := New()
+	var readyDuringStop error
+	m.OnStop("http", func(ctx context.Context) error {
+		_, readyDuringStop = m.CheckReady(ctx)
+		return nil
+	})
+
+	if _, err := m.CheckReady(context.Background()); err != nil {
+		t.Fatalf("до остановки ожидали готовность, получили %v", err)
+	}
+	_ = m.Shutdown(context.Background())
+	if !errors.Is(readyDuringStop, ErrStopping) {
+		t.Fatalf("во время остановки ожидали ErrStopping, получили %v", readyDuringStop)
+	}
+}
+
+// Проверяет: фоновая задача отменяется на своем шаге, и Manager ждет ее завершения
+// до перехода к следующему (ранее зарегистрированному) шагу
+func TestManager_Go_WaitsForTask(t *testing.T) {
+	m := New()
+	var order []string
+	m.OnStop("postgres", func(context.Context) error {
+		order = append(order, "postgres")
+		return nil
+	})
+	m.Go("snapshots", func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond) // последний снимок
+		order = append(order, "snapshots")
+	})
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	want := []string{"snapshots", "postgres"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("порядок остановки %v, ожидали %v", order, want)
+	}
+}
+
+// Проверяет: зависшая задача не блокирует остановку дольше срока, остальные шаги выполняются
+func TestManager_Go_Deadline(t *testing.T) {
+	m := New()
+	closed := false
+	m.OnStop("postgres", func(context.Context) error {
+		closed = true
+		return nil
+	})
+	block := make(chan struct{})
+	defer close(block)
+	m.Go("stuck", func(context.Context) { <-block })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := m.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ожидали DeadlineExceeded, получили %v", err)
+	}
+	if !closed {
+		t.Fatal("после истечения срока остальные шаги тоже должны выполниться")
+	}
+}
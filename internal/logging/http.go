@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// HeaderRequestID — заголовок с идентификатором запроса.
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLen ограничивает длину идентификатора, пришедшего от клиента.
+const maxRequestIDLen = 128
+
+// Middleware присваивает каждому HTTP-запросу request_id (из X-Request-ID или новый),
+// возвращает его в заголовке ответа и пишет строку лога по завершении запроса.
+// Ставится внутри tracing.Middleware, чтобы в лог попадал trace_id.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(HeaderRequestID)
+		if !ValidRequestID(id) {
+			id = NewRequestID()
+		}
+		w.Header().Set(HeaderRequestID, id)
+
+		rec := NewStatusWriter(w)
+		inner := r.WithContext(WithRequestID(r.Context(), id))
+		next.ServeHTTP(rec, inner)
+		// ServeMux записывает шаблон маршрута в свою копию запроса; он нужен здесь
+		// и во внешних middleware (трассировка).
+		r.Pattern = inner.Pattern
+
+		level := slog.LevelInfo
+		if rec.Status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(inner.Context(), level, "http request",
+			slog.String("method", r.Method),
+			slog.String("route", r.Pattern),
+			slog.Int("status", rec.Status),
+			slog.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// NewRequestID создает случайный идентификатор запроса (16 байт в hex).
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// ValidRequestID пропускает только короткие идентификаторы из печатных ASCII-символов,
+// чтобы клиент не мог подделать строки лога.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// StatusWriter запоминает код ответа для middleware логов, метрик, трассировки и лимитов.
+// Status — код ответа, http.StatusOK, пока обработчик не вызвал WriteHeader.
+type StatusWriter struct {
+	http.ResponseWriter
+	Status int
+}
+
+// NewStatusWriter оборачивает w.
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{ResponseWriter: w, Status: http.StatusOK}
+}
+
+func (w *StatusWriter) WriteHeader(status int) {
+	w.Status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap нужен http.ResponseController (Flush и т.п.).
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,194 @@
+// Package logging настраивает структурированные логи slog: уровень, формат вывода,
+// поля из контекста (request_id, trace_id, order_uid...) и маскирование персональных данных.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Имена полей, общие для всего сервиса.
+const (
+	KeyOrderUID  = "order_uid"
+	KeyTopic     = "topic"
+	KeyPartition = "partition"
+	KeyOffset    = "offset"
+	KeyRequestID = "request_id"
+	KeyTraceID   = "trace_id"
+	KeySpanID    = "span_id"
+	KeyError     = "error"
+)
+
+// Форматы вывода.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config содержит настройки логирования.
+type Config struct {
+	// Level — debug, info, warn или error.
+	Level string
+	// Format — text или json.
+	Format string
+	// ShowSensitive отключает маскирование: payload и персональные данные попадут в лог как есть.
+	// Только для локальной отладки.
+	ShowSensitive bool
+}
+
+// New создает логгер по cfg, пишущий в w.
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	if !cfg.ShowSensitive {
+		opts.ReplaceAttr = redact
+	}
+
+	var h slog.Handler
+	switch cfg.Format {
+	case "", FormatText:
+		h = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+	return slog.New(&contextHandler{Handler: h}), nil
+}
+
+// Setup создает логгер в stderr и делает его логгером slog по умолчанию
+// (туда же уходит и стандартный log).
+func Setup(cfg Config) (*slog.Logger, error) {
+	logger, err := New(os.Stderr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// ParseLevel разбирает уровень логирования, пустая строка — info.
+func ParseLevel(s string) (slog.Level, error) {
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+	return level, nil
+}
+
+// Err возвращает поле error для slog.
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+type ctxKey int
+
+const (
+	attrsKey ctxKey = iota
+	requestIDKey
+)
+
+// With возвращает контекст, к логам которого добавляются поля args (пары ключ-значение или slog.Attr).
+// Поля накапливаются: вложенные вызовы дополняют поля родителя.
+func With(ctx context.Context, args ...any) context.Context {
+	parent, _ := ctx.Value(attrsKey).([]slog.Attr)
+	// Record используется только для разбора args в slog.Attr.
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.Add(args...)
+	attrs := make([]slog.Attr, len(parent), len(parent)+r.NumAttrs())
+	copy(attrs, parent)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return context.WithValue(ctx, attrsKey, attrs)
+}
+
+// WithRequestID сохраняет идентификатор запроса в контексте.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID возвращает идентификатор запроса из контекста или пустую строку.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// contextHandler добавляет к записи поля из контекста: request_id, trace_id, span_id
+// и поля, сохраненные With.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			r.AddAttrs(slog.String(KeyRequestID, id))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(
+				slog.String(KeyTraceID, sc.TraceID().String()),
+				slog.String(KeySpanID, sc.SpanID().String()),
+			)
+		}
+		if attrs, ok := ctx.Value(attrsKey).([]slog.Attr); ok {
+			r.AddAttrs(attrs...)
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// Redacted — значение, которым заменяются скрытые поля.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys — поля, значения которых не пишутся в лог: сырые сообщения,
+// заказы целиком и персональные данные покупателя и оплаты.
+var sensitiveKeys = map[string]bool{
+	"payload":     true,
+	"value":       true,
+	"body":        true,
+	"order":       true,
+	"delivery":    true,
+	"payment":     true,
+	"name":        true,
+	"phone":       true,
+	"email":       true,
+	"address":     true,
+	"city":        true,
+	"zip":         true,
+	"region":      true,
+	"customer_id": true,
+	"transaction": true,
+	"password":    true,
+	"token":       true,
+}
+
+// redact заменяет значения чувствительных полей на Redacted (ReplaceAttr для slog.HandlerOptions).
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
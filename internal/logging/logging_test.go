@@ -0,0 +1,151 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("invalid JSON log line %q: %v", buf.String(), err)
+	}
+	return rec
+}
+
+func TestNew_RedactsSensitiveFieldsByDefault(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{Format: FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("msg",
+		slog.String("payload", `{"phone":"+79990000000"}`),
+		slog.Group("delivery", slog.String("email", "a@b.c"), slog.String("city", "Moscow")),
+		slog.String(KeyOrderUID, "b563feb7b2b84b6test"),
+	)
+
+	out := buf.String()
+	for _, leaked := range []string{"+79990000000", "a@b.c", "Moscow"} {
+		if strings.Contains(out, leaked) {
+			t.Fatalf("sensitive value %q leaked: %s", leaked, out)
+		}
+	}
+	rec := decodeLine(t, &buf)
+	if rec["payload"] != Redacted || rec[KeyOrderUID] != "b563feb7b2b84b6test" {
+		t.Fatalf("unexpected record %v", rec)
+	}
+}
+
+func TestNew_ShowSensitive(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{Format: FormatJSON, ShowSensitive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("msg", slog.String("phone", "+79990000000"))
+	if rec := decodeLine(t, &buf); rec["phone"] != "+79990000000" {
+		t.Fatalf("expected raw value, got %v", rec["phone"])
+	}
+}
+
+func TestNew_LevelAndFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{Level: "warn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "msg=shown") {
+		t.Fatalf("unexpected text output %q", out)
+	}
+
+	if _, err := New(&buf, Config{Level: "verbose"}); err == nil {
+		t.Fatal("expected error for unknown level")
+	}
+	if _, err := New(&buf, Config{Format: "xml"}); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{Format: FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	ctx = WithRequestID(ctx, "req-1")
+	ctx = With(ctx, KeyPartition, 3)
+	ctx = With(ctx, slog.String(KeyOrderUID, "uid-1"))
+
+	logger.InfoContext(ctx, "msg")
+	rec := decodeLine(t, &buf)
+	want := map[string]any{
+		KeyRequestID: "req-1",
+		KeyTraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		KeySpanID:    "00f067aa0ba902b7",
+		KeyPartition: float64(3),
+		KeyOrderUID:  "uid-1",
+	}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Fatalf("field %s = %v, want %v (record %v)", k, rec[k], v, rec)
+		}
+	}
+}
+
+func TestMiddleware_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{Format: FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(prev)
+
+	var seen string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	req := httptest.NewRequest("GET", "/order/abc", nil)
+	req.Header.Set(HeaderRequestID, "client-id-42")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if seen != "client-id-42" || rec.Header().Get(HeaderRequestID) != "client-id-42" {
+		t.Fatalf("request id not propagated: ctx %q header %q", seen, rec.Header().Get(HeaderRequestID))
+	}
+	line := decodeLine(t, &buf)
+	if line[KeyRequestID] != "client-id-42" || line["status"] != float64(http.StatusNotFound) {
+		t.Fatalf("unexpected access log %v", line)
+	}
+
+	// Небезопасный идентификатор заменяется сгенерированным.
+	buf.Reset()
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderRequestID, "bad\nid")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if seen == "bad\nid" || len(seen) != 32 {
+		t.Fatalf("expected generated request id, got %q", seen)
+	}
+}
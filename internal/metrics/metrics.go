@@ -0,0 +1,201 @@
+// Package metrics содержит метрики Prometheus для consumer, БД, HTTP и gRPC.
+// Все методы *Metrics безопасно вызывать на nil: тогда метрики не пишутся.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Классы результата обработки сообщения.
+const (
+	ClassOK         = "ok"
+	ClassDecode     = "decode"
+	ClassValidation = "validation"
+	ClassStore      = "store"
+	ClassConflict   = "conflict"
+)
+
+// Metrics — набор метрик сервиса, зарегистрированных в одном реестре.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	MessagesConsumed *prometheus.CounterVec
+	MessagesFailed   *prometheus.CounterVec
+	MessagesDLQ      *prometheus.CounterVec
+	HandleDuration   *prometheus.HistogramVec
+	ConsumerLag      *prometheus.GaugeVec
+
+	DBQueryDuration *prometheus.HistogramVec
+	DBRetries       *prometheus.CounterVec
+
+	HTTPRequests    *prometheus.CounterVec
+	HTTPDuration    *prometheus.HistogramVec
+	HTTPRateLimited *prometheus.CounterVec
+
+	GRPCRequests *prometheus.CounterVec
+	GRPCDuration *prometheus.HistogramVec
+}
+
+// New создает метрики и регистрирует их в reg.
+// В тестах передается свой prometheus.NewRegistry(), чтобы проверять значения.
+func New(reg *prometheus.Registry) *Metrics {
+	m := &Metrics{
+		registry: reg,
+		MessagesConsumed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "orders_messages_consumed_total",
+			Help: "Сообщения, прочитанные из Kafka.",
+		}, []string{"topic"}),
+		MessagesFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "orders_messages_failed_total",
+			Help: "Сообщения, которые не удалось обработать, по классу ошибки.",
+		}, []string{"class"}),
+		MessagesDLQ: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "orders_messages_dlq_total",
+			Help: "Сообщения, отправленные в DLQ, по классу ошибки.",
+		}, []string{"class"}),
+		HandleDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "orders_message_handle_duration_seconds",
+			Help:    "Время обработки одного сообщения.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"class"}),
+		ConsumerLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "orders_consumer_lag",
+			Help: "Отставание consumer от конца партиции (сообщений).",
+		}, []string{"topic", "partition"}),
+		DBQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "orders_db_query_duration_seconds",
+			Help:    "Время одной попытки запроса к БД.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"op", "result"}),
+		DBRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "orders_db_retries_total",
+			Help: "Повторные попытки запросов к БД после временной ошибки.",
+		}, []string{"op"}),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "orders_http_requests_total",
+			Help: "HTTP-запросы по маршруту и статусу.",
+		}, []string{"method", "route", "status"}),
+		HTTPDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "orders_http_request_duration_seconds",
+			Help:    "Время обработки HTTP-запроса.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		HTTPRateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "orders_http_rate_limited_total",
+			Help: "Запросы HTTP (429) и gRPC (RESOURCE_EXHAUSTED), отклоненные лимитом, по группе маршрутов и виду лимита (requests, not_found).",
+		}, []string{"route", "limit"}),
+		GRPCRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "orders_grpc_requests_total",
+			Help: "gRPC-вызовы по методу и коду ответа.",
+		}, []string{"method", "code"}),
+		GRPCDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "orders_grpc_request_duration_seconds",
+			Help:    "Время выполнения gRPC-вызова (для потоков — время жизни потока).",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+	}
+
+	reg.MustRegister(
+		m.MessagesConsumed, m.MessagesFailed, m.MessagesDLQ, m.HandleDuration, m.ConsumerLag,
+		m.DBQueryDuration, m.DBRetries,
+		m.HTTPRequests, m.HTTPDuration, m.HTTPRateLimited,
+		m.GRPCRequests, m.GRPCDuration,
+	)
+	return m
+}
+
+// NewDefault создает реестр с метриками процесса и Go runtime.
+func NewDefault() *Metrics {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return New(reg)
+}
+
+// Register добавляет в реестр дополнительный сборщик (например, статистику кэша).
+func (m *Metrics) Register(c prometheus.Collector) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(c)
+}
+
+// Handler отдает метрики в текстовом формате Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// MessageConsumed учитывает прочитанное сообщение и отставание его партиции.
+func (m *Metrics) MessageConsumed(topic string, partition int, lag int64) {
+	if m == nil {
+		return
+	}
+	m.MessagesConsumed.WithLabelValues(topic).Inc()
+	if lag >= 0 {
+		m.ConsumerLag.WithLabelValues(topic, strconv.Itoa(partition)).Set(float64(lag))
+	}
+}
+
+// MessageHandled учитывает результат обработки сообщения.
+func (m *Metrics) MessageHandled(class string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.HandleDuration.WithLabelValues(class).Observe(d.Seconds())
+	if class != ClassOK {
+		m.MessagesFailed.WithLabelValues(class).Inc()
+	}
+}
+
+// MessageDLQ учитывает сообщение, отправленное в DLQ.
+func (m *Metrics) MessageDLQ(class string) {
+	if m == nil {
+		return
+	}
+	m.MessagesDLQ.WithLabelValues(class).Inc()
+}
+
+// DBQuery учитывает одну попытку запроса к БД.
+func (m *Metrics) DBQuery(op string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.DBQueryDuration.WithLabelValues(op, result).Observe(d.Seconds())
+}
+
+// DBRetry учитывает повторную попытку запроса к БД.
+func (m *Metrics) DBRetry(op string) {
+	if m == nil {
+		return
+	}
+	m.DBRetries.WithLabelValues(op).Inc()
+}
+
+// RateLimited учитывает запрос, отклоненный лимитом kind для группы маршрутов route.
+func (m *Metrics) RateLimited(route, kind string) {
+	if m == nil {
+		return
+	}
+	m.HTTPRateLimited.WithLabelValues(route, kind).Inc()
+}
+
+// GRPCCall учитывает завершенный gRPC-вызов method с кодом code.
+func (m *Metrics) GRPCCall(method, code string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.GRPCRequests.WithLabelValues(method, code).Inc()
+	m.GRPCDuration.WithLabelValues(method).Observe(d.Seconds())
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /order/{uid}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("uid") == "missing" {
+			http.Error(w, "order not found", http.StatusNotFound)
+		}
+	})
+	h := m.Middleware(mux)
+
+	for _, path := range []string{"/order/a", "/order/b", "/order/missing", "/nope"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues("GET", "GET /order/{uid}", "200")); got != 2 {
+		t.Fatalf("expected 2 ok requests, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues("GET", "GET /order/{uid}", "404")); got != 1 {
+		t.Fatalf("expected 1 not found request, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Fatalf("expected 1 unmatched request, got %v", got)
+	}
+}
+
+func TestMessages_ByClass(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	m.MessageConsumed("orders", 3, 42)
+	m.MessageHandled(ClassOK, time.Millisecond)
+	m.MessageHandled(ClassValidation, time.Millisecond)
+	m.MessageDLQ(ClassValidation)
+
+	if got := testutil.ToFloat64(m.ConsumerLag.WithLabelValues("orders", "3")); got != 42 {
+		t.Fatalf("expected lag 42, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.MessagesFailed.WithLabelValues(ClassValidation)); got != 1 {
+		t.Fatalf("expected 1 failed, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.MessagesFailed); got != 1 {
+		t.Fatalf("ok messages must not be counted as failed, got %d series", got)
+	}
+	if got := testutil.ToFloat64(m.MessagesDLQ.WithLabelValues(ClassValidation)); got != 1 {
+		t.Fatalf("expected 1 DLQ, got %v", got)
+	}
+}
+
+func TestHandler_TextFormat(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	m.DBRetry("get_order")
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `orders_db_retries_total{op="get_order"} 1`) {
+		t.Fatalf("metric not exported:\n%s", w.Body.String())
+	}
+}
+
+func TestNilMetrics_NoPanic(t *testing.T) {
+	var m *Metrics
+	m.MessageConsumed("orders", 0, 1)
+	m.MessageHandled(ClassStore, time.Second)
+	m.DBQuery("get_order", time.Second, nil)
+	h := m.Middleware(http.NotFoundHandler())
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
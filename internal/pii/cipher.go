@@ -0,0 +1,207 @@
+// Package pii защищает персональные данные: шифрование полей при хранении
+// (AES-256-GCM с ротацией ключей) и маскирование значений в ответах API.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// prefix отмечает зашифрованное значение: enc:v1:<key id>:<base64(nonce|ciphertext)>.
+// Значения без префикса считаются открытыми (записанными до включения шифрования).
+const prefix = "enc:v1:"
+
+// KeySize — длина ключа AES-256.
+const KeySize = 32
+
+// ErrUnknownKey возвращается, если значение зашифровано ключом, которого нет у провайдера.
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// KeyProvider выдает ключи шифрования.
+// Новые значения шифруются текущим ключом, старые расшифровываются ключом по его id,
+// поэтому после ротации старые ключи должны оставаться доступны до перешифрования.
+type KeyProvider interface {
+	// CurrentKey возвращает id и ключ для шифрования новых значений.
+	CurrentKey() (id string, key []byte, err error)
+	// Key возвращает ключ по id.
+	Key(id string) ([]byte, error)
+}
+
+// Keyring — набор ключей в памяти с одним текущим.
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyring создает набор ключей. current должен быть среди keys, все ключи — по KeySize байт.
+func NewKeyring(current string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current key %q not found", current)
+	}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("key %q: expected %d bytes, got %d", id, KeySize, len(key))
+		}
+	}
+	return &Keyring{current: current, keys: keys}, nil
+}
+
+// keyFile — формат локального файла ключей:
+//
+//	{"current": "2024-06", "keys": {"2024-01": "<base64>", "2024-06": "<base64>"}}
+type keyFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// LoadKeyFile читает набор ключей из JSON-файла (для разработки и простых установок).
+// Ключ генерируется, например, командой openssl rand -base64 32.
+func LoadKeyFile(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f keyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("key file %s: %w", path, err)
+	}
+	keys := make(map[string][]byte, len(f.Keys))
+	for id, encoded := range f.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key file %s: key %q: %w", path, id, err)
+		}
+		keys[id] = key
+	}
+	return NewKeyring(f.Current, keys)
+}
+
+// CurrentKey возвращает текущий ключ.
+func (k *Keyring) CurrentKey() (string, []byte, error) {
+	return k.current, k.keys[k.current], nil
+}
+
+// Key возвращает ключ по id.
+func (k *Keyring) Key(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// Cipher шифрует и расшифровывает отдельные строковые поля.
+// Методы безопасно вызывать на nil: тогда значения не шифруются.
+type Cipher struct {
+	keys KeyProvider
+}
+
+// NewCipher создает шифратор с ключами keys.
+func NewCipher(keys KeyProvider) *Cipher {
+	return &Cipher{keys: keys}
+}
+
+// Encrypt шифрует plain текущим ключом. Пустая строка остается пустой.
+func (c *Cipher) Encrypt(plain string) (string, error) {
+	if c == nil || plain == "" {
+		return plain, nil
+	}
+	id, key, err := c.keys.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), []byte(id))
+	return prefix + id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает значение. Открытые значения (без префикса) возвращаются как есть.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if c == nil {
+		return "", errors.New("encrypted value but encryption is not configured")
+	}
+	id, data, err := split(value)
+	if err != nil {
+		return "", err
+	}
+	key, err := c.keys.Key(id)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("encrypted value too short")
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("decrypt with key %q: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+// NeedsRekey сообщает, что значение открыто или зашифровано не текущим ключом
+// и должно быть перешифровано после ротации.
+func (c *Cipher) NeedsRekey(value string) bool {
+	if c == nil || value == "" {
+		return false
+	}
+	if !IsEncrypted(value) {
+		return true
+	}
+	current, _, err := c.keys.CurrentKey()
+	if err != nil {
+		return false
+	}
+	id, _, err := split(value)
+	return err == nil && id != current
+}
+
+// IsEncrypted сообщает, что значение зашифровано Cipher.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// split разбирает зашифрованное значение на id ключа и данные.
+func split(value string) (string, []byte, error) {
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", nil, errors.New("malformed encrypted value")
+	}
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	return id, data, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
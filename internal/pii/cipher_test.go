@@ -0,0 +1,115 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKeyring(t *testing.T, current string, ids ...string) *Keyring {
+	t.Helper()
+	keys := make(map[string][]byte, len(ids))
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, KeySize)
+	}
+	k, err := NewKeyring(current, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c := NewCipher(testKeyring(t, "k1", "k1"))
+
+	enc, err := c.Encrypt("+79161231234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(enc) || strings.Contains(enc, "79161231234") {
+		t.Fatalf("value not encrypted: %s", enc)
+	}
+	again, _ := c.Encrypt("+79161231234")
+	if again == enc {
+		t.Fatal("expected random nonce to produce different ciphertexts")
+	}
+	plain, err := c.Decrypt(enc)
+	if err != nil || plain != "+79161231234" {
+		t.Fatalf("got %q, %v", plain, err)
+	}
+
+	// открытые значения, записанные до включения шифрования, читаются как есть
+	if v, err := c.Decrypt("legacy"); err != nil || v != "legacy" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+	if v, _ := c.Encrypt(""); v != "" {
+		t.Fatalf("empty value must stay empty, got %q", v)
+	}
+}
+
+func TestCipher_Rotation(t *testing.T) {
+	old := NewCipher(testKeyring(t, "k1", "k1"))
+	enc, err := old.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := NewCipher(testKeyring(t, "k2", "k1", "k2"))
+	if plain, err := rotated.Decrypt(enc); err != nil || plain != "secret" {
+		t.Fatalf("old key must still decrypt: %q, %v", plain, err)
+	}
+	if !rotated.NeedsRekey(enc) || !rotated.NeedsRekey("plain") {
+		t.Fatal("values under old key or plaintext must need rekey")
+	}
+	fresh, _ := rotated.Encrypt("secret")
+	if rotated.NeedsRekey(fresh) {
+		t.Fatal("value under current key must not need rekey")
+	}
+
+	// без старого ключа значение не расшифровать
+	withoutOld := NewCipher(testKeyring(t, "k2", "k2"))
+	if _, err := withoutOld.Decrypt(enc); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestCipher_Tampered(t *testing.T) {
+	c := NewCipher(testKeyring(t, "k1", "k1"))
+	enc, _ := c.Encrypt("secret")
+	tampered := enc[:len(enc)-2] + "AA"
+	if _, err := c.Decrypt(tampered); err == nil {
+		t.Fatal("expected error for tampered value")
+	}
+	var nilCipher *Cipher
+	if _, err := nilCipher.Decrypt(enc); err == nil {
+		t.Fatal("expected error decrypting without keys")
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, KeySize))
+	path := filepath.Join(t.TempDir(), "keys.json")
+	data := `{"current": "2024-06", "keys": {"2024-06": "` + key + `"}}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	k, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _, _ := k.CurrentKey(); id != "2024-06" {
+		t.Fatalf("unexpected current key %q", id)
+	}
+
+	short := `{"current": "a", "keys": {"a": "c2hvcnQ="}}`
+	if err := os.WriteFile(path, []byte(short), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeyFile(path); err == nil {
+		t.Fatal("expected error for short key")
+	}
+}
@@ -0,0 +1,103 @@
+package pii
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// lookupContext отделяет ключ поиска от ключа шифрования и задает префикс
+// хэша открытых значений (его же вычисляет SQL-миграция 000004).
+const lookupContext = "lookup:"
+
+// NormalizePhone приводит телефон к виду, в котором он ищется: "+" и только цифры.
+// "+7 (916) 123-12-34" и "79161231234" дают "+79161231234". Без цифр — пустая строка.
+func NormalizePhone(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "+" + b.String()
+}
+
+// NormalizeEmail приводит email к нижнему регистру без пробелов по краям.
+func NormalizeEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// LookupHash возвращает слепой индекс нормализованного значения — HMAC-SHA256
+// на ключе, производном от текущего ключа шифрования. По нему значение ищется в БД,
+// не храня его открытым. Без шифрования (nil) — SHA-256 без ключа.
+// Пустое значение дает пустую строку.
+func (c *Cipher) LookupHash(normalized string) (string, error) {
+	if normalized == "" {
+		return "", nil
+	}
+	if c == nil {
+		return plainLookupHash(normalized), nil
+	}
+	_, key, err := c.keys.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	return keyedLookupHash(key, normalized), nil
+}
+
+// LookupHashes возвращает все хэши, под которыми значение может быть записано:
+// по каждому ключу провайдера (если он умеет их перечислить, как Keyring) и без ключа —
+// для строк, записанных до включения шифрования или ротации и еще не перешифрованных.
+func (c *Cipher) LookupHashes(normalized string) ([]string, error) {
+	if normalized == "" {
+		return nil, nil
+	}
+	hashes := []string{plainLookupHash(normalized)}
+	if c == nil {
+		return hashes, nil
+	}
+	current, _, err := c.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{current}
+	if l, ok := c.keys.(interface{ IDs() []string }); ok {
+		ids = l.IDs()
+	}
+	for _, id := range ids {
+		key, err := c.keys.Key(id)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, keyedLookupHash(key, normalized))
+	}
+	return hashes, nil
+}
+
+func plainLookupHash(v string) string {
+	sum := sha256.Sum256([]byte(lookupContext + v))
+	return hex.EncodeToString(sum[:])
+}
+
+func keyedLookupHash(key []byte, v string) string {
+	sub := hmac.New(sha256.New, key)
+	sub.Write([]byte(lookupContext))
+	mac := hmac.New(sha256.New, sub.Sum(nil))
+	mac.Write([]byte(v))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IDs возвращает id всех ключей набора.
+func (k *Keyring) IDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,58 @@
+package pii
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"+7 (916) 123-12-34": "+79161231234",
+		"79161231234":        "+79161231234",
+		"no digits":          "",
+	} {
+		if got := NormalizePhone(in); got != want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := NormalizeEmail("  Ivan@Mail.RU "); got != "ivan@mail.ru" {
+		t.Errorf("NormalizeEmail = %q", got)
+	}
+}
+
+func TestLookupHash(t *testing.T) {
+	var plain *Cipher
+	h, err := plain.LookupHash("+79161231234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// то же значение вычисляет миграция 000004 для открытых строк:
+	// encode(sha256(convert_to('lookup:' || value, 'UTF8')), 'hex')
+	if h != "c4c9d795e25554f69f51243a8ac172b919201950991d3fc01fe917ceff4765a4" {
+		t.Fatalf("unexpected plain hash %q", h)
+	}
+	if h2, _ := plain.LookupHash("+79161231234"); h2 != h {
+		t.Fatal("hash must be deterministic")
+	}
+	if empty, _ := plain.LookupHash(""); empty != "" {
+		t.Fatal("empty value must not be hashed")
+	}
+
+	old := NewCipher(testKeyring(t, "k1", "k1", "k2"))
+	rotated := NewCipher(testKeyring(t, "k2", "k1", "k2"))
+	written, _ := old.LookupHash("+79161231234")
+	current, _ := rotated.LookupHash("+79161231234")
+	if written == h || written == current {
+		t.Fatal("keyed hashes must differ from the plain hash and between keys")
+	}
+
+	candidates, err := rotated.LookupHashes("+79161231234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{h, written, current} {
+		if !slices.Contains(candidates, want) {
+			t.Fatalf("candidates %v must contain %q", candidates, want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package pii
+
+import "strings"
+
+// maskRune — символ, которым закрываются скрытые символы.
+const maskRune = '*'
+
+// Middle оставляет head первых и tail последних символов, остальные заменяет на '*'
+// с сохранением длины. Если строка не длиннее head+tail, закрывается целиком.
+func Middle(s string, head, tail int) string {
+	r := []rune(s)
+	if len(r) <= head+tail {
+		return strings.Repeat(string(maskRune), len(r))
+	}
+	for i := head; i < len(r)-tail; i++ {
+		r[i] = maskRune
+	}
+	return string(r)
+}
+
+// Phone маскирует телефон, оставляя код страны и последние 4 цифры: +79161231234 → +7******1234.
+func Phone(s string) string {
+	return Middle(s, 2, 4)
+}
+
+// Email оставляет первый символ имени и домен: ivan@mail.ru → i***@mail.ru.
+func Email(s string) string {
+	local, domain, ok := strings.Cut(s, "@")
+	if !ok {
+		return Middle(s, 1, 0)
+	}
+	if local == "" {
+		return "@" + domain
+	}
+	return string([]rune(local)[:1]) + "***@" + domain
+}
+
+// Name оставляет первую букву каждого слова: Иван Петров → И*** П***.
+func Name(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		words[i] = string([]rune(w)[:1]) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+// Tail оставляет только последние n символов: номера транзакций и т.п.
+func Tail(s string, n int) string {
+	return Middle(s, 0, n)
+}
+
+// Redact скрывает значение полностью.
+func Redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "***"
+}
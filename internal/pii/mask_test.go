@@ -0,0 +1,25 @@
+package pii
+
+import "testing"
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"phone", Phone("+79161231234"), "+7******1234"},
+		{"short phone", Phone("123"), "***"},
+		{"email", Email("ivan@mail.ru"), "i***@mail.ru"},
+		{"email without at", Email("ivan"), "i***"},
+		{"name", Name("Иван Петров"), "И*** П***"},
+		{"transaction", Tail("b563feb7b2b84b6test", 4), "***************test"},
+		{"redact", Redact("Lenina 1"), "***"},
+		{"redact empty", Redact(""), ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen возвращается, пока автомат разомкнут и вызовы не выполняются.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// OpenError — отказ разомкнутого автомата со временем до пробного вызова.
+// errors.Is(err, ErrCircuitOpen) для него истинно.
+type OpenError struct {
+	// RetryAfter — через сколько автомат пропустит пробный вызов; 0 — пробный вызов уже идет.
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string { return ErrCircuitOpen.Error() }
+
+func (e *OpenError) Is(target error) bool { return target == ErrCircuitOpen }
+
+// RetryAfter возвращает время до пробного вызова, если err — отказ разомкнутого автомата.
+func RetryAfter(err error) (time.Duration, bool) {
+	var open *OpenError
+	if errors.As(err, &open) {
+		return open.RetryAfter, true
+	}
+	return 0, errors.Is(err, ErrCircuitOpen)
+}
+
+// BreakerState — состояние автомата.
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// Breaker — автомат (circuit breaker): после threshold временных ошибок подряд
+// размыкается на cooldown, затем пропускает один пробный вызов.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+// NewBreaker создает автомат с порогом ошибок threshold и временем размыкания cooldown.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// State возвращает текущее состояние автомата.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state()
+}
+
+func (b *Breaker) state() BreakerState {
+	if b.failures < b.threshold {
+		return BreakerClosed
+	}
+	if b.now().Sub(b.openedAt) < b.cooldown {
+		return BreakerOpen
+	}
+	return BreakerHalfOpen
+}
+
+// Allow сообщает, можно ли выполнить вызов. В полуоткрытом состоянии
+// пропускается только один пробный вызов.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state() {
+	case BreakerOpen:
+		return &OpenError{RetryAfter: b.cooldown - b.now().Sub(b.openedAt)}
+	case BreakerHalfOpen:
+		if b.probing {
+			return &OpenError{}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Success фиксирует успешный вызов и замыкает автомат.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure фиксирует временную ошибку. При достижении порога автомат размыкается.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+	}
+}
+
+// Do выполняет fn через автомат. Ошибки, для которых check возвращает false,
+// не считаются отказами (например, «запись не найдена»).
+func Do[T any](b *Breaker, check TemporaryErrorChecker, fn func() (T, error)) (T, error) {
+	if b == nil {
+		return fn()
+	}
+	if err := b.Allow(); err != nil {
+		var zero T
+		return zero, err
+	}
+	result, err := fn()
+	if err != nil && check(err) {
+		b.Failure()
+	} else {
+		b.Success()
+	}
+	return result, err
+}
+
+// Check — проверка readiness: не готов, пока автомат разомкнут.
+func (b *Breaker) Check(ctx context.Context) (any, error) {
+	state := b.State()
+	if state == BreakerOpen {
+		return state, ErrCircuitOpen
+	}
+	return state, nil
+}
@@ -0,0 +1,68 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTemp = errors.New("temporary")
+
+func alwaysTemporary(error) bool { return true }
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	calls := 0
+	fail := func() (int, error) { calls++; return 0, errTemp }
+
+	Do(b, alwaysTemporary, fail)
+	Do(b, alwaysTemporary, fail)
+	if b.State() != BreakerOpen {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+	if _, err := Do(b, alwaysTemporary, fail); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+	now = now.Add(20 * time.Second)
+	if d, ok := RetryAfter(b.Allow()); !ok || d != 40*time.Second {
+		t.Fatalf("expected retry after 40s, got %v %v", d, ok)
+	}
+	now = now.Add(-20 * time.Second)
+
+	// после cooldown пропускается один пробный вызов
+	now = now.Add(time.Minute)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("expected half-open, got %s", b.State())
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("expected probe allowed, got %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected second probe rejected, got %v", err)
+	}
+	b.Success()
+	if b.State() != BreakerClosed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+}
+
+func TestBreaker_PermanentErrorsNotCounted(t *testing.T) {
+	b := NewBreaker(1, time.Minute)
+	notFound := errors.New("not found")
+	check := func(err error) bool { return !errors.Is(err, notFound) }
+
+	for i := 0; i < 3; i++ {
+		if _, err := Do(b, check, func() (int, error) { return 0, notFound }); !errors.Is(err, notFound) {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+}
@@ -2,16 +2,27 @@ package retry
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
+
+	"github.com/mitrich772/go-order-service/internal/logging"
 )
 
 // TemporaryErrorChecker проверяет, является ли ошибка временной
 type TemporaryErrorChecker func(error) bool
 
+// Notify вызывается перед каждой повторной попыткой с номером неудачной попытки и ее ошибкой.
+type Notify func(attempt int, err error)
+
 // Retry выполняет функцию fn несколько раз с задержкой
 // check ожидает функцию для отброса невременных ошибок
 func Retry[T any](maxRetries int, delay time.Duration, check TemporaryErrorChecker, fn func() (T, error)) (T, error) {
+	return RetryNotify(maxRetries, delay, check, nil, fn)
+}
+
+// RetryNotify работает как Retry и дополнительно вызывает notify перед каждым повтором
+// (например, чтобы посчитать повторы в метриках). notify может быть nil.
+func RetryNotify[T any](maxRetries int, delay time.Duration, check TemporaryErrorChecker, notify Notify, fn func() (T, error)) (T, error) {
 	var lastErr error
 	var result T
 
@@ -25,7 +36,14 @@ func Retry[T any](maxRetries int, delay time.Duration, check TemporaryErrorCheck
 			return result, lastErr
 		}
 
-		log.Printf("Retry попытка %d/%d не удалась: %v", i+1, maxRetries, lastErr)
+		slog.Warn("Retry: попытка не удалась",
+			slog.Int("attempt", i+1),
+			slog.Int("max_attempts", maxRetries),
+			logging.Err(lastErr),
+		)
+		if notify != nil && i+1 < maxRetries {
+			notify(i+1, lastErr)
+		}
 		time.Sleep(delay)
 	}
 
@@ -0,0 +1,148 @@
+// Package tracing настраивает OpenTelemetry: экспортер спанов и W3C trace-context.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортеры спанов.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+	ExporterMemory = "memory"
+)
+
+// Propagator — формат передачи контекста трассировки (W3C traceparent и baggage).
+var Propagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// Config содержит настройки трассировки.
+type Config struct {
+	// Exporter — none, stdout, otlp или memory.
+	Exporter string
+	// Endpoint — адрес OTLP/HTTP коллектора (host:port), пустой — из OTEL_EXPORTER_OTLP_ENDPOINT.
+	Endpoint string
+	// Insecure отключает TLS для OTLP.
+	Insecure bool
+	// ServiceName — имя сервиса в ресурсах спанов.
+	ServiceName string
+	// SampleRatio — доля трассируемых корневых спанов (0..1).
+	SampleRatio float64
+}
+
+// Provider — настроенный провайдер трассировки.
+type Provider struct {
+	*sdktrace.TracerProvider
+	// Memory заполнен только для экспортера memory.
+	Memory *tracetest.InMemoryExporter
+}
+
+// Setup создает провайдер по cfg и делает его глобальным вместе с Propagator.
+// Для экспортера none возвращается nil: используется no-op провайдер otel по умолчанию.
+func Setup(ctx context.Context, cfg Config) (*Provider, error) {
+	otel.SetTextMapPropagator(Propagator)
+	if cfg.Exporter == "" || cfg.Exporter == ExporterNone {
+		return nil, nil
+	}
+
+	p := &Provider{}
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+		exporter = exp
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		exporter = exp
+	case ExporterMemory:
+		p.Memory = tracetest.NewInMemoryExporter()
+		exporter = p.Memory
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	p.TracerProvider = NewProvider(cfg, exporter)
+	otel.SetTracerProvider(p.TracerProvider)
+	return p, nil
+}
+
+// NewProvider создает провайдер с заданным экспортером, не делая его глобальным.
+// Экспортер memory работает синхронно, остальные — пачками.
+func NewProvider(cfg Config, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	name := cfg.ServiceName
+	if name == "" {
+		name = "order-service"
+	}
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	var processor sdktrace.TracerProviderOption
+	if _, ok := exporter.(*tracetest.InMemoryExporter); ok {
+		processor = sdktrace.WithSyncer(exporter)
+	} else {
+		processor = sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(5*time.Second))
+	}
+
+	return sdktrace.NewTracerProvider(
+		processor,
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", name))),
+	)
+}
+
+// Shutdown отправляет оставшиеся спаны и останавливает провайдер. Безопасен для nil.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	return p.TracerProvider.Shutdown(ctx)
+}
+
+// instrumentation — имя библиотеки инструментирования в спанах.
+const instrumentation = "github.com/mitrich772/go-order-service"
+
+// Start начинает спан name у текущего глобального провайдера.
+// Трейсер берется при каждом вызове, чтобы подхватывать провайдер, установленный позже (в тестах).
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, opts...)
+}
+
+// End завершает спан, отмечая ошибку, если она есть.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware_ContinuesTraceAndNamesByRoute(t *testing.T) {
+	p, err := Setup(context.Background(), Config{Exporter: ExporterMemory})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Shutdown(context.Background())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /order/{uid}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "store.get")
+		span.End()
+	})
+
+	req := httptest.NewRequest("GET", "/order/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	Middleware(mux).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := p.Memory.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	child, server := spans[0], spans[1]
+	if server.Name != "GET /order/{uid}" || server.SpanKind != trace.SpanKindServer {
+		t.Fatalf("unexpected server span %q %v", server.Name, server.SpanKind)
+	}
+	if server.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace not continued: %s", server.SpanContext.TraceID())
+	}
+	if child.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Fatal("handler span is not a child of the server span")
+	}
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), Config{Exporter: "jaeger"}); err == nil {
+		t.Fatal("expected error for unknown exporter")
+	}
+	p, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	if err != nil || p != nil {
+		t.Fatalf("expected nil provider for none, got %v %v", p, err)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"net/http"
+)
+
+// adminTokenHeader — заголовок с токеном администратора.
+const adminTokenHeader = "X-Admin-Token"
+
+// requireAdmin пропускает запрос только от вызывающего с ролью admin.
+// Если аутентификация не настроена (нет ни Auth, ни AdminToken), admin-эндпоинты отключены.
+// Запросы ограничены лимитом группы admin.
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	admin := s.RateLimit.Limit(RouteAdmin, requireRole(next, RoleAdmin))
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.authenticator() == nil || s.Cache == nil {
+			http.NotFound(w, r)
+			return
+		}
+		admin(w, r)
+	}
+}
+
+// CacheStatsHandler возвращает счетчики кэша.
+func (s *Server) CacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.Cache.Stats())
+}
+
+// CacheInspectHandler возвращает заказ из кэша без обращения к БД.
+func (s *Server) CacheInspectHandler(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+	order, ok := s.Cache.Inspect(uid)
+	if !ok {
+		s.Audit.Record(r, AuditEvent{Action: AuditCacheInspect, OrderUID: uid, Result: AuditNotFound})
+		writeProblem(w, r, http.StatusNotFound, "key not cached")
+		return
+	}
+	s.Audit.Record(r, AuditEvent{Action: AuditCacheInspect, OrderUID: uid, Result: AuditOK})
+	writeJSON(w, order)
+}
+
+// CacheEvictHandler удаляет заказ из кэша.
+func (s *Server) CacheEvictHandler(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+	if !s.Cache.Evict(uid) {
+		s.Audit.Record(r, AuditEvent{Action: AuditCacheEvict, OrderUID: uid, Result: AuditNotFound})
+		writeProblem(w, r, http.StatusNotFound, "key not cached")
+		return
+	}
+	s.Audit.Record(r, AuditEvent{Action: AuditCacheEvict, OrderUID: uid, Result: AuditOK})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CachePurgeHandler полностью очищает кэш.
+func (s *Server) CachePurgeHandler(w http.ResponseWriter, r *http.Request) {
+	s.Cache.Purge()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CacheWarmHandler заново прогревает кэш из БД.
+func (s *Server) CacheWarmHandler(w http.ResponseWriter, r *http.Request) {
+	n, err := s.Cache.Warm()
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, map[string]int{"loaded": n})
+}
+
+// CacheWarmupHandler возвращает прогресс фонового прогрева кэша.
+func (s *Server) CacheWarmupHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.Cache.WarmupStatus())
+}
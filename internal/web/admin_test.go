@@ -0,0 +1,148 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mitrich772/go-order-service/internal/cache"
+	"github.com/mitrich772/go-order-service/internal/database"
+	mockdb "github.com/mitrich772/go-order-service/internal/database/mocks"
+	"github.com/mitrich772/go-order-service/internal/health"
+)
+
+func newAdminServer(t *testing.T) (*Server, *mockdb.MockDatabase) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	mockDB := mockdb.NewMockDatabase(ctrl)
+	mockDB.EXPECT().GetLastNOrders(gomock.Any(), 10).Return([]database.Order{{OrderUID: "a"}}, nil)
+
+	store := cache.NewDBWithCacheStore(mockDB, 10)
+	if _, err := store.Warm(); err != nil {
+		t.Fatal(err)
+	}
+	return &Server{Store: store, Cache: store, AdminToken: "secret"}, mockDB
+}
+
+func adminRequest(mux http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set(adminTokenHeader, token)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdmin_RequiresToken(t *testing.T) {
+	srv, _ := newAdminServer(t)
+	mux := srv.Routes()
+
+	if w := adminRequest(mux, "GET", "/admin/cache/stats", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", w.Code)
+	}
+	if w := adminRequest(mux, "GET", "/admin/cache/stats", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with wrong token, got %d", w.Code)
+	}
+
+	// роль analyst не дает доступа к admin-эндпоинтам
+	srv.Auth = NewAuthenticator(AuthConfig{APIKeys: map[string]Principal{
+		"k-ana":   {Subject: "ana", Role: RoleAnalyst},
+		"k-admin": {Subject: "root", Role: RoleAdmin},
+	}})
+	if w := adminRequest(srv.Routes(), "GET", "/admin/cache/stats", "k-ana"); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for analyst, got %d", w.Code)
+	}
+	if w := adminRequest(srv.Routes(), "GET", "/admin/cache/stats", "k-admin"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for admin key, got %d", w.Code)
+	}
+	srv.Auth = nil
+
+	// без настроенного токена эндпоинты отключены
+	srv.AdminToken = ""
+	if w := adminRequest(srv.Routes(), "GET", "/admin/cache/stats", "secret"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when admin disabled, got %d", w.Code)
+	}
+}
+
+func TestAdmin_StatsInspectEvictPurge(t *testing.T) {
+	srv, _ := newAdminServer(t)
+	mux := srv.Routes()
+
+	w := adminRequest(mux, "GET", "/admin/cache/stats", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var st cache.Stats
+	if err := json.NewDecoder(w.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Size != 1 || st.Warmed != 1 {
+		t.Fatalf("expected size=1 warmed=1, got %+v", st)
+	}
+
+	if w := adminRequest(mux, "GET", "/admin/cache/keys/a", "secret"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for cached key, got %d", w.Code)
+	}
+	if w := adminRequest(mux, "GET", "/admin/cache/keys/zzz", "secret"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing key, got %d", w.Code)
+	}
+	if w := adminRequest(mux, "DELETE", "/admin/cache/keys/a", "secret"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 on evict, got %d", w.Code)
+	}
+	if w := adminRequest(mux, "DELETE", "/admin/cache/keys/a", "secret"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 on second evict, got %d", w.Code)
+	}
+	if w := adminRequest(mux, "POST", "/admin/cache/purge", "secret"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 on purge, got %d", w.Code)
+	}
+}
+
+func TestAdmin_Warm(t *testing.T) {
+	srv, mockDB := newAdminServer(t)
+	mux := srv.Routes()
+
+	mockDB.EXPECT().GetLastNOrders(gomock.Any(), 10).Return([]database.Order{{OrderUID: "a"}, {OrderUID: "b"}}, nil)
+
+	w := adminRequest(mux, "POST", "/admin/cache/warm", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var res map[string]int
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res["loaded"] != 2 {
+		t.Fatalf("expected loaded=2, got %v", res)
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	srv, _ := newAdminServer(t)
+	srv.Health = health.NewRegistry(time.Second)
+	srv.Health.AddReadiness("cache_warmup", health.CheckerFunc(func(ctx context.Context) (any, error) {
+		return nil, errors.New("cache warm-up in progress")
+	}))
+	mux := srv.Routes()
+
+	if w := adminRequest(mux, "GET", "/healthz", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from /healthz, got %d", w.Code)
+	}
+	w := adminRequest(mux, "GET", "/readyz", "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 from /readyz, got %d", w.Code)
+	}
+	var report health.Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Components["cache_warmup"].Status != health.StatusDown {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
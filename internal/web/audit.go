@@ -0,0 +1,79 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// Действия в журнале аудита.
+const (
+	AuditOrderRead    = "order.read"
+	AuditCacheInspect = "cache.inspect"
+	AuditCacheEvict   = "cache.evict"
+)
+
+// Результаты действий в журнале аудита.
+const (
+	AuditOK       = "ok"
+	AuditNotFound = "not_found"
+)
+
+// AuditEvent — запись журнала аудита об обращении к заказу.
+type AuditEvent struct {
+	Time       time.Time `json:"time"`
+	Subject    string    `json:"subject"`
+	Role       Role      `json:"role,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	Action     string    `json:"action"`
+	OrderUID   string    `json:"order_uid"`
+	Result     string    `json:"result"`
+	Masked     bool      `json:"masked"`
+	RequestID  string    `json:"request_id,omitempty"`
+	RemoteAddr string    `json:"remote_addr"`
+}
+
+// AuditLog пишет события аудита в w по одному JSON на строку.
+// Методы безопасно вызывать на nil: тогда аудит не ведется.
+type AuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewAuditLog создает журнал аудита, пишущий в w.
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{enc: json.NewEncoder(w)}
+}
+
+// Record дополняет событие вызывающим, временем и request_id из запроса и записывает его.
+// Анонимные обращения записываются с subject anonymous.
+func (l *AuditLog) Record(r *http.Request, e AuditEvent) {
+	l.RecordContext(r.Context(), r.RemoteAddr, e)
+}
+
+// RecordContext — Record для обращений не по HTTP (gRPC): вызывающий и request_id
+// берутся из ctx, адрес клиента передается явно.
+func (l *AuditLog) RecordContext(ctx context.Context, remoteAddr string, e AuditEvent) {
+	if l == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	e.Subject = "anonymous"
+	if p, ok := PrincipalFrom(ctx); ok {
+		e.Subject, e.Role, e.Method = p.Subject, p.Role, p.Method
+	}
+	e.RequestID = logging.RequestID(ctx)
+	e.RemoteAddr = remoteAddr
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(e); err != nil {
+		slog.ErrorContext(ctx, "Ошибка записи журнала аудита", logging.Err(err))
+	}
+}
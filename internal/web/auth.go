@@ -0,0 +1,389 @@
+package web
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// Role — роль вызывающего, определяет доступные эндпоинты и видимость полей.
+type Role string
+
+const (
+	// RoleSupport — поддержка: заказы с полными персональными данными.
+	RoleSupport Role = "support"
+	// RoleAnalyst — аналитик: заказы с замаскированными персональными данными.
+	RoleAnalyst Role = "analyst"
+	// RoleAdmin — администратор: все, включая /admin/.
+	RoleAdmin Role = "admin"
+	// RoleIngest — партнер: только прием заказов POST /orders.
+	RoleIngest Role = "ingest"
+)
+
+// orderReaders — роли, которым доступен GET /order/{uid}.
+var orderReaders = []Role{RoleSupport, RoleAnalyst, RoleAdmin}
+
+// orderWriters — роли, которым доступен прием заказов по HTTP.
+var orderWriters = []Role{RoleIngest, RoleAdmin}
+
+// piiReaders — роли, которые видят персональные данные без маски.
+var piiReaders = []Role{RoleSupport, RoleAdmin}
+
+// CanReadOrders сообщает, что роли доступно чтение заказов (HTTP и gRPC).
+func (r Role) CanReadOrders() bool {
+	return slices.Contains(orderReaders, r)
+}
+
+// CanViewPII сообщает, что роль видит персональные данные без маски.
+func (r Role) CanViewPII() bool {
+	return slices.Contains(piiReaders, r)
+}
+
+// ParseRole проверяет имя роли.
+func ParseRole(s string) (Role, error) {
+	switch r := Role(s); r {
+	case RoleSupport, RoleAnalyst, RoleAdmin, RoleIngest:
+		return r, nil
+	default:
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+}
+
+// Principal — аутентифицированный вызывающий.
+type Principal struct {
+	Subject string `json:"subject"`
+	Role    Role   `json:"role"`
+	// Method — способ аутентификации: api_key или jwt.
+	Method string `json:"method"`
+}
+
+// Способы аутентификации.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// apiKeyHeader — заголовок со статическим API-ключом.
+const apiKeyHeader = "X-API-Key"
+
+// jwtLeeway — допустимое расхождение часов при проверке exp и nbf.
+const jwtLeeway = 30 * time.Second
+
+// ErrNoCredentials — запрос без учетных данных.
+var ErrNoCredentials = errors.New("no credentials")
+
+// ErrInvalidCredentials — учетные данные переданы, но не прошли проверку.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// AuthConfig содержит ключи для проверки учетных данных.
+// Токены проверяются локально, без обращения к внешнему серверу.
+type AuthConfig struct {
+	// APIKeys — статические ключи: ключ → владелец и роль.
+	APIKeys map[string]Principal
+	// JWTSecret — общий секрет для токенов HS256, пустой отключает HS256.
+	JWTSecret []byte
+	// JWTPublicKey — открытый ключ для токенов RS256, nil отключает RS256.
+	JWTPublicKey *rsa.PublicKey
+	// Issuer и Audience, если заданы, сверяются с iss и aud токена.
+	Issuer   string
+	Audience string
+}
+
+// Authenticator проверяет API-ключи и JWT bearer-токены.
+type Authenticator struct {
+	cfg AuthConfig
+	now func() time.Time
+}
+
+// NewAuthenticator создает проверку учетных данных по cfg.
+func NewAuthenticator(cfg AuthConfig) *Authenticator {
+	return &Authenticator{cfg: cfg, now: time.Now}
+}
+
+// Authenticate определяет вызывающего по заголовкам X-API-Key, X-Admin-Token
+// или Authorization: Bearer (JWT или API-ключ).
+// Возвращает ErrNoCredentials, если учетных данных нет. Безопасен для nil.
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	return a.AuthenticateHeader(r.Header)
+}
+
+// AuthenticateHeader — Authenticate по готовым заголовкам, например по метаданным gRPC.
+func (a *Authenticator) AuthenticateHeader(h http.Header) (Principal, error) {
+	if a == nil {
+		return Principal{}, ErrNoCredentials
+	}
+	if key := h.Get(apiKeyHeader); key != "" {
+		return a.apiKey(key)
+	}
+	if key := h.Get(adminTokenHeader); key != "" {
+		return a.apiKey(key)
+	}
+	token, ok := strings.CutPrefix(h.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	if strings.Count(token, ".") == 2 {
+		return a.jwt(token)
+	}
+	return a.apiKey(token)
+}
+
+// apiKey ищет статический ключ. Сравниваются все ключи, чтобы время ответа не зависело от совпадения.
+func (a *Authenticator) apiKey(key string) (Principal, error) {
+	var found *Principal
+	for k, p := range a.cfg.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			found = &p
+		}
+	}
+	if found == nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	p := *found
+	p.Method = MethodAPIKey
+	return p, nil
+}
+
+// jwtHeader — заголовок JWT.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// jwtClaims — проверяемые поля JWT. Роль передается в claim role.
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Role      string   `json:"role"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+}
+
+// audience — claim aud: строка или массив строк.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// jwt проверяет подпись (HS256 или RS256), срок действия, издателя, аудиторию и роль токена.
+func (a *Authenticator) jwt(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, fmt.Errorf("%w: header: %v", ErrInvalidCredentials, err)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: signature encoding", ErrInvalidCredentials)
+	}
+
+	// Алгоритм принимается только тот, для которого настроен ключ: alg=none и подмена RS256 на HS256 отвергаются.
+	switch {
+	case header.Alg == "HS256" && len(a.cfg.JWTSecret) > 0:
+		mac := hmac.New(sha256.New, a.cfg.JWTSecret)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return Principal{}, fmt.Errorf("%w: bad signature", ErrInvalidCredentials)
+		}
+	case header.Alg == "RS256" && a.cfg.JWTPublicKey != nil:
+		sum := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(a.cfg.JWTPublicKey, crypto.SHA256, sum[:], sig); err != nil {
+			return Principal{}, fmt.Errorf("%w: bad signature", ErrInvalidCredentials)
+		}
+	default:
+		return Principal{}, fmt.Errorf("%w: unsupported alg %q", ErrInvalidCredentials, header.Alg)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, fmt.Errorf("%w: claims: %v", ErrInvalidCredentials, err)
+	}
+	now := a.now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(jwtLeeway)) {
+		return Principal{}, fmt.Errorf("%w: token expired", ErrInvalidCredentials)
+	}
+	if claims.NotBefore != 0 && now.Add(jwtLeeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return Principal{}, fmt.Errorf("%w: token not yet valid", ErrInvalidCredentials)
+	}
+	if a.cfg.Issuer != "" && claims.Issuer != a.cfg.Issuer {
+		return Principal{}, fmt.Errorf("%w: issuer %q", ErrInvalidCredentials, claims.Issuer)
+	}
+	if a.cfg.Audience != "" && !slices.Contains(claims.Audience, a.cfg.Audience) {
+		return Principal{}, fmt.Errorf("%w: audience", ErrInvalidCredentials)
+	}
+	role, err := ParseRole(claims.Role)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if claims.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: empty subject", ErrInvalidCredentials)
+	}
+	return Principal{Subject: claims.Subject, Role: role, Method: MethodJWT}, nil
+}
+
+// decodeSegment разбирает base64url-сегмент JWT в v.
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ParseAPIKeys разбирает список ключей вида "subject:role:key,subject2:role:key2".
+// Ошибка называет только номер записи (с 1): текст записи может содержать ключ.
+func ParseAPIKeys(spec string) (map[string]Principal, error) {
+	keys := make(map[string]Principal)
+	for i, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid API key entry #%d, want subject:role:key", i+1)
+		}
+		role, err := ParseRole(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid API key entry #%d: unknown role", i+1)
+		}
+		keys[parts[2]] = Principal{Subject: parts[0], Role: role}
+	}
+	return keys, nil
+}
+
+// LoadRSAPublicKey читает открытый ключ RS256 из PEM-файла (PKIX или PKCS#1).
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an RSA public key", path)
+	}
+	return key, nil
+}
+
+type principalKey struct{}
+
+// WithPrincipal кладет вызывающего в контекст.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom возвращает вызывающего, определенного middleware аутентификации.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// authenticator возвращает проверку учетных данных сервера. Без Auth единственный
+// вариант — AdminToken с ролью admin; без обоих аутентификация выключена (nil).
+func (s *Server) authenticator() *Authenticator {
+	if s.Auth != nil {
+		return s.Auth
+	}
+	if s.AdminToken == "" {
+		return nil
+	}
+	return NewAuthenticator(AuthConfig{
+		APIKeys: map[string]Principal{s.AdminToken: {Subject: "admin", Role: RoleAdmin}},
+	})
+}
+
+// authenticate определяет вызывающего и кладет его в контекст запроса.
+// Запросы без учетных данных проходят дальше анонимно, с неверными — получают 401.
+func authenticate(auth *Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := auth.Authenticate(r)
+		switch {
+		case err == nil:
+			inner := withCaller(r, p)
+			next.ServeHTTP(w, inner)
+			// шаблон маршрута из ServeMux нужен внешним middleware (метрики, трассировка, лог)
+			r.Pattern = inner.Pattern
+		case errors.Is(err, ErrNoCredentials):
+			next.ServeHTTP(w, r)
+		default:
+			slog.WarnContext(r.Context(), "Отказ в аутентификации", logging.Err(err))
+			unauthorized(w, r)
+		}
+	})
+}
+
+// withCaller возвращает копию запроса с вызывающим p в контексте и в полях лога.
+func withCaller(r *http.Request, p Principal) *http.Request {
+	ctx := WithPrincipal(r.Context(), p)
+	ctx = logging.With(ctx, slog.String("subject", p.Subject), slog.String("role", string(p.Role)))
+	return r.WithContext(ctx)
+}
+
+// requireRole пропускает только вызывающих с одной из ролей roles:
+// без учетных данных — 401, с другой ролью — 403.
+func requireRole(next http.HandlerFunc, roles ...Role) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := PrincipalFrom(r.Context())
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
+		if !slices.Contains(roles, p.Role) {
+			writeProblem(w, r, http.StatusForbidden, "forbidden")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// hasRole сообщает, что вызывающий запроса имеет одну из ролей roles.
+func hasRole(r *http.Request, roles ...Role) bool {
+	p, ok := PrincipalFrom(r.Context())
+	return ok && slices.Contains(roles, p.Role)
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="order-service"`)
+	writeProblem(w, r, http.StatusUnauthorized, "unauthorized")
+}
@@ -0,0 +1,215 @@
+package web
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockcache "github.com/mitrich772/go-order-service/internal/cache/mocks"
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+func segment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hs256Token(t *testing.T, secret []byte, claims map[string]any) string {
+	t.Helper()
+	signed := segment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + segment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rs256Token(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	signed := segment(t, map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + segment(t, claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func bearer(token string) *http.Request {
+	req := httptest.NewRequest("GET", "/order/123", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestAuthenticate_APIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys("alice:support:k-alice, bob:analyst:k-bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAuthenticator(AuthConfig{APIKeys: keys})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(apiKeyHeader, "k-bob")
+	p, err := auth.Authenticate(req)
+	if err != nil || p.Subject != "bob" || p.Role != RoleAnalyst || p.Method != MethodAPIKey {
+		t.Fatalf("unexpected principal %+v, %v", p, err)
+	}
+	if p, err := auth.Authenticate(bearer("k-alice")); err != nil || p.Role != RoleSupport {
+		t.Fatalf("bearer API key: %+v, %v", p, err)
+	}
+	if _, err := auth.Authenticate(bearer("nope")); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := auth.Authenticate(httptest.NewRequest("GET", "/", nil)); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("expected ErrNoCredentials, got %v", err)
+	}
+
+	if _, err := ParseAPIKeys("alice:root:k"); err == nil {
+		t.Fatal("expected error for unknown role")
+	}
+	// текст записи может оказаться ключом и в ошибку не попадает
+	for spec, want := range map[string]string{
+		"alice:support:k-alice,s3cr3t-key": "invalid API key entry #2, want subject:role:key",
+		"alice:s3cr3t:key":                 "invalid API key entry #1: unknown role",
+		"alice:support:k,,bob:s3cr3t":      "invalid API key entry #3, want subject:role:key",
+	} {
+		_, err := ParseAPIKeys(spec)
+		if err == nil || err.Error() != want || strings.Contains(err.Error(), "s3cr3t") {
+			t.Fatalf("%q: got error %v, want %q", spec, err, want)
+		}
+	}
+}
+
+func TestAuthenticate_JWT(t *testing.T) {
+	secret := []byte("hmac-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	auth := NewAuthenticator(AuthConfig{
+		JWTSecret:    secret,
+		JWTPublicKey: &rsaKey.PublicKey,
+		Issuer:       "sso",
+		Audience:     "order-service",
+	})
+	auth.now = func() time.Time { return now }
+
+	claims := func(mod func(map[string]any)) map[string]any {
+		c := map[string]any{
+			"sub": "carol", "role": "admin", "iss": "sso", "aud": []string{"order-service"},
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		if mod != nil {
+			mod(c)
+		}
+		return c
+	}
+
+	p, err := auth.Authenticate(bearer(hs256Token(t, secret, claims(nil))))
+	if err != nil || p.Subject != "carol" || p.Role != RoleAdmin || p.Method != MethodJWT {
+		t.Fatalf("HS256: %+v, %v", p, err)
+	}
+	if _, err := auth.Authenticate(bearer(rs256Token(t, rsaKey, claims(nil)))); err != nil {
+		t.Fatalf("RS256: %v", err)
+	}
+
+	invalid := map[string]string{
+		"wrong secret": hs256Token(t, []byte("other"), claims(nil)),
+		"expired":      hs256Token(t, secret, claims(func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() })),
+		"no exp":       hs256Token(t, secret, claims(func(c map[string]any) { delete(c, "exp") })),
+		"not before":   hs256Token(t, secret, claims(func(c map[string]any) { c["nbf"] = now.Add(time.Hour).Unix() })),
+		"issuer":       hs256Token(t, secret, claims(func(c map[string]any) { c["iss"] = "evil" })),
+		"audience":     hs256Token(t, secret, claims(func(c map[string]any) { c["aud"] = "billing" })),
+		"role":         hs256Token(t, secret, claims(func(c map[string]any) { c["role"] = "root" })),
+		"alg none": segment(t, map[string]string{"alg": "none"}) + "." +
+			segment(t, claims(nil)) + ".",
+	}
+	for name, token := range invalid {
+		if _, err := auth.Authenticate(bearer(token)); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: expected ErrInvalidCredentials, got %v", name, err)
+		}
+	}
+
+	// без секрета HS256 не принимается, даже если подпись сделана открытым ключом RS256
+	rsOnly := NewAuthenticator(AuthConfig{JWTPublicKey: &rsaKey.PublicKey})
+	rsOnly.now = auth.now
+	if _, err := rsOnly.Authenticate(bearer(hs256Token(t, secret, claims(nil)))); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected HS256 rejected without secret, got %v", err)
+	}
+}
+
+func TestRoutes_RoleAccessAndAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	keys, err := ParseAPIKeys("sup:support:k-sup,ana:analyst:k-ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var audit bytes.Buffer
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().Get(gomock.Any(), "123").
+		Return(&database.Order{OrderUID: "123", Delivery: database.Delivery{Phone: "+79161231234"}}, nil).
+		AnyTimes()
+	srv := &Server{Store: store, Auth: NewAuthenticator(AuthConfig{APIKeys: keys}), Audit: NewAuditLog(&audit)}
+	mux := srv.Routes()
+
+	get := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/order/123", nil)
+		if key != "" {
+			req.Header.Set(apiKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get(""); w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected 401 for anonymous, got %d", w.Code)
+	}
+	if w := get("bad"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for bad key, got %d", w.Code)
+	}
+
+	var order database.Order
+	if err := json.NewDecoder(get("k-sup").Body).Decode(&order); err != nil || order.Delivery.Phone != "+79161231234" {
+		t.Fatalf("support must see full phone: %q, %v", order.Delivery.Phone, err)
+	}
+	if err := json.NewDecoder(get("k-ana").Body).Decode(&order); err != nil || order.Delivery.Phone != "+7******1234" {
+		t.Fatalf("analyst must see masked phone: %q, %v", order.Delivery.Phone, err)
+	}
+
+	dec := json.NewDecoder(&audit)
+	var events []AuditEvent
+	for dec.More() {
+		var e AuditEvent
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 audit events, got %d: %+v", len(events), events)
+	}
+	if e := events[0]; e.Subject != "sup" || e.Role != RoleSupport || e.Action != AuditOrderRead || e.OrderUID != "123" || e.Masked {
+		t.Fatalf("unexpected audit event %+v", e)
+	}
+	if e := events[1]; e.Subject != "ana" || !e.Masked || e.RequestID == "" {
+		t.Fatalf("unexpected audit event %+v", e)
+	}
+}
@@ -0,0 +1,222 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// Поддерживаемые кодировки ответов (Content-Encoding).
+const (
+	EncodingZstd   = "zstd"
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// brotliLevel — уровень сжатия brotli: ответы сжимаются на лету, поэтому ниже
+// максимального (11), где сжатие сильно замедляется при небольшом выигрыше.
+const brotliLevel = 5
+
+// compressMinSize — ответы меньше этого размера не сжимаются: выигрыш меньше накладных расходов.
+const compressMinSize = 1024
+
+// ParseCompression разбирает список кодировок через запятую в порядке предпочтения сервера
+// ("zstd,br,gzip"). Пустая строка и "off" выключают сжатие.
+func ParseCompression(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return nil, nil
+	}
+	var encodings []string
+	for _, enc := range strings.Split(s, ",") {
+		enc = strings.ToLower(strings.TrimSpace(enc))
+		switch enc {
+		case EncodingZstd, EncodingBrotli, EncodingGzip:
+		default:
+			return nil, fmt.Errorf("unsupported encoding %q (supported: %s, %s, %s)", enc, EncodingZstd, EncodingBrotli, EncodingGzip)
+		}
+		if !slices.Contains(encodings, enc) {
+			encodings = append(encodings, enc)
+		}
+	}
+	return encodings, nil
+}
+
+// Compressor сжимает ответы в кодировке, выбранной по Accept-Encoding клиента.
+// Методы безопасно вызывать на nil: тогда ответы не сжимаются.
+type Compressor struct {
+	encodings []string
+	zstd      *zstd.Encoder
+	brotli    sync.Pool
+	gzip      sync.Pool
+}
+
+// NewCompressor создает Compressor для кодировок в порядке предпочтения сервера
+// (см. ParseCompression). Пустой список дает nil.
+func NewCompressor(encodings []string) *Compressor {
+	if len(encodings) == 0 {
+		return nil
+	}
+	c := &Compressor{encodings: encodings}
+	if slices.Contains(encodings, EncodingZstd) {
+		// EncodeAll безопасно вызывать из нескольких горутин.
+		c.zstd, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	}
+	c.brotli.New = func() any { return brotli.NewWriterLevel(nil, brotliLevel) }
+	c.gzip.New = func() any { return gzip.NewWriter(nil) }
+	return c
+}
+
+// Handler сжимает ответ next целиком. Подходит для обработчиков, отдающих ответ разом
+// (не потоки): тело накапливается в памяти. Сжимаются только ответы 200 текстовых форматов
+// от compressMinSize байт; сильный ETag становится слабым, так как байты ответа
+// зависят от кодировки.
+func (c *Compressor) Handler(next http.HandlerFunc) http.HandlerFunc {
+	if c == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		enc := c.negotiate(r.Header.Get("Accept-Encoding"))
+		if enc == "" {
+			next(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, status: http.StatusOK}
+		next(cw, r)
+		c.finish(cw, enc)
+	}
+}
+
+// negotiate выбирает кодировку с наибольшим q из Accept-Encoding; при равных q —
+// первую по предпочтению сервера. Пустая строка — отвечать без сжатия.
+func (c *Compressor) negotiate(header string) string {
+	if header == "" {
+		return ""
+	}
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	best, bestQ := "", 0.0
+	for _, enc := range c.encodings {
+		q, ok := accepted[enc]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// finish отправляет накопленный ответ, сжав его, если это имеет смысл.
+func (c *Compressor) finish(cw *compressWriter, enc string) {
+	w, h, body := cw.ResponseWriter, cw.Header(), cw.buf.Bytes()
+	if cw.status == http.StatusOK && len(body) >= compressMinSize && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		if compressed, err := c.encode(enc, body); err != nil {
+			slog.Error("Ошибка сжатия ответа", slog.String("encoding", enc), logging.Err(err))
+		} else {
+			body = compressed
+			h.Set("Content-Encoding", enc)
+			h.Set("Content-Length", strconv.Itoa(len(body)))
+			weakenETag(h)
+		}
+	}
+	if cw.status == http.StatusNotModified {
+		// клиент мог получить этот ETag вместе со сжатым телом, где он слабый
+		weakenETag(h)
+	}
+	w.WriteHeader(cw.status)
+	if _, err := w.Write(body); err != nil {
+		slog.Debug("Клиент не дочитал ответ", logging.Err(err))
+	}
+}
+
+func (c *Compressor) encode(enc string, body []byte) ([]byte, error) {
+	switch enc {
+	case EncodingZstd:
+		return c.zstd.EncodeAll(body, make([]byte, 0, len(body)/2)), nil
+	case EncodingBrotli:
+		br := c.brotli.Get().(*brotli.Writer)
+		defer c.brotli.Put(br)
+		return compressStream(br, body)
+	case EncodingGzip:
+		gz := c.gzip.Get().(*gzip.Writer)
+		defer c.gzip.Put(gz)
+		return compressStream(gz, body)
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", enc)
+}
+
+// streamEncoder — потоковый кодировщик из пула (gzip, brotli).
+type streamEncoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// compressStream сжимает body переиспользуемым кодировщиком enc.
+func compressStream(enc streamEncoder, body []byte) ([]byte, error) {
+	var out bytes.Buffer
+	enc.Reset(&out)
+	if _, err := enc.Write(body); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func weakenETag(h http.Header) {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set("ETag", "W/"+etag)
+	}
+}
+
+// compressible сообщает, стоит ли сжимать ответ такого типа.
+func compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mt, "text/") || mt == "application/json" ||
+		strings.HasSuffix(mt, "+json") || strings.HasSuffix(mt, "+xml") ||
+		mt == "application/xml" || mt == "application/x-ndjson"
+}
+
+// compressWriter накапливает ответ до решения о сжатии.
+type compressWriter struct {
+	http.ResponseWriter
+	buf    bytes.Buffer
+	status int
+}
+
+func (cw *compressWriter) WriteHeader(status int) { cw.status = status }
+
+func (cw *compressWriter) Write(p []byte) (int, error) { return cw.buf.Write(p) }
+
+// Unwrap открывает исходный ResponseWriter для http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter { return cw.ResponseWriter }
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressor_Negotiate(t *testing.T) {
+	c := NewCompressor([]string{EncodingZstd, EncodingBrotli, EncodingGzip})
+	for header, want := range map[string]string{
+		"":                          "",
+		"identity":                  "",
+		"gzip":                      EncodingGzip,
+		"gzip, deflate, br, zstd":   EncodingZstd,
+		"gzip;q=1.0, zstd;q=0.5":    EncodingGzip,
+		"zstd;q=0, gzip;q=0.1":      EncodingGzip,
+		"*":                         EncodingZstd,
+		"*;q=0.5, zstd;q=0":         EncodingBrotli,
+		"br;q=1, gzip;q=bad, zstd ": EncodingZstd,
+		"gzip, br":                  EncodingBrotli,
+		"zstd;q=0.5, br;q=0.8":      EncodingBrotli,
+	} {
+		if got := c.negotiate(header); got != want {
+			t.Errorf("negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+	if encs, err := ParseCompression("zstd, BR,gzip,br"); err != nil || len(encs) != 3 || encs[1] != EncodingBrotli {
+		t.Fatalf("unexpected encodings %v %v", encs, err)
+	}
+	if _, err := ParseCompression("zstd,deflate"); err == nil {
+		t.Fatal("expected error for unsupported encoding")
+	}
+	if encs, err := ParseCompression("off"); err != nil || encs != nil {
+		t.Fatalf("off must disable compression: %v %v", encs, err)
+	}
+}
+
+// Проверяет: большой JSON сжимается выбранной кодировкой с ослабленным ETag,
+// маленький и ошибки отдаются как есть
+func TestCompressor_Handler(t *testing.T) {
+	c := NewCompressor([]string{EncodingZstd, EncodingBrotli, EncodingGzip})
+	big := strings.Repeat(`{"name":"Кроссовки"}`, 200)
+	h := c.Handler(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"abc"`)
+		switch r.URL.Path {
+		case "/big":
+			io.WriteString(w, big)
+		case "/small":
+			io.WriteString(w, `{}`)
+		default:
+			http.Error(w, strings.Repeat("x", 2000), http.StatusNotFound)
+		}
+	})
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		w := httptest.NewRecorder()
+		h(w, req)
+		return w
+	}
+
+	w := get("/big", "gzip")
+	if w.Header().Get("Content-Encoding") != EncodingGzip || w.Header().Get("ETag") != `W/"abc"` || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(gz); string(body) != big {
+		t.Fatal("gzip body does not match")
+	}
+
+	w = get("/big", "zstd")
+	zr, err := zstd.NewReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != big || w.Body.Len() >= len(big) {
+		t.Fatal("zstd body does not match or is not smaller")
+	}
+
+	w = get("/big", "br")
+	if w.Header().Get("Content-Encoding") != EncodingBrotli {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	if body, _ := io.ReadAll(brotli.NewReader(w.Body)); string(body) != big || w.Body.Len() >= len(big) {
+		t.Fatal("brotli body does not match or is not smaller")
+	}
+
+	for _, path := range []string{"/small", "/missing"} {
+		w = get(path, "gzip")
+		if w.Header().Get("Content-Encoding") != "" || w.Header().Get("ETag") != `"abc"` {
+			t.Fatalf("%s must not be compressed: %v", path, w.Header())
+		}
+	}
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status must be kept, got %d", w.Code)
+	}
+
+	var disabled *Compressor
+	w = httptest.NewRecorder()
+	disabled.Handler(h)(w, httptest.NewRequest(http.MethodGet, "/big", nil))
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Fatal("nil compressor must not compress")
+	}
+}
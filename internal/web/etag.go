@@ -0,0 +1,110 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/cache"
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+// encodedOrdersSize — сколько сериализованных заказов помнит сервер.
+const encodedOrdersSize = 1000
+
+// encodedOrder — тело ответа с заказом и его ETag (хэш тела).
+type encodedOrder struct {
+	// source — объект заказа из хранилища, по которому построено тело.
+	source *database.Order
+	format Format
+	masked bool
+	body   []byte
+	etag   string
+}
+
+type encodedKey struct {
+	uid    string
+	masked bool
+	format Format
+}
+
+// encodedOrders запоминает тела последних выданных заказов, чтобы частый опрос одного
+// заказа не сериализовал его заново. Запись действительна, пока хранилище отдает тот же
+// объект: сохранение заказа кладет в кэш новый. Нулевое значение готово к работе.
+type encodedOrders struct {
+	mu  sync.Mutex
+	lru *cache.LRU[encodedKey, encodedOrder]
+}
+
+// encode возвращает тело ответа в формате format и ETag заказа order (до маскирования)
+// для вызывающего с маской или без.
+func (e *encodedOrders) encode(order *database.Order, masked bool, format Format) (encodedOrder, error) {
+	key := encodedKey{uid: order.OrderUID, masked: masked, format: format}
+	e.mu.Lock()
+	if e.lru == nil {
+		e.lru = cache.NewLru[encodedKey, encodedOrder](encodedOrdersSize)
+	}
+	enc, ok := e.lru.Get(key)
+	e.mu.Unlock()
+	if ok && enc.source == order {
+		return enc, nil
+	}
+
+	view := order
+	if masked {
+		view = database.MaskOrder(order)
+	}
+	var buf bytes.Buffer
+	ow := newOrderWriter(format, &buf, true)
+	if err := errors.Join(ow.begin(), ow.write(view), ow.end()); err != nil {
+		return encodedOrder{}, err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	enc = encodedOrder{source: order, format: format, masked: masked, body: buf.Bytes(), etag: `"` + hex.EncodeToString(sum[:16]) + `"`}
+	e.mu.Lock()
+	e.lru.Set(key, enc)
+	e.mu.Unlock()
+	return enc, nil
+}
+
+// serveEncoded отдает заказ с ETag и Cache-Control; If-None-Match с тем же ETag
+// дает 304 без тела (обрабатывает http.ServeContent). Ответ без маски всегда private:
+// общий кэш не должен отдать персональные данные другому вызывающему.
+func (s *Server) serveEncoded(w http.ResponseWriter, r *http.Request, enc encodedOrder) {
+	h := w.Header()
+	h.Set("Content-Type", enc.format.ContentType())
+	h.Add("Vary", "Accept")
+	h.Set("ETag", enc.etag)
+	cc := s.CacheControl
+	if !enc.masked {
+		cc = privateCacheControl(cc)
+	}
+	if cc != "" {
+		h.Set("Cache-Control", cc)
+	}
+	if s.authenticator() != nil {
+		// маска зависит от учетных данных вызывающего
+		h.Add("Vary", "Authorization, "+apiKeyHeader+", "+adminTokenHeader)
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(enc.body))
+}
+
+// privateCacheControl убирает из Cache-Control разрешения общим кэшам (public, s-maxage)
+// и добавляет private.
+func privateCacheControl(cc string) string {
+	directives := []string{"private"}
+	for _, d := range strings.Split(cc, ",") {
+		d = strings.TrimSpace(d)
+		name, _, _ := strings.Cut(strings.ToLower(d), "=")
+		if d == "" || name == "public" || name == "private" || name == "s-maxage" {
+			continue
+		}
+		directives = append(directives, d)
+	}
+	return strings.Join(directives, ", ")
+}
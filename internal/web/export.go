@@ -0,0 +1,118 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// exportBatch — сколько заказов выгрузка читает из хранилища за раз: в памяти
+// одновременно только одна пачка.
+const exportBatch = 500
+
+// OrderExportHandler выгружает заказы потоком: GET /orders?customer_id=…&track_number=…
+// &delivery_service=…&created_after=…&created_before=… (RFC 3339, полуинтервал) &limit=…
+// Формат — по ?format= или Accept (JSON, NDJSON, CSV, XML). Заказы идут от новых к старым
+// и читаются пачками по exportBatch; каждая пачка отправляется клиенту сразу.
+func (s *Server) OrderExportHandler(w http.ResponseWriter, r *http.Request) {
+	format, err := negotiateFormat(r)
+	if err != nil {
+		formatError(w, r, err)
+		return
+	}
+	q := r.URL.Query()
+	filter := database.OrderFilter{
+		CustomerID:      q.Get("customer_id"),
+		TrackNumber:     q.Get("track_number"),
+		DeliveryService: q.Get("delivery_service"),
+	}
+	for name, dst := range map[string]*time.Time{"created_after": &filter.CreatedFrom, "created_before": &filter.CreatedTo} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*dst = t
+		}
+	}
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			writeProblem(w, r, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+	}
+
+	// первая пачка читается до заголовков ответа: ошибка хранилища — ответ об ошибке, а не обрыв
+	filter.Limit = exportLimit(limit, 0)
+	batch, err := s.Store.List(r.Context(), filter)
+	if err != nil {
+		storeProblem(w, r, err, "Ошибка выгрузки заказов")
+		return
+	}
+
+	masked := !s.canViewPII(r)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Add("Vary", "Accept")
+	fw := newFlushWriter(w)
+	ow := newOrderWriter(format, fw, false)
+	sent := 0
+	err = ow.begin()
+	for err == nil && len(batch) > 0 {
+		for i := range batch {
+			if err = ow.write(s.presentOrder(r, &batch[i], masked)); err != nil {
+				break
+			}
+		}
+		sent += len(batch)
+		if err != nil || len(batch) < filter.Limit || limit > 0 && sent >= limit {
+			break
+		}
+		if err = fw.flush(); err != nil {
+			break
+		}
+		last := batch[len(batch)-1]
+		filter.After = &database.OrderCursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID}
+		filter.Limit = exportLimit(limit, sent)
+		batch, err = s.Store.List(r.Context(), filter)
+	}
+	if err == nil {
+		if err = ow.end(); err == nil {
+			err = fw.flush()
+		}
+	}
+	if err != nil {
+		// заголовки уже отправлены: клиент увидит оборванный ответ
+		slog.ErrorContext(r.Context(), "Выгрузка заказов прервана", slog.Int("sent", sent), logging.Err(err))
+	}
+}
+
+// exportLimit — размер следующей пачки с учетом общего limit (0 — без ограничения).
+func exportLimit(limit, sent int) int {
+	if limit > 0 && limit-sent < exportBatch {
+		return limit - sent
+	}
+	return exportBatch
+}
+
+// writeOrders отдает готовый список заказов в формате format.
+func writeOrders(w http.ResponseWriter, r *http.Request, format Format, orders []*database.Order) {
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Add("Vary", "Accept")
+	ow := newOrderWriter(format, w, false)
+	err := ow.begin()
+	for i := 0; err == nil && i < len(orders); i++ {
+		err = ow.write(orders[i])
+	}
+	if err == nil {
+		err = ow.end()
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка записи списка заказов", logging.Err(err))
+	}
+}
@@ -0,0 +1,117 @@
+package web
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockcache "github.com/mitrich772/go-order-service/internal/cache/mocks"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/retry"
+)
+
+// Проверяет: выгрузка читает хранилище пачками по курсору, соблюдает limit и фильтры
+// и отдает NDJSON с маской для analyst
+func TestOrderExport_Batches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	created := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	first := make([]database.Order, exportBatch)
+	for i := range first {
+		first[i] = database.Order{OrderUID: fmt.Sprintf("o%03d", i), DateCreated: created, Delivery: database.Delivery{Phone: "+79161231234"}}
+	}
+	last := first[exportBatch-1]
+	gomock.InOrder(
+		store.EXPECT().List(gomock.Any(), database.OrderFilter{CustomerID: "c1", CreatedFrom: created, Limit: exportBatch}).
+			Return(first, nil),
+		store.EXPECT().List(gomock.Any(), database.OrderFilter{
+			CustomerID: "c1", CreatedFrom: created, Limit: 100,
+			After: &database.OrderCursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID},
+		}).Return([]database.Order{{OrderUID: "tail"}}, nil),
+	)
+	h := newUIServer(t, store, true, &bytes.Buffer{})
+
+	w := uiGet(h, "/orders?customer_id=c1&created_after=2025-03-01T00:00:00Z&limit=600&format=ndjson", "analyst")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != exportBatch+1 || !strings.Contains(lines[exportBatch], `"tail"`) {
+		t.Fatalf("expected %d lines, got %d", exportBatch+1, len(lines))
+	}
+	if strings.Contains(w.Body.String(), "+79161231234") {
+		t.Fatal("analyst must get masked phones")
+	}
+}
+
+// Проверяет: CSV по Accept, ошибки параметров, 406, 503 и 500 до начала ответа
+func TestOrderExport_FormatsAndErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	order := uiOrder("a")
+	gomock.InOrder(
+		store.EXPECT().List(gomock.Any(), gomock.Any()).Return([]database.Order{*order}, nil),
+		store.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, retry.ErrCircuitOpen),
+		store.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, errors.New("decrypt failed")),
+	)
+	h := newUIServer(t, store, true, &bytes.Buffer{})
+
+	req := func(target, accept string) int {
+		r, _ := http.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set(apiKeyHeader, "support")
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code == http.StatusOK {
+			rows, err := csv.NewReader(w.Body).ReadAll()
+			if err != nil || len(rows) != 1+len(order.Items) || rows[0][0] != "order_uid" {
+				t.Fatalf("bad csv %v %v", rows, err)
+			}
+		}
+		return w.Code
+	}
+	for _, tc := range []struct {
+		target, accept string
+		code           int
+	}{
+		{"/orders", "text/csv", http.StatusOK},
+		{"/orders", "image/png", http.StatusNotAcceptable},
+		{"/orders?format=pdf", "", http.StatusBadRequest},
+		{"/orders?limit=0", "", http.StatusBadRequest},
+		{"/orders?created_before=yesterday", "", http.StatusBadRequest},
+		{"/orders", "", http.StatusServiceUnavailable},
+		{"/orders", "", http.StatusInternalServerError},
+	} {
+		if code := req(tc.target, tc.accept); code != tc.code {
+			t.Errorf("%s (%s): expected %d, got %d", tc.target, tc.accept, tc.code, code)
+		}
+	}
+}
+
+// Проверяет: GET /order/{uid} отдает XML по Accept со своим ETag
+func TestOrderHandler_XML(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	order := uiOrder("uid-1")
+	store.EXPECT().Get(gomock.Any(), "uid-1").Return(order, nil).Times(2)
+	h := newUIServer(t, store, false, &bytes.Buffer{})
+
+	asJSON := uiGet(h, "/order/uid-1", "")
+	r, _ := http.NewRequest(http.MethodGet, "/order/uid-1", nil)
+	r.Header.Set("Accept", "application/xml")
+	asXML := httptest.NewRecorder()
+	h.ServeHTTP(asXML, r)
+	if asXML.Header().Get("Content-Type") != FormatXML.ContentType() || !strings.HasPrefix(asXML.Body.String(), "<?xml") {
+		t.Fatalf("unexpected xml response %v %s", asXML.Header(), asXML.Body)
+	}
+	if asXML.Header().Get("ETag") == asJSON.Header().Get("ETag") {
+		t.Fatal("representations must have different ETags")
+	}
+}
@@ -0,0 +1,337 @@
+package web
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+// Format — формат выдачи заказов, выбирается по ?format= или заголовку Accept.
+type Format string
+
+// Форматы выдачи заказов.
+const (
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+	FormatXML    Format = "xml"
+)
+
+// formats — форматы в порядке предпочтения сервера при равном q в Accept.
+var formats = []Format{FormatJSON, FormatNDJSON, FormatCSV, FormatXML}
+
+// ContentType возвращает Content-Type ответа в этом формате.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXML:
+		return "application/xml; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
+
+// formatMediaTypes сопоставляет типы из Accept форматам.
+var formatMediaTypes = map[string]Format{
+	"application/json":     FormatJSON,
+	"application/x-ndjson": FormatNDJSON,
+	"application/ndjson":   FormatNDJSON,
+	"text/csv":             FormatCSV,
+	"application/xml":      FormatXML,
+	"text/xml":             FormatXML,
+}
+
+var (
+	// errBadFormat — ?format= не из списка форматов (400).
+	errBadFormat = errors.New("format must be one of json, ndjson, csv, xml")
+	// errNotAcceptable — Accept не допускает ни одного формата (406).
+	errNotAcceptable = errors.New("none of the accepted media types is supported: application/json, application/x-ndjson, text/csv, application/xml")
+)
+
+// negotiateFormat выбирает формат: ?format= важнее Accept; без них — JSON.
+func negotiateFormat(r *http.Request) (Format, error) {
+	if v := r.URL.Query().Get("format"); v != "" {
+		f := Format(strings.ToLower(v))
+		for _, known := range formats {
+			if f == known {
+				return f, nil
+			}
+		}
+		return "", errBadFormat
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return FormatJSON, nil
+	}
+	// q каждого формата: точный тип важнее text/* и application/*, они — */*.
+	type weight struct {
+		q           float64
+		specificity int
+	}
+	weights := make(map[Format]weight, len(formats))
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		for typ, f := range formatMediaTypes {
+			spec := 0
+			switch {
+			case mt == typ:
+				spec = 3
+			case strings.HasSuffix(mt, "/*") && strings.HasPrefix(typ, strings.TrimSuffix(mt, "*")):
+				spec = 2
+			case mt == "*/*":
+				spec = 1
+			default:
+				continue
+			}
+			if w, ok := weights[f]; !ok || spec > w.specificity || spec == w.specificity && q > w.q {
+				weights[f] = weight{q: q, specificity: spec}
+			}
+		}
+	}
+	best, bestQ := Format(""), 0.0
+	for _, f := range formats {
+		if w := weights[f]; w.q > bestQ {
+			best, bestQ = f, w.q
+		}
+	}
+	if best == "" {
+		return "", errNotAcceptable
+	}
+	return best, nil
+}
+
+// formatError отвечает на ошибку negotiateFormat.
+func formatError(w http.ResponseWriter, r *http.Request, err error) {
+	code := http.StatusNotAcceptable
+	if errors.Is(err, errBadFormat) {
+		code = http.StatusBadRequest
+	}
+	writeProblem(w, r, code, err.Error())
+}
+
+// orderWriter пишет заказы потоком: begin, заказы по одному, end.
+// Список в JSON — {"orders": [...]}, в XML — <orders>…</orders>, в NDJSON — заказ на строку,
+// в CSV — строка на товар с полями заказа (см. csvHeader). Один заказ (single) в JSON и XML
+// пишется без обертки.
+type orderWriter interface {
+	begin() error
+	write(order *database.Order) error
+	end() error
+}
+
+func newOrderWriter(f Format, w io.Writer, single bool) orderWriter {
+	switch f {
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}
+	case FormatXML:
+		return &xmlWriter{w: w, enc: xml.NewEncoder(w), single: single}
+	default:
+		return &jsonWriter{w: w, single: single}
+	}
+}
+
+type jsonWriter struct {
+	w      io.Writer
+	single bool
+	n      int
+}
+
+func (j *jsonWriter) begin() error {
+	if j.single {
+		return nil
+	}
+	_, err := io.WriteString(j.w, `{"orders":[`)
+	return err
+}
+
+func (j *jsonWriter) write(order *database.Order) error {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	if j.n > 0 {
+		data = append([]byte{','}, data...)
+	}
+	j.n++
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) end() error {
+	tail := "]}\n"
+	if j.single {
+		tail = "\n"
+	}
+	_, err := io.WriteString(j.w, tail)
+	return err
+}
+
+type ndjsonWriter struct{ enc *json.Encoder }
+
+func (n *ndjsonWriter) begin() error                      { return nil }
+func (n *ndjsonWriter) write(order *database.Order) error { return n.enc.Encode(order) }
+func (n *ndjsonWriter) end() error                        { return nil }
+
+type xmlWriter struct {
+	w      io.Writer
+	enc    *xml.Encoder
+	single bool
+}
+
+var xmlOrders = xml.StartElement{Name: xml.Name{Local: "orders"}}
+
+func (x *xmlWriter) begin() error {
+	if _, err := io.WriteString(x.w, xml.Header); err != nil {
+		return err
+	}
+	if x.single {
+		return nil
+	}
+	return x.enc.EncodeToken(xmlOrders)
+}
+
+func (x *xmlWriter) write(order *database.Order) error {
+	return x.enc.EncodeElement(order, xml.StartElement{Name: xml.Name{Local: "order"}})
+}
+
+func (x *xmlWriter) end() error {
+	if !x.single {
+		if err := x.enc.EncodeToken(xmlOrders.End()); err != nil {
+			return err
+		}
+	}
+	if err := x.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(x.w, "\n")
+	return err
+}
+
+// csvHeader — колонки CSV: поля заказа, доставки (delivery_*), оплаты (payment_*)
+// и товара (item_*). Заказ занимает строку на каждый товар, поля заказа в них повторяются.
+var csvHeader = []string{
+	"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id",
+	"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city", "delivery_address",
+	"delivery_region", "delivery_email",
+	"payment_transaction", "payment_request_id", "payment_currency", "payment_provider",
+	"payment_amount", "payment_dt", "payment_bank", "payment_delivery_cost",
+	"payment_goods_total", "payment_custom_fee",
+	"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name", "item_sale",
+	"item_size", "item_total_price", "item_nm_id", "item_brand", "item_status",
+}
+
+type csvWriter struct{ w *csv.Writer }
+
+func (c *csvWriter) begin() error { return c.w.Write(csvHeader) }
+
+func (c *csvWriter) write(o *database.Order) error {
+	d, p := o.Delivery, o.Payment
+	row := []string{
+		o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature, o.CustomerID,
+		o.DeliveryService, o.ShardKey, itoa(int64(o.SmID)), o.DateCreated.UTC().Format(time.RFC3339), o.OofShard,
+		d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email,
+		p.Transaction, p.RequestID, p.Currency, p.Provider,
+		ftoa(p.Amount), itoa(p.PaymentDT), p.Bank, ftoa(p.DeliveryCost),
+		ftoa(p.GoodsTotal), ftoa(p.CustomFee),
+	}
+	orderCols := len(row)
+	if len(o.Items) == 0 {
+		return c.w.Write(csvSafe(append(row, make([]string, len(csvHeader)-orderCols)...)))
+	}
+	for _, it := range o.Items {
+		row = append(row[:orderCols],
+			itoa(it.ChrtID), it.TrackNumber, ftoa(it.Price), it.RID, it.Name, ftoa(it.Sale),
+			it.Size, ftoa(it.TotalPrice), itoa(it.NmID), it.Brand, itoa(int64(it.Status)),
+		)
+		if err := c.w.Write(csvSafe(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter) end() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Значения, которые csvSafe оставляет как есть, хотя они начинаются с + или -:
+// десятичное число целиком и телефон в формате E.164.
+var (
+	csvNumber = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?$`)
+	csvPhone  = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+)
+
+// csvSafe защищает от формул при открытии выгрузки в табличном редакторе:
+// значения, начинающиеся с =, @, табуляции или перевода строки, а также с + и -,
+// получают префикс '. Не меняются только отрицательные числа и телефоны E.164:
+// «+1+HYPERLINK(...)» начинается с цифры после знака, но остается формулой.
+func csvSafe(row []string) []string {
+	out := make([]string, len(row))
+	for i, v := range row {
+		out[i] = v
+		if v == "" {
+			continue
+		}
+		switch v[0] {
+		case '=', '@', '\t', '\r', '\n':
+			out[i] = "'" + v
+		case '+', '-':
+			if !csvNumber.MatchString(v) && !csvPhone.MatchString(v) {
+				out[i] = "'" + v
+			}
+		}
+	}
+	return out
+}
+
+func itoa(v int64) string   { return strconv.FormatInt(v, 10) }
+func ftoa(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+// flushWriter буферизует поток выгрузки и после каждой пачки заказов отдает
+// накопленное клиенту, не дожидаясь конца ответа.
+type flushWriter struct {
+	*bufio.Writer
+	rc *http.ResponseController
+}
+
+func newFlushWriter(w http.ResponseWriter) *flushWriter {
+	return &flushWriter{Writer: bufio.NewWriterSize(w, 32<<10), rc: http.NewResponseController(w)}
+}
+
+func (f *flushWriter) flush() error {
+	if err := f.Writer.Flush(); err != nil {
+		return err
+	}
+	if err := f.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("flush response: %w", err)
+	}
+	return nil
+}
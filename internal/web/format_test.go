@@ -0,0 +1,168 @@
+package web
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	for _, tc := range []struct {
+		query, accept string
+		want          Format
+		err           error
+	}{
+		{"", "", FormatJSON, nil},
+		{"", "*/*", FormatJSON, nil},
+		{"", "text/csv", FormatCSV, nil},
+		{"", "text/html, application/xml;q=0.9, */*;q=0.8", FormatXML, nil},
+		{"", "application/json;q=0.5, application/x-ndjson", FormatNDJSON, nil},
+		{"", "application/json;q=0, */*", FormatNDJSON, nil},
+		{"", "text/*", FormatCSV, nil},
+		{"", "image/png", "", errNotAcceptable},
+		{"?format=XML", "text/csv", FormatXML, nil},
+		{"?format=yaml", "", "", errBadFormat},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/orders"+tc.query, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		got, err := negotiateFormat(req)
+		if got != tc.want || err != tc.err {
+			t.Errorf("%q %q: got %q, %v; want %q, %v", tc.query, tc.accept, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+// Проверяет: CSV — строка на товар с повторенными полями заказа, заказ без товаров —
+// одна строка; значения, похожие на формулы, экранируются, телефоны — нет
+func TestCSVWriter(t *testing.T) {
+	order := uiOrder("uid-1")
+	order.Delivery.Phone = "+79161231234"
+	order.Items = append(order.Items[:1], order.Items[0])
+	order.Items[1].Name = "=HYPERLINK(\"x\")"
+	empty := uiOrder("uid-2")
+	empty.Items = nil
+
+	var buf bytes.Buffer
+	ow := newOrderWriter(FormatCSV, &buf, false)
+	if err := ow.begin(); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range []*database.Order{order, empty} {
+		if err := ow.write(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ow.end(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || len(rows[0]) != len(csvHeader) {
+		t.Fatalf("expected header and 3 rows of %d columns, got %d rows", len(csvHeader), len(rows))
+	}
+	col := func(name string) int {
+		for i, h := range csvHeader {
+			if h == name {
+				return i
+			}
+		}
+		t.Fatalf("no column %s", name)
+		return 0
+	}
+	if rows[1][col("order_uid")] != "uid-1" || rows[2][col("order_uid")] != "uid-1" || rows[3][col("order_uid")] != "uid-2" {
+		t.Fatalf("unexpected order columns %v", rows)
+	}
+	if rows[1][col("delivery_phone")] != "+79161231234" || rows[2][col("item_name")] != `'=HYPERLINK("x")` {
+		t.Fatalf("unexpected escaping: %q %q", rows[1][col("delivery_phone")], rows[2][col("item_name")])
+	}
+	if rows[3][col("item_name")] != "" {
+		t.Fatal("order without items must have empty item columns")
+	}
+}
+
+// Проверяет: значения со знаком в начале остаются как есть, только если это число
+// или телефон E.164 целиком
+func TestCSVSafe(t *testing.T) {
+	for in, want := range map[string]string{
+		"+79161231234":               "+79161231234",
+		"-15":                        "-15",
+		"-0.5":                       "-0.5",
+		"+1+HYPERLINK(\"http://x\")": "'+1+HYPERLINK(\"http://x\")",
+		"-1+cmd|' /C calc'!A0":       "'-1+cmd|' /C calc'!A0",
+		"+7 916 123-12-34":           "'+7 916 123-12-34",
+		"-":                          "'-",
+		"@SUM(A1)":                   "'@SUM(A1)",
+		"12-05":                      "12-05",
+		"\t=1+1":                     "'\t=1+1",
+		"\r=1+1":                     "'\r=1+1",
+		"\n=1+1":                     "'\n=1+1",
+	} {
+		if got := csvSafe([]string{in})[0]; got != want {
+			t.Errorf("csvSafe(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// Проверяет: XML-список разбирается обратно, имена элементов — как в JSON
+func TestXMLWriter(t *testing.T) {
+	var buf bytes.Buffer
+	ow := newOrderWriter(FormatXML, &buf, false)
+	if err := ow.begin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ow.write(uiOrder("uid-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ow.end(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<order><order_uid>uid-1</order_uid>") || !strings.Contains(buf.String(), "<items><item>") {
+		t.Fatalf("unexpected xml %s", buf.String())
+	}
+	var doc struct {
+		Orders []database.Order `xml:"order"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Orders) != 1 || doc.Orders[0].OrderUID != "uid-1" || len(doc.Orders[0].Items) == 0 {
+		t.Fatalf("unexpected orders %+v", doc.Orders)
+	}
+}
+
+// Проверяет: JSON-список совпадает с ответом поиска по ключу, NDJSON — заказ на строку
+func TestJSONWriters(t *testing.T) {
+	for _, f := range []Format{FormatJSON, FormatNDJSON} {
+		var buf bytes.Buffer
+		ow := newOrderWriter(f, &buf, false)
+		_ = ow.begin()
+		_ = ow.write(uiOrder("a"))
+		_ = ow.write(uiOrder("b"))
+		_ = ow.end()
+		if f == FormatJSON {
+			var resp struct {
+				Orders []database.Order `json:"orders"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &resp); err != nil || len(resp.Orders) != 2 {
+				t.Fatalf("bad json list %v: %s", err, buf.String())
+			}
+			continue
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 || !json.Valid([]byte(lines[1])) {
+			t.Fatalf("bad ndjson %s", buf.String())
+		}
+	}
+}
@@ -1,52 +1,130 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/mitrich772/go-order-service/internal/cache"
 	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/feed"
+	"github.com/mitrich772/go-order-service/internal/health"
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"github.com/mitrich772/go-order-service/internal/metrics"
+	"github.com/mitrich772/go-order-service/internal/tracing"
 )
 
 // Server структура для работы с endpoints
 type Server struct {
 	Store cache.OrderStore
 	Tpl   *template.Template
+	// UI — шаблоны страниц /ui/ (см. ParseUI), nil отключает их.
+	UI *template.Template
+
+	// Cache — администрирование кэша, nil если кэш выключен.
+	Cache cache.Admin
+	// AdminToken — токен с ролью admin для /admin/ эндпоинтов, пустой отключает их (если не задан Auth).
+	AdminToken string
+	// Auth — проверка API-ключей и JWT. Если задан, /order/ доступен только ролям
+	// support, analyst и admin; nil оставляет /order/ открытым с маскированием.
+	Auth *Authenticator
+	// Audit — журнал обращений к заказам, nil отключает его.
+	Audit *AuditLog
+	// Health — проверки для /healthz и /readyz, nil отключает эндпоинты.
+	Health *health.Registry
+	// Metrics — метрики HTTP и эндпоинт /metrics, nil отключает их.
+	Metrics *metrics.Metrics
+	// RateLimit — лимиты запросов к /order/, /orders, /orders/lookup, /orders/search, /orders/stream, /orders/ws, /ui/ и /admin/, nil отключает их.
+	RateLimit *RateLimiter
+	// Ingest — прием заказов по HTTP (POST /orders, POST /orders:batch) для ролей
+	// ingest и admin; nil отключает эндпоинты.
+	Ingest *Ingester
+	// Feed — лента новых заказов для GET /orders/stream (SSE) и GET /orders/ws (WebSocket),
+	// nil отключает эндпоинты.
+	Feed *feed.Feed
+	// CacheControl — заголовок Cache-Control ответов GET /order/ (вместе с ETag),
+	// пустой не отправляется. Ответы без маски всегда получают private.
+	CacheControl string
+	// Compression — сжатие ответов /order/, /orders/lookup, /orders/search и /ui/
+	// по Accept-Encoding, nil отключает его.
+	Compression *Compressor
+	// API — описание HTTP API: /openapi.json, страница /docs и проверка параметров
+	// запросов по нему; nil отключает их.
+	API *APISpec
+	// UnmaskedPII отдает персональные данные без маски всем вызывающим (только для разработки).
+	UnmaskedPII bool
+
+	encoded encodedOrders
 }
 
 // IndexHandler рендерит главную страницу (форма для ввода ID заказа)
 func (s *Server) IndexHandler(w http.ResponseWriter, r *http.Request) {
 	if s.Tpl == nil {
-		http.Error(w, "template not set", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, "template not set")
 		return
 	}
 	err := s.Tpl.Execute(w, nil)
 	if err != nil {
-		log.Fatalln(err)
+		slog.ErrorContext(r.Context(), "Ошибка рендеринга страницы", logging.Err(err))
 	}
 }
 
-// OrderHandler возвращает данные заказа по ID в формате JSON
+// OrderHandler возвращает данные заказа по ID в формате по ?format= или Accept
+// (JSON, NDJSON, CSV, XML) с ETag — хэшем ответа: If-None-Match с ним дает 304 без тела.
+// Персональные данные маскируются, если вызывающему их видеть нельзя (см. canViewPII).
 func (s *Server) OrderHandler(w http.ResponseWriter, r *http.Request) {
+	format, err := negotiateFormat(r)
+	if err != nil {
+		formatError(w, r, err)
+		return
+	}
 	uid := strings.TrimPrefix(r.URL.Path, "/order/")
-	order, err := s.GetOrder(uid)
+	order, err := s.GetOrder(r.Context(), uid)
 	if err != nil {
-		http.Error(w, "order not found", http.StatusNotFound)
+		if database.IsNotFound(err) {
+			s.Audit.Record(r, AuditEvent{Action: AuditOrderRead, OrderUID: uid, Result: AuditNotFound})
+		}
+		storeProblem(w, r, err, "Ошибка чтения заказа", slog.String(logging.KeyOrderUID, uid))
 		return
 	}
-	writeJSON(w, order)
+	masked := !s.canViewPII(r)
+	enc, err := s.encoded.encode(order, masked, format)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка сериализации заказа", slog.String(logging.KeyOrderUID, uid), logging.Err(err))
+		writeProblem(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+	s.Audit.Record(r, AuditEvent{Action: AuditOrderRead, OrderUID: uid, Result: AuditOK, Masked: masked})
+	s.serveEncoded(w, r, enc)
+}
+
+// canViewPII сообщает, может ли вызывающий видеть персональные данные без маски:
+// полные значения видят роли support и admin, если не включен UnmaskedPII.
+func (s *Server) canViewPII(r *http.Request) bool {
+	return s.UnmaskedPII || hasRole(r, piiReaders...)
+}
+
+// requireOrderReader закрывает доступ к заказам для анонимных вызывающих, если настроен Auth.
+func (s *Server) requireOrderReader(next http.HandlerFunc) http.HandlerFunc {
+	if s.Auth == nil {
+		return next
+	}
+	return requireRole(next, orderReaders...)
 }
 
 // GetOrder ищет заказ в Store
-func (s *Server) GetOrder(uid string) (*database.Order, error) {
-	if uid == "" {
-		return nil, fmt.Errorf("order_uid required")
+func (s *Server) GetOrder(ctx context.Context, uid string) (*database.Order, error) {
+	if uid == "" || strings.Contains(uid, "/") {
+		return nil, fmt.Errorf("%w: order_uid required in path /order/{order_uid}", database.ErrInvalid)
 	}
-	order, err := s.Store.Get(uid)
+	order, err := s.Store.Get(ctx, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -59,28 +137,81 @@ func writeJSON(w http.ResponseWriter, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	err := json.NewEncoder(w).Encode(v)
 	if err != nil {
-		log.Fatalln(err)
+		slog.Error("Ошибка записи JSON-ответа", logging.Err(err))
 	}
 }
 
-// Start запускает HTTP-сервер
-func Start(cacheStore cache.OrderStore, tpl *template.Template, port string) {
+// Routes регистрирует все обработчики сервера
+func (s *Server) Routes() http.Handler {
 	mux := http.NewServeMux()
-	srv := &Server{
-		Store: cacheStore,
-		Tpl:   tpl,
+
+	mux.HandleFunc("/", s.IndexHandler)
+	mux.HandleFunc("/order/", s.RateLimit.Limit(RouteOrder, s.requireOrderReader(s.Compression.Handler(s.OrderHandler))))
+	mux.HandleFunc("GET /orders", s.RateLimit.Limit(RouteOrder, s.requireOrderReader(s.OrderExportHandler)))
+	mux.HandleFunc("GET /orders/lookup", s.RateLimit.Limit(RouteOrder, s.requireOrderReader(s.Compression.Handler(s.OrderLookupHandler))))
+	mux.HandleFunc("GET /orders/search", s.RateLimit.Limit(RouteOrder, s.requireOrderReader(s.Compression.Handler(s.OrderSearchHandler))))
+
+	if s.Ingest != nil {
+		mux.HandleFunc("POST /orders", s.RateLimit.Limit(RouteIngest, requireRole(s.Ingest.CreateOrderHandler, orderWriters...)))
+		mux.HandleFunc("POST /orders:batch", s.RateLimit.Limit(RouteIngest, requireRole(s.Ingest.BatchOrdersHandler, orderWriters...)))
+	}
+
+	if s.Feed != nil {
+		mux.HandleFunc("GET /orders/stream", s.queryToken(s.RateLimit.Limit(RouteOrder, s.requireOrderReader(s.OrderStreamHandler))))
+		mux.HandleFunc("GET /orders/ws", s.queryToken(s.RateLimit.Limit(RouteOrder, s.requireOrderReader(s.OrderSocketHandler))))
 	}
 
-	mux.HandleFunc("/", srv.IndexHandler)
-	mux.HandleFunc("/order/", srv.OrderHandler)
+	if s.UI != nil {
+		mux.HandleFunc("GET /ui/{$}", s.RateLimit.Limit(RouteOrder, s.uiAuth(s.Compression.Handler(s.UIOrdersHandler))))
+		mux.HandleFunc("GET /ui/orders/{uid}", s.RateLimit.Limit(RouteOrder, s.uiAuth(s.Compression.Handler(s.UIOrderHandler))))
+		// формы входа и выхода принимаются только со страниц этого сайта
+		csrf := http.NewCrossOriginProtection()
+		mux.Handle("POST /ui/login", csrf.Handler(s.RateLimit.Limit(RouteOrder, s.UILoginHandler)))
+		mux.Handle("POST /ui/logout", csrf.Handler(http.HandlerFunc(s.UILogoutHandler)))
+	}
+
+	if s.API != nil {
+		mux.HandleFunc("GET /openapi.json", s.Compression.Handler(s.API.SpecHandler))
+		mux.HandleFunc("GET /docs", s.Compression.Handler(s.API.DocsHandler))
+	}
 
 	fs := http.FileServer(http.Dir("static"))
 	mux.Handle("/static/", http.StripPrefix("/static/", fs))
 
+	if s.Health != nil {
+		mux.HandleFunc("GET /healthz", s.Health.LivenessHandler)
+		mux.HandleFunc("GET /readyz", s.Health.ReadinessHandler)
+	}
+	if s.Metrics != nil {
+		mux.Handle("GET /metrics", s.Metrics.Handler())
+	}
+
+	mux.HandleFunc("GET /admin/cache/stats", s.requireAdmin(s.CacheStatsHandler))
+	mux.HandleFunc("GET /admin/cache/keys/{uid}", s.requireAdmin(s.CacheInspectHandler))
+	mux.HandleFunc("DELETE /admin/cache/keys/{uid}", s.requireAdmin(s.CacheEvictHandler))
+	mux.HandleFunc("POST /admin/cache/purge", s.requireAdmin(s.CachePurgeHandler))
+	mux.HandleFunc("POST /admin/cache/warm", s.requireAdmin(s.CacheWarmHandler))
+	mux.HandleFunc("GET /admin/cache/warmup", s.requireAdmin(s.CacheWarmupHandler))
+
+	return tracing.Middleware(logging.Middleware(s.Metrics.Middleware(authenticate(s.authenticator(), s.API.Validate(mux)))))
+}
+
+// Start запускает HTTP-сервер в отдельной горутине.
+// Возвращенный сервер останавливается через Shutdown: новые соединения не принимаются,
+// текущие запросы дорабатывают до истечения контекста.
+func Start(srv *Server, port string) *http.Server {
+	httpSrv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           srv.Routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
 	go func() {
-		log.Println("Web сервер запущен на порту", port)
-		if err := http.ListenAndServe(":"+port, mux); err != nil {
-			log.Fatal(err)
+		slog.Info("Web сервер запущен", slog.String("port", port))
+		if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Web сервер остановлен", logging.Err(err))
+			os.Exit(1)
 		}
 	}()
+	return httpSrv
 }
@@ -1,16 +1,22 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	mockcache "github.com/mitrich772/go-order-service/internal/cache/mocks"
 	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/retry"
+	"gorm.io/gorm"
 )
 
 func TestServer_GetOrder(t *testing.T) {
@@ -22,21 +28,21 @@ func TestServer_GetOrder(t *testing.T) {
 	srv := Server{Store: mockStore}
 
 	// Позитивный случай
-	mockStore.EXPECT().Get("123").Return(&database.Order{OrderUID: "123"}, nil)
-	order, err := srv.GetOrder("123")
+	mockStore.EXPECT().Get(gomock.Any(), "123").Return(&database.Order{OrderUID: "123"}, nil)
+	order, err := srv.GetOrder(context.Background(), "123")
 	if err != nil || order.OrderUID != "123" {
 		t.Fatalf("expected order 123, got %v, err: %v", order, err)
 	}
 
 	// uid пустой
-	_, err = srv.GetOrder("")
-	if err == nil {
-		t.Fatal("expected error for empty uid")
+	_, err = srv.GetOrder(context.Background(), "")
+	if !database.IsInvalid(err) {
+		t.Fatalf("expected invalid request error for empty uid, got %v", err)
 	}
 
 	// заказ не найден
-	mockStore.EXPECT().Get("999").Return(nil, fmt.Errorf("not found"))
-	_, err = srv.GetOrder("999")
+	mockStore.EXPECT().Get(gomock.Any(), "999").Return(nil, fmt.Errorf("not found"))
+	_, err = srv.GetOrder(context.Background(), "999")
 	if err == nil {
 		t.Fatal("expected error for missing order")
 	}
@@ -49,7 +55,7 @@ func TestOrderHandler(t *testing.T) {
 	srv := Server{Store: mockStore, Tpl: template.Must(template.New("index").Parse("ok"))}
 
 	// успешный запрос
-	mockStore.EXPECT().Get("123").Return(&database.Order{OrderUID: "123"}, nil)
+	mockStore.EXPECT().Get(gomock.Any(), "123").Return(&database.Order{OrderUID: "123"}, nil)
 	req := httptest.NewRequest("GET", "/order/123", nil)
 	w := httptest.NewRecorder()
 	srv.OrderHandler(w, req)
@@ -66,22 +72,182 @@ func TestOrderHandler(t *testing.T) {
 	}
 
 	// заказ не найден
-	mockStore.EXPECT().Get("999").Return(nil, fmt.Errorf("not found"))
+	mockStore.EXPECT().Get(gomock.Any(), "999").Return(nil, fmt.Errorf("get: %w", gorm.ErrRecordNotFound))
 	req = httptest.NewRequest("GET", "/order/999", nil)
 	w = httptest.NewRecorder()
 	srv.OrderHandler(w, req)
 	if w.Code != http.StatusNotFound {
 		t.Fatalf("expected 404, got %d", w.Code)
 	}
+}
 
-	// пустой UID
-	req = httptest.NewRequest("GET", "/order/", nil)
-	w = httptest.NewRecorder()
-	srv.OrderHandler(w, req)
-	if w.Code != http.StatusNotFound {
-		t.Fatalf("expected 404, got %d", w.Code)
+// Проверяет: ошибки /order/ — problem+json с request_id и кодом по классу ошибки,
+// 503 с Retry-After по времени до пробного вызова автомата
+func TestOrderHandler_Problems(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().Get(gomock.Any(), "missing").Return(nil, database.ErrNotFound)
+	store.EXPECT().Get(gomock.Any(), "open").Return(nil, &retry.OpenError{RetryAfter: 1500 * time.Millisecond})
+	store.EXPECT().Get(gomock.Any(), "slow").Return(nil, fmt.Errorf("query: %w", context.DeadlineExceeded))
+	store.EXPECT().Get(gomock.Any(), "broken").Return(nil, errors.New("decrypt: bad key"))
+	h := (&Server{Store: store}).Routes()
+
+	for _, tc := range []struct {
+		path       string
+		code       int
+		retryAfter string
+	}{
+		{"/order/missing", http.StatusNotFound, ""},
+		{"/order/", http.StatusBadRequest, ""},
+		{"/order/a/b", http.StatusBadRequest, ""},
+		{"/order/open", http.StatusServiceUnavailable, "2"},
+		{"/order/slow", http.StatusServiceUnavailable, "5"},
+		{"/order/broken", http.StatusInternalServerError, ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.code || w.Header().Get("Retry-After") != tc.retryAfter {
+			t.Errorf("%s: expected %d (Retry-After %q), got %d (%q)", tc.path, tc.code, tc.retryAfter, w.Code, w.Header().Get("Retry-After"))
+			continue
+		}
+		if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+			t.Errorf("%s: expected problem+json, got %q", tc.path, ct)
+		}
+		var p Problem
+		if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+		if p.Status != tc.code || p.Title != http.StatusText(tc.code) || p.RequestID != "req-1" || p.Instance != tc.path {
+			t.Errorf("%s: bad problem %+v", tc.path, p)
+		}
+		if tc.code == http.StatusInternalServerError && strings.Contains(p.Detail, "bad key") {
+			t.Errorf("%s: internal error leaked: %q", tc.path, p.Detail)
+		}
+	}
+}
+func TestOrderHandler_MasksPIIForNonAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mockcache.NewMockOrderStore(ctrl)
+	srv := Server{Store: mockStore, AdminToken: "secret"}
+	order := &database.Order{OrderUID: "123", Delivery: database.Delivery{Phone: "+79161231234"}}
+	mockStore.EXPECT().Get(gomock.Any(), "123").Return(order, nil).Times(2)
+
+	mux := srv.Routes()
+	get := func(token string) database.Order {
+		req := httptest.NewRequest("GET", "/order/123", nil)
+		if token != "" {
+			req.Header.Set(adminTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var got database.Order
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	if got := get(""); got.Delivery.Phone != "+7******1234" {
+		t.Fatalf("expected masked phone, got %q", got.Delivery.Phone)
+	}
+	if got := get("secret"); got.Delivery.Phone != "+79161231234" {
+		t.Fatalf("expected full phone for admin, got %q", got.Delivery.Phone)
+	}
+	if order.Delivery.Phone != "+79161231234" {
+		t.Fatal("cached order must not be masked in place")
+	}
+}
+
+// Проверяет: ответ содержит ETag и Cache-Control, повтор с If-None-Match дает 304 без тела,
+// новый объект заказа в хранилище меняет ETag
+func TestOrderHandler_ETag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mockcache.NewMockOrderStore(ctrl)
+	srv := &Server{Store: mockStore, CacheControl: "private, no-cache"}
+	h := srv.Routes()
+	order := &database.Order{OrderUID: "123", TrackNumber: "WB1"}
+	updated := &database.Order{OrderUID: "123", TrackNumber: "WB2"}
+	gomock.InOrder(
+		mockStore.EXPECT().Get(gomock.Any(), "123").Return(order, nil).Times(2),
+		mockStore.EXPECT().Get(gomock.Any(), "123").Return(updated, nil),
+	)
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/order/123", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Header().Get("Cache-Control") != "private, no-cache" {
+		t.Fatalf("unexpected response %d %v", first.Code, first.Header())
+	}
+	if w := get(etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("expected 304 without body, got %d %q", w.Code, w.Body)
+	}
+	if w := get(etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Fatalf("changed order must get new ETag, got %d %v", w.Code, w.Header())
 	}
 }
+
+// Проверяет: при одном ADMIN_TOKEN ответ зависит от X-Admin-Token (Vary), ответ без маски
+// private даже при публичном Cache-Control, с маской — как настроено
+func TestOrderHandler_CacheHeadersByMask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mockcache.NewMockOrderStore(ctrl)
+	srv := &Server{Store: mockStore, AdminToken: "secret", CacheControl: "public, max-age=60, s-maxage=120"}
+	order := &database.Order{OrderUID: "123", Delivery: database.Delivery{Phone: "+79161231234"}}
+	mockStore.EXPECT().Get(gomock.Any(), "123").Return(order, nil).Times(4)
+	h := srv.Routes()
+	get := func(token string) http.Header {
+		req := httptest.NewRequest(http.MethodGet, "/order/123", nil)
+		if token != "" {
+			req.Header.Set(adminTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Header()
+	}
+
+	for _, token := range []string{"", "secret"} {
+		if vary := strings.Join(get(token).Values("Vary"), ", "); !strings.Contains(vary, adminTokenHeader) || !strings.Contains(vary, apiKeyHeader) {
+			t.Fatalf("token %q: Vary must list credential headers, got %q", token, vary)
+		}
+	}
+	if cc := get("").Get("Cache-Control"); cc != "public, max-age=60, s-maxage=120" {
+		t.Fatalf("masked response keeps configured Cache-Control, got %q", cc)
+	}
+	if cc := get("secret").Get("Cache-Control"); cc != "private, max-age=60" {
+		t.Fatalf("unmasked response must be private, got %q", cc)
+	}
+}
+
+func TestPrivateCacheControl(t *testing.T) {
+	cases := map[string]string{
+		"":                         "private",
+		"private, no-cache":        "private, no-cache",
+		"public, max-age=60":       "private, max-age=60",
+		"max-age=60, S-Maxage=600": "private, max-age=60",
+	}
+	for in, want := range cases {
+		if got := privateCacheControl(in); got != want {
+			t.Errorf("privateCacheControl(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
 func TestIndexHandler(t *testing.T) {
 	tpl := template.Must(template.New("index").Parse("<html>ok</html>"))
 	srv := Server{Tpl: tpl}
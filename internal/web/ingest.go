@@ -0,0 +1,386 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/cache"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// Ограничения приема заказов по HTTP.
+const (
+	maxOrderBody  = 1 << 20  // один заказ
+	maxBatchBody  = 16 << 20 // NDJSON-пакет
+	maxBatchLines = 1000
+	// maxIdempotencyKeys — сколько ответов по Idempotency-Key помнит экземпляр.
+	maxIdempotencyKeys = 10000
+)
+
+// Результат приема одного заказа.
+const (
+	IngestAccepted = "accepted" // сохранен или поставлен в очередь
+	IngestInvalid  = "invalid"  // не разобран или не прошел проверку, повтор бесполезен
+	IngestConflict = "conflict" // нарушает ограничение БД, повтор бесполезен
+	IngestFailed   = "failed"   // временная ошибка хранилища или Kafka, можно повторить
+)
+
+// OrderSink принимает новые заказы: cache.OrderStore пишет их в БД,
+// kafka.OrderPublisher ставит в топик заказов для Consumer.
+type OrderSink interface {
+	Save(ctx context.Context, order *database.Order) error
+}
+
+// OrderResult — итог приема одного заказа.
+type OrderResult struct {
+	// Line — номер строки NDJSON-пакета (с 1), в ответе на одиночный заказ не заполняется.
+	Line     int                   `json:"line,omitempty"`
+	OrderUID string                `json:"order_uid,omitempty"`
+	Status   string                `json:"status"`
+	Errors   []database.FieldError `json:"errors,omitempty"`
+	Error    string                `json:"error,omitempty"`
+}
+
+// BatchResult — итог приема NDJSON-пакета: счетчики и результат по каждой строке.
+// Заказы со статусом conflict учитываются в Invalid: повторять их так же бесполезно.
+type BatchResult struct {
+	Accepted int           `json:"accepted"`
+	Invalid  int           `json:"invalid"`
+	Failed   int           `json:"failed"`
+	Results  []OrderResult `json:"results"`
+}
+
+// Ingester принимает заказы по HTTP теми же разбором и проверкой, что и Consumer,
+// и передает их в OrderSink. Повтор запроса с тем же Idempotency-Key возвращает
+// сохраненный ответ, не принимая заказы второй раз.
+type Ingester struct {
+	sink   OrderSink
+	queued bool
+	keys   *idempotencyCache
+}
+
+// NewIngester создает прием заказов. queued — sink ставит заказы в очередь
+// (Kafka), а не записывает: успешный ответ тогда 202 Accepted вместо 201 Created.
+func NewIngester(sink OrderSink, queued bool) *Ingester {
+	return &Ingester{
+		sink:   sink,
+		queued: queued,
+		keys:   newIdempotencyCache(24*time.Hour, maxIdempotencyKeys),
+	}
+}
+
+// CreateOrderHandler принимает один заказ в JSON (POST /orders).
+// Ответ: 201 (202 при очереди), 400 — не JSON, 422 — ошибки полей,
+// 409 — заказ нарушает ограничение БД, 503 — хранилище недоступно.
+func (in *Ingester) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r, maxOrderBody)
+	if !ok {
+		return
+	}
+	in.idempotent(w, r, body, func() (int, any, bool) {
+		res := in.accept(r, body)
+		switch res.Status {
+		case IngestAccepted:
+			if in.queued {
+				return http.StatusAccepted, res, true
+			}
+			return http.StatusCreated, res, true
+		case IngestInvalid:
+			if res.Errors == nil {
+				return http.StatusBadRequest, res, true
+			}
+			return http.StatusUnprocessableEntity, res, true
+		case IngestConflict:
+			return http.StatusConflict, res, true
+		default:
+			return http.StatusServiceUnavailable, res, false
+		}
+	})
+}
+
+// BatchOrdersHandler принимает пакет заказов в NDJSON, по заказу на строку (POST /orders:batch).
+// Заказы обрабатываются независимо: ответ 200 содержит результат каждой строки.
+// Пакет больше maxBatchLines заказов или со слишком длинной строкой отклоняется с 413
+// целиком, до приема первого заказа.
+// Пакет с временными ошибками (failed) не сохраняется под Idempotency-Key: его можно
+// отправить повторно целиком: SaveOrder записывает заказ поверх сохраненного с тем же
+// order_uid, поэтому уже принятые заказы перезапишутся теми же данными.
+func (in *Ingester) BatchOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r, maxBatchBody)
+	if !ok {
+		return
+	}
+	in.idempotent(w, r, body, func() (int, any, bool) {
+		lines, err := splitBatch(body)
+		if err != nil {
+			return http.StatusRequestEntityTooLarge, newProblem(r, http.StatusRequestEntityTooLarge, err.Error()), true
+		}
+		batch := BatchResult{Results: make([]OrderResult, 0, len(lines))}
+		for _, l := range lines {
+			res := in.accept(r, l.data)
+			res.Line = l.number
+			batch.Results = append(batch.Results, res)
+			switch res.Status {
+			case IngestAccepted:
+				batch.Accepted++
+			case IngestInvalid, IngestConflict:
+				batch.Invalid++
+			default:
+				batch.Failed++
+			}
+		}
+		return http.StatusOK, batch, batch.Failed == 0
+	})
+}
+
+// batchLine — непустая строка NDJSON-пакета и ее номер (с 1).
+type batchLine struct {
+	number int
+	data   []byte
+}
+
+// splitBatch делит пакет на непустые строки. Ошибка — пакет больше maxBatchLines заказов
+// или строка длиннее maxOrderBody.
+func splitBatch(body []byte) ([]batchLine, error) {
+	var lines []batchLine
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	sc.Buffer(make([]byte, 0, 64<<10), maxOrderBody)
+	number := 0
+	for sc.Scan() {
+		number++
+		data := bytes.TrimSpace(sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(lines) == maxBatchLines {
+			return nil, fmt.Errorf("batch exceeds %d orders", maxBatchLines)
+		}
+		lines = append(lines, batchLine{number: number, data: data})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", number+1, err)
+	}
+	return lines, nil
+}
+
+// accept разбирает, проверяет и передает в sink один заказ.
+func (in *Ingester) accept(r *http.Request, data []byte) OrderResult {
+	ctx := r.Context()
+	order, err := database.OrderFromJSON(data)
+	if err != nil {
+		return OrderResult{Status: IngestInvalid, Error: "invalid JSON: " + err.Error()}
+	}
+	res := OrderResult{OrderUID: order.OrderUID}
+	ctx = logging.With(ctx, slog.String(logging.KeyOrderUID, order.OrderUID))
+
+	if err := database.ValidateOrder(order); err != nil {
+		res.Status = IngestInvalid
+		var ve *database.ValidationError
+		if errors.As(err, &ve) {
+			res.Errors = ve.Fields
+		} else {
+			res.Error = err.Error()
+		}
+		slog.InfoContext(ctx, "Заказ отклонен проверкой", logging.Err(err))
+		return res
+	}
+	if err := in.sink.Save(ctx, order); err != nil {
+		if database.IsConflict(err) {
+			slog.InfoContext(ctx, "Заказ отклонен БД", logging.Err(err))
+			res.Status = IngestConflict
+			res.Error = database.ErrConflict.Error()
+			return res
+		}
+		slog.ErrorContext(ctx, "Ошибка приема заказа", logging.Err(err))
+		res.Status = IngestFailed
+		res.Error = "order store unavailable, retry later"
+		return res
+	}
+	res.Status = IngestAccepted
+	slog.InfoContext(ctx, "Заказ принят по HTTP", slog.Bool("queued", in.queued))
+	return res
+}
+
+// idempotent выполняет handle один раз для пары (вызывающий, Idempotency-Key).
+// Повтор с тем же телом получает сохраненный ответ и заголовок Idempotent-Replayed;
+// тот же ключ с другим телом — 422, пока первый запрос выполняется — 409.
+// handle возвращает код, тело и final: false — ответ с временной ошибкой, он не сохраняется,
+// чтобы запрос можно было повторить с тем же ключом.
+func (in *Ingester) idempotent(w http.ResponseWriter, r *http.Request, body []byte, handle func() (int, any, bool)) {
+	key := r.Header.Get(headerIdempotencyKey)
+	if key == "" {
+		status, v, _ := handle()
+		writeStatusJSON(w, status, v)
+		return
+	}
+	if !validRequestKey(key) {
+		writeProblem(w, r, http.StatusBadRequest, "invalid Idempotency-Key")
+		return
+	}
+
+	scope := ClientKey(r.Context(), r.RemoteAddr) + "|" + r.Pattern + "|" + key
+	sum := sha256.Sum256(body)
+	entry, state := in.keys.begin(scope, sum)
+	switch state {
+	case idemReplay:
+		w.Header().Set("Idempotent-Replayed", "true")
+		writeRaw(w, entry.status, entry.contentType, entry.body)
+		return
+	case idemConflict:
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Idempotency-Key was used with a different request")
+		return
+	case idemInProgress:
+		writeProblem(w, r, http.StatusConflict, "request with this Idempotency-Key is in progress")
+		return
+	}
+
+	status, v, final := handle()
+	data, err := json.Marshal(v)
+	if err != nil {
+		in.keys.abort(scope)
+		slog.ErrorContext(r.Context(), "Ошибка записи JSON-ответа", logging.Err(err))
+		writeProblem(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+	data = append(data, '\n')
+	if !final {
+		in.keys.abort(scope)
+	} else {
+		in.keys.finish(scope, status, jsonContentType(v), data)
+	}
+	writeRaw(w, status, jsonContentType(v), data)
+}
+
+// headerIdempotencyKey — заголовок ключа идемпотентности.
+const headerIdempotencyKey = "Idempotency-Key"
+
+// validRequestKey пропускает ключи до 255 печатных ASCII-символов.
+func validRequestKey(key string) bool {
+	if len(key) > 255 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// readBody читает тело запроса не больше limit байт; при ошибке сам пишет ответ.
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("body exceeds %d bytes", limit))
+		} else {
+			writeProblem(w, r, http.StatusBadRequest, "cannot read body")
+		}
+		return nil, false
+	}
+	return body, true
+}
+
+// jsonContentType — Content-Type ответа с телом v: описание ошибки (*Problem) — problem+json.
+func jsonContentType(v any) string {
+	if _, ok := v.(*Problem); ok {
+		return problemContentType
+	}
+	return "application/json"
+}
+
+// writeStatusJSON пишет v в JSON с кодом status.
+func writeStatusJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", jsonContentType(v))
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Ошибка записи JSON-ответа", logging.Err(err))
+	}
+}
+
+// writeRaw пишет готовое JSON-тело с кодом status.
+func writeRaw(w http.ResponseWriter, status int, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// Состояния ключа идемпотентности.
+const (
+	idemNew = iota
+	idemReplay
+	idemConflict
+	idemInProgress
+)
+
+type idemEntry struct {
+	hash        [32]byte
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyCache хранит ответы по ключам идемпотентности в памяти экземпляра ttl.
+// Число ключей ограничено size: при переполнении вытесняются давно использованные,
+// их повтор будет выполнен заново.
+type idempotencyCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries *cache.LRU[string, *idemEntry]
+}
+
+func newIdempotencyCache(ttl time.Duration, size int) *idempotencyCache {
+	return &idempotencyCache{ttl: ttl, now: time.Now, entries: cache.NewLru[string, *idemEntry](size)}
+}
+
+// begin резервирует ключ под новый запрос или сообщает, что делать с повтором.
+func (c *idempotencyCache) begin(key string, hash [32]byte) (*idemEntry, int) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries.Get(key); ok && now.Before(e.expires) {
+		switch {
+		case e.hash != hash:
+			return nil, idemConflict
+		case !e.done:
+			return nil, idemInProgress
+		default:
+			return e, idemReplay
+		}
+	}
+	c.entries.Set(key, &idemEntry{hash: hash, expires: now.Add(c.ttl)})
+	return nil, idemNew
+}
+
+// finish сохраняет ответ для повторов.
+func (c *idempotencyCache) finish(key string, status int, contentType string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries.Peek(key); ok {
+		e.done, e.status, e.contentType, e.body = true, status, contentType, body
+	}
+}
+
+// abort освобождает ключ после временной ошибки.
+func (c *idempotencyCache) abort(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries.Delete(key)
+}
@@ -0,0 +1,350 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/retry"
+	"github.com/mitrich772/go-order-service/producer/generate"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// fakeSink запоминает принятые заказы; uid из fail возвращают временную ошибку,
+// из conflict — нарушение ограничения БД.
+type fakeSink struct {
+	mu       sync.Mutex
+	saved    []string
+	fail     map[string]bool
+	conflict map[string]bool
+}
+
+func (s *fakeSink) Save(_ context.Context, o *database.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail[o.OrderUID] {
+		return errors.New("db down")
+	}
+	if s.conflict[o.OrderUID] {
+		return fmt.Errorf("save: %w", database.ErrConflict)
+	}
+	s.saved = append(s.saved, o.OrderUID)
+	return nil
+}
+
+func orderJSON(t *testing.T, mutate func(o *database.Order)) string {
+	t.Helper()
+	o := generate.MakeOrder()
+	if mutate != nil {
+		mutate(&o)
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func newIngestServer(sink OrderSink, queued bool) http.Handler {
+	srv := &Server{
+		Auth: NewAuthenticator(AuthConfig{APIKeys: map[string]Principal{
+			"partner": {Subject: "partner", Role: RoleIngest},
+			"analyst": {Subject: "analyst", Role: RoleAnalyst},
+		}}),
+		Ingest: NewIngester(sink, queued),
+	}
+	return srv.Routes()
+}
+
+func post(h http.Handler, path, key, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(apiKeyHeader, key)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// Проверяет: валидный заказ — 201 и запись в sink; в режиме очереди — 202
+func TestCreateOrder_Accepted(t *testing.T) {
+	for _, queued := range []bool{false, true} {
+		sink := &fakeSink{}
+		h := newIngestServer(sink, queued)
+		body := orderJSON(t, nil)
+
+		w := post(h, "/orders", "partner", body)
+		want := http.StatusCreated
+		if queued {
+			want = http.StatusAccepted
+		}
+		if w.Code != want {
+			t.Fatalf("queued=%v: ожидали %d, получили %d: %s", queued, want, w.Code, w.Body)
+		}
+		var res OrderResult
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Status != IngestAccepted || len(sink.saved) != 1 || sink.saved[0] != res.OrderUID {
+			t.Fatalf("неожиданный результат %+v, сохранено %v", res, sink.saved)
+		}
+	}
+}
+
+// Проверяет: ошибки проверки возвращаются списком полей с кодом 422, не-JSON — 400
+func TestCreateOrder_Invalid(t *testing.T) {
+	sink := &fakeSink{}
+	h := newIngestServer(sink, false)
+
+	w := post(h, "/orders", "partner", orderJSON(t, func(o *database.Order) {
+		o.CustomerID = ""
+		o.Delivery.Email = "not-an-email"
+	}))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("ожидали 422, получили %d: %s", w.Code, w.Body)
+	}
+	var res OrderResult
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]string{}
+	for _, e := range res.Errors {
+		fields[e.Field] = e.Rule
+	}
+	if res.Status != IngestInvalid || fields["customer_id"] != "required" || fields["delivery.email"] != "email" {
+		t.Fatalf("неожиданные ошибки полей: %+v", res)
+	}
+
+	if w := post(h, "/orders", "partner", "{not json"); w.Code != http.StatusBadRequest {
+		t.Fatalf("ожидали 400, получили %d", w.Code)
+	}
+	if len(sink.saved) != 0 {
+		t.Fatalf("невалидные заказы не должны сохраняться: %v", sink.saved)
+	}
+}
+
+// Проверяет: прием заказов доступен только ролям ingest и admin
+func TestCreateOrder_Auth(t *testing.T) {
+	h := newIngestServer(&fakeSink{}, false)
+	body := orderJSON(t, nil)
+	if w := post(h, "/orders", "", body); w.Code != http.StatusUnauthorized {
+		t.Fatalf("без ключа ожидали 401, получили %d", w.Code)
+	}
+	if w := post(h, "/orders", "analyst", body); w.Code != http.StatusForbidden {
+		t.Fatalf("для analyst ожидали 403, получили %d", w.Code)
+	}
+}
+
+// Проверяет: повтор с тем же Idempotency-Key возвращает сохраненный ответ без второй записи,
+// тот же ключ с другим телом — 422
+func TestCreateOrder_Idempotency(t *testing.T) {
+	sink := &fakeSink{}
+	h := newIngestServer(sink, false)
+	body := orderJSON(t, nil)
+
+	first := post(h, "/orders", "partner", body, headerIdempotencyKey, "k-1")
+	second := post(h, "/orders", "partner", body, headerIdempotencyKey, "k-1")
+	if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+		t.Fatalf("ожидали 201 и 201, получили %d и %d", first.Code, second.Code)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" || second.Body.String() != first.Body.String() {
+		t.Fatalf("повтор должен вернуть сохраненный ответ: %q", second.Body)
+	}
+	if len(sink.saved) != 1 {
+		t.Fatalf("заказ сохранен %d раз, ожидали 1", len(sink.saved))
+	}
+
+	if w := post(h, "/orders", "partner", orderJSON(t, nil), headerIdempotencyKey, "k-1"); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("ключ с другим телом: ожидали 422, получили %d", w.Code)
+	}
+}
+
+// Проверяет: временная ошибка хранилища — 503, и повтор с тем же ключом выполняется заново
+func TestCreateOrder_RetryAfterFailure(t *testing.T) {
+	body := orderJSON(t, nil)
+	var o database.Order
+	_ = json.Unmarshal([]byte(body), &o)
+	sink := &fakeSink{fail: map[string]bool{o.OrderUID: true}}
+	h := newIngestServer(sink, false)
+
+	if w := post(h, "/orders", "partner", body, headerIdempotencyKey, "k-2"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("ожидали 503, получили %d", w.Code)
+	}
+	sink.fail = nil
+	if w := post(h, "/orders", "partner", body, headerIdempotencyKey, "k-2"); w.Code != http.StatusCreated {
+		t.Fatalf("после восстановления ожидали 201, получили %d", w.Code)
+	}
+}
+
+// Проверяет: нарушение ограничения БД — 409 со статусом conflict, ответ сохраняется под ключом
+func TestCreateOrder_Conflict(t *testing.T) {
+	body := orderJSON(t, nil)
+	var o database.Order
+	_ = json.Unmarshal([]byte(body), &o)
+	h := newIngestServer(&fakeSink{conflict: map[string]bool{o.OrderUID: true}}, false)
+
+	for range 2 {
+		w := post(h, "/orders", "partner", body, headerIdempotencyKey, "k-3")
+		var res OrderResult
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusConflict || res.Status != IngestConflict {
+			t.Fatalf("ожидали 409 conflict, получили %d %s", w.Code, w.Body)
+		}
+	}
+}
+
+// Проверяет: NDJSON-пакет обрабатывается построчно, результат содержит номер строки и статус
+func TestBatchOrders(t *testing.T) {
+	sink := &fakeSink{}
+	h := newIngestServer(sink, false)
+	body := strings.Join([]string{
+		orderJSON(t, nil),
+		"",
+		orderJSON(t, func(o *database.Order) { o.Locale = "" }),
+		"{broken",
+		orderJSON(t, nil),
+	}, "\n")
+
+	w := post(h, "/orders:batch", "partner", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ожидали 200, получили %d: %s", w.Code, w.Body)
+	}
+	var batch BatchResult
+	if err := json.Unmarshal(w.Body.Bytes(), &batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch.Accepted != 2 || batch.Invalid != 2 || batch.Failed != 0 || len(batch.Results) != 4 {
+		t.Fatalf("неожиданные счетчики: %+v", batch)
+	}
+	lines := []int{batch.Results[0].Line, batch.Results[1].Line, batch.Results[2].Line, batch.Results[3].Line}
+	if lines[0] != 1 || lines[1] != 3 || lines[2] != 4 || lines[3] != 5 {
+		t.Fatalf("неверные номера строк: %v", lines)
+	}
+	if r := batch.Results[1]; r.Status != IngestInvalid || len(r.Errors) != 1 || r.Errors[0].Field != "locale" {
+		t.Fatalf("неверный результат строки 3: %+v", r)
+	}
+	if len(sink.saved) != 2 {
+		t.Fatalf("сохранено %d заказов, ожидали 2", len(sink.saved))
+	}
+}
+
+// dbSink пишет заказы через GormDatabase, как cache.OrderStore в сервисе.
+type dbSink struct{ db *database.GormDatabase }
+
+func (s dbSink) Save(ctx context.Context, o *database.Order) error { return s.db.SaveOrder(ctx, o) }
+
+// Проверяет: повторно отправленный пакет перезаписывает уже принятые заказы, а не упирается
+// в уникальность order_uid, и автомат БД остается замкнутым
+func TestBatchOrders_ResendUpserts(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := database.NewGormDatabase(gormDB, 1, 0)
+	breaker := retry.NewBreaker(1, time.Minute)
+	db.UseBreaker(breaker)
+	h := newIngestServer(dbSink{db}, false)
+
+	orders := []database.Order{generate.MakeOrder(), generate.MakeOrder()}
+	var lines []string
+	for _, o := range orders {
+		data, err := json.Marshal(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(data))
+	}
+	body := strings.Join(lines, "\n")
+	// Каждая отправка пакета — по транзакции SaveOrder на заказ.
+	for range 2 {
+		for _, o := range orders {
+			mock.ExpectBegin()
+			mock.ExpectExec(`INSERT INTO "orders" (.+) ON CONFLICT \("order_uid"\) DO UPDATE`).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectQuery(`INSERT INTO "deliveries" (.+) ON CONFLICT \("order_uid"\) DO UPDATE`).
+				WillReturnRows(sqlmock.NewRows([]string{"delivery_id"}).AddRow(1))
+			mock.ExpectQuery(`INSERT INTO "payments" (.+) ON CONFLICT \("order_uid"\) DO UPDATE`).
+				WillReturnRows(sqlmock.NewRows([]string{"payment_id"}).AddRow(1))
+			mock.ExpectExec(`DELETE FROM "items"`).WillReturnResult(sqlmock.NewResult(0, 1))
+			items := sqlmock.NewRows([]string{"item_id"})
+			for i := range o.Items {
+				items.AddRow(i + 1)
+			}
+			mock.ExpectQuery(`INSERT INTO "items"`).WillReturnRows(items)
+			mock.ExpectCommit()
+		}
+	}
+	for attempt := 1; attempt <= 2; attempt++ {
+		w := post(h, "/orders:batch", "partner", body)
+		var batch BatchResult
+		if err := json.Unmarshal(w.Body.Bytes(), &batch); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != http.StatusOK || batch.Accepted != 2 {
+			t.Fatalf("попытка %d: ожидали 2 принятых заказа, получили %d %+v", attempt, w.Code, batch)
+		}
+	}
+	if state := breaker.State(); state != retry.BreakerClosed {
+		t.Fatalf("ожидали замкнутый автомат, получили %s", state)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Проверяет: пакет больше maxBatchLines заказов или со слишком длинной строкой отклоняется
+// с 413 целиком, не сохранив ни одного заказа
+func TestBatchOrders_TooLarge(t *testing.T) {
+	order := orderJSON(t, nil)
+	for name, body := range map[string]string{
+		"lines": strings.Repeat(order+"\n", maxBatchLines+1),
+		"line":  order + "\n" + strings.Repeat("x", maxOrderBody+1),
+	} {
+		sink := &fakeSink{}
+		h := newIngestServer(sink, false)
+		w := post(h, "/orders:batch", "partner", body, headerIdempotencyKey, "k-"+name)
+		if w.Code != http.StatusRequestEntityTooLarge || w.Header().Get("Content-Type") != problemContentType {
+			t.Fatalf("%s: ожидали 413 problem, получили %d %q", name, w.Code, w.Header().Get("Content-Type"))
+		}
+		if len(sink.saved) != 0 {
+			t.Fatalf("%s: отклоненный пакет сохранил %d заказов", name, len(sink.saved))
+		}
+	}
+}
+
+// Проверяет: кэш ключей идемпотентности ограничен, давно использованные ключи вытесняются
+func TestIdempotencyCache_Bounded(t *testing.T) {
+	c := newIdempotencyCache(time.Hour, 2)
+	for _, key := range []string{"a", "b", "c"} {
+		if _, state := c.begin(key, [32]byte{1}); state != idemNew {
+			t.Fatalf("%s: ожидали новый ключ, получили %d", key, state)
+		}
+		c.finish(key, http.StatusCreated, "application/json", []byte("{}"))
+	}
+	if n := c.entries.Len(); n != 2 {
+		t.Fatalf("ожидали 2 ключа, получили %d", n)
+	}
+	if _, state := c.begin("c", [32]byte{1}); state != idemReplay {
+		t.Fatalf("свежий ключ должен повторяться, получили %d", state)
+	}
+	if _, state := c.begin("a", [32]byte{1}); state != idemNew {
+		t.Fatalf("вытесненный ключ должен выполняться заново, получили %d", state)
+	}
+}
@@ -0,0 +1,267 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/feed"
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// Параметры потоков новых заказов (SSE и WebSocket).
+const (
+	// liveHeartbeat — период пустых сообщений (SSE-комментарий, WebSocket ping),
+	// чтобы прокси не закрывали простаивающее соединение и обрыв обнаруживался вовремя.
+	liveHeartbeat = 15 * time.Second
+	// liveWriteTimeout — сколько ждать записи одного сообщения клиенту. Клиент, который
+	// не читает дольше, отключается: иначе переполнится его буфер в ленте.
+	liveWriteTimeout = 10 * time.Second
+	// sseRetry — через сколько EventSource переподключается после обрыва.
+	sseRetry = 3 * time.Second
+)
+
+// accessTokenParam — параметр запроса с API-ключом или JWT: EventSource и WebSocket
+// в браузере не умеют передавать заголовки.
+const accessTokenParam = "access_token"
+
+// liveFilter — фильтры подписки на новые заказы, пустое поле не фильтрует.
+type liveFilter struct {
+	DeliveryService string
+	Currency        string
+	CustomerID      string
+}
+
+func parseLiveFilter(q url.Values) liveFilter {
+	return liveFilter{
+		DeliveryService: q.Get("delivery_service"),
+		Currency:        q.Get("currency"),
+		CustomerID:      q.Get("customer_id"),
+	}
+}
+
+func (f liveFilter) match(o *database.Order) bool {
+	return (f.DeliveryService == "" || f.DeliveryService == o.DeliveryService) &&
+		(f.Currency == "" || f.Currency == o.Payment.Currency) &&
+		(f.CustomerID == "" || f.CustomerID == o.CustomerID)
+}
+
+// lastEventID возвращает номер последнего полученного клиентом события:
+// заголовок Last-Event-ID (EventSource отправляет его при переподключении)
+// или параметр last_event_id. 0 — продолжать не с чего.
+func lastEventID(r *http.Request) (uint64, error) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid last event id %q", v)
+	}
+	return id, nil
+}
+
+// subscribeLive разбирает параметры подписки и подписывается на ленту.
+// При ошибке ответ уже записан.
+func (s *Server) subscribeLive(w http.ResponseWriter, r *http.Request) (*feed.Subscription, liveFilter, bool) {
+	after, err := lastEventID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return nil, liveFilter{}, false
+	}
+	sub, err := s.Feed.Subscribe(after)
+	if err != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(err)))
+		writeProblem(w, r, http.StatusServiceUnavailable, "order feed unavailable")
+		return nil, liveFilter{}, false
+	}
+	slog.InfoContext(r.Context(), "Подписка на новые заказы",
+		slog.String("route", r.Pattern),
+		slog.Bool("resumed", after != 0),
+		slog.Bool("missed", sub.Missed()),
+		slog.Int("subscribers", s.Feed.Subscribers()),
+	)
+	return sub, parseLiveFilter(r.URL.Query()), true
+}
+
+// presentLive маскирует персональные данные так же, как OrderHandler,
+// и записывает выдачу заказа в журнал аудита.
+func (s *Server) presentLive(r *http.Request, order *database.Order) *database.Order {
+	masked := !s.canViewPII(r)
+	if masked {
+		order = database.MaskOrder(order)
+	}
+	s.Audit.Record(r, AuditEvent{Action: AuditOrderRead, OrderUID: order.OrderUID, Result: AuditOK, Masked: masked})
+	return order
+}
+
+// OrderStreamHandler отдает новые заказы потоком Server-Sent Events.
+// События: order (id — номер в ленте, data — заказ в JSON), reset (часть заказов
+// после Last-Event-ID потеряна, состояние нужно перезагрузить) и lagged (клиент
+// не успевал читать; поток закрывается, EventSource переподключится с Last-Event-ID).
+func (s *Server) OrderStreamHandler(w http.ResponseWriter, r *http.Request) {
+	sub, filter, ok := s.subscribeLive(w, r)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no") // nginx не должен буферизовать поток
+	w.WriteHeader(http.StatusOK)
+
+	send := func(msg string) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+		if _, err := fmt.Fprint(w, msg); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	first := fmt.Sprintf("retry: %d\n\n", sseRetry.Milliseconds())
+	if sub.Missed() {
+		first += "event: reset\ndata: {}\n\n"
+	}
+	if !send(first) {
+		return
+	}
+
+	heartbeat := time.NewTicker(liveHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		var msg string
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			msg = ": ping\n\n"
+		case ev, ok := <-sub.C():
+			if !ok {
+				if errors.Is(sub.Err(), feed.ErrLagged) {
+					slog.WarnContext(r.Context(), "Подписчик SSE не успевал читать заказы и отключен")
+					send("event: lagged\ndata: {}\n\n")
+				}
+				return
+			}
+			if !filter.match(ev.Order) {
+				continue
+			}
+			data, err := json.Marshal(s.presentLive(r, ev.Order))
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Ошибка сериализации заказа", logging.Err(err))
+				continue
+			}
+			msg = fmt.Sprintf("id: %d\nevent: order\ndata: %s\n\n", ev.ID, data)
+		}
+		if !send(msg) {
+			return
+		}
+	}
+}
+
+// liveMessage — сообщение WebSocket: type order (с id и order) или reset.
+type liveMessage struct {
+	Type  string          `json:"type"`
+	ID    string          `json:"id,omitempty"`
+	Order *database.Order `json:"order,omitempty"`
+}
+
+// OrderSocketHandler отдает новые заказы по WebSocket сообщениями liveMessage в JSON.
+// Медленный клиент отключается с кодом 1013 (try again later) и может переподключиться
+// с last_event_id; при остановке сервиса соединение закрывается с кодом 1001.
+func (s *Server) OrderSocketHandler(w http.ResponseWriter, r *http.Request) {
+	sub, filter, ok := s.subscribeLive(w, r)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	// Accept проверяет Origin: со страниц других сайтов подключиться нельзя.
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		slog.WarnContext(r.Context(), "WebSocket не установлен", logging.Err(err))
+		return
+	}
+	defer conn.CloseNow()
+	// Клиент ничего не присылает; чтение нужно только для ping/pong и закрытия.
+	ctx := conn.CloseRead(r.Context())
+
+	send := func(msg liveMessage) bool {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			slog.ErrorContext(ctx, "Ошибка сериализации заказа", logging.Err(err))
+			return true
+		}
+		wctx, cancel := context.WithTimeout(ctx, liveWriteTimeout)
+		defer cancel()
+		return conn.Write(wctx, websocket.MessageText, data) == nil
+	}
+	if sub.Missed() && !send(liveMessage{Type: "reset"}) {
+		return
+	}
+
+	heartbeat := time.NewTicker(liveHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			pctx, cancel := context.WithTimeout(ctx, liveWriteTimeout)
+			err := conn.Ping(pctx)
+			cancel()
+			if err != nil {
+				return
+			}
+		case ev, ok := <-sub.C():
+			if !ok {
+				if errors.Is(sub.Err(), feed.ErrLagged) {
+					slog.WarnContext(ctx, "Подписчик WebSocket не успевал читать заказы и отключен")
+					_ = conn.Close(websocket.StatusTryAgainLater, "client too slow, reconnect with last_event_id")
+					return
+				}
+				_ = conn.Close(websocket.StatusGoingAway, "server shutting down")
+				return
+			}
+			if !filter.match(ev.Order) {
+				continue
+			}
+			msg := liveMessage{Type: "order", ID: strconv.FormatUint(ev.ID, 10), Order: s.presentLive(r, ev.Order)}
+			if !send(msg) {
+				return
+			}
+		}
+	}
+}
+
+// queryToken принимает учетные данные из параметра access_token для маршрутов,
+// которые открывает браузер без своих заголовков. Учетные данные из заголовков важнее.
+func (s *Server) queryToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get(accessTokenParam)
+		if _, ok := PrincipalFrom(r.Context()); ok || token == "" {
+			next(w, r)
+			return
+		}
+		p, err := s.authenticator().AuthenticateHeader(http.Header{"Authorization": {"Bearer " + token}})
+		if err != nil {
+			slog.WarnContext(r.Context(), "Отказ в аутентификации", logging.Err(err))
+			unauthorized(w, r)
+			return
+		}
+		next(w, withCaller(r, p))
+	}
+}
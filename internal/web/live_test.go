@@ -0,0 +1,244 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/feed"
+	"github.com/mitrich772/go-order-service/producer/generate"
+)
+
+// newLiveServer запускает сервер с лентой заказов и ключами support и analyst.
+func newLiveServer(t *testing.T, f *feed.Feed, audit *bytes.Buffer) *httptest.Server {
+	t.Helper()
+	srv := &Server{
+		Feed: f,
+		Auth: NewAuthenticator(AuthConfig{APIKeys: map[string]Principal{
+			"support": {Subject: "support", Role: RoleSupport},
+			"analyst": {Subject: "analyst", Role: RoleAnalyst},
+			"partner": {Subject: "partner", Role: RoleIngest},
+		}}),
+		Audit: NewAuditLog(audit),
+	}
+	ts := httptest.NewServer(srv.Routes())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func liveOrder(uid, service, currency string) *database.Order {
+	o := generate.MakeOrder()
+	o.OrderUID = uid
+	o.DeliveryService = service
+	o.Payment.Currency = currency
+	return &o
+}
+
+// waitSubscribers ждет, пока обработчик подпишется на ленту.
+func waitSubscribers(t *testing.T, f *feed.Feed, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for f.Subscribers() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("подписчиков %d, ожидали %d", f.Subscribers(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// sseEvent — разобранное событие SSE.
+type sseEvent struct {
+	id, event, data string
+}
+
+// openStream подключается к /orders/stream и возвращает канал разобранных событий.
+func openStream(t *testing.T, ts *httptest.Server, query string, header ...string) (<-chan sseEvent, *http.Response) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/orders/stream?"+query, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer close(events)
+		sc := bufio.NewScanner(resp.Body)
+		var ev sseEvent
+		for sc.Scan() {
+			field, value, _ := strings.Cut(sc.Text(), ": ")
+			switch field {
+			case "":
+				if ev.event != "" {
+					events <- ev
+				}
+				ev = sseEvent{}
+			case "id":
+				ev.id = value
+			case "event":
+				ev.event = value
+			case "data":
+				ev.data = value
+			}
+		}
+	}()
+	return events, resp
+}
+
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("поток закрыт")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("нет события")
+	}
+	return sseEvent{}
+}
+
+// Проверяет: SSE отдает заказы по фильтрам с номером события, маскирует данные для analyst
+// и пишет выдачу в аудит; после закрытия ленты поток завершается
+func TestOrderStream_FilterAndMask(t *testing.T) {
+	f := feed.New(8, 0)
+	var audit bytes.Buffer
+	ts := newLiveServer(t, f, &audit)
+
+	events, resp := openStream(t, ts, "delivery_service=meest&currency=USD&access_token=analyst")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("ожидали 200 text/event-stream, получили %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	waitSubscribers(t, f, 1)
+
+	want := liveOrder("uid-1", "meest", "USD")
+	f.Publish(liveOrder("skip-service", "dhl", "USD"))
+	f.Publish(liveOrder("skip-currency", "meest", "RUB"))
+	f.Publish(want)
+
+	ev := nextEvent(t, events)
+	var got database.Order
+	if err := json.Unmarshal([]byte(ev.data), &got); err != nil {
+		t.Fatal(err)
+	}
+	if ev.event != "order" || got.OrderUID != "uid-1" || ev.id == "" {
+		t.Fatalf("неверное событие: %+v", ev)
+	}
+	if got.Delivery.Phone == want.Delivery.Phone {
+		t.Fatal("analyst должен получать телефон с маской")
+	}
+	if !strings.Contains(audit.String(), `"order_uid":"uid-1"`) || strings.Contains(audit.String(), "skip") {
+		t.Fatalf("в аудите должна быть только выданная запись: %s", audit.String())
+	}
+
+	f.Close()
+	if _, ok := <-events; ok {
+		t.Fatal("после закрытия ленты поток должен завершиться")
+	}
+}
+
+// Проверяет: с Last-Event-ID поток досылает пропущенное из истории,
+// с неизвестным номером — начинается с события reset
+func TestOrderStream_Resume(t *testing.T) {
+	f := feed.New(8, 10)
+	ts := newLiveServer(t, f, &bytes.Buffer{})
+
+	first, _ := openStream(t, ts, "", "X-API-Key", "support")
+	waitSubscribers(t, f, 1)
+	f.Publish(liveOrder("a", "meest", "USD"))
+	f.Publish(liveOrder("b", "meest", "USD"))
+	a := nextEvent(t, first)
+
+	resumed, _ := openStream(t, ts, "", "X-API-Key", "support", "Last-Event-ID", a.id)
+	if ev := nextEvent(t, resumed); ev.event != "order" || !strings.Contains(ev.data, `"order_uid":"b"`) {
+		t.Fatalf("ожидали досылку заказа b, получили %+v", ev)
+	}
+
+	stale, _ := openStream(t, ts, "last_event_id=1", "X-API-Key", "support")
+	if ev := nextEvent(t, stale); ev.event != "reset" {
+		t.Fatalf("ожидали reset для устаревшего номера, получили %+v", ev)
+	}
+}
+
+// Проверяет доступ к потокам: без учетных данных и с неверным токеном — 401,
+// чужая роль — 403, неверный Last-Event-ID — 400
+func TestOrderStream_Access(t *testing.T) {
+	ts := newLiveServer(t, feed.New(1, 0), &bytes.Buffer{})
+	cases := []struct {
+		query string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"access_token=wrong", http.StatusUnauthorized},
+		{"access_token=partner", http.StatusForbidden},
+		{"access_token=support&last_event_id=abc", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		resp, err := http.Get(ts.URL + "/orders/stream?" + c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != c.want {
+			t.Errorf("%q: код %d, ожидали %d", c.query, resp.StatusCode, c.want)
+		}
+	}
+}
+
+// Проверяет: WebSocket присылает заказы по фильтру customer_id, при остановке
+// сервиса соединение закрывается с кодом 1001
+func TestOrderSocket(t *testing.T) {
+	f := feed.New(8, 0)
+	ts := newLiveServer(t, f, &bytes.Buffer{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/orders/ws?customer_id=c1"
+	conn, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{
+		HTTPHeader: http.Header{apiKeyHeader: {"support"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+	waitSubscribers(t, f, 1)
+
+	other := liveOrder("skip", "meest", "USD")
+	other.CustomerID = "c2"
+	want := liveOrder("uid-1", "meest", "USD")
+	want.CustomerID = "c1"
+	f.Publish(other)
+	f.Publish(want)
+
+	_, data, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg liveMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "order" || msg.ID == "" || msg.Order.OrderUID != "uid-1" || msg.Order.Delivery.Phone != want.Delivery.Phone {
+		t.Fatalf("неверное сообщение: %s", data)
+	}
+
+	f.Close()
+	if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusGoingAway {
+		t.Fatalf("ожидали закрытие 1001, получили %v", err)
+	}
+}
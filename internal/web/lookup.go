@@ -0,0 +1,55 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+// OrderLookupHandler ищет заказы по одному вторичному ключу:
+// GET /orders/lookup?track_number=… (или customer_id, phone, email).
+// Возвращает до cache.LookupLimit последних заказов в формате по ?format= или Accept
+// (JSON — {"orders": [...]}, NDJSON, CSV, XML). Поиск по телефону и email
+// доступен только тем, кто видит персональные данные без маски (см. canViewPII).
+func (s *Server) OrderLookupHandler(w http.ResponseWriter, r *http.Request) {
+	format, err := negotiateFormat(r)
+	if err != nil {
+		formatError(w, r, err)
+		return
+	}
+	q := r.URL.Query()
+	var field database.LookupField
+	var value string
+	for _, f := range database.LookupFields {
+		v := strings.TrimSpace(q.Get(string(f)))
+		if v == "" {
+			continue
+		}
+		if field != "" {
+			field = ""
+			break
+		}
+		field, value = f, v
+	}
+	if field == "" {
+		writeProblem(w, r, http.StatusBadRequest, "exactly one of track_number, customer_id, phone, email is required")
+		return
+	}
+	masked := !s.canViewPII(r)
+	if field.Personal() && masked {
+		writeProblem(w, r, http.StatusForbidden, "lookup by "+string(field)+" requires access to personal data")
+		return
+	}
+
+	orders, err := s.Store.Lookup(r.Context(), field, value)
+	if err != nil {
+		storeProblem(w, r, err, "Ошибка поиска заказов", slog.String("field", string(field)))
+		return
+	}
+	for i, order := range orders {
+		orders[i] = s.presentOrder(r, order, masked)
+	}
+	writeOrders(w, r, format, orders)
+}
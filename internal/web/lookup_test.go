@@ -0,0 +1,71 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockcache "github.com/mitrich772/go-order-service/internal/cache/mocks"
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+// Проверяет: поиск по трек-номеру доступен analyst с маскированием и пишется в аудит
+func TestOrderLookup_Track(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	order := uiOrder("uid-1")
+	store.EXPECT().Lookup(gomock.Any(), database.LookupTrackNumber, "WBTRACK").
+		Return([]*database.Order{order}, nil)
+
+	var audit bytes.Buffer
+	h := newUIServer(t, store, true, &audit)
+	w := uiGet(h, "/orders/lookup?track_number=WBTRACK", "analyst")
+	if w.Code != http.StatusOK {
+		t.Fatalf("ожидался 200, получили %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Orders []*database.Order `json:"orders"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Orders) != 1 || resp.Orders[0].Delivery.Phone == order.Delivery.Phone {
+		t.Fatalf("ожидался один заказ с маской, получили %+v", resp.Orders)
+	}
+	if !strings.Contains(audit.String(), `"order_uid":"uid-1"`) || !strings.Contains(audit.String(), `"masked":true`) {
+		t.Fatalf("выдача не записана в аудит: %s", audit.String())
+	}
+}
+
+// Проверяет: телефон и email ищут только те, кто видит персональные данные;
+// нужен ровно один ключ; ошибка хранилища дает 503
+func TestOrderLookup_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().Lookup(gomock.Any(), database.LookupPhone, "+7 916 123-12-34").
+		Return(nil, nil)
+	store.EXPECT().Lookup(gomock.Any(), database.LookupEmail, "a@b.c").
+		Return(nil, fmt.Errorf("lookup: %w", database.ErrUnavailable))
+
+	h := newUIServer(t, store, true, &bytes.Buffer{})
+	for _, tc := range []struct {
+		target, key string
+		code        int
+	}{
+		{"/orders/lookup?phone=%2B7+916+123-12-34", "analyst", http.StatusForbidden},
+		{"/orders/lookup?phone=%2B7+916+123-12-34", "support", http.StatusOK},
+		{"/orders/lookup?email=a@b.c", "support", http.StatusServiceUnavailable},
+		{"/orders/lookup?track_number=a&customer_id=b", "support", http.StatusBadRequest},
+		{"/orders/lookup?track_number=+", "support", http.StatusBadRequest},
+		{"/orders/lookup?track_number=a", "", http.StatusUnauthorized},
+		{"/orders/lookup?track_number=a", "partner", http.StatusForbidden},
+	} {
+		if w := uiGet(h, tc.target, tc.key); w.Code != tc.code {
+			t.Errorf("%s (%s): ожидался %d, получили %d", tc.target, tc.key, tc.code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/mitrich772/go-order-service/api/openapi"
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// APISpec — описание HTTP API (api/openapi): отдается в /openapi.json и на странице /docs
+// и проверяет параметры запросов до обработчиков. Методы безопасно вызывать на nil:
+// тогда описание не отдается и запросы не проверяются.
+type APISpec struct {
+	doc    *openapi3.T
+	json   []byte
+	loaded time.Time
+	router routers.Router
+	docs   *template.Template
+	page   docsPage
+}
+
+// validateOptions — проверка запросов: только параметры пути, query и заголовков.
+// Тело проверяют сами обработчики (прием заказов отвечает 422 со списком полей),
+// учетные данные — authenticate и requireRole; значения по умолчанию в запрос не подставляются.
+var validateOptions = openapi3filter.Options{
+	ExcludeRequestBody:  true,
+	SkipSettingDefaults: true,
+	AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+}
+
+// NewAPISpec загружает встроенное описание API. docs — шаблон страницы /docs
+// (templates/docs.html), nil отключает страницу.
+func NewAPISpec(docs *template.Template) (*APISpec, error) {
+	doc, err := openapi.Load(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("encode openapi: %w", err)
+	}
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("openapi router: %w", err)
+	}
+	return &APISpec{
+		doc:    doc,
+		json:   data,
+		loaded: time.Now(),
+		router: router,
+		docs:   docs,
+		page:   newDocsPage(doc),
+	}, nil
+}
+
+// SpecHandler отдает описание в JSON (GET /openapi.json).
+func (a *APISpec) SpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "", a.loaded, bytes.NewReader(a.json))
+}
+
+// DocsHandler показывает страницу документации, построенную по описанию (GET /docs).
+func (a *APISpec) DocsHandler(w http.ResponseWriter, r *http.Request) {
+	if a.docs == nil {
+		http.NotFound(w, r)
+		return
+	}
+	var buf bytes.Buffer
+	if err := a.docs.Execute(&buf, a.page); err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка рендеринга документации API", logging.Err(err))
+		writeProblem(w, r, http.StatusInternalServerError, "template error")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := buf.WriteTo(w); err != nil {
+		slog.DebugContext(r.Context(), "Клиент не дочитал ответ", logging.Err(err))
+	}
+}
+
+// Validate проверяет параметры запросов к описанным эндпоинтам: неверные отклоняются
+// с 400 до обработчика. Запросы к неописанным путям и методам (страницы /ui/, статика,
+// 404 и 405) проходят без проверки.
+func (a *APISpec) Validate(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, params, err := a.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: params,
+			Route:      route,
+			Options:    &validateOptions,
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, requestErrorDetail(err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestErrorDetail — короткое описание ошибки проверки для клиента: параметр и причина,
+// без схемы, которую kin-openapi добавляет в текст ошибки.
+func requestErrorDetail(err error) string {
+	var re *openapi3filter.RequestError
+	if !errors.As(err, &re) || re.Parameter == nil {
+		return "invalid request"
+	}
+	reason := re.Reason
+	var se *openapi3.SchemaError
+	var pe *openapi3filter.ParseError
+	switch {
+	case errors.As(re.Err, &se):
+		reason = se.Reason
+	case errors.As(re.Err, &pe):
+		reason = pe.Reason
+	case reason == "" && re.Err != nil:
+		reason = re.Err.Error()
+	}
+	return fmt.Sprintf("invalid %s parameter %q: %s", re.Parameter.In, re.Parameter.Name, reason)
+}
+
+// docsPage — данные страницы /docs: операции по тегам и схемы.
+type docsPage struct {
+	Title       string
+	Version     string
+	Description string
+	Groups      []docsGroup
+	Schemas     []docsSchema
+}
+
+type docsGroup struct {
+	Name        string
+	Description string
+	Operations  []docsOperation
+}
+
+type docsOperation struct {
+	ID          string
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Params      []docsField
+	Body        []docsContent
+	Responses   []docsResponse
+}
+
+type docsResponse struct {
+	Code        string
+	Description string
+	Content     []docsContent
+}
+
+// docsContent — тип содержимого и его схема.
+type docsContent struct {
+	MediaType string
+	Type      docsType
+}
+
+type docsSchema struct {
+	Name        string
+	Description string
+	Props       []docsField
+}
+
+// docsField — параметр запроса или свойство схемы.
+type docsField struct {
+	Name        string
+	In          string
+	Required    bool
+	Type        docsType
+	Description string
+}
+
+// docsType — тип значения; Ref — имя схемы из components, на нее ведет ссылка.
+type docsType struct {
+	Text string
+	Ref  string
+}
+
+func newDocsPage(doc *openapi3.T) docsPage {
+	page := docsPage{Title: doc.Info.Title, Version: doc.Info.Version, Description: doc.Info.Description}
+	groups := make(map[string]int, len(doc.Tags))
+	for _, tag := range doc.Tags {
+		groups[tag.Name] = len(page.Groups)
+		page.Groups = append(page.Groups, docsGroup{Name: tag.Name, Description: tag.Description})
+	}
+	paths := doc.Paths.InMatchingOrder()
+	slices.Sort(paths)
+	for _, path := range paths {
+		item := doc.Paths.Value(path)
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			op := item.GetOperation(method)
+			if op == nil {
+				continue
+			}
+			o := docsOperation{ID: op.OperationID, Method: method, Path: path, Summary: op.Summary, Description: op.Description}
+			for _, p := range append(item.Parameters, op.Parameters...) {
+				v := p.Value
+				o.Params = append(o.Params, docsField{Name: v.Name, In: v.In, Required: v.Required, Type: schemaType(v.Schema), Description: v.Description})
+			}
+			if op.RequestBody != nil {
+				o.Body = contentTypes(op.RequestBody.Value.Content)
+			}
+			codes := make([]string, 0, op.Responses.Len())
+			for code := range op.Responses.Map() {
+				codes = append(codes, code)
+			}
+			slices.Sort(codes)
+			for _, code := range codes {
+				resp := op.Responses.Value(code).Value
+				o.Responses = append(o.Responses, docsResponse{Code: code, Description: deref(resp.Description), Content: contentTypes(resp.Content)})
+			}
+			i, ok := groups[firstTag(op.Tags)]
+			if !ok {
+				i = len(page.Groups)
+				groups[firstTag(op.Tags)] = i
+				page.Groups = append(page.Groups, docsGroup{Name: firstTag(op.Tags)})
+			}
+			page.Groups[i].Operations = append(page.Groups[i].Operations, o)
+		}
+	}
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		s := doc.Components.Schemas[name].Value
+		ds := docsSchema{Name: name, Description: s.Description}
+		props := make([]string, 0, len(s.Properties))
+		for prop := range s.Properties {
+			props = append(props, prop)
+		}
+		slices.Sort(props)
+		for _, prop := range props {
+			ps := s.Properties[prop]
+			ds.Props = append(ds.Props, docsField{
+				Name:        prop,
+				Required:    slices.Contains(s.Required, prop),
+				Type:        schemaType(ps),
+				Description: ps.Value.Description,
+			})
+		}
+		page.Schemas = append(page.Schemas, ds)
+	}
+	return page
+}
+
+func contentTypes(content openapi3.Content) []docsContent {
+	types := make([]string, 0, len(content))
+	for mt := range content {
+		types = append(types, mt)
+	}
+	slices.Sort(types)
+	out := make([]docsContent, 0, len(types))
+	for _, mt := range types {
+		out = append(out, docsContent{MediaType: mt, Type: schemaType(content[mt].Schema)})
+	}
+	return out
+}
+
+// schemaType описывает тип схемы: имя схемы из components, массив, тип с форматом,
+// ограничениями и допустимыми значениями.
+func schemaType(ref *openapi3.SchemaRef) docsType {
+	if ref == nil {
+		return docsType{Text: "any"}
+	}
+	if ref.Ref != "" {
+		name := ref.Ref[strings.LastIndex(ref.Ref, "/")+1:]
+		return docsType{Text: name, Ref: name}
+	}
+	s := ref.Value
+	if s.Type.Is(openapi3.TypeArray) {
+		item := schemaType(s.Items)
+		return docsType{Text: "array of " + item.Text, Ref: item.Ref}
+	}
+	var parts []string
+	if s.Type != nil {
+		parts = append(parts, strings.Join(s.Type.Slice(), "|"))
+	}
+	if s.Format != "" {
+		parts = append(parts, "("+s.Format+")")
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		parts = append(parts, "{"+strings.Join(values, ", ")+"}")
+	}
+	if s.Min != nil {
+		parts = append(parts, fmt.Sprintf("≥ %v", *s.Min))
+	}
+	if s.MaxLength != nil {
+		parts = append(parts, fmt.Sprintf("≤ %d chars", *s.MaxLength))
+	}
+	if len(parts) == 0 {
+		return docsType{Text: "any"}
+	}
+	return docsType{Text: strings.Join(parts, " ")}
+}
+
+func firstTag(tags []string) string {
+	if len(tags) == 0 {
+		return "other"
+	}
+	return tags[0]
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
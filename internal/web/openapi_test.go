@@ -0,0 +1,329 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/golang/mock/gomock"
+	"github.com/mitrich772/go-order-service/internal/cache"
+	mockcache "github.com/mitrich772/go-order-service/internal/cache/mocks"
+	"github.com/mitrich772/go-order-service/internal/database"
+	mockdb "github.com/mitrich772/go-order-service/internal/database/mocks"
+	"github.com/mitrich772/go-order-service/internal/health"
+	"github.com/mitrich772/go-order-service/internal/retry"
+)
+
+func init() {
+	// ответы, которые описание задает строкой: проверяется только код и тип содержимого
+	for _, ct := range []string{"application/x-ndjson", "application/xml", "text/html"} {
+		openapi3filter.RegisterBodyDecoder(ct, openapi3filter.PlainBodyDecoder)
+	}
+}
+
+func newTestAPISpec(t *testing.T) *APISpec {
+	t.Helper()
+	api, err := NewAPISpec(template.Must(template.ParseFiles("../../templates/docs.html")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api
+}
+
+// Проверяет: схемы описания совпадают с типами Go — те же поля JSON, обязательны ровно
+// поля без omitempty, совместимые типы значений
+func TestAPISpec_MatchesTypes(t *testing.T) {
+	api := newTestAPISpec(t)
+	types := map[string]reflect.Type{
+		"Order":            reflect.TypeFor[database.Order](),
+		"Delivery":         reflect.TypeFor[database.Delivery](),
+		"Payment":          reflect.TypeFor[database.Payment](),
+		"Item":             reflect.TypeFor[database.Item](),
+		"OrderResult":      reflect.TypeFor[OrderResult](),
+		"FieldError":       reflect.TypeFor[database.FieldError](),
+		"BatchResult":      reflect.TypeFor[BatchResult](),
+		"SearchResponse":   reflect.TypeFor[searchResponse](),
+		"SearchResult":     reflect.TypeFor[searchResult](),
+		"SearchHighlights": reflect.TypeFor[database.SearchHighlights](),
+		"ItemHighlight":    reflect.TypeFor[database.ItemHighlight](),
+		"Problem":          reflect.TypeFor[Problem](),
+		"CacheStats":       reflect.TypeFor[cache.Stats](),
+		"WarmupStatus":     reflect.TypeFor[cache.WarmupStatus](),
+		"HealthReport":     reflect.TypeFor[health.Report](),
+		"ComponentStatus":  reflect.TypeFor[health.ComponentStatus](),
+	}
+	for name := range api.doc.Components.Schemas {
+		if _, ok := types[name]; !ok && name != "OrderList" {
+			t.Errorf("schema %s: no Go type to check against", name)
+		}
+	}
+	for name, typ := range types {
+		ref := api.doc.Components.Schemas[name]
+		if ref == nil {
+			t.Errorf("schema %s is missing", name)
+			continue
+		}
+		schema := ref.Value
+		fields := map[string]reflect.StructField{}
+		var required []string
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			tag := f.Tag.Get("json")
+			jsonName, opts, _ := strings.Cut(tag, ",")
+			if jsonName == "-" || !f.IsExported() {
+				continue
+			}
+			fields[jsonName] = f
+			if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+				required = append(required, jsonName)
+			}
+		}
+		for prop := range schema.Properties {
+			if _, ok := fields[prop]; !ok {
+				t.Errorf("%s.%s: not a field of %s", name, prop, typ)
+			}
+		}
+		for jsonName, f := range fields {
+			prop, ok := schema.Properties[jsonName]
+			if !ok {
+				t.Errorf("%s.%s: field %s is not described", name, jsonName, f.Name)
+				continue
+			}
+			if !kindMatches(f.Type, prop) {
+				t.Errorf("%s.%s: schema type %v does not match Go type %s", name, jsonName, prop.Value.Type, f.Type)
+			}
+		}
+		got := slices.Clone(schema.Required)
+		slices.Sort(got)
+		slices.Sort(required)
+		if !slices.Equal(got, required) {
+			t.Errorf("%s: required %v, Go fields without omitempty %v", name, got, required)
+		}
+	}
+}
+
+// kindMatches сравнивает тип поля Go с типом схемы.
+func kindMatches(t reflect.Type, ref *openapi3.SchemaRef) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	s := ref.Value
+	switch {
+	case t == reflect.TypeFor[time.Time]():
+		return s.Type.Is(openapi3.TypeString) && s.Format == "date-time"
+	case t.Kind() == reflect.String:
+		return s.Type.Is(openapi3.TypeString)
+	case t.Kind() == reflect.Bool:
+		return s.Type.Is(openapi3.TypeBoolean)
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return s.Type.Is(openapi3.TypeInteger)
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return s.Type.Is(openapi3.TypeNumber)
+	case t.Kind() == reflect.Slice:
+		return s.Type.Is(openapi3.TypeArray) && kindMatches(t.Elem(), s.Items)
+	case t.Kind() == reflect.Map:
+		return s.Type.Is(openapi3.TypeObject) && s.AdditionalProperties.Schema != nil &&
+			kindMatches(t.Elem(), s.AdditionalProperties.Schema)
+	case t.Kind() == reflect.Struct:
+		return s.Type.Is(openapi3.TypeObject)
+	case t.Kind() == reflect.Interface:
+		return s.Type == nil
+	}
+	return false
+}
+
+// contractServer — сервер со всеми описанными эндпоинтами и проверкой ответов по описанию.
+type contractServer struct {
+	t   *testing.T
+	api *APISpec
+	h   http.Handler
+}
+
+// do выполняет запрос и проверяет, что код, заголовки и тело ответа соответствуют описанию.
+func (c *contractServer) do(method, target, key, body string, header ...string) *httptest.ResponseRecorder {
+	c.t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(apiKeyHeader, key)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	c.h.ServeHTTP(w, req)
+
+	route, params, err := c.api.router.FindRoute(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: not described: %v", method, target, err)
+	}
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request: req, PathParams: params, Route: route, Options: &validateOptions,
+		},
+		Status:  w.Code,
+		Header:  w.Header(),
+		Body:    io.NopCloser(bytes.NewReader(w.Body.Bytes())),
+		Options: &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	if err := openapi3filter.ValidateResponse(context.Background(), input); err != nil {
+		c.t.Errorf("%s %s (%s): response %d does not match the spec: %v\n%s", method, target, key, w.Code, err, w.Body.String())
+	}
+	return w
+}
+
+// Проверяет: ответы обработчиков — коды, типы содержимого и тела — соответствуют описанию
+func TestAPISpec_Contract(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	order := uiOrder("uid-1")
+	store.EXPECT().Get(gomock.Any(), "uid-1").Return(order, nil).AnyTimes()
+	store.EXPECT().Get(gomock.Any(), "missing").Return(nil, database.ErrNotFound)
+	store.EXPECT().Get(gomock.Any(), "open").Return(nil, &retry.OpenError{RetryAfter: time.Second})
+	store.EXPECT().Get(gomock.Any(), "broken").Return(nil, io.ErrUnexpectedEOF)
+	store.EXPECT().List(gomock.Any(), gomock.Any()).Return([]database.Order{*order}, nil).Times(2)
+	store.EXPECT().Lookup(gomock.Any(), database.LookupTrackNumber, order.TrackNumber).Return([]*database.Order{order}, nil)
+	store.EXPECT().Search(gomock.Any(), gomock.Any()).Return([]database.SearchResult{{
+		Order: order, Rank: 0.5,
+		Highlights: database.SearchHighlights{Items: []database.ItemHighlight{{NmID: 1, Name: "<mark>nike</mark>"}}},
+	}}, nil)
+
+	db := mockdb.NewMockDatabase(ctrl)
+	db.EXPECT().GetLastNOrders(gomock.Any(), 10).Return([]database.Order{*order}, nil)
+	admin := cache.NewDBWithCacheStore(db, 10)
+	if _, err := admin.Warm(); err != nil {
+		t.Fatal(err)
+	}
+	checks := health.NewRegistry(time.Second)
+	checks.AddReadiness("db", health.CheckerFunc(func(context.Context) (any, error) { return nil, io.EOF }))
+
+	api := newTestAPISpec(t)
+	srv := &Server{
+		Store:  store,
+		Cache:  admin,
+		Health: checks,
+		Ingest: NewIngester(&fakeSink{}, false),
+		API:    api,
+		Auth: NewAuthenticator(AuthConfig{APIKeys: map[string]Principal{
+			"support": {Subject: "support", Role: RoleSupport},
+			"analyst": {Subject: "analyst", Role: RoleAnalyst},
+			"partner": {Subject: "partner", Role: RoleIngest},
+			"root":    {Subject: "root", Role: RoleAdmin},
+		}}),
+	}
+	c := &contractServer{t: t, api: api, h: srv.Routes()}
+
+	for _, tc := range []struct {
+		method, target, key string
+		code                int
+	}{
+		{http.MethodGet, "/order/uid-1", "support", http.StatusOK},
+		{http.MethodGet, "/order/uid-1", "analyst", http.StatusOK},
+		{http.MethodGet, "/order/uid-1?format=xml", "support", http.StatusOK},
+		{http.MethodGet, "/order/uid-1?format=pdf", "support", http.StatusBadRequest},
+		{http.MethodGet, "/order/uid-1", "", http.StatusUnauthorized},
+		{http.MethodGet, "/order/uid-1", "partner", http.StatusForbidden},
+		{http.MethodGet, "/order/missing", "support", http.StatusNotFound},
+		{http.MethodGet, "/order/open", "support", http.StatusServiceUnavailable},
+		{http.MethodGet, "/order/broken", "support", http.StatusInternalServerError},
+		{http.MethodGet, "/orders?limit=5", "analyst", http.StatusOK},
+		{http.MethodGet, "/orders?format=ndjson", "analyst", http.StatusOK},
+		{http.MethodGet, "/orders?limit=0", "analyst", http.StatusBadRequest},
+		{http.MethodGet, "/orders/lookup?track_number=" + order.TrackNumber, "support", http.StatusOK},
+		{http.MethodGet, "/orders/lookup", "support", http.StatusBadRequest},
+		{http.MethodGet, "/orders/search?items=nike", "analyst", http.StatusOK},
+		{http.MethodGet, "/orders/search?items=nike&page_size=0", "analyst", http.StatusBadRequest},
+		{http.MethodGet, "/admin/cache/stats", "root", http.StatusOK},
+		{http.MethodGet, "/admin/cache/keys/uid-1", "root", http.StatusOK},
+		{http.MethodGet, "/admin/cache/keys/none", "root", http.StatusNotFound},
+		{http.MethodGet, "/admin/cache/warmup", "root", http.StatusOK},
+		{http.MethodGet, "/admin/cache/stats", "support", http.StatusForbidden},
+		{http.MethodGet, "/healthz", "", http.StatusOK},
+		{http.MethodGet, "/readyz", "", http.StatusServiceUnavailable},
+		{http.MethodGet, "/openapi.json", "", http.StatusOK},
+		{http.MethodGet, "/docs", "", http.StatusOK},
+	} {
+		if w := c.do(tc.method, tc.target, tc.key, ""); w.Code != tc.code {
+			t.Errorf("%s %s (%s): expected %d, got %d", tc.method, tc.target, tc.key, tc.code, w.Code)
+		}
+	}
+
+	// условный GET и прием заказов
+	etag := c.do(http.MethodGet, "/order/uid-1", "support", "").Header().Get("ETag")
+	if w := c.do(http.MethodGet, "/order/uid-1", "support", "", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: expected 304, got %d", w.Code)
+	}
+	for _, tc := range []struct {
+		target, body string
+		header       []string
+		code         int
+	}{
+		{"/orders", orderJSON(t, nil), nil, http.StatusCreated},
+		{"/orders", orderJSON(t, func(o *database.Order) { o.CustomerID = "" }), nil, http.StatusUnprocessableEntity},
+		{"/orders", "{", nil, http.StatusBadRequest},
+		{"/orders", orderJSON(t, nil), []string{headerIdempotencyKey, "key with spaces"}, http.StatusBadRequest},
+		{"/orders:batch", orderJSON(t, nil) + "\n{\n", nil, http.StatusOK},
+	} {
+		if w := c.do(http.MethodPost, tc.target, "partner", tc.body, tc.header...); w.Code != tc.code {
+			t.Errorf("POST %s: expected %d, got %d: %s", tc.target, tc.code, w.Code, w.Body.String())
+		}
+	}
+}
+
+// Проверяет: неверные параметры отклоняются по описанию до обработчика (хранилище не вызывается)
+// с problem+json, неописанные пути проходят без проверки; /openapi.json отдает описание
+func TestAPISpec_ValidatesRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	h := (&Server{Store: store, API: newTestAPISpec(t)}).Routes()
+
+	for _, tc := range []struct {
+		target, param string
+	}{
+		{"/order/uid-1?format=pdf", `"format"`},
+		{"/orders?limit=0", `"limit"`},
+		{"/orders?created_after=yesterday", `"created_after"`},
+		{"/orders/search?items=nike&page_size=ten", `"page_size"`},
+		{"/orders/search?items=" + strings.Repeat("a", 201), `"items"`},
+	} {
+		w := uiGet(h, tc.target, "")
+		if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != problemContentType {
+			t.Errorf("%s: expected 400 problem, got %d %q", tc.target, w.Code, w.Header().Get("Content-Type"))
+			continue
+		}
+		var p Problem
+		if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(p.Detail, tc.param) || strings.Contains(p.Detail, "Schema:") {
+			t.Errorf("%s: detail should name %s briefly, got %q", tc.target, tc.param, p.Detail)
+		}
+	}
+
+	// /order/ без uid не описан: отвечает обработчик
+	if w := uiGet(h, "/order/", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("/order/: expected 400 from handler, got %d", w.Code)
+	}
+
+	w := uiGet(h, "/openapi.json", "")
+	var doc struct {
+		OpenAPI string         `json:"openapi"`
+		Paths   map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Paths["/order/{order_uid}"] == nil {
+		t.Fatalf("unexpected spec: %s %v", doc.OpenAPI, doc.Paths)
+	}
+}
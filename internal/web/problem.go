@@ -0,0 +1,95 @@
+package web
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"github.com/mitrich772/go-order-service/internal/retry"
+)
+
+// problemContentType — Content-Type ответов об ошибках (RFC 7807).
+const problemContentType = "application/problem+json"
+
+// unavailableRetryAfter — Retry-After ответа 503, если автомат не сообщил время
+// до пробного вызова (таймаут, обрыв соединения).
+const unavailableRetryAfter = 5 * time.Second
+
+// Problem — тело ответа об ошибке по RFC 7807. Type всегда about:blank,
+// поэтому Title — стандартный текст кода ответа, подробности — в Detail.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Instance — путь запроса, в котором произошла ошибка.
+	Instance string `json:"instance,omitempty"`
+	// RequestID — идентификатор запроса из X-Request-ID, по нему ищутся записи в логах.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// newProblem создает описание ошибки status для запроса r.
+func newProblem(r *http.Request, status int, detail string) *Problem {
+	return &Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: logging.RequestID(r.Context()),
+	}
+}
+
+// writeProblem отвечает ошибкой status в формате application/problem+json.
+// Заголовки успешного ответа (ETag, Cache-Control), выставленные до ошибки, снимаются.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	p := newProblem(r, status, detail)
+	h := w.Header()
+	h.Del("ETag")
+	h.Del("Content-Encoding")
+	h.Set("Cache-Control", "no-store")
+	h.Set("Content-Type", problemContentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		slog.DebugContext(r.Context(), "Клиент не дочитал ответ", logging.Err(err))
+	}
+}
+
+// storeProblem отвечает на ошибку хранилища по ее классу: не найдено — 404,
+// неверный запрос — 400, конфликт с сохраненными данными — 409,
+// хранилище недоступно — 503 с Retry-After, остальное — 500.
+// Ошибки 503 и 500 пишутся в лог с сообщением msg; текст внутренних ошибок клиенту не отдается.
+func storeProblem(w http.ResponseWriter, r *http.Request, err error, msg string, attrs ...any) {
+	switch {
+	case database.IsNotFound(err):
+		writeProblem(w, r, http.StatusNotFound, "order not found")
+	case database.IsInvalid(err):
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+	case database.IsConflict(err):
+		slog.InfoContext(r.Context(), msg, append(attrs, logging.Err(err))...)
+		writeProblem(w, r, http.StatusConflict, database.ErrConflict.Error())
+	case database.IsUnavailable(err):
+		slog.WarnContext(r.Context(), msg, append(attrs, logging.Err(err))...)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(err)))
+		writeProblem(w, r, http.StatusServiceUnavailable, "order store unavailable, retry later")
+	default:
+		slog.ErrorContext(r.Context(), msg, append(attrs, logging.Err(err))...)
+		writeProblem(w, r, http.StatusInternalServerError, "internal error")
+	}
+}
+
+// retryAfterSeconds — значение Retry-After для недоступного хранилища: время до
+// пробного вызова разомкнутого автомата, иначе unavailableRetryAfter; не меньше секунды.
+func retryAfterSeconds(err error) int {
+	wait, ok := retry.RetryAfter(err)
+	if !ok || wait <= 0 {
+		wait = unavailableRetryAfter
+	}
+	return max(1, int(math.Ceil(wait.Seconds())))
+}
@@ -0,0 +1,324 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"github.com/mitrich772/go-order-service/internal/metrics"
+)
+
+// Группы маршрутов, для которых задаются лимиты.
+const (
+	RouteOrder  = "order"
+	RouteIngest = "ingest"
+	RouteAdmin  = "admin"
+)
+
+// Виды лимитов в метриках и логах.
+const (
+	limitRequests = "requests"
+	limitNotFound = "not_found"
+)
+
+// sweepInterval — как часто удаляются корзины, успевшие наполниться (клиент затих).
+const sweepInterval = time.Minute
+
+// Limit — параметры token bucket: Rate запросов в секунду, Burst — емкость корзины.
+// Нулевой Rate отключает лимит.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled сообщает, что лимит задан.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0
+}
+
+// ParseLimit разбирает лимит вида "<n>/<s|m|h>[:burst]", например "20/s:40" или "30/m".
+// Без burst емкость равна числу запросов за период. Пустая строка или "off" — лимит выключен.
+func ParseLimit(spec string) (Limit, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "off" {
+		return Limit{}, nil
+	}
+	rate, burst, hasBurst := strings.Cut(spec, ":")
+	count, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q: want <n>/<s|m|h>[:burst]", spec)
+	}
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: count must be a positive number", spec)
+	}
+	var per time.Duration
+	switch period {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid limit %q: period must be s, m or h", spec)
+	}
+	l := Limit{Rate: n / per.Seconds(), Burst: int(math.Ceil(n))}
+	if hasBurst {
+		if l.Burst, err = strconv.Atoi(burst); err != nil || l.Burst < 1 {
+			return Limit{}, fmt.Errorf("invalid limit %q: burst must be a positive integer", spec)
+		}
+	}
+	return l, nil
+}
+
+// ParseRouteLimits разбирает лимиты маршрутов вида "order=20/s:40,admin=5/s".
+// Допустимые группы: order, ingest, admin; "off" выключает все лимиты маршрутов.
+func ParseRouteLimits(spec string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	if strings.TrimSpace(spec) == "off" {
+		return limits, nil
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, limit, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid route limit %q: want route=<limit>", entry)
+		}
+		route = strings.TrimSpace(route)
+		if route != RouteOrder && route != RouteIngest && route != RouteAdmin {
+			return nil, fmt.Errorf("unknown route %q: want %s, %s or %s", route, RouteOrder, RouteIngest, RouteAdmin)
+		}
+		l, err := ParseLimit(limit)
+		if err != nil {
+			return nil, err
+		}
+		limits[route] = l
+	}
+	return limits, nil
+}
+
+// RateLimitConfig содержит настройки RateLimiter.
+type RateLimitConfig struct {
+	// Routes — лимиты запросов по группам маршрутов (RouteOrder, RouteIngest, RouteAdmin).
+	Routes map[string]Limit
+	// NotFound — отдельный, более строгий лимит ответов 404 на клиента:
+	// перебор случайных uid упирается в него раньше, чем в общий лимит.
+	NotFound Limit
+	// Metrics — счетчик отказов, nil отключает его.
+	Metrics *metrics.Metrics
+}
+
+// RateLimiter ограничивает частоту запросов token bucket'ом на клиента:
+// аутентифицированного — по subject API-ключа или JWT, анонимного — по IP.
+// Методы безопасно вызывать на nil: тогда лимиты не действуют.
+type RateLimiter struct {
+	routes   map[string]Limit
+	notFound Limit
+	metrics  *metrics.Metrics
+	now      func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter создает RateLimiter; без лимитов возвращает nil.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	routes := make(map[string]Limit)
+	for route, l := range cfg.Routes {
+		if l.Enabled() {
+			routes[route] = l
+		}
+	}
+	if len(routes) == 0 && !cfg.NotFound.Enabled() {
+		return nil
+	}
+	return &RateLimiter{
+		routes:   routes,
+		notFound: cfg.NotFound,
+		metrics:  cfg.Metrics,
+		now:      time.Now,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// Limit применяет к next лимит группы route и лимит ответов 404.
+// При превышении отвечает 429 с Retry-After (секунды до появления токена).
+func (l *RateLimiter) Limit(route string, next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		client := ClientKey(ctx, r.RemoteAddr)
+		if wait, ok := l.Allow(ctx, route, client, 1); !ok {
+			reject(w, r, wait)
+			return
+		}
+		if !l.notFound.Enabled() {
+			next(w, r)
+			return
+		}
+
+		// Бюджет 404 проверяется до запроса (без списания), списывается только за 404.
+		if wait, ok := l.AllowNotFound(ctx, route, client); !ok {
+			reject(w, r, wait)
+			return
+		}
+		rec := logging.NewStatusWriter(w)
+		next(rec, r)
+		if rec.Status == http.StatusNotFound {
+			l.NotFound(client, 1)
+		}
+	}
+}
+
+// Allow списывает cost запросов группы route из корзины клиента client (см. ClientKey).
+// При превышении ничего не списывает, учитывает отказ в метриках и возвращает время
+// ожидания и false. Запрос дороже емкости корзины проходит только при полной корзине
+// и уводит ее в долг, так что средняя частота не превышает лимит при любом cost.
+func (l *RateLimiter) Allow(ctx context.Context, route, client string, cost int) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+	limit, ok := l.routes[route]
+	if !ok {
+		return 0, true
+	}
+	if wait := l.take(route+"|"+client, limit, float64(cost)); wait > 0 {
+		l.rejected(ctx, route, limitRequests, wait)
+		return wait, false
+	}
+	return 0, true
+}
+
+// AllowNotFound проверяет, что у клиента остался бюджет ответов «не найдено», ничего не списывая.
+// Списывает NotFound после ответа.
+func (l *RateLimiter) AllowNotFound(ctx context.Context, route, client string) (time.Duration, bool) {
+	if l == nil || !l.notFound.Enabled() {
+		return 0, true
+	}
+	if wait := l.take(limitNotFound+"|"+client, l.notFound, 0); wait > 0 {
+		l.rejected(ctx, route, limitNotFound, wait)
+		return wait, false
+	}
+	return 0, true
+}
+
+// NotFound списывает n ответов «не найдено» из бюджета клиента (при нехватке — в долг).
+func (l *RateLimiter) NotFound(client string, n int) {
+	if l == nil || !l.notFound.Enabled() || n <= 0 {
+		return
+	}
+	l.debit(limitNotFound+"|"+client, l.notFound, float64(n))
+}
+
+// take списывает cost токенов из корзины key и возвращает 0,
+// либо, если токенов не хватает, ничего не списывает и возвращает время ожидания.
+// cost 0 только проверяет, что в корзине есть хотя бы один токен. Для cost больше
+// емкости нужна полная корзина, остаток уходит в долг.
+func (l *RateLimiter) take(key string, limit Limit, cost float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucket(key, limit)
+	need := min(max(cost, 1), float64(limit.Burst))
+	if b.tokens < need {
+		return time.Duration((need - b.tokens) / limit.Rate * float64(time.Second))
+	}
+	b.tokens -= cost
+	return 0
+}
+
+// debit списывает n токенов из корзины key без проверки, при нехватке — в долг.
+func (l *RateLimiter) debit(key string, limit Limit, n float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bucket(key, limit).tokens -= n
+}
+
+// bucket возвращает пополненную корзину key, создавая полную. Вызывается под l.mu.
+func (l *RateLimiter) bucket(key string, limit Limit) *bucket {
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limit: limit, tokens: float64(limit.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+	return b
+}
+
+// sweep удаляет наполнившиеся корзины, чтобы память не росла с числом клиентов.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.refill(now); b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rejected учитывает отказ в метриках и логе.
+func (l *RateLimiter) rejected(ctx context.Context, route, kind string, wait time.Duration) {
+	l.metrics.RateLimited(route, kind)
+	slog.WarnContext(ctx, "Превышен лимит запросов",
+		slog.String("route", route),
+		slog.String("limit", kind),
+		slog.Duration("retry_after", wait),
+	)
+}
+
+// reject отвечает 429 с Retry-After.
+func reject(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(RetryAfterSeconds(wait)))
+	writeProblem(w, r, http.StatusTooManyRequests, "too many requests")
+}
+
+// RetryAfterSeconds округляет время ожидания вверх до целых секунд для Retry-After.
+func RetryAfterSeconds(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
+
+// bucket — корзина токенов одного клиента.
+type bucket struct {
+	limit   Limit
+	tokens  float64
+	updated time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+		b.updated = now
+	}
+}
+
+// ClientKey определяет клиента для лимита: subject аутентифицированного вызывающего
+// из ctx (все его запросы делят одну корзину независимо от IP и протокола),
+// иначе IP-адрес соединения remoteAddr.
+func ClientKey(ctx context.Context, remoteAddr string) string {
+	if p, ok := PrincipalFrom(ctx); ok {
+		return "subject:" + p.Subject
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return "ip:" + host
+}
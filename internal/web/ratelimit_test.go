@@ -0,0 +1,211 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockcache "github.com/mitrich772/go-order-service/internal/cache/mocks"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseLimit(t *testing.T) {
+	cases := map[string]Limit{
+		"":        {},
+		"off":     {},
+		"20/s:40": {Rate: 20, Burst: 40},
+		"30/m":    {Rate: 0.5, Burst: 30},
+		"3600/h":  {Rate: 1, Burst: 3600},
+	}
+	for spec, want := range cases {
+		got, err := ParseLimit(spec)
+		if err != nil || got != want {
+			t.Errorf("ParseLimit(%q) = %+v, %v; ожидали %+v", spec, got, err, want)
+		}
+	}
+	for _, bad := range []string{"20", "20/d", "0/s", "x/s", "10/s:0", "10/s:x"} {
+		if _, err := ParseLimit(bad); err == nil {
+			t.Errorf("ParseLimit(%q): ожидали ошибку", bad)
+		}
+	}
+}
+
+func TestParseRouteLimits(t *testing.T) {
+	limits, err := ParseRouteLimits("order=20/s:40, admin=5/s")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if limits[RouteOrder] != (Limit{Rate: 20, Burst: 40}) || limits[RouteAdmin] != (Limit{Rate: 5, Burst: 5}) {
+		t.Fatalf("неверные лимиты: %+v", limits)
+	}
+	if _, err := ParseRouteLimits("orders=1/s"); err == nil {
+		t.Fatal("ожидали ошибку для неизвестного маршрута")
+	}
+}
+
+// newTestLimiter создает RateLimiter с управляемыми часами.
+func newTestLimiter(cfg RateLimitConfig) (*RateLimiter, *time.Time) {
+	now := time.Unix(1_700_000_000, 0)
+	l := NewRateLimiter(cfg)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func serve(h http.HandlerFunc, remote string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/order/x", nil)
+	req.RemoteAddr = remote
+	w := httptest.NewRecorder()
+	h(w, req)
+	return w
+}
+
+// Проверяет: после исчерпания burst — 429 с Retry-After, у другого клиента своя корзина,
+// токены восстанавливаются со временем
+func TestRateLimiter_Requests(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+	l, now := newTestLimiter(RateLimitConfig{Routes: map[string]Limit{RouteOrder: {Rate: 0.5, Burst: 2}}, Metrics: m})
+	h := l.Limit(RouteOrder, func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 2; i++ {
+		if w := serve(h, "10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("запрос %d: ожидали 200, получили %d", i, w.Code)
+		}
+	}
+	w := serve(h, "10.0.0.1:5678")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("ожидали 429, получили %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("Retry-After = %q, ожидали 2", got)
+	}
+	if w := serve(h, "10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Fatalf("другой клиент: ожидали 200, получили %d", w.Code)
+	}
+
+	*now = now.Add(2 * time.Second)
+	if w := serve(h, "10.0.0.1:1234"); w.Code != http.StatusOK {
+		t.Fatalf("после паузы ожидали 200, получили %d", w.Code)
+	}
+	if got := testutil.ToFloat64(m.HTTPRateLimited.WithLabelValues(RouteOrder, limitRequests)); got != 1 {
+		t.Fatalf("orders_http_rate_limited_total = %v, ожидали 1", got)
+	}
+}
+
+// Проверяет: бюджет 404 расходуют только ответы 404; после него отклоняются и существующие заказы
+func TestRateLimiter_NotFoundBudget(t *testing.T) {
+	l, now := newTestLimiter(RateLimitConfig{NotFound: Limit{Rate: 1.0 / 60, Burst: 2}})
+	status := http.StatusOK
+	h := l.Limit(RouteOrder, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(status) })
+
+	for i := 0; i < 5; i++ {
+		if w := serve(h, "10.0.0.1:1"); w.Code != http.StatusOK {
+			t.Fatalf("успешные запросы не должны расходовать бюджет 404, получили %d", w.Code)
+		}
+	}
+	status = http.StatusNotFound
+	for i := 0; i < 2; i++ {
+		if w := serve(h, "10.0.0.1:1"); w.Code != http.StatusNotFound {
+			t.Fatalf("ожидали 404, получили %d", w.Code)
+		}
+	}
+	status = http.StatusOK
+	w := serve(h, "10.0.0.1:1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("ожидали 429 с Retry-After 60, получили %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	*now = now.Add(time.Minute)
+	if w := serve(h, "10.0.0.1:1"); w.Code != http.StatusOK {
+		t.Fatalf("после паузы ожидали 200, получили %d", w.Code)
+	}
+}
+
+// Проверяет: пакетный запрос дороже емкости проходит при полной корзине и уводит ее в долг,
+// промахи пакета списываются из бюджета 404 разом
+func TestRateLimiter_Cost(t *testing.T) {
+	l, now := newTestLimiter(RateLimitConfig{
+		Routes:   map[string]Limit{RouteOrder: {Rate: 10, Burst: 20}},
+		NotFound: Limit{Rate: 1, Burst: 5},
+	})
+	ctx := context.Background()
+
+	if _, ok := l.Allow(ctx, RouteOrder, "c", 100); !ok {
+		t.Fatal("пакет при полной корзине должен пройти")
+	}
+	wait, ok := l.Allow(ctx, RouteOrder, "c", 1)
+	if ok || wait != 8100*time.Millisecond {
+		t.Fatalf("после пакета на 100 ожидали отказ на 8.1s (долг 80 токенов), получили %v %v", wait, ok)
+	}
+	*now = now.Add(10 * time.Second)
+	if _, ok := l.Allow(ctx, RouteOrder, "c", 1); !ok {
+		t.Fatal("после погашения долга запрос должен пройти")
+	}
+
+	l.NotFound("c", 7)
+	if wait, ok := l.AllowNotFound(ctx, RouteOrder, "c"); ok || wait != 3*time.Second {
+		t.Fatalf("после 7 промахов при емкости 5 ожидали отказ на 3s, получили %v %v", wait, ok)
+	}
+	if _, ok := l.AllowNotFound(ctx, RouteOrder, "other"); !ok {
+		t.Fatal("у другого клиента свой бюджет")
+	}
+}
+
+// Проверяет: аутентифицированный клиент ограничивается по subject, а не по IP
+func TestRateLimiter_KeyedBySubject(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&database.Order{}, nil).AnyTimes()
+
+	l, _ := newTestLimiter(RateLimitConfig{Routes: map[string]Limit{RouteOrder: {Rate: 1, Burst: 1}}})
+	srv := &Server{
+		Store:     store,
+		Auth:      NewAuthenticator(AuthConfig{APIKeys: map[string]Principal{"k1": {Subject: "svc", Role: RoleSupport}}}),
+		RateLimit: l,
+	}
+	h := srv.Routes()
+
+	codes := make([]int, 0, 2)
+	for _, ip := range []string{"10.0.0.1:1", "10.0.0.2:1"} {
+		req := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+		req.RemoteAddr = ip
+		req.Header.Set(apiKeyHeader, "k1")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	if fmt.Sprint(codes) != fmt.Sprint([]int{http.StatusOK, http.StatusTooManyRequests}) {
+		t.Fatalf("ожидали [200 429] для одного ключа с разных IP, получили %v", codes)
+	}
+}
+
+// Проверяет: старые наполнившиеся корзины удаляются
+func TestRateLimiter_Sweep(t *testing.T) {
+	l, now := newTestLimiter(RateLimitConfig{Routes: map[string]Limit{RouteOrder: {Rate: 1, Burst: 1}}})
+	h := l.Limit(RouteOrder, func(w http.ResponseWriter, r *http.Request) {})
+	for i := 0; i < 100; i++ {
+		serve(h, fmt.Sprintf("10.0.1.%d:1", i))
+	}
+	*now = now.Add(2 * sweepInterval)
+	serve(h, "10.0.0.1:1")
+	if n := len(l.buckets); n != 1 {
+		t.Fatalf("ожидали 1 корзину после очистки, осталось %d", n)
+	}
+}
+
+func TestRateLimiter_Nil(t *testing.T) {
+	if l := NewRateLimiter(RateLimitConfig{}); l != nil {
+		t.Fatal("без лимитов ожидали nil")
+	}
+	var l *RateLimiter
+	if w := serve(l.Limit(RouteOrder, func(w http.ResponseWriter, r *http.Request) {}), "10.0.0.1:1"); w.Code != http.StatusOK {
+		t.Fatalf("nil RateLimiter должен пропускать запросы, получили %d", w.Code)
+	}
+}
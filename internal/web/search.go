@@ -0,0 +1,117 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+)
+
+// Страницы полнотекстового поиска: размер по умолчанию и наибольший, глубина выдачи
+// (дальние страницы требуют ранжировать все совпадения) и длина запроса.
+const (
+	searchDefaultPageSize = 20
+	searchMaxPageSize     = 100
+	searchMaxOffset       = 1000
+	searchMaxQueryLen     = 200
+)
+
+// searchResponse — ответ GET /orders/search.
+type searchResponse struct {
+	Results []searchResult `json:"results"`
+	// NextPageToken пустой на последней странице.
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+type searchResult struct {
+	Order      *database.Order           `json:"order"`
+	Rank       float64                   `json:"rank"`
+	Highlights database.SearchHighlights `json:"highlights"`
+}
+
+// searchPageToken — позиция следующей страницы; запросы должны совпадать с первым.
+type searchPageToken struct {
+	Offset int `json:"offset"`
+}
+
+// OrderSearchHandler ищет заказы полнотекстовым поиском:
+// GET /orders/search?items=nike&delivery=казань — по названиям и брендам товаров
+// и по городу, региону и адресу доставки (заданные запросы должны совпасть оба).
+// Выдача — от более релевантных к менее, страницами page_size по page_token.
+// Совпадения по адресу учитываются только для тех, кто видит персональные данные.
+func (s *Server) OrderSearchHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	search := database.OrderSearch{
+		Items:    strings.TrimSpace(q.Get("items")),
+		Delivery: strings.TrimSpace(q.Get("delivery")),
+	}
+	if search.Items == "" && search.Delivery == "" {
+		writeProblem(w, r, http.StatusBadRequest, "items or delivery query is required")
+		return
+	}
+	if len(search.Items) > searchMaxQueryLen || len(search.Delivery) > searchMaxQueryLen {
+		writeProblem(w, r, http.StatusBadRequest, "search query must be at most "+strconv.Itoa(searchMaxQueryLen)+" bytes")
+		return
+	}
+	size := searchDefaultPageSize
+	if v := q.Get("page_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeProblem(w, r, http.StatusBadRequest, "page_size must be a positive integer")
+			return
+		}
+		size = min(n, searchMaxPageSize)
+	}
+	if v := q.Get("page_token"); v != "" {
+		offset, ok := decodeSearchToken(v)
+		if !ok {
+			writeProblem(w, r, http.StatusBadRequest, "invalid page_token")
+			return
+		}
+		search.Offset = offset
+	}
+	masked := !s.canViewPII(r)
+	search.IncludeAddress = !masked
+	search.Limit = size + 1 // лишний заказ показывает, есть ли следующая страница
+
+	results, err := s.Store.Search(r.Context(), search)
+	if err != nil {
+		storeProblem(w, r, err, "Ошибка полнотекстового поиска заказов")
+		return
+	}
+	resp := searchResponse{Results: make([]searchResult, 0, min(len(results), size))}
+	if len(results) > size {
+		results = results[:size]
+		if next := search.Offset + size; next < searchMaxOffset {
+			resp.NextPageToken = encodeSearchToken(next)
+		}
+	}
+	for _, res := range results {
+		resp.Results = append(resp.Results, searchResult{
+			Order:      s.presentOrder(r, res.Order, masked),
+			Rank:       res.Rank,
+			Highlights: res.Highlights,
+		})
+	}
+	writeJSON(w, resp)
+}
+
+func encodeSearchToken(offset int) string {
+	data, _ := json.Marshal(searchPageToken{Offset: offset})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchToken(s string) (int, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, false
+	}
+	var t searchPageToken
+	if err := json.Unmarshal(data, &t); err != nil || t.Offset < 0 || t.Offset >= searchMaxOffset {
+		return 0, false
+	}
+	return t.Offset, true
+}
@@ -0,0 +1,86 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockcache "github.com/mitrich772/go-order-service/internal/cache/mocks"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/retry"
+)
+
+// Проверяет: запросы передаются в хранилище, адрес учитывается только для support,
+// лишний заказ превращается в next_page_token, который ведет на следующую страницу
+func TestOrderSearch_Paging(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	results := func(uids ...string) []database.SearchResult {
+		out := make([]database.SearchResult, len(uids))
+		for i, uid := range uids {
+			out[i] = database.SearchResult{Order: uiOrder(uid), Rank: 0.5,
+				Highlights: database.SearchHighlights{City: "<mark>Казань</mark>"}}
+		}
+		return out
+	}
+	gomock.InOrder(
+		store.EXPECT().Search(gomock.Any(), database.OrderSearch{Items: "nike", Delivery: "казань", Limit: 3}).
+			Return(results("a", "b", "c"), nil),
+		store.EXPECT().Search(gomock.Any(), database.OrderSearch{Items: "nike", Delivery: "казань", Offset: 2, Limit: 3}).
+			Return(results("c"), nil),
+		store.EXPECT().Search(gomock.Any(), database.OrderSearch{Delivery: "ленина", IncludeAddress: true, Limit: 21}).
+			Return(nil, nil),
+	)
+	h := newUIServer(t, store, true, &bytes.Buffer{})
+
+	w := uiGet(h, "/orders/search?items=nike&delivery=%D0%BA%D0%B0%D0%B7%D0%B0%D0%BD%D1%8C&page_size=2", "analyst")
+	if w.Code != http.StatusOK {
+		t.Fatalf("ожидался 200, получили %d: %s", w.Code, w.Body)
+	}
+	var first searchResponse
+	if err := json.NewDecoder(w.Body).Decode(&first); err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Results) != 2 || first.NextPageToken == "" || first.Results[0].Highlights.City == "" {
+		t.Fatalf("неверная первая страница: %+v", first)
+	}
+
+	w = uiGet(h, "/orders/search?items=nike&delivery=%D0%BA%D0%B0%D0%B7%D0%B0%D0%BD%D1%8C&page_size=2&page_token="+first.NextPageToken, "analyst")
+	var second searchResponse
+	if err := json.NewDecoder(w.Body).Decode(&second); err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Results) != 1 || second.Results[0].Order.OrderUID != "c" || second.NextPageToken != "" {
+		t.Fatalf("неверная вторая страница: %+v", second)
+	}
+
+	if w := uiGet(h, "/orders/search?delivery=ленина", "support"); w.Code != http.StatusOK {
+		t.Fatalf("ожидался 200, получили %d", w.Code)
+	}
+}
+
+// Проверяет коды ошибок: нет запроса, неверные page_size и page_token, хранилище недоступно
+func TestOrderSearch_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().Search(gomock.Any(), gomock.Any()).Return(nil, retry.ErrCircuitOpen)
+	h := newUIServer(t, store, true, &bytes.Buffer{})
+
+	for _, tc := range []struct {
+		target string
+		code   int
+	}{
+		{"/orders/search", http.StatusBadRequest},
+		{"/orders/search?items=nike&page_size=0", http.StatusBadRequest},
+		{"/orders/search?items=nike&page_token=bad", http.StatusBadRequest},
+		{"/orders/search?items=nike&page_token=" + encodeSearchToken(searchMaxOffset), http.StatusBadRequest},
+		{"/orders/search?items=nike", http.StatusServiceUnavailable},
+	} {
+		if w := uiGet(h, tc.target, "analyst"); w.Code != tc.code {
+			t.Errorf("%s: ожидался %d, получили %d", tc.target, tc.code, w.Code)
+		}
+	}
+}
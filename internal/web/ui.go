@@ -0,0 +1,279 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/internal/logging"
+)
+
+// Страницы /ui/.
+const (
+	// uiRecentOrders — сколько заказов показывает список.
+	uiRecentOrders = 50
+	// uiTokenCookie хранит API-ключ или JWT, введенный на странице входа:
+	// браузер не умеет сам передавать заголовки при переходе по ссылке.
+	uiTokenCookie = "order_ui_token"
+	// totalsTolerance — допустимое расхождение сумм (копейки при округлении), как у валидатора.
+	totalsTolerance = 0.01
+)
+
+var uiFuncs = template.FuncMap{
+	"money": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"datetime": func(t time.Time) string {
+		if t.IsZero() {
+			return "—"
+		}
+		return t.UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+	"unix": func(sec int64) time.Time {
+		if sec == 0 {
+			return time.Time{}
+		}
+		return time.Unix(sec, 0)
+	},
+}
+
+// ParseUI загружает шаблоны страниц /ui/ (*.html из dir).
+func ParseUI(dir string) (*template.Template, error) {
+	return template.New("ui").Funcs(uiFuncs).ParseGlob(filepath.Join(dir, "*.html"))
+}
+
+// uiPage — общие данные страниц: заголовок, вошедший пользователь и признак маскирования.
+type uiPage struct {
+	Title  string
+	Caller *Principal
+	Masked bool
+}
+
+// ordersPage — список заказов с формой поиска.
+type ordersPage struct {
+	uiPage
+	Track    string
+	Customer string
+	Orders   []*database.Order
+	// More — найдено больше заказов, чем показано.
+	More bool
+}
+
+// orderPage — карточка заказа.
+type orderPage struct {
+	uiPage
+	Order  *database.Order
+	Totals orderTotals
+}
+
+// errorPage — ошибка или форма входа (Login).
+type errorPage struct {
+	uiPage
+	Message string
+	Login   bool
+	// Next — страница, на которую вернуться после входа.
+	Next string
+}
+
+// orderTotals — проверка сумм заказа: сумма товаров против goods_total
+// и товары + доставка + сборы против суммы оплаты.
+type orderTotals struct {
+	Items    float64
+	Expected float64
+	GoodsOK  bool
+	AmountOK bool
+}
+
+func checkTotals(o *database.Order) orderTotals {
+	var t orderTotals
+	for _, item := range o.Items {
+		t.Items += item.TotalPrice
+	}
+	t.Expected = t.Items + o.Payment.DeliveryCost + o.Payment.CustomFee
+	t.GoodsOK = math.Abs(t.Items-o.Payment.GoodsTotal) <= totalsTolerance
+	t.AmountOK = math.Abs(t.Expected-o.Payment.Amount) <= totalsTolerance
+	return t
+}
+
+// newUIPage заполняет общие данные страницы по запросу.
+func (s *Server) newUIPage(r *http.Request, title string) uiPage {
+	page := uiPage{Title: title, Masked: !s.canViewPII(r)}
+	if p, ok := PrincipalFrom(r.Context()); ok {
+		page.Caller = &p
+	}
+	return page
+}
+
+// UIOrdersHandler показывает последние заказы; параметры track и customer фильтруют список,
+// uid открывает карточку заказа.
+func (s *Server) UIOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if uid := strings.TrimSpace(q.Get("uid")); uid != "" {
+		http.Redirect(w, r, "/ui/orders/"+url.PathEscape(uid), http.StatusSeeOther)
+		return
+	}
+	page := ordersPage{
+		uiPage:   s.newUIPage(r, "Последние заказы"),
+		Track:    strings.TrimSpace(q.Get("track")),
+		Customer: strings.TrimSpace(q.Get("customer")),
+	}
+	if page.Track != "" || page.Customer != "" {
+		page.Title = "Результаты поиска"
+	}
+	orders, err := s.Store.List(r.Context(), database.OrderFilter{
+		TrackNumber: page.Track,
+		CustomerID:  page.Customer,
+		Limit:       uiRecentOrders + 1,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка получения списка заказов", logging.Err(err))
+		s.renderUIError(w, r, http.StatusServiceUnavailable, "Хранилище заказов недоступно, попробуйте позже.")
+		return
+	}
+	if len(orders) > uiRecentOrders {
+		orders, page.More = orders[:uiRecentOrders], true
+	}
+	page.Orders = make([]*database.Order, len(orders))
+	for i := range orders {
+		page.Orders[i] = s.presentOrder(r, &orders[i], page.Masked)
+	}
+	s.renderUI(w, r, http.StatusOK, "orders.html", page)
+}
+
+// UIOrderHandler показывает карточку заказа /ui/orders/{uid}.
+func (s *Server) UIOrderHandler(w http.ResponseWriter, r *http.Request) {
+	uid := r.PathValue("uid")
+	order, err := s.GetOrder(r.Context(), uid)
+	switch {
+	case err == nil:
+	case database.IsNotFound(err):
+		s.Audit.Record(r, AuditEvent{Action: AuditOrderRead, OrderUID: uid, Result: AuditNotFound})
+		s.renderUIError(w, r, http.StatusNotFound, fmt.Sprintf("Заказ %q не найден.", uid))
+		return
+	case database.IsUnavailable(err):
+		slog.WarnContext(r.Context(), "Ошибка получения заказа", slog.String(logging.KeyOrderUID, uid), logging.Err(err))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(err)))
+		s.renderUIError(w, r, http.StatusServiceUnavailable, "Хранилище заказов недоступно, попробуйте позже.")
+		return
+	default:
+		slog.ErrorContext(r.Context(), "Ошибка получения заказа", slog.String(logging.KeyOrderUID, uid), logging.Err(err))
+		s.renderUIError(w, r, http.StatusInternalServerError, "Не удалось показать заказ, попробуйте позже.")
+		return
+	}
+	page := orderPage{uiPage: s.newUIPage(r, "Заказ "+uid), Totals: checkTotals(order)}
+	page.Order = s.presentOrder(r, order, page.Masked)
+	s.renderUI(w, r, http.StatusOK, "order.html", page)
+}
+
+// presentOrder маскирует заказ для выдачи и записывает выдачу в журнал аудита.
+func (s *Server) presentOrder(r *http.Request, order *database.Order, masked bool) *database.Order {
+	s.Audit.Record(r, AuditEvent{Action: AuditOrderRead, OrderUID: order.OrderUID, Result: AuditOK, Masked: masked})
+	if masked {
+		return database.MaskOrder(order)
+	}
+	return order
+}
+
+// UILoginHandler проверяет введенный API-ключ или JWT и сохраняет его в cookie.
+func (s *Server) UILoginHandler(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSpace(r.PostFormValue("token"))
+	next := uiNext(r.PostFormValue("next"))
+	p, err := s.authenticator().AuthenticateHeader(http.Header{"Authorization": {"Bearer " + token}})
+	if err != nil {
+		slog.WarnContext(r.Context(), "Отказ во входе в интерфейс", logging.Err(err))
+		s.renderUI(w, r, http.StatusUnauthorized, "error.html", errorPage{
+			uiPage:  s.newUIPage(r, "Вход"),
+			Message: "Неверный ключ.",
+			Login:   true,
+			Next:    next,
+		})
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     uiTokenCookie,
+		Value:    token,
+		Path:     "/ui/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	slog.InfoContext(r.Context(), "Вход в интерфейс", slog.String("subject", p.Subject), slog.String("role", string(p.Role)))
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// UILogoutHandler удаляет cookie с ключом.
+func (s *Server) UILogoutHandler(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: uiTokenCookie, Path: "/ui/", MaxAge: -1, HttpOnly: true})
+	http.Redirect(w, r, "/ui/", http.StatusSeeOther)
+}
+
+// uiNext допускает возврат после входа только на страницы /ui/ этого сайта.
+func uiNext(next string) string {
+	if !strings.HasPrefix(next, "/ui/") || strings.ContainsAny(next, "\\\r\n") {
+		return "/ui/"
+	}
+	return next
+}
+
+// uiAuth определяет вызывающего по cookie, если в заголовках учетных данных не было,
+// и закрывает страницы для ролей без доступа к заказам: вместо 401/403 показывается
+// форма входа. Неверный ключ в cookie удаляется.
+func (s *Server) uiAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := PrincipalFrom(r.Context()); !ok {
+			if c, err := r.Cookie(uiTokenCookie); err == nil && c.Value != "" {
+				p, err := s.authenticator().AuthenticateHeader(http.Header{"Authorization": {"Bearer " + c.Value}})
+				if err == nil {
+					r = withCaller(r, p)
+				} else {
+					slog.WarnContext(r.Context(), "Отказ в аутентификации", logging.Err(err))
+					http.SetCookie(w, &http.Cookie{Name: uiTokenCookie, Path: "/ui/", MaxAge: -1, HttpOnly: true})
+				}
+			}
+		}
+		if s.Auth == nil {
+			next(w, r)
+			return
+		}
+		p, ok := PrincipalFrom(r.Context())
+		switch {
+		case !ok:
+			s.renderUI(w, r, http.StatusUnauthorized, "error.html", errorPage{
+				uiPage:  s.newUIPage(r, "Вход"),
+				Message: "Для просмотра заказов войдите с API-ключом или JWT.",
+				Login:   true,
+				Next:    uiNext(r.URL.RequestURI()),
+			})
+		case !p.Role.CanReadOrders():
+			s.renderUIError(w, r, http.StatusForbidden, fmt.Sprintf("Роли %s просмотр заказов недоступен.", p.Role))
+		default:
+			next(w, r)
+		}
+	}
+}
+
+func (s *Server) renderUIError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	s.renderUI(w, r, status, "error.html", errorPage{uiPage: s.newUIPage(r, http.StatusText(status)), Message: message})
+}
+
+// renderUI выполняет шаблон в буфер, чтобы ошибка шаблона не оставила половину страницы
+// с кодом 200.
+func (s *Server) renderUI(w http.ResponseWriter, r *http.Request, status int, name string, data any) {
+	var buf bytes.Buffer
+	if err := s.UI.ExecuteTemplate(&buf, name, data); err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка рендеринга страницы", slog.String("template", name), logging.Err(err))
+		http.Error(w, "template error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
@@ -0,0 +1,191 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockcache "github.com/mitrich772/go-order-service/internal/cache/mocks"
+	"github.com/mitrich772/go-order-service/internal/database"
+	"github.com/mitrich772/go-order-service/producer/generate"
+	"gorm.io/gorm"
+)
+
+// newUIServer собирает маршруты со страницами /ui/ из templates/ui.
+func newUIServer(t *testing.T, store *mockcache.MockOrderStore, auth bool, audit *bytes.Buffer) http.Handler {
+	t.Helper()
+	ui, err := ParseUI("../../templates/ui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &Server{Store: store, UI: ui, Audit: NewAuditLog(audit)}
+	if auth {
+		srv.Auth = NewAuthenticator(AuthConfig{APIKeys: map[string]Principal{
+			"support": {Subject: "support", Role: RoleSupport},
+			"analyst": {Subject: "analyst", Role: RoleAnalyst},
+			"partner": {Subject: "partner", Role: RoleIngest},
+		}})
+	}
+	return srv.Routes()
+}
+
+func uiGet(h http.Handler, target, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if key != "" {
+		req.Header.Set(apiKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func uiOrder(uid string) *database.Order {
+	o := generate.MakeOrder()
+	o.OrderUID = uid
+	return &o
+}
+
+// Проверяет: карточка заказа показывает доставку, оплату с проверкой сумм и товары,
+// значения экранируются, для analyst персональные данные скрыты
+func TestUIOrder_Details(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	order := uiOrder("uid-1")
+	order.Items[0].Name = `<script>alert(1)</script>`
+	store.EXPECT().Get(gomock.Any(), "uid-1").Return(order, nil).Times(2)
+	var audit bytes.Buffer
+	h := newUIServer(t, store, true, &audit)
+
+	w := uiGet(h, "/ui/orders/uid-1", "support")
+	body := w.Body.String()
+	phone := strings.TrimPrefix(order.Delivery.Phone, "+") // html/template экранирует "+"
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("ожидали 200 text/html, получили %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{order.TrackNumber, phone, order.Payment.Currency, "&lt;script&gt;", "✓ Сумма товаров"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("в карточке нет %q", want)
+		}
+	}
+	if strings.Contains(body, "<script>alert") {
+		t.Fatal("название товара должно экранироваться")
+	}
+
+	body = uiGet(h, "/ui/orders/uid-1", "analyst").Body.String()
+	if strings.Contains(body, phone) || !strings.Contains(body, "Персональные данные скрыты") {
+		t.Fatal("analyst должен видеть телефон с маской")
+	}
+	if n := strings.Count(audit.String(), `"action":"order.read"`); n != 2 {
+		t.Fatalf("ожидали 2 записи аудита, получили %d", n)
+	}
+}
+
+// Проверяет: расхождение сумм оплаты и товаров отмечается на карточке
+func TestCheckTotals(t *testing.T) {
+	order := uiOrder("uid-1")
+	if got := checkTotals(order); !got.GoodsOK || !got.AmountOK {
+		t.Fatalf("суммы сгенерированного заказа должны сходиться: %+v", got)
+	}
+	order.Payment.GoodsTotal += 10
+	order.Payment.Amount -= 1
+	if got := checkTotals(order); got.GoodsOK || got.AmountOK {
+		t.Fatalf("ожидали расхождение обеих сумм: %+v", got)
+	}
+}
+
+// Проверяет ошибки карточки: заказ не найден — 404, хранилище недоступно — 503
+func TestUIOrder_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().Get(gomock.Any(), "missing").Return(nil, gorm.ErrRecordNotFound)
+	store.EXPECT().Get(gomock.Any(), "down").Return(nil, context.DeadlineExceeded)
+	h := newUIServer(t, store, false, &bytes.Buffer{})
+
+	if w := uiGet(h, "/ui/orders/missing", ""); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "не найден") {
+		t.Fatalf("ожидали 404, получили %d", w.Code)
+	}
+	if w := uiGet(h, "/ui/orders/down", ""); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("ожидали 503, получили %d", w.Code)
+	}
+}
+
+// Проверяет: список передает фильтры поиска в хранилище, uid перенаправляет на карточку
+func TestUIOrders_Search(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().List(gomock.Any(), database.OrderFilter{TrackNumber: "WBTRACK", Limit: uiRecentOrders + 1}).
+		Return([]database.Order{*uiOrder("a"), *uiOrder("b")}, nil)
+	store.EXPECT().List(gomock.Any(), database.OrderFilter{Limit: uiRecentOrders + 1}).
+		Return(nil, errors.New("db down"))
+	h := newUIServer(t, store, false, &bytes.Buffer{})
+
+	w := uiGet(h, "/ui/?track=WBTRACK", "")
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, `href="/ui/orders/a"`) || !strings.Contains(body, `value="WBTRACK"`) {
+		t.Fatalf("неверный список: %d\n%s", w.Code, body)
+	}
+	if w := uiGet(h, "/ui/", ""); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("ожидали 503, получили %d", w.Code)
+	}
+	if w := uiGet(h, "/ui/?uid=a%2Fb", ""); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/ui/orders/a%2Fb" {
+		t.Fatalf("ожидали переход на карточку, получили %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+// Проверяет вход: без ключа — форма входа с 401, после входа ключ хранится в cookie
+// и возвращает на исходную страницу; чужая роль — 403; вход с другого сайта отклоняется
+func TestUI_Login(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockcache.NewMockOrderStore(ctrl)
+	store.EXPECT().Get(gomock.Any(), "uid-1").Return(uiOrder("uid-1"), nil)
+	h := newUIServer(t, store, true, &bytes.Buffer{})
+
+	w := uiGet(h, "/ui/orders/uid-1", "")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `action="/ui/login"`) {
+		t.Fatalf("ожидали форму входа с 401, получили %d", w.Code)
+	}
+
+	login := func(token, next string, header ...string) *httptest.ResponseRecorder {
+		form := url.Values{"token": {token}, "next": {next}}
+		req := httptest.NewRequest(http.MethodPost, "/ui/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	if w := login("wrong", "/ui/"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("неверный ключ: ожидали 401, получили %d", w.Code)
+	}
+	if w := login("support", "/ui/", "Sec-Fetch-Site", "cross-site"); w.Code != http.StatusForbidden {
+		t.Fatalf("вход с другого сайта: ожидали 403, получили %d", w.Code)
+	}
+	if w := login("support", "https://evil.example/"); w.Header().Get("Location") != "/ui/" {
+		t.Fatalf("возврат на чужой сайт: Location %q", w.Header().Get("Location"))
+	}
+
+	w = login("support", "/ui/orders/uid-1")
+	cookies := w.Result().Cookies()
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/ui/orders/uid-1" || len(cookies) != 1 || !cookies[0].HttpOnly {
+		t.Fatalf("ожидали переход с cookie, получили %d %q %v", w.Code, w.Header().Get("Location"), cookies)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/ui/orders/uid-1", nil)
+	req.AddCookie(cookies[0])
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "support (support)") {
+		t.Fatalf("с cookie ожидали карточку, получили %d", rec.Code)
+	}
+
+	if w := uiGet(h, "/ui/orders/uid-1", "partner"); w.Code != http.StatusForbidden {
+		t.Fatalf("роль ingest: ожидали 403, получили %d", w.Code)
+	}
+}
@@ -0,0 +1,10 @@
+-- Откат возможен только после расшифровки данных: зашифрованные значения не помещаются в VARCHAR.
+ALTER TABLE payments
+    ALTER COLUMN transaction TYPE VARCHAR(36),
+    ALTER COLUMN bank TYPE VARCHAR(50);
+
+ALTER TABLE deliveries
+    ALTER COLUMN name TYPE VARCHAR(100),
+    ALTER COLUMN phone TYPE VARCHAR(20),
+    ALTER COLUMN address TYPE VARCHAR(200),
+    ALTER COLUMN email TYPE VARCHAR(100);
@@ -0,0 +1,11 @@
+-- Зашифрованные значения (enc:v1:<key>:<base64>) длиннее исходных,
+-- поэтому персональные данные доставки и платежа хранятся в TEXT.
+ALTER TABLE deliveries
+    ALTER COLUMN name TYPE TEXT,
+    ALTER COLUMN phone TYPE TEXT,
+    ALTER COLUMN address TYPE TEXT,
+    ALTER COLUMN email TYPE TEXT;
+
+ALTER TABLE payments
+    ALTER COLUMN transaction TYPE TEXT,
+    ALTER COLUMN bank TYPE TEXT;
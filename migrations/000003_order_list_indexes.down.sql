@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_orders_track_number;
+DROP INDEX IF EXISTS idx_orders_customer_date;
+DROP INDEX IF EXISTS idx_orders_date_created;
@@ -0,0 +1,5 @@
+-- Выборки заказов (ListOrders, прогрев newest) идут от новых к старым с курсором
+-- (date_created, order_uid); фильтры — по покупателю и трек-номеру.
+CREATE INDEX IF NOT EXISTS idx_orders_date_created ON orders (date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_date ON orders (customer_id, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_track_number ON orders (track_number);
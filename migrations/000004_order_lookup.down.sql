@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_deliveries_email_lookup;
+DROP INDEX IF EXISTS idx_deliveries_phone_lookup;
+
+ALTER TABLE deliveries
+    DROP COLUMN IF EXISTS email_lookup,
+    DROP COLUMN IF EXISTS phone_lookup;
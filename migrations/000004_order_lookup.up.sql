@@ -0,0 +1,20 @@
+-- Поиск заказов по телефону и email. Значения могут храниться зашифрованными,
+-- поэтому ищется слепой индекс — хэш нормализованного значения (pii.LookupHash).
+-- Поиск по трек-номеру и покупателю использует индексы из 000003.
+ALTER TABLE deliveries
+    ADD COLUMN IF NOT EXISTS phone_lookup VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS email_lookup VARCHAR(64);
+
+-- Открытые значения заполняются здесь тем же хэшем, что и без шифрования в Go:
+-- sha256('lookup:' || значение), телефон — "+" и цифры, email — в нижнем регистре.
+-- Зашифрованные строки заполняет migrate -action rekey.
+UPDATE deliveries
+SET phone_lookup = encode(sha256(convert_to('lookup:+' || regexp_replace(phone, '[^0-9]', '', 'g'), 'UTF8')), 'hex')
+WHERE phone NOT LIKE 'enc:v1:%' AND regexp_replace(phone, '[^0-9]', '', 'g') <> '';
+
+UPDATE deliveries
+SET email_lookup = encode(sha256(convert_to('lookup:' || lower(trim(email)), 'UTF8')), 'hex')
+WHERE email NOT LIKE 'enc:v1:%' AND trim(email) <> '';
+
+CREATE INDEX IF NOT EXISTS idx_deliveries_phone_lookup ON deliveries (phone_lookup);
+CREATE INDEX IF NOT EXISTS idx_deliveries_email_lookup ON deliveries (email_lookup);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_deliveries_search;
+DROP INDEX IF EXISTS idx_items_search;
+
+ALTER TABLE deliveries DROP COLUMN IF EXISTS search;
+ALTER TABLE items DROP COLUMN IF EXISTS search;
+
+DROP TEXT SEARCH CONFIGURATION IF EXISTS order_search;
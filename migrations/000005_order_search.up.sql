@@ -0,0 +1,29 @@
+-- Полнотекстовый поиск по товарам и адресам доставки (SearchOrders).
+-- Конфигурация order_search стемминг-ует латиницу английским словарем, кириллицу — русским:
+-- локали заказов en и ru, а названия и бренды часто смешивают оба языка.
+CREATE TEXT SEARCH CONFIGURATION order_search (COPY = pg_catalog.russian);
+ALTER TEXT SEARCH CONFIGURATION order_search
+    ALTER MAPPING FOR asciiword, asciihword, hword_asciipart WITH english_stem;
+ALTER TEXT SEARCH CONFIGURATION order_search
+    ALTER MAPPING FOR word, hword, hword_part WITH russian_stem;
+
+-- Вес A — бренд, B — название товара.
+ALTER TABLE items
+    ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('order_search', coalesce(brand, '')), 'A') ||
+        setweight(to_tsvector('order_search', coalesce(name, '')), 'B')
+    ) STORED;
+
+-- Вес A — город, B — регион, D — адрес. Адрес — персональные данные: при включенном
+-- шифровании он хранится как enc:v1:… и в индекс не попадает, а вызывающим без доступа
+-- к персональным данным поиск по весу D недоступен (см. SearchOrders).
+ALTER TABLE deliveries
+    ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('order_search', coalesce(city, '')), 'A') ||
+        setweight(to_tsvector('order_search', coalesce(region, '')), 'B') ||
+        setweight(to_tsvector('order_search',
+            CASE WHEN address LIKE 'enc:v1:%' THEN '' ELSE coalesce(address, '') END), 'D')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_items_search ON items USING GIN (search);
+CREATE INDEX IF NOT EXISTS idx_deliveries_search ON deliveries USING GIN (search);
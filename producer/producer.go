@@ -5,16 +5,33 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"time"
 
+	orderkafka "github.com/mitrich772/go-order-service/internal/kafka"
+	"github.com/mitrich772/go-order-service/internal/logging"
+	"github.com/mitrich772/go-order-service/internal/tracing"
 	"github.com/mitrich772/go-order-service/producer/generate"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func main() {
 	kafkaPort := flag.String("port", "9092", "Порт Kafka")
+	traceExporter := flag.String("trace", "none", "Экспортер трассировки: none, stdout, otlp")
 	flag.Parse()
+
+	traces, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    *traceExporter,
+		Insecure:    true,
+		ServiceName: "order-producer",
+	})
+	if err != nil {
+		fatal("Ошибка настройки трассировки", err)
+	}
+	defer traces.Shutdown(context.Background())
+
 	fmt.Printf("Порт для Kafka: %s\n", *kafkaPort)
 	writer := &kafka.Writer{
 		Addr:     kafka.TCP("localhost:" + *kafkaPort),
@@ -28,19 +45,35 @@ func main() {
 
 	jsonData, err := json.Marshal(order)
 	if err != nil {
-		log.Fatal("Ошибка маршалинга JSON:", err)
+		fatal("Ошибка маршалинга JSON", err)
 	}
 
-	err = writer.WriteMessages(context.Background(),
-		kafka.Message{
-			Key:   []byte(order.OrderUID),
-			Value: jsonData,
-			Time:  time.Now(),
-		},
-	)
+	ctx, span := tracing.Start(context.Background(), "kafka.produce", trace.WithSpanKind(trace.SpanKindProducer))
+	msg := kafka.Message{
+		Key:   []byte(order.OrderUID),
+		Value: jsonData,
+		Time:  time.Now(),
+	}
+	// traceparent в заголовках связывает обработку заказа в сервисе с этой трассой,
+	// X-Request-ID попадает в логи сервиса как request_id
+	orderkafka.InjectTrace(ctx, &msg)
+	requestID := logging.NewRequestID()
+	orderkafka.HeaderCarrier{Headers: &msg.Headers}.Set(logging.HeaderRequestID, requestID)
+
+	err = writer.WriteMessages(ctx, msg)
+	tracing.End(span, err)
 	if err != nil {
-		log.Fatal("Ошибка при отправке сообщения:", err)
+		fatal("Ошибка при отправке сообщения", err)
 	}
 	fmt.Printf("Id: %s\n", order.OrderUID)
-	log.Println("Случайный заказ успешно отправлен в Kafka")
+	slog.Info("Случайный заказ успешно отправлен в Kafka",
+		slog.String(logging.KeyOrderUID, order.OrderUID),
+		slog.String(logging.KeyRequestID, requestID),
+	)
+}
+
+// fatal логирует ошибку и завершает программу.
+func fatal(msg string, err error) {
+	slog.Error(msg, logging.Err(err))
+	os.Exit(1)
 }
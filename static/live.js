@@ -0,0 +1,103 @@
+// Лента новых заказов: /orders/stream (SSE) или /orders/ws (WebSocket).
+// Браузер не передает заголовки в EventSource и WebSocket, поэтому API-ключ
+// отправляется параметром access_token.
+(() => {
+  const maxItems = 50;
+  const statusEl = document.getElementById("liveStatus");
+  const listEl = document.getElementById("liveOrders");
+  const button = document.getElementById("liveBtn");
+
+  let source = null; // EventSource или WebSocket
+  let lastEventId = "";
+
+  function setStatus(text) {
+    statusEl.textContent = text;
+  }
+
+  function params() {
+    const q = new URLSearchParams();
+    const filters = {
+      delivery_service: "liveService",
+      currency: "liveCurrency",
+      customer_id: "liveCustomer",
+    };
+    for (const [name, id] of Object.entries(filters)) {
+      const value = document.getElementById(id).value.trim();
+      if (value) q.set(name, value);
+    }
+    const apiKey = document.getElementById("apiKey").value.trim();
+    if (apiKey) q.set("access_token", apiKey);
+    if (lastEventId) q.set("last_event_id", lastEventId);
+    return q;
+  }
+
+  function addOrder(id, order) {
+    lastEventId = id;
+    const li = document.createElement("li");
+    const link = document.createElement("a");
+    link.href = `/ui/orders/${encodeURIComponent(order.order_uid)}`;
+    link.textContent = order.order_uid;
+    const total = order.payment ? `${order.payment.amount} ${order.payment.currency}` : "";
+    li.append(link, ` — ${order.delivery_service} — ${total} — ${order.date_created}`);
+    listEl.prepend(li);
+    while (listEl.children.length > maxItems) listEl.lastChild.remove();
+  }
+
+  function reset() {
+    listEl.replaceChildren();
+    setStatus("часть заказов пропущена, список начат заново");
+  }
+
+  function connectSSE() {
+    const es = new EventSource(`/orders/stream?${params()}`);
+    es.onopen = () => setStatus("подключено (SSE)");
+    es.onerror = () => setStatus("переподключение...");
+    es.addEventListener("order", (e) => addOrder(e.lastEventId, JSON.parse(e.data)));
+    es.addEventListener("reset", reset);
+    // после lagged сервер закрывает поток, EventSource переподключится с Last-Event-ID
+    es.addEventListener("lagged", () => setStatus("не успеваем за потоком, переподключение..."));
+    return es;
+  }
+
+  function connectWS() {
+    const proto = location.protocol === "https:" ? "wss" : "ws";
+    const ws = new WebSocket(`${proto}://${location.host}/orders/ws?${params()}`);
+    ws.onopen = () => setStatus("подключено (WebSocket)");
+    ws.onmessage = (e) => {
+      const msg = JSON.parse(e.data);
+      if (msg.type === "order") addOrder(msg.id, msg.order);
+      else if (msg.type === "reset") reset();
+    };
+    ws.onclose = (e) => {
+      if (source !== ws) return;
+      // 1013 — клиент не успевал читать, 1001 — сервис перезапускается
+      if (e.code === 1013 || e.code === 1001) {
+        setStatus("переподключение...");
+        setTimeout(() => {
+          if (source === ws) source = connectWS();
+        }, 3000);
+        return;
+      }
+      setStatus(`отключено (код ${e.code})`);
+      source = null;
+      button.textContent = "Подключиться";
+    };
+    return ws;
+  }
+
+  button.addEventListener("click", () => {
+    if (source) {
+      const s = source;
+      source = null;
+      s.close();
+      setStatus("не подключено");
+      button.textContent = "Подключиться";
+      return;
+    }
+    lastEventId = "";
+    listEl.replaceChildren();
+    const transport = document.getElementById("liveTransport").value;
+    source = transport === "ws" ? connectWS() : connectSSE();
+    button.textContent = "Отключиться";
+  });
+})();
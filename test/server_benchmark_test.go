@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"html/template"
 	"testing"
 
@@ -51,7 +52,7 @@ func BenchmarkGetOrder(b *testing.B) {
 	b.Run("with cache", func(b *testing.B) {
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
-			if _, err := serverWithCache.GetOrder(uid); err != nil {
+			if _, err := serverWithCache.GetOrder(context.Background(), uid); err != nil {
 				b.Fatalf("ошибка GetOrder с кешем: %v", err)
 			}
 		}
@@ -60,7 +61,7 @@ func BenchmarkGetOrder(b *testing.B) {
 	b.Run("without cache", func(b *testing.B) {
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
-			if _, err := serverWithoutCache.GetOrder(uid); err != nil {
+			if _, err := serverWithoutCache.GetOrder(context.Background(), uid); err != nil {
 				b.Fatalf("ошибка GetOrder без кеша: %v", err)
 			}
 		}